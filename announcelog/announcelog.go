@@ -0,0 +1,170 @@
+// Package announcelog records the provider announcements made by the node
+// so they can be inspected and followed as they happen.
+package announcelog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	routing "github.com/libp2p/go-libp2p-core/routing"
+)
+
+var log = logging.Logger("announcelog")
+
+// DefaultSize is the number of recent announcements kept by a Log.
+const DefaultSize = 256
+
+// subscriberBuffer is the number of announcements buffered for each
+// subscriber. Announcements to a subscriber that falls further behind are
+// dropped rather than stalling the provider.
+const subscriberBuffer = 64
+
+// Announcement is a single provide announcement.
+type Announcement struct {
+	Cid  cid.Cid
+	Time time.Time
+	// Peers are the peers that answered while the announcement was routed.
+	Peers []peer.ID
+	Err   error
+}
+
+// Log keeps the most recent announcements and fans new ones out to
+// subscribers.
+type Log struct {
+	mu     sync.Mutex
+	recent []Announcement
+	next   int
+	full   bool
+	subs   map[chan Announcement]struct{}
+	now    func() time.Time
+}
+
+// New returns a Log keeping the last DefaultSize announcements.
+func New() *Log {
+	return NewWithSize(DefaultSize)
+}
+
+// NewWithSize returns a Log keeping the last size announcements.
+func NewWithSize(size int) *Log {
+	if size < 1 {
+		size = 1
+	}
+	return &Log{
+		recent: make([]Announcement, size),
+		subs:   make(map[chan Announcement]struct{}),
+		now:    time.Now,
+	}
+}
+
+// Recent returns the recorded announcements, oldest first.
+func (l *Log) Recent() []Announcement {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.recentLocked()
+}
+
+func (l *Log) recentLocked() []Announcement {
+	if !l.full {
+		return append([]Announcement(nil), l.recent[:l.next]...)
+	}
+	out := make([]Announcement, 0, len(l.recent))
+	out = append(out, l.recent[l.next:]...)
+	return append(out, l.recent[:l.next]...)
+}
+
+// Subscribe returns the recorded announcements along with a channel
+// receiving every announcement made from now on. The channel is closed once
+// ctx is done.
+func (l *Log) Subscribe(ctx context.Context) ([]Announcement, <-chan Announcement) {
+	ch := make(chan Announcement, subscriberBuffer)
+
+	l.mu.Lock()
+	recent := l.recentLocked()
+	l.subs[ch] = struct{}{}
+	l.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		l.mu.Lock()
+		delete(l.subs, ch)
+		close(ch)
+		l.mu.Unlock()
+	}()
+	return recent, ch
+}
+
+// Record adds an announcement to the log.
+func (l *Log) Record(a Announcement) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.recent[l.next] = a
+	l.next++
+	if l.next == len(l.recent) {
+		l.next = 0
+		l.full = true
+	}
+
+	for ch := range l.subs {
+		select {
+		case ch <- a:
+		default:
+			log.Debugf("dropping announcement of %s for a slow subscriber", a.Cid)
+		}
+	}
+}
+
+// Router wraps r so that every call to Provide is recorded in l.
+func (l *Log) Router(r routing.ContentRouting) routing.ContentRouting {
+	return &router{ContentRouting: r, log: l}
+}
+
+type router struct {
+	routing.ContentRouting
+	log *Log
+}
+
+func (r *router) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	if !announce {
+		return r.ContentRouting.Provide(ctx, c, announce)
+	}
+
+	// the events are passed on to whoever was listening to them on ctx,
+	// such as 'ipfs dht provide'
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	ctx, events := routing.RegisterForQueryEvents(ctx)
+
+	peersCh := make(chan []peer.ID, 1)
+	go func() {
+		var peers []peer.ID
+		seen := make(map[peer.ID]struct{})
+		for e := range events {
+			routing.PublishQueryEvent(parent, e)
+			if e.Type != routing.PeerResponse {
+				continue
+			}
+			if _, ok := seen[e.ID]; !ok {
+				seen[e.ID] = struct{}{}
+				peers = append(peers, e.ID)
+			}
+		}
+		peersCh <- peers
+	}()
+
+	start := r.log.now()
+	err := r.ContentRouting.Provide(ctx, c, announce)
+	cancel()
+
+	r.log.Record(Announcement{
+		Cid:   c,
+		Time:  start,
+		Peers: <-peersCh,
+		Err:   err,
+	})
+	return err
+}
@@ -0,0 +1,153 @@
+package announcelog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	u "github.com/ipfs/go-ipfs-util"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	routing "github.com/libp2p/go-libp2p-core/routing"
+	"github.com/libp2p/go-libp2p-core/test"
+)
+
+// stubRouting answers Provide by reporting a response from each of its
+// peers, like a DHT walking towards the closest peers.
+type stubRouting struct {
+	routing.ContentRouting
+	peers []peer.ID
+	err   error
+}
+
+func (r *stubRouting) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	for _, p := range r.peers {
+		routing.PublishQueryEvent(ctx, &routing.QueryEvent{Type: routing.SendingQuery, ID: p})
+		routing.PublishQueryEvent(ctx, &routing.QueryEvent{Type: routing.PeerResponse, ID: p})
+	}
+	return r.err
+}
+
+func testCid(s string) cid.Cid {
+	return cid.NewCidV0(u.Hash([]byte(s)))
+}
+
+func TestFollowAnnouncements(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p1, err := test.RandPeerID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := test.RandPeerID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := New()
+	r := l.Router(&stubRouting{peers: []peer.ID{p1, p2, p1}})
+
+	recent, follow := l.Subscribe(ctx)
+	if len(recent) != 0 {
+		t.Fatalf("expected an empty log, got %d announcements", len(recent))
+	}
+
+	c := testCid("announced")
+	if err := r.Provide(ctx, c, true); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case a := <-follow:
+		if !a.Cid.Equals(c) {
+			t.Fatalf("expected announcement of %s, got %s", c, a.Cid)
+		}
+		if a.Time.IsZero() {
+			t.Fatal("expected the announcement time to be set")
+		}
+		if len(a.Peers) != 2 || a.Peers[0] != p1 || a.Peers[1] != p2 {
+			t.Fatalf("expected peers [%s %s], got %v", p1, p2, a.Peers)
+		}
+		if a.Err != nil {
+			t.Fatal(a.Err)
+		}
+	case <-ctx.Done():
+		t.Fatal("announcement never showed up")
+	}
+
+	// Not announcing to the network is not recorded.
+	if err := r.Provide(ctx, testCid("local"), false); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Recent(); len(got) != 1 {
+		t.Fatalf("expected 1 recorded announcement, got %d", len(got))
+	}
+
+	cancel()
+	for range follow {
+	}
+}
+
+func TestAnnouncementError(t *testing.T) {
+	l := New()
+	failure := errors.New("no peers")
+	r := l.Router(&stubRouting{err: failure})
+
+	if err := r.Provide(context.Background(), testCid("failed"), true); err != failure {
+		t.Fatalf("expected the routing error to be returned, got %v", err)
+	}
+	recent := l.Recent()
+	if len(recent) != 1 || recent[0].Err != failure {
+		t.Fatalf("expected the failed announcement to be recorded, got %v", recent)
+	}
+}
+
+func TestEventsPassedOn(t *testing.T) {
+	p, err := test.RandPeerID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New().Router(&stubRouting{peers: []peer.ID{p}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, events := routing.RegisterForQueryEvents(ctx)
+	go func() {
+		defer cancel()
+		if err := r.Provide(ctx, testCid("listened"), true); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	var got []routing.QueryEventType
+	for e := range events {
+		if e.ID != p {
+			t.Fatalf("expected an event about %s, got %s", p, e.ID)
+		}
+		got = append(got, e.Type)
+	}
+	if len(got) != 2 || got[0] != routing.SendingQuery || got[1] != routing.PeerResponse {
+		t.Fatalf("expected the query events to be passed on, got %v", got)
+	}
+}
+
+func TestRecentWrapsAround(t *testing.T) {
+	l := NewWithSize(3)
+	var cids []cid.Cid
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		c := testCid(s)
+		cids = append(cids, c)
+		l.Record(Announcement{Cid: c})
+	}
+
+	recent := l.Recent()
+	if len(recent) != 3 {
+		t.Fatalf("expected 3 announcements, got %d", len(recent))
+	}
+	for i, a := range recent {
+		if !a.Cid.Equals(cids[i+2]) {
+			t.Fatalf("announcement %d: expected %s, got %s", i, cids[i+2], a.Cid)
+		}
+	}
+}
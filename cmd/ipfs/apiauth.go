@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	cmdhttp "github.com/ipfs/go-ipfs-cmds/http"
+)
+
+// apiAuthEnv gives the token of --api-auth when the option isn't set.
+const apiAuthEnv = "IPFS_API_AUTH"
+
+func init() {
+	// The token is only sent in the Authorization header, never in the
+	// URL of the request where it could be logged.
+	cmdhttp.OptionSkipMap[corecmds.ApiAuthOption] = true
+}
+
+// apiAuthToken returns the bearer token to send to the API, "" for none.
+func apiAuthToken(req *cmds.Request) string {
+	if token, ok := req.Options[corecmds.ApiAuthOption].(string); ok {
+		return token
+	}
+	return os.Getenv(apiAuthEnv)
+}
+
+// authTransport sets the bearer token on every request.
+type authTransport struct {
+	token string
+	next  http.RoundTripper // http.DefaultTransport if nil
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	// RoundTrippers must not modify the request they are given.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return next.RoundTrip(req)
+}
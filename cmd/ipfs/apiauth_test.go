@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+	corehttp "github.com/ipfs/go-ipfs/core/corehttp"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	cmdhttp "github.com/ipfs/go-ipfs-cmds/http"
+)
+
+func TestApiAuthToken(t *testing.T) {
+	setenv := func(v string) {
+		if err := os.Setenv(apiAuthEnv, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old, had := os.LookupEnv(apiAuthEnv)
+	defer func() {
+		if had {
+			os.Setenv(apiAuthEnv, old)
+		} else {
+			os.Unsetenv(apiAuthEnv)
+		}
+	}()
+
+	setenv("")
+	if token := apiAuthToken(&cmds.Request{Options: cmds.OptMap{}}); token != "" {
+		t.Fatalf("expected no token, got %q", token)
+	}
+	setenv("from-env")
+	if token := apiAuthToken(&cmds.Request{Options: cmds.OptMap{}}); token != "from-env" {
+		t.Fatalf("expected the token of $%s, got %q", apiAuthEnv, token)
+	}
+	req := &cmds.Request{Options: cmds.OptMap{corecmds.ApiAuthOption: "from-option"}}
+	if token := apiAuthToken(req); token != "from-option" {
+		t.Fatalf("expected the option to win, got %q", token)
+	}
+}
+
+// authRecorder records the Authorization header and the query of the
+// requests it serves.
+type authRecorder struct {
+	auth, query []string
+}
+
+func (r *authRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.auth = append(r.auth, req.Header.Get("Authorization"))
+	r.query = append(r.query, req.URL.RawQuery)
+	http.Error(w, "nope", http.StatusForbidden)
+}
+
+func TestAuthTransport(t *testing.T) {
+	rec := &authRecorder{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "apiauth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "api.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usrv := &http.Server{Handler: rec}
+	go usrv.Serve(l)
+	defer usrv.Close()
+
+	for host, next := range map[string]http.RoundTripper{
+		ts.Listener.Addr().String(): nil,
+		"unix":                      apiTransport("unix", sock, 0, 0, 0, nil),
+	} {
+		req, err := cmds.NewRequest(context.Background(), []string{"version"}, cmds.OptMap{corecmds.ApiAuthOption: "s3cret"}, nil, nil, corecmds.Root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transport := &authTransport{token: apiAuthToken(req), next: next}
+		exe := cmdhttp.NewClient(host,
+			cmdhttp.ClientWithAPIPrefix(corehttp.APIPath),
+			cmdhttp.ClientWithHTTPClient(&http.Client{Transport: transport}),
+		)
+		re, _ := cmds.NewChanResponsePair(req)
+		exe.Execute(req, re, nil)
+	}
+
+	if len(rec.auth) != 2 {
+		t.Fatalf("expected a request over tcp and one over the unix socket, got %d", len(rec.auth))
+	}
+	for i := range rec.auth {
+		if rec.auth[i] != "Bearer s3cret" {
+			t.Errorf("expected the bearer token, got %q", rec.auth[i])
+		}
+		if strings.Contains(rec.query[i], "s3cret") {
+			t.Errorf("the token must not be in the URL: %s", rec.query[i])
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	ma "github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+func listenAPI(t *testing.T) (net.Listener, ma.Multiaddr) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := manet.FromNetAddr(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, addr
+}
+
+func TestSelectAPIAddrFailover(t *testing.T) {
+	down, downAddr := listenAPI(t)
+	down.Close()
+
+	up, upAddr := listenAPI(t)
+	defer up.Close()
+
+	addrs, err := parseAPIAddrs(downAddr.String() + ", " + upAddr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 {
+		t.Fatalf("expected two endpoints, got %v", addrs)
+	}
+
+	addr, _, err := selectAPIAddr(context.Background(), addrs, defaultAPIResolveTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !addr.Equal(upAddr) {
+		t.Fatalf("expected the second endpoint %s, got %s", upAddr, addr)
+	}
+
+	// the first endpoint is preferred when it's up
+	addr, _, err = selectAPIAddr(context.Background(), []ma.Multiaddr{upAddr, downAddr}, defaultAPIResolveTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !addr.Equal(upAddr) {
+		t.Fatalf("expected the first endpoint %s, got %s", upAddr, addr)
+	}
+
+	if _, _, err := selectAPIAddr(context.Background(), []ma.Multiaddr{downAddr, downAddr}, defaultAPIResolveTimeout); err == nil {
+		t.Fatal("expected an error with every endpoint down")
+	}
+}
+
+func TestSelectAPIAddrResolvedFailover(t *testing.T) {
+	defer func(r *madns.Resolver) { dnsResolver = r }(dnsResolver)
+
+	up, upAddr := listenAPI(t)
+	defer up.Close()
+	port, err := upAddr.ValueForProtocol(ma.P_TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the API listens on the second address the name resolves to
+	dnsResolver = &madns.Resolver{Backend: &madns.MockBackend{
+		IP: map[string][]net.IPAddr{
+			"example.com": {{IP: net.ParseIP("127.0.0.2")}, {IP: net.ParseIP("127.0.0.1")}},
+		},
+	}}
+	addr := ma.StringCast("/dns4/example.com/tcp/" + port)
+
+	_, resolved, err := selectAPIAddr(context.Background(), []ma.Multiaddr{addr}, defaultAPIResolveTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resolved.Equal(upAddr) {
+		t.Fatalf("expected the second resolved address %s, got %s", upAddr, resolved)
+	}
+
+	up.Close()
+	if _, _, err := selectAPIAddr(context.Background(), []ma.Multiaddr{addr}, defaultAPIResolveTimeout); err == nil {
+		t.Fatal("expected an error with every resolved address down")
+	}
+}
+
+func TestParseAPIAddrs(t *testing.T) {
+	for _, s := range []string{"", ",", "/ip4/127.0.0.1/tcp/5001,not-an-addr"} {
+		if _, err := parseAPIAddrs(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestApiAddrOption(t *testing.T) {
+	defer os.Setenv(EnvAPIAddr, os.Getenv(EnvAPIAddr))
+	os.Unsetenv(EnvAPIAddr)
+
+	req := &cmds.Request{Options: cmds.OptMap{}}
+	if addrs, err := apiAddrOption(req); err != nil || addrs != nil {
+		t.Fatalf("expected no endpoint, got %v (%v)", addrs, err)
+	}
+
+	os.Setenv(EnvAPIAddr, "/ip4/127.0.0.1/tcp/5002")
+	addrs, err := apiAddrOption(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0].String() != "/ip4/127.0.0.1/tcp/5002" {
+		t.Fatalf("expected the endpoint of $%s, got %v", EnvAPIAddr, addrs)
+	}
+
+	// the flag comes first
+	req.Options[corecmds.ApiOption] = "/ip4/127.0.0.1/tcp/5003"
+	addrs, err = apiAddrOption(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0].String() != "/ip4/127.0.0.1/tcp/5003" {
+		t.Fatalf("expected the endpoint of --%s, got %v", corecmds.ApiOption, addrs)
+	}
+
+	delete(req.Options, corecmds.ApiOption)
+	os.Setenv(EnvAPIAddr, "not-an-addr")
+	if _, err := apiAddrOption(req); err == nil || !strings.Contains(err.Error(), EnvAPIAddr) {
+		t.Fatalf("expected an error about $%s, got %v", EnvAPIAddr, err)
+	}
+}
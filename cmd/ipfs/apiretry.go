@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+	repo "github.com/ipfs/go-ipfs/repo"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// apiRetryBackoff is the delay before connecting to the API again the first
+// time, doubled on every following one. Declared as a var for testing
+// purposes.
+var apiRetryBackoff = 100 * time.Millisecond
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// apiRetries returns the number of times to connect to the API again when
+// it refuses connections, as requested with --api-retry. Streaming commands
+// that change state are never retried.
+func apiRetries(req *cmds.Request, details cmdDetails) int {
+	retries, _ := req.Options[corecmds.ApiRetryOption].(int)
+	if retries <= 0 || (details.streaming && !details.idempotent) {
+		return 0
+	}
+	return retries
+}
+
+// retryDial returns dial connecting again, up to retries times and waiting
+// longer every time, while the API refuses connections. Nothing is sent
+// before being connected, so the requests are never sent twice.
+func retryDial(dial dialFunc, retries int) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		backoff := apiRetryBackoff
+		conn, err := dial(ctx, network, addr)
+		for i := 0; i < retries && isConnRefused(err); i++ {
+			log.Debugf("connecting to the API again after: %s", err)
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, err
+			}
+			backoff *= 2
+
+			conn, err = dial(ctx, network, addr)
+		}
+		return conn, err
+	}
+}
+
+// waitAPIAddr reads the API file of the repo at repoPath like
+// fsrepo.APIAddr, waiting for it up to retries times, longer every time,
+// while the repo is locked without one: a daemon that is starting and didn't
+// open its API yet.
+func waitAPIAddr(repoPath string, retries int) (ma.Multiaddr, error) {
+	backoff := apiRetryBackoff
+	for i := 0; ; i++ {
+		addr, err := fsrepo.APIAddr(repoPath)
+		if err != repo.ErrApiNotRunning || i >= retries {
+			return addr, err
+		}
+		if locked, _ := fsrepo.LockedByOtherProcess(repoPath); !locked {
+			return addr, err
+		}
+		log.Debugf("waiting for the daemon holding the repo lock to open its API")
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isConnRefused reports whether err is the API not accepting connections,
+// like a daemon not listening yet, or whose unix socket isn't created yet.
+func isConnRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+	repo "github.com/ipfs/go-ipfs/repo"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	lockfile "github.com/ipfs/go-fs-lock"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestApiRetries(t *testing.T) {
+	req := &cmds.Request{Options: cmds.OptMap{}}
+	if n := apiRetries(req, cmdDetails{}); n != 0 {
+		t.Fatalf("expected no retries without --%s, got %d", corecmds.ApiRetryOption, n)
+	}
+
+	req.Options[corecmds.ApiRetryOption] = 3
+	if n := apiRetries(req, cmdDetails{}); n != 3 {
+		t.Fatalf("expected 3 retries, got %d", n)
+	}
+	if n := apiRetries(req, cmdDetails{streaming: true, idempotent: true}); n != 3 {
+		t.Fatalf("expected idempotent streaming commands to be retried, got %d retries", n)
+	}
+	if n := apiRetries(req, cmdDetails{streaming: true}); n != 0 {
+		t.Fatalf("expected streaming commands not to be retried, got %d retries", n)
+	}
+}
+
+func TestApiRetryDial(t *testing.T) {
+	defer func(backoff time.Duration) { apiRetryBackoff = backoff }(apiRetryBackoff)
+	apiRetryBackoff = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "apiretry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "api.sock")
+
+	client := &http.Client{Transport: apiTransport("unix", sock, 0, 0, 0, nil)}
+	if _, err := client.Get("http://unix/api/v0/version"); err == nil {
+		t.Fatal("expected the request to fail without daemon")
+	}
+
+	// the daemon starts listening after a while
+	ready := make(chan *http.Server)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		l, err := net.Listen("unix", sock)
+		if err != nil {
+			close(ready)
+			return
+		}
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})}
+		go srv.Serve(l)
+		ready <- srv
+	}()
+
+	client = &http.Client{Transport: apiTransport("unix", sock, 0, 0, 10, nil)}
+	resp, err := client.Get("http://unix/api/v0/version")
+	if srv := <-ready; srv != nil {
+		defer srv.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Fatalf("expected the daemon to answer, got %q", body)
+	}
+}
+
+func TestWaitAPIAddr(t *testing.T) {
+	defer func(backoff time.Duration) { apiRetryBackoff = backoff }(apiRetryBackoff)
+	apiRetryBackoff = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "apiretry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// no daemon holding the lock, nothing to wait for
+	if _, err := waitAPIAddr(dir, 10); err != repo.ErrApiNotRunning {
+		t.Fatalf("expected no API without a daemon, got %v", err)
+	}
+
+	// a starting daemon holds the lock, and writes its API file after a while
+	lk, err := lockfile.Lock(dir, fsrepo.LockFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lk.Close()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ioutil.WriteFile(filepath.Join(dir, "api"), []byte("/ip4/127.0.0.1/tcp/5001"), 0644)
+	}()
+
+	if _, err := waitAPIAddr(dir, 0); err != repo.ErrApiNotRunning {
+		t.Fatalf("expected no API without retries, got %v", err)
+	}
+	addr, err := waitAPIAddr(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.String() != "/ip4/127.0.0.1/tcp/5001" {
+		t.Fatalf("expected the API of the daemon, got %s", addr)
+	}
+}
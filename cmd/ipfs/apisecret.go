@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/ipfs/go-ipfs/core/corehttp"
+)
+
+// apiSecretEnv gives the secret sent to an API requiring one, see
+// corehttp.APISecretOption.
+const apiSecretEnv = "IPFS_API_SECRET"
+
+// secretTransport sets the API secret on every request.
+type secretTransport struct {
+	secret string
+	next   http.RoundTripper // http.DefaultTransport if nil
+}
+
+func (t *secretTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	// RoundTrippers must not modify the request they are given.
+	req = req.Clone(req.Context())
+	req.Header.Set(corehttp.APISecretHeader, t.secret)
+	return next.RoundTrip(req)
+}
+
+// apiSecret returns the secret to send to the API, "" for none.
+func apiSecret() string {
+	return os.Getenv(apiSecretEnv)
+}
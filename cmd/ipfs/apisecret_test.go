@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+	corehttp "github.com/ipfs/go-ipfs/core/corehttp"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+func TestAPISecretSent(t *testing.T) {
+	var secrets []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secrets = append(secrets, r.Header.Get(corehttp.APISecretHeader))
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer ts.Close()
+	addr, err := manet.FromNetAddr(ts.Listener.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "apisecret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	env := &oldcmds.Context{ConfigRoot: dir}
+
+	old, had := os.LookupEnv(apiSecretEnv)
+	defer func() {
+		if had {
+			os.Setenv(apiSecretEnv, old)
+		} else {
+			os.Unsetenv(apiSecretEnv)
+		}
+	}()
+
+	for _, secret := range []string{"s3cret", ""} {
+		if err := os.Setenv(apiSecretEnv, secret); err != nil {
+			t.Fatal(err)
+		}
+		req, err := cmds.NewRequest(context.Background(), []string{"id"}, cmds.OptMap{corecmds.ApiOption: addr.String()}, nil, nil, Root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exe, err := makeExecutor(req, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		re, _ := cmds.NewChanResponsePair(req)
+		exe.Execute(req, re, env)
+	}
+
+	if len(secrets) != 2 || secrets[0] != "s3cret" || secrets[1] != "" {
+		t.Fatalf("expected the secret to be sent only when $%s is set, got %q", apiSecretEnv, secrets)
+	}
+}
@@ -0,0 +1,54 @@
+// +build !windows
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckAPISocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apisocket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the dial reports a missing socket
+	sock := filepath.Join(dir, "api.sock")
+	if err := checkAPISocket(sock); err != nil {
+		t.Fatalf("expected a missing socket to be left to the dial, got %s", err)
+	}
+
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := checkAPISocket(sock); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "api")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkAPISocket(file); err == nil || !strings.Contains(err.Error(), "is not a socket") {
+		t.Fatalf("expected a regular file to be rejected, got %v", err)
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("root can connect to any socket")
+	}
+	if err := os.Chmod(sock, 0); err != nil {
+		t.Fatal(err)
+	}
+	err = checkAPISocket(sock)
+	if err == nil || !strings.Contains(err.Error(), "is not accessible: permission denied") {
+		t.Fatalf("expected the socket to be inaccessible, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// apiTimeoutOption returns the duration given with --api-timeout, zero when
+// the option isn't set.
+func apiTimeoutOption(req *cmds.Request) (time.Duration, error) {
+	s, ok := req.Options[corecmds.ApiTimeoutOption].(string)
+	if !ok {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s: %s", corecmds.ApiTimeoutOption, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("--%s must be positive", corecmds.ApiTimeoutOption)
+	}
+	return d, nil
+}
+
+// apiTransport returns the transport reaching the API listening on addr,
+// giving up on connecting after dialTimeout and on waiting for the headers of
+// a response after headerTimeout unless zero, connecting again up to retries
+// times while the API refuses connections, and over TLS with tlsConfig if not
+// nil. It returns nil for the default transport.
+//
+// The transfer of a response body isn't bound: once the daemon answered, a
+// large 'ipfs get' or 'ipfs cat' must not be cut short.
+func apiTransport(network, addr string, dialTimeout, headerTimeout time.Duration, retries int, tlsConfig *tls.Config) http.RoundTripper {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	dial := dialer.DialContext
+	if retries > 0 {
+		dial = retryDial(dial, retries)
+	}
+	if network == "unix" {
+		return &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dial(ctx, "unix", addr)
+			},
+			ResponseHeaderTimeout: headerTimeout,
+		}
+	}
+
+	if dialTimeout == 0 && headerTimeout == 0 && retries == 0 && tlsConfig == nil {
+		return nil
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = dial
+	t.ResponseHeaderTimeout = headerTimeout
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig
+		return &httpsTransport{next: t}
+	}
+	return t
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestApiTimeoutOption(t *testing.T) {
+	req := &cmds.Request{Options: cmds.OptMap{}}
+	if d, err := apiTimeoutOption(req); err != nil || d != 0 {
+		t.Fatalf("expected no timeout without --%s, got %s (%v)", corecmds.ApiTimeoutOption, d, err)
+	}
+
+	req.Options[corecmds.ApiTimeoutOption] = "30s"
+	if d, err := apiTimeoutOption(req); err != nil || d != 30*time.Second {
+		t.Fatalf("expected 30s, got %s (%v)", d, err)
+	}
+
+	for _, s := range []string{"soon", "0s", "-1m"} {
+		req.Options[corecmds.ApiTimeoutOption] = s
+		if _, err := apiTimeoutOption(req); err == nil {
+			t.Errorf("expected --%s=%s to be rejected", corecmds.ApiTimeoutOption, s)
+		}
+	}
+}
+
+func TestApiTransport(t *testing.T) {
+	if apiTransport("tcp", "127.0.0.1:5001", 0, 0, 0, nil) != nil {
+		t.Fatal("expected the default transport without timeout")
+	}
+
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	// the server waits for its handlers to return when closed
+	defer close(block)
+
+	client := &http.Client{
+		Transport: apiTransport("tcp", srv.Listener.Addr().String(), time.Second, 100*time.Millisecond, 0, nil),
+	}
+	start := time.Now()
+	if _, err := client.Get(srv.URL); err == nil {
+		t.Fatal("expected the request to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the request to give up after 100ms, took %s", elapsed)
+	}
+
+	// a body taking longer than the timeout to transfer is read whole
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("started "))
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("done"))
+	}))
+	defer slow.Close()
+	client.Transport = apiTransport("tcp", slow.Listener.Addr().String(), time.Second, 100*time.Millisecond, 0, nil)
+	resp, err := client.Get(slow.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "started done" {
+		t.Fatalf("expected the whole body, got %q (%v)", body, err)
+	}
+
+	dir, err := ioutil.TempDir("", "apitimeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "api.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usrv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})}
+	go usrv.Serve(l)
+	defer usrv.Close()
+
+	client = &http.Client{Transport: apiTransport("unix", sock, 0, 0, 0, nil)}
+	resp, err = client.Get("http://unix/api/v0/version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Fatalf("expected the unix socket server to answer, got %q", body)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// pTLS is the code of /tls, as in /dns4/example.com/tcp/443/tls/http.
+const pTLS = 0x01c0
+
+func init() {
+	// our version of go-multiaddr doesn't know /tls yet
+	if ma.ProtocolWithCode(pTLS).Code == 0 {
+		ma.AddProtocol(ma.Protocol{Name: "tls", Code: pTLS, VCode: ma.CodeToVarint(pTLS)})
+	}
+}
+
+// apiUsesTLS tells whether the API at addr is reached over TLS, with /tls or
+// /https.
+func apiUsesTLS(addr ma.Multiaddr) bool {
+	useTLS := false
+	ma.ForEach(addr, func(c ma.Component) bool {
+		switch c.Protocol().Code {
+		case pTLS, ma.P_HTTPS:
+			useTLS = true
+		case ma.P_UNIX:
+			// the path of a unix socket runs to the end of the multiaddr,
+			// the protocols after the socket being its last elements: the
+			// directories of the socket itself don't count
+			p := strings.TrimSuffix(c.Value(), "/http")
+			useTLS = strings.HasSuffix(p, "/tls") || strings.HasSuffix(p, "/https")
+		}
+		return !useTLS
+	})
+	return useTLS
+}
+
+// apiTLSConfig returns the TLS config to reach the API at addr, checking its
+// certificate against the CAs of the file given with --api-cacert if any, or
+// those of the system.
+func apiTLSConfig(req *cmds.Request, addr ma.Multiaddr) (*tls.Config, error) {
+	network, host, err := manet.DialArgs(addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		return nil, fmt.Errorf("TLS is not supported for unix socket API endpoints: %s", addr)
+	}
+	serverName, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{ServerName: serverName}
+
+	caFile, _ := req.Options[corecmds.ApiCACertOption].(string)
+	if caFile == "" {
+		return config, nil
+	}
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading --%s: %s", corecmds.ApiCACertOption, err)
+	}
+	config.RootCAs = x509.NewCertPool()
+	if !config.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", caFile)
+	}
+	return config, nil
+}
+
+// httpsTransport sends the requests of the API client, which always asks
+// for http:// URLs, with https://.
+type httpsTransport struct {
+	next http.RoundTripper
+}
+
+func (t *httpsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the request they are given.
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "https"
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+func TestApiUsesTLS(t *testing.T) {
+	for s, expected := range map[string]bool{
+		"/ip4/127.0.0.1/tcp/5001":            false,
+		"/dns4/example.com/tcp/443/https":    true,
+		"/dns4/example.com/tcp/443/tls/http": true,
+		"/unix/run/ipfs/api.sock":            false,
+		"/unix/run/ipfs/api.sock/tls/http":   true,
+		"/unix/run/ipfs/api.sock/https":      true,
+		"/unix/srv/tls/api.sock":             false,
+		"/unix/srv/https/api.sock/http":      false,
+	} {
+		if useTLS := apiUsesTLS(ma.StringCast(s)); useTLS != expected {
+			t.Errorf("%s: expected %t, got %t", s, expected, useTLS)
+		}
+	}
+
+	req := &cmds.Request{Options: cmds.OptMap{}}
+	if _, err := apiTLSConfig(req, ma.StringCast("/unix/run/ipfs/api.sock/tls/http")); err == nil {
+		t.Fatal("expected TLS over a unix socket to be rejected")
+	}
+}
+
+func TestApiTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "apitls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tcpAddr, err := manet.FromNetAddr(srv.Listener.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := tcpAddr.Encapsulate(ma.StringCast("/https"))
+	network, host, err := manet.DialArgs(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(req *cmds.Request) error {
+		config, err := apiTLSConfig(req, addr)
+		if err != nil {
+			return err
+		}
+		// the API client asks for http:// URLs
+		client := &http.Client{Transport: apiTransport(network, host, 0, 0, 0, config)}
+		resp, err := client.Get("http://" + host + "/api/v0/version")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if err := get(&cmds.Request{Options: cmds.OptMap{}}); err == nil {
+		t.Fatal("expected the self-signed certificate to be rejected without --api-cacert")
+	}
+	if err := get(&cmds.Request{Options: cmds.OptMap{corecmds.ApiCACertOption: caFile}}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestCommandsNoRepo(t *testing.T) {
+	req, err := cmds.NewRequest(context.Background(), []string{"commands"}, cmds.OptMap{"no-repo": true}, nil, nil, Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re, res := cmds.NewChanResponsePair(req)
+	go func() {
+		re.CloseWithError(cmds.NewExecutor(Root).Execute(req, re, nil))
+	}()
+	v, err := res.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := commandsClientCmd.Encoders[cmds.Text](req)(&buf).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	listed := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		listed[line] = true
+	}
+
+	for _, c := range []string{"ipfs cid base32", "ipfs dag car verify", "ipfs version"} {
+		if !listed[c] {
+			t.Errorf("expected %q, running without a repo, to be listed", c)
+		}
+	}
+	for _, c := range []string{"ipfs add", "ipfs dag", "ipfs dag get", "ipfs pin ls"} {
+		if listed[c] {
+			t.Errorf("expected %q, using the repo, not to be listed", c)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"runtime"
 	"sort"
+	"strings"
 	"sync"
 
 	multierror "github.com/hashicorp/go-multierror"
@@ -40,10 +41,12 @@ import (
 
 const (
 	adjustFDLimitKwd          = "manage-fdlimit"
+	announceKwd               = "announce"
 	enableGCKwd               = "enable-gc"
 	initOptionKwd             = "init"
 	initConfigOptionKwd       = "init-config"
 	initProfileOptionKwd      = "init-profile"
+	importRoutingTableKwd     = "import-routing-table"
 	ipfsMountKwd              = "mount-ipfs"
 	ipnsMountKwd              = "mount-ipns"
 	migrateKwd                = "migrate"
@@ -56,6 +59,7 @@ const (
 	routingOptionDHTServerKwd = "dhtserver"
 	routingOptionNoneKwd      = "none"
 	routingOptionDefaultKwd   = "default"
+	trackBlockAccessKwd       = "track-block-access"
 	unencryptTransportKwd     = "disable-transport-encryption"
 	unrestrictedApiAccessKwd  = "unrestricted-api"
 	writableKwd               = "writable"
@@ -175,6 +179,9 @@ Headers.
 		cmds.BoolOption(enablePubSubKwd, "Instantiate the ipfs daemon with the experimental pubsub feature enabled."),
 		cmds.BoolOption(enableIPNSPubSubKwd, "Enable IPNS record distribution through pubsub; enables pubsub."),
 		cmds.BoolOption(enableMultiplexKwd, "Add the experimental 'go-multiplex' stream muxer to libp2p on construction.").WithDefault(true),
+		cmds.StringsOption(announceKwd, "Address to announce instead of Addresses.Announce, for this run only. Can be passed multiple times."),
+		cmds.BoolOption(trackBlockAccessKwd, "Record when blocks are last accessed, for 'ipfs repo cold-blocks'."),
+		cmds.StringOption(importRoutingTableKwd, "Seed the DHT routing table from a snapshot saved with 'ipfs dht routingtable export', connecting to its peers in the background."),
 
 		// TODO: add way to override addresses. tricky part: updating the config if also --init.
 		// cmds.StringOption(apiAddrKwd, "Address for the daemon rpc API (overrides config)"),
@@ -225,6 +232,10 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 	}
 
 	cctx := env.(*oldcmds.Context)
+	plugins, err := cctx.GetPlugins()
+	if err != nil {
+		return err
+	}
 
 	// check transport encryption flag.
 	unencrypted, _ := req.Options[unencryptTransportKwd].(bool)
@@ -297,6 +308,8 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 	ipnsps, _ := req.Options[enableIPNSPubSubKwd].(bool)
 	pubsub, _ := req.Options[enablePubSubKwd].(bool)
 	mplex, _ := req.Options[enableMultiplexKwd].(bool)
+	announce, _ := req.Options[announceKwd].([]string)
+	trackAccess, _ := req.Options[trackBlockAccessKwd].(bool)
 
 	// Start assembling node config
 	ncfg := &core.BuildCfg{
@@ -304,10 +317,12 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 		Permanent:                   true, // It is temporary way to signify that node is permanent
 		Online:                      !offline,
 		DisableEncryptedConnections: unencrypted,
+		Announce:                    announce,
 		ExtraOpts: map[string]bool{
-			"pubsub": pubsub,
-			"ipnsps": ipnsps,
-			"mplex":  mplex,
+			"pubsub":     pubsub,
+			"ipnsps":     ipnsps,
+			"mplex":      mplex,
+			"blockatime": trackAccess,
 		},
 		//TODO(Kubuxu): refactor Online vs Offline by adding Permanent vs Ephemeral
 	}
@@ -371,11 +386,11 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 
 	// Start "core" plugins. We want to do this *before* starting the HTTP
 	// API as the user may be relying on these plugins.
-	err = cctx.Plugins.Start(node)
+	err = plugins.Start(node)
 	if err != nil {
 		return err
 	}
-	node.Process.AddChild(goprocess.WithTeardown(cctx.Plugins.Close))
+	node.Process.AddChild(goprocess.WithTeardown(plugins.Close))
 
 	// construct api endpoint - every time
 	apiErrc, err := serveHTTPApi(req, cctx)
@@ -400,6 +415,11 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 		return err
 	}
 
+	// seed the routing table - if the --import-routing-table flag is present
+	if err := maybeImportRoutingTable(req, node); err != nil {
+		return err
+	}
+
 	// construct http gateway
 	gwErrc, err := serveHTTPGateway(req, cctx)
 	if err != nil {
@@ -420,6 +440,7 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 
 	// initialize metrics collector
 	prometheus.MustRegister(&corehttp.IpfsNodeCollector{Node: node})
+	prometheus.MustRegister(corehttp.CommandDurationMetric)
 
 	// The daemon is *finally* ready.
 	fmt.Printf("Daemon is ready\n")
@@ -462,7 +483,7 @@ func serveHTTPApi(req *cmds.Request, cctx *oldcmds.Context) (<-chan error, error
 	if apiAddr == "" {
 		apiAddrs = cfg.Addresses.API
 	} else {
-		apiAddrs = append(apiAddrs, apiAddr)
+		apiAddrs = append(apiAddrs, strings.Split(apiAddr, ",")...)
 	}
 
 	listenerAddrs := make(map[string]bool, len(listeners))
@@ -509,8 +530,10 @@ func serveHTTPApi(req *cmds.Request, cctx *oldcmds.Context) (<-chan error, error
 	}
 
 	var opts = []corehttp.ServeOption{
+		corehttp.APISecretOption(),
 		corehttp.MetricsCollectionOption("api"),
 		corehttp.CheckVersionOption(),
+		corehttp.IdempotencyOption(corehttp.DefaultIdempotencyWindow),
 		corehttp.CommandsOption(*cctx),
 		corehttp.WebUIOption,
 		gatewayOpt,
@@ -638,22 +661,8 @@ func serveHTTPGateway(req *cmds.Request, cctx *oldcmds.Context) (<-chan error, e
 	cmdctx := *cctx
 	cmdctx.Gateway = true
 
-	var opts = []corehttp.ServeOption{
-		corehttp.MetricsCollectionOption("gateway"),
-		corehttp.HostnameOption(),
-		corehttp.GatewayOption(writable, "/ipfs", "/ipns"),
-		corehttp.VersionOption(),
-		corehttp.CheckVersionOption(),
-		corehttp.CommandsROOption(cmdctx),
-	}
-
-	if cfg.Experimental.P2pHttpProxy {
-		opts = append(opts, corehttp.P2PProxyOption())
-	}
-
-	if len(cfg.Gateway.RootRedirect) > 0 {
-		opts = append(opts, corehttp.RedirectOption("", cfg.Gateway.RootRedirect))
-	}
+	// 'ipfs gateway config' reports the handlers of these same options
+	opts := corehttp.GatewayServeOptions(cfg, writable, cmdctx)
 
 	node, err := cctx.ConstructNode()
 	if err != nil {
@@ -723,6 +732,34 @@ func maybeRunGC(req *cmds.Request, node *core.IpfsNode) (<-chan error, error) {
 	return errc, nil
 }
 
+// maybeImportRoutingTable connects the node to the peers of the routing table
+// snapshot given with --import-routing-table, in the background.
+func maybeImportRoutingTable(req *cmds.Request, node *core.IpfsNode) error {
+	path, _ := req.Options[importRoutingTableKwd].(string)
+	if path == "" {
+		return nil
+	}
+	if !node.IsOnline || node.DHT == nil {
+		return cmds.Errorf(cmds.ErrClient, "--%s requires the DHT, which the daemon doesn't run", importRoutingTableKwd)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	peers, err := commands.ReadRoutingTableSnapshot(f)
+	if err != nil {
+		return fmt.Errorf("invalid routing table snapshot %s: %s", path, err)
+	}
+
+	go func() {
+		out := commands.ImportRoutingTable(req.Context, node, peers)
+		log.Infof("seeded the routing table from %s: connected to %d of %d peers", path, out.Connected, out.Peers)
+	}()
+	return nil
+}
+
 // merge does fan-in of multiple read-only error channels
 // taken from http://blog.golang.org/pipelines
 func merge(cs ...<-chan error) <-chan error {
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+)
+
+// withDeadline returns ctx with the deadline passed with --deadline, if any.
+// Deadlines that already passed are rejected rather than failing the command
+// halfway.
+func withDeadline(ctx context.Context, args []string, now time.Time) (context.Context, context.CancelFunc, error) {
+	val, found, err := rootOptionArg(args, corecmds.DeadlineOption)
+	if err != nil || !found {
+		return ctx, func() {}, err
+	}
+
+	deadline, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid deadline %q, expected a time like 2006-01-02T15:04:05Z07:00", val)
+	}
+	if !deadline.After(now) {
+		return nil, nil, fmt.Errorf("deadline %s is in the past", val)
+	}
+
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	return ctx, cancel, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithDeadline(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	ctx, cancel, err := withDeadline(context.Background(), []string{"ipfs", "id"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("expected no deadline without --deadline")
+	}
+
+	// near future, relative to the real clock so the context stays live
+	soon := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	ctx, cancel, err = withDeadline(context.Background(), []string{"ipfs", "--deadline", soon.Format(time.RFC3339), "id"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok || !deadline.Equal(soon) {
+		t.Fatalf("expected deadline %s, got %s (set: %t)", soon, deadline, ok)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("context should still be live, got %s", ctx.Err())
+	}
+
+	for _, args := range [][]string{
+		{"ipfs", "id", "--deadline=2023-12-31T23:59:59Z"},
+		{"ipfs", "id", "--deadline=2024-01-01T00:00:00Z"},
+		{"ipfs", "id", "--deadline=tomorrow"},
+		{"ipfs", "id", "--deadline"},
+	} {
+		if _, _, err := withDeadline(context.Background(), args, now); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"net"
 	"strings"
 	"testing"
+	"time"
 
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
 	ma "github.com/multiformats/go-multiaddr"
 	madns "github.com/multiformats/go-multiaddr-dns"
 )
@@ -35,34 +39,39 @@ func makeResolver(n uint8) *madns.Resolver {
 func TestApiEndpointResolveDNSOneResult(t *testing.T) {
 	dnsResolver = makeResolver(1)
 
-	addr, err := resolveAddr(ctx, testAddr)
+	addrs, err := resolveAddr(ctx, testAddr, defaultAPIResolveTimeout)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if ref, _ := ma.NewMultiaddr("/ip4/192.0.2.0/tcp/5001"); !addr.Equal(ref) {
-		t.Errorf("resolved address was different than expected")
+	if ref, _ := ma.NewMultiaddr("/ip4/192.0.2.0/tcp/5001"); len(addrs) != 1 || !addrs[0].Equal(ref) {
+		t.Errorf("resolved addresses were different than expected: %v", addrs)
 	}
 }
 
 func TestApiEndpointResolveDNSMultipleResults(t *testing.T) {
 	dnsResolver = makeResolver(4)
 
-	addr, err := resolveAddr(ctx, testAddr)
+	addrs, err := resolveAddr(ctx, testAddr, defaultAPIResolveTimeout)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if ref, _ := ma.NewMultiaddr("/ip4/192.0.2.0/tcp/5001"); !addr.Equal(ref) {
-		t.Errorf("resolved address was different than expected")
+	if len(addrs) != 4 {
+		t.Fatalf("expected the 4 resolved addresses, got %v", addrs)
+	}
+	for i, addr := range addrs {
+		if ref, _ := ma.NewMultiaddr(fmt.Sprintf("/ip4/192.0.2.%d/tcp/5001", i)); !addr.Equal(ref) {
+			t.Errorf("resolved address %d was different than expected: %s", i, addr)
+		}
 	}
 }
 
 func TestApiEndpointResolveDNSNoResults(t *testing.T) {
 	dnsResolver = makeResolver(0)
 
-	addr, err := resolveAddr(ctx, testAddr)
-	if addr != nil || err == nil {
+	addrs, err := resolveAddr(ctx, testAddr, defaultAPIResolveTimeout)
+	if addrs != nil || err == nil {
 		t.Error("expected test address not to resolve, and to throw an error")
 	}
 
@@ -70,3 +79,47 @@ func TestApiEndpointResolveDNSNoResults(t *testing.T) {
 		t.Errorf("expected error not thrown; actual: %v", err)
 	}
 }
+
+// hangingBackend never answers before the context is done.
+type hangingBackend struct{}
+
+func (hangingBackend) LookupIPAddr(ctx context.Context, _ string) ([]net.IPAddr, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (hangingBackend) LookupTXT(ctx context.Context, _ string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestApiEndpointResolveDNSTimeout(t *testing.T) {
+	dnsResolver = &madns.Resolver{Backend: hangingBackend{}}
+
+	start := time.Now()
+	if _, err := resolveAddr(ctx, testAddr, 50*time.Millisecond); err == nil {
+		t.Fatal("expected the resolution to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the resolution to give up after 50ms, took %s", elapsed)
+	}
+}
+
+func TestApiResolveTimeoutOption(t *testing.T) {
+	req := &cmds.Request{Options: cmds.OptMap{}}
+	if d, err := apiResolveTimeoutOption(req); err != nil || d != defaultAPIResolveTimeout {
+		t.Fatalf("expected the default timeout without --%s, got %s (%v)", corecmds.ApiResolveTimeoutOption, d, err)
+	}
+
+	req.Options[corecmds.ApiResolveTimeoutOption] = "30s"
+	if d, err := apiResolveTimeoutOption(req); err != nil || d != 30*time.Second {
+		t.Fatalf("expected 30s, got %s (%v)", d, err)
+	}
+
+	for _, s := range []string{"soon", "0s", "-1m"} {
+		req.Options[corecmds.ApiResolveTimeoutOption] = s
+		if _, err := apiResolveTimeoutOption(req); err == nil {
+			t.Errorf("expected --%s=%s to be rejected", corecmds.ApiResolveTimeoutOption, s)
+		}
+	}
+}
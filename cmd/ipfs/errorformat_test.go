@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestErrorFormat(t *testing.T) {
+	for _, c := range []struct {
+		args   []string
+		format string
+		fails  bool
+	}{
+		{[]string{"ipfs", "id"}, errorFormatText, false},
+		{[]string{"ipfs", "--error-format=json", "id"}, errorFormatJSON, false},
+		{[]string{"ipfs", "--error-format", "text", "id"}, errorFormatText, false},
+		{[]string{"ipfs", "--error-format=xml", "id"}, errorFormatText, true},
+	} {
+		format, err := errorFormat(c.args)
+		if (err != nil) != c.fails || format != c.format {
+			t.Errorf("%v: expected %s (fails: %t), got %s (%v)", c.args, c.format, c.fails, format, err)
+		}
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	var buf bytes.Buffer
+	writeError(&buf, errorFormatText, errors.New("no repo"))
+	if buf.String() != "Error: no repo\n" {
+		t.Fatalf("unexpected text error %q", buf.String())
+	}
+
+	buf.Reset()
+	writeError(&buf, errorFormatJSON, errors.New(`no "repo"`))
+	if expected := `{"Message":"no \"repo\"","Code":0,"Type":"error"}` + "\n"; buf.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	writeError(&buf, errorFormatJSON, cmds.Errorf(cmds.ErrClient, "bad argument"))
+	if expected := `{"Message":"bad argument","Code":1,"Type":"error"}` + "\n"; buf.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestCopyJSONErrors(t *testing.T) {
+	in := "Error: argument \"ipfs-path\" is required\n\nUSAGE\n  ipfs cat <ipfs-path>...\nError: no newline"
+	var buf bytes.Buffer
+	copyJSONErrors(&buf, strings.NewReader(in))
+	expected := `{"Message":"argument \"ipfs-path\" is required","Code":0,"Type":"error"}` + "\n" +
+		"\nUSAGE\n  ipfs cat <ipfs-path>...\n" +
+		`{"Message":"no newline","Code":0,"Type":"error"}` + "\n"
+	if buf.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buf.String())
+	}
+}
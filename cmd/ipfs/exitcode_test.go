@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestClassifyError(t *testing.T) {
+	for _, c := range []struct {
+		err    error
+		parsed bool
+		code   int
+	}{
+		{errors.New("Unknown Command \"fo\"\n\nDid you mean this?\n\n\tfiles"), false, ExitCommandNotFound},
+		{errors.New(`argument "ipfs-path" is required`), false, ExitUsage},
+		{errors.New(`unknown option "bogus"`), false, ExitUsage},
+		{cmds.Errorf(cmds.ErrClient, "invalid peer ID"), true, ExitUsage},
+		{cmds.Error{Message: "invalid encoding", Code: cmds.ErrClient}, true, ExitUsage},
+		{errors.New("cannot connect to the api. Is the daemon running?"), true, ExitDaemonUnreachable},
+		{daemonUnreachable(errors.New("no API endpoint reachable")), true, ExitDaemonUnreachable},
+		{usageError(errors.New("--api cannot be used with --offline")), true, ExitUsage},
+		{errors.New("repo locked"), true, ExitInternal},
+		{cmds.Errorf(cmds.ErrNormal, "not found"), true, ExitInternal},
+	} {
+		cerr := classifyError(c.err, c.parsed)
+		if cerr.ExitCode != c.code {
+			t.Errorf("%q (parsed: %t): expected exit code %d, got %d", c.err, c.parsed, c.code, cerr.ExitCode)
+		}
+		if cerr.Error() != c.err.Error() {
+			t.Errorf("expected the message %q to be kept, got %q", c.err, cerr)
+		}
+	}
+}
+
+func TestMakeExecutorExitCodes(t *testing.T) {
+	// no daemon runs on the repo
+	dir, err := ioutil.TempDir("", "exitcode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	env := &oldcmds.Context{ConfigRoot: dir}
+	down, downAddr := listenAPI(t)
+	down.Close()
+
+	for _, c := range []struct {
+		path []string
+		opts cmds.OptMap
+		code int
+	}{
+		{[]string{"cat"}, cmds.OptMap{corecmds.NoDaemonOption: true, corecmds.ApiOption: downAddr.String()}, ExitUsage},
+		{[]string{"log"}, cmds.OptMap{corecmds.NoDaemonOption: true}, ExitUsage},
+		{[]string{"cat"}, cmds.OptMap{corecmds.ApiOption: downAddr.String(), corecmds.ApiTimeoutOption: "soon"}, ExitUsage},
+		{[]string{"log"}, cmds.OptMap{}, ExitDaemonUnreachable},
+		// several endpoints are probed, all down
+		{[]string{"cat"}, cmds.OptMap{corecmds.ApiOption: downAddr.String() + "," + downAddr.String()}, ExitDaemonUnreachable},
+	} {
+		req, err := cmds.NewRequest(context.Background(), c.path, c.opts, nil, nil, Root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = makeExecutor(req, env)
+		name := fmt.Sprintf("%v %v", c.path, c.opts)
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+			continue
+		}
+		if code := classifyError(err, true).ExitCode; code != c.code {
+			t.Errorf("%s: expected exit code %d, got %d (%s)", name, c.code, code, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/go-ipfs-cmds/cli"
+)
+
+func TestExternalCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub command is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "external-commands")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the stub records the arguments it is run with
+	argsFile := filepath.Join(dir, "args")
+	stub := filepath.Join(dir, "mytool")
+	script := "#!/bin/sh\nfor a in \"$@\"; do echo \"$a\"; done > " + argsFile + "\n"
+	if err := ioutil.WriteFile(stub, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"ExternalCommands": {"mytool": "` + stub + `", "add": "` + stub + `"}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "config"), []byte(cfg), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"ipfs", "-c", dir, "mytool", "sub", "--flag", "a b"}
+	root, err := withExternalCommands(Root, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Subcommands["add"] != Root.Subcommands["add"] {
+		t.Fatal("external commands must not replace built-in ones")
+	}
+	if _, found := Root.Subcommands["mytool"]; found {
+		t.Fatal("the CLI root should not be modified")
+	}
+
+	out, err := ioutil.TempFile(dir, "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	buildEnv := func(context.Context, *cmds.Request) (cmds.Environment, error) {
+		return nil, nil
+	}
+	makeExecutor := func(req *cmds.Request, _ interface{}) (cmds.Executor, error) {
+		return cmds.NewExecutor(req.Root), nil
+	}
+	if err := cli.Run(context.Background(), root, args, os.Stdin, out, out, buildEnv, makeExecutor); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "sub\n--flag\na b\n"; string(got) != expected {
+		t.Fatalf("expected the stub to get %q, got %q", expected, got)
+	}
+
+	// without a repo there are no external commands
+	root, err = withExternalCommands(Root, []string{"ipfs", "--config=" + filepath.Join(dir, "missing"), "id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != Root {
+		t.Fatal("expected the CLI root without a config")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "config"), []byte(`{"ExternalCommands": {"mytool": ""}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := withExternalCommands(Root, args); err == nil || !strings.Contains(err.Error(), "mytool") {
+		t.Fatalf("expected an error for an empty binary, got %v", err)
+	}
+}
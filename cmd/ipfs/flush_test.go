@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestFlushTimeoutOption(t *testing.T) {
+	d, err := flushTimeoutOption(&cmds.Request{Options: cmds.OptMap{}})
+	if err != nil || d != 0 {
+		t.Fatalf("expected the default without --flush-timeout, got %s, %v", d, err)
+	}
+
+	d, err = flushTimeoutOption(&cmds.Request{Options: cmds.OptMap{corecmds.FlushTimeoutOption: "10s"}})
+	if err != nil || d != 10*time.Second {
+		t.Fatalf("expected 10s, got %s, %v", d, err)
+	}
+
+	for _, v := range []string{"soon", "0s", "-1s"} {
+		if _, err := flushTimeoutOption(&cmds.Request{Options: cmds.OptMap{corecmds.FlushTimeoutOption: v}}); err == nil {
+			t.Errorf("%s: expected an error", v)
+		}
+	}
+}
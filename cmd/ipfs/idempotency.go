@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+
+	corehttp "github.com/ipfs/go-ipfs/core/corehttp"
+)
+
+// idempotencyTransport sets the idempotency key header on every request.
+type idempotencyTransport struct {
+	key  string
+	next http.RoundTripper // http.DefaultTransport if nil
+}
+
+func (t *idempotencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	// RoundTrippers must not modify the request they are given.
+	req = req.Clone(req.Context())
+	req.Header.Set(corehttp.IdempotencyKeyHeader, t.key)
+	return next.RoundTrip(req)
+}
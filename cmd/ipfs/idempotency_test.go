@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corehttp "github.com/ipfs/go-ipfs/core/corehttp"
+)
+
+func TestIdempotencyTransport(t *testing.T) {
+	applied := 0
+	root := http.NewServeMux()
+	mux, err := corehttp.IdempotencyOption(time.Minute)(nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		applied++
+		w.Write([]byte("applied"))
+	})
+	ts := httptest.NewServer(root)
+	defer ts.Close()
+
+	c := &http.Client{Transport: &idempotencyTransport{key: "key"}}
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+corehttp.APIPath+"/pin/add", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status %d", resp.StatusCode)
+		}
+		if req.Header.Get(corehttp.IdempotencyKeyHeader) != "" {
+			t.Fatal("the transport must not modify the caller's request")
+		}
+	}
+	if applied != 1 {
+		t.Fatalf("expected the request to be applied once, got %d", applied)
+	}
+}
@@ -106,6 +106,11 @@ environment variable:
 			}
 		}
 
+		// the datastores of the repo are provided by plugins
+		if _, err := cctx.GetPlugins(); err != nil {
+			return err
+		}
+
 		profiles, _ := req.Options[profileOptionName].(string)
 		return doInit(os.Stdout, cctx.ConfigRoot, empty, nBitsForKeypair, profiles, conf)
 	},
@@ -3,10 +3,10 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"math/rand"
-	"net"
 	"net/http"
 	"os"
 	"runtime/pprof"
@@ -42,22 +42,49 @@ var dnsResolver = madns.DefaultResolver
 
 const (
 	EnvEnableProfiling = "IPFS_PROF"
+	EnvAPIAddr         = "IPFS_API"
+	EnvPluginTimeout   = "IPFS_PLUGIN_TIMEOUT"
 	cpuProfile         = "ipfs.cpuprof"
 	heapProfile        = "ipfs.memprof"
 )
 
-func loadPlugins(repoPath string) (*loader.PluginLoader, error) {
+// loadPlugins loads, initializes and injects the plugins of the repo at
+// repoPath, but the ones named in disabled.
+func loadPlugins(repoPath string, disabled []string) (*loader.PluginLoader, error) {
 	plugins, err := loader.NewPluginLoader(repoPath)
 	if err != nil {
 		return nil, fmt.Errorf("error loading plugins: %s", err)
 	}
 
-	if err := plugins.Initialize(); err != nil {
-		return nil, fmt.Errorf("error initializing plugins: %s", err)
+	unknown, err := plugins.Disable(disabled...)
+	if err != nil {
+		return nil, err
+	}
+	// a typo mustn't keep the daemon from starting
+	for _, name := range unknown {
+		fmt.Fprintf(os.Stderr, "Warning: --%s=%s matches no plugin, ignoring it\n", corecmds.DisablePluginOption, name)
 	}
 
-	if err := plugins.Inject(); err != nil {
-		return nil, fmt.Errorf("error initializing plugins: %s", err)
+	timeout, err := pluginTimeout()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, pl := range plugins.Plugins() {
+		names = append(names, pl.Name)
+	}
+	err = initPlugins(names, timeout, func() error {
+		if err := plugins.Initialize(); err != nil {
+			return fmt.Errorf("error initializing plugins: %s", pluginError(plugins, err))
+		}
+
+		if err := plugins.Inject(); err != nil {
+			return fmt.Errorf("error initializing plugins: %s", pluginError(plugins, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return plugins, nil
 }
@@ -79,11 +106,16 @@ func mainRet() int {
 
 	// we'll call this local helper to output errors.
 	// this is so we control how to print errors in one place.
+	errFormat, formatErr := errorFormat(os.Args)
 	printErr := func(err error) {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		writeError(os.Stderr, errFormat, err)
+	}
+	if formatErr != nil {
+		printErr(formatErr)
+		return 1
 	}
 
-	stopFunc, err := profileIfEnabled()
+	stopFunc, err := profileIfEnabled(os.Args)
 	if err != nil {
 		printErr(err)
 		return 1
@@ -122,7 +154,10 @@ func mainRet() int {
 	// so we need to make sure it's stable
 	os.Args[0] = "ipfs"
 
+	// cli.Run only builds the environment once the command line is parsed
+	var parsed bool
 	buildEnv := func(ctx context.Context, req *cmds.Request) (cmds.Environment, error) {
+		parsed = true
 		checkDebug(req)
 		repoPath, err := getRepoPath(req)
 		if err != nil {
@@ -130,46 +165,118 @@ func mainRet() int {
 		}
 		log.Debugf("config path is %s", repoPath)
 
-		plugins, err := loadPlugins(repoPath)
+		overrides, _ := req.Options[corecmds.WithConfigOption].([]string)
+
+		disabledPlugins, _ := req.Options[corecmds.DisablePluginOption].([]string)
+		loadRepoPlugins := func() (*loader.PluginLoader, error) {
+			return loadPlugins(repoPath, disabledPlugins)
+		}
+		// the commands not using the repo only load the plugins if they
+		// construct a node or decode blocks, the others may open the repo,
+		// its datastore provided by a plugin, without constructing one.
+		var plugins *loader.PluginLoader
+		if details := commandDetails(req.Path); details.loadsPlugins() {
+			if plugins, err = loadRepoPlugins(); err != nil {
+				return nil, err
+			}
+		}
+
+		flushTimeout, err := flushTimeoutOption(req)
+		if err != nil {
+			return nil, err
+		}
+
+		maxMemory, err := maxMemoryOption(req)
 		if err != nil {
 			return nil, err
 		}
 
 		// this sets up the function that will initialize the node
 		// this is so that we can construct the node lazily.
-		return &oldcmds.Context{
-			ConfigRoot: repoPath,
-			LoadConfig: loadConfig,
-			ReqLog:     &oldcmds.ReqLog{},
-			Plugins:    plugins,
-			ConstructNode: func() (n *core.IpfsNode, err error) {
-				if req == nil {
-					return nil, errors.New("constructing node without a request")
-				}
+		cctx := &oldcmds.Context{
+			ConfigRoot:   repoPath,
+			LoadConfig:   configLoader(overrides),
+			ReqLog:       &oldcmds.ReqLog{},
+			Plugins:      plugins,
+			LoadPlugins:  loadRepoPlugins,
+			FlushTimeout: flushTimeout,
+			MaxMemory:    maxMemory,
+		}
+		cctx.ConstructNode = func() (n *core.IpfsNode, err error) {
+			if req == nil {
+				return nil, errors.New("constructing node without a request")
+			}
+			if _, err := cctx.GetPlugins(); err != nil {
+				return nil, err
+			}
 
-				r, err := fsrepo.Open(repoPath)
-				if err != nil { // repo is owned by the node
-					return nil, err
-				}
+			r, err := fsrepo.Open(repoPath)
+			if err != nil { // repo is owned by the node
+				return nil, err
+			}
 
-				// ok everything is good. set it on the invocation (for ownership)
-				// and return it.
-				n, err = core.NewNode(ctx, &core.BuildCfg{
-					Repo: r,
-				})
+			// apply --with-config in memory only, the overridden repo
+			// refuses to write its config back.
+			if len(overrides) > 0 {
+				or, err := repo.WithConfigOverrides(r, overrides)
 				if err != nil {
+					r.Close()
 					return nil, err
 				}
+				r = or
+			}
 
-				return n, nil
-			},
-		}, nil
+			// ok everything is good. set it on the invocation (for ownership)
+			// and return it.
+			n, err = core.NewNode(ctx, &core.BuildCfg{
+				Repo: r,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return n, nil
+		}
+		return cctx, nil
+	}
+
+	stdout, err := outputFile(os.Args)
+	if err != nil {
+		printErr(err)
+		return 1
+	}
+	if stdout != os.Stdout {
+		defer stdout.Close()
 	}
 
-	err = cli.Run(ctx, Root, os.Args, os.Stdin, os.Stdout, os.Stderr, buildEnv, makeExecutor)
+	ctx, cancel, err := withDeadline(ctx, os.Args, time.Now())
 	if err != nil {
+		printErr(err)
 		return 1
 	}
+	defer cancel()
+
+	// a config that can't be read shouldn't keep the built-in commands, like
+	// 'ipfs config edit', from running
+	root, err := withExternalCommands(Root, os.Args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: external commands not loaded: %s\n", err)
+		root = Root
+	}
+
+	// cli.Run prints its errors itself
+	stderr, waitStderr := os.Stderr, func() {}
+	if errFormat == errorFormatJSON {
+		if stderr, waitStderr, err = jsonErrors(os.Stderr); err != nil {
+			printErr(err)
+			return 1
+		}
+	}
+	err = cli.Run(ctx, root, os.Args, os.Stdin, stdout, stderr, buildEnv, makeExecutor)
+	waitStderr()
+	if err != nil {
+		return classifyError(err, parsed).ExitCode
+	}
 
 	// everything went better than expected :)
 	return 0
@@ -191,22 +298,64 @@ func checkDebug(req *cmds.Request) {
 	}
 }
 
-func apiAddrOption(req *cmds.Request) (ma.Multiaddr, error) {
-	apiAddrStr, apiSpecified := req.Options[corecmds.ApiOption].(string)
-	if !apiSpecified {
+// apiAddrOption returns the API endpoints given with --api, or else with
+// $IPFS_API, none if neither is set.
+func apiAddrOption(req *cmds.Request) ([]ma.Multiaddr, error) {
+	if apiAddrStr, apiSpecified := req.Options[corecmds.ApiOption].(string); apiSpecified {
+		return parseAPIAddrs(apiAddrStr)
+	}
+	apiAddrStr := os.Getenv(EnvAPIAddr)
+	if apiAddrStr == "" {
 		return nil, nil
 	}
-	return ma.NewMultiaddr(apiAddrStr)
+	addrs, err := parseAPIAddrs(apiAddrStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $%s: %s", EnvAPIAddr, err)
+	}
+	return addrs, nil
+}
+
+// flushTimeoutOption returns how long to wait for the repo to be flushed
+// before the node used by the command is closed, zero for the default.
+func flushTimeoutOption(req *cmds.Request) (time.Duration, error) {
+	s, ok := req.Options[corecmds.FlushTimeoutOption].(string)
+	if !ok {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s: %s", corecmds.FlushTimeoutOption, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("--%s must be positive", corecmds.FlushTimeoutOption)
+	}
+	return d, nil
 }
 
 func makeExecutor(req *cmds.Request, env interface{}) (cmds.Executor, error) {
-	exe := cmds.NewExecutor(req.Root)
+	// 'ipfs pin add --from-file' reads its paths before the request is
+	// checked, for them to be sent in its body
+	if err := corecmds.ReadPinAddLists(req); err != nil {
+		return nil, err
+	}
+
+	var exe cmds.Executor = cmds.NewExecutor(req.Root)
 	cctx := env.(*oldcmds.Context)
+	if cctx.MaxMemory > 0 {
+		exe = &memLimitExecutor{Executor: exe, limit: cctx.MaxMemory}
+	}
 	details := commandDetails(req.Path)
 
 	// Check if the command is disabled.
 	if details.cannotRunOnClient && details.cannotRunOnDaemon {
-		return nil, fmt.Errorf("command disabled: %v", req.Path)
+		return nil, usageError(fmt.Errorf("command disabled: %v", req.Path))
+	}
+
+	// Config overrides only apply to the repo opened by this process, the
+	// daemon reads its config on its own.
+	overrides, _ := req.Options[corecmds.WithConfigOption].([]string)
+	if len(overrides) > 0 && req.Command == daemonCmd {
+		return nil, usageError(fmt.Errorf("--%s cannot be used to start the daemon", corecmds.WithConfigOption))
 	}
 
 	// Can we just run this locally?
@@ -214,81 +363,147 @@ func makeExecutor(req *cmds.Request, env interface{}) (cmds.Executor, error) {
 		return exe, nil
 	}
 
-	// Get the API option from the commandline.
-	apiAddr, err := apiAddrOption(req)
+	// Never use the daemon when asked to run on the repo directly (unless
+	// we're trying to _run_ the daemon). --offline still uses a running
+	// daemon, which then runs the command offline.
+	noDaemon, _ := req.Options[corecmds.NoDaemonOption].(bool)
+	if noDaemon && req.Command != daemonCmd {
+		if details.cannotRunOnClient {
+			return nil, usageError(fmt.Errorf("command must be run on the daemon, which --%s doesn't use: %v", corecmds.NoDaemonOption, req.Path))
+		}
+		if _, apiSpecified := req.Options[corecmds.ApiOption]; apiSpecified {
+			return nil, usageError(fmt.Errorf("--%s cannot be used with --%s", corecmds.ApiOption, corecmds.NoDaemonOption))
+		}
+		return exe, nil
+	}
+
+	// Get the API option from the commandline, or the environment.
+	apiAddrs, err := apiAddrOption(req)
 	if err != nil {
-		return nil, err
+		return nil, usageError(err)
 	}
 
 	// Require that the command be run on the daemon when the API flag is
-	// passed (unless we're trying to _run_ the daemon).
-	daemonRequested := apiAddr != nil && req.Command != daemonCmd
+	// passed (unless we're trying to _run_ the daemon). $IPFS_API is only
+	// used like the API file of the repo, when there is a daemon to use.
+	_, apiFlag := req.Options[corecmds.ApiOption].(string)
+	daemonRequested := apiFlag && req.Command != daemonCmd
 
 	// Run this on the client if required.
 	if details.cannotRunOnDaemon || req.Command.External {
 		if daemonRequested {
 			// User requested that the command be run on the daemon but we can't.
 			// NOTE: We drop this check for the `ipfs daemon` command.
-			return nil, errors.New("api flag specified but command cannot be run on the daemon")
+			return nil, usageError(errors.New("api flag specified but command cannot be run on the daemon"))
 		}
 		return exe, nil
 	}
 
-	// Finally, look in the repo for an API file.
-	if apiAddr == nil {
-		var err error
-		apiAddr, err = fsrepo.APIAddr(cctx.ConfigRoot)
+	// Finally, look in the repo for an API file, waiting for a starting
+	// daemon to write it when asked to retry.
+	if len(apiAddrs) == 0 {
+		apiAddr, err := waitAPIAddr(cctx.ConfigRoot, apiRetries(req, details))
 		switch err {
-		case nil, repo.ErrApiNotRunning:
+		case nil:
+			apiAddrs = []ma.Multiaddr{apiAddr}
+		case repo.ErrApiNotRunning:
 		default:
 			return nil, err
 		}
 	}
 
 	// Still no api specified? Run it on the client or fail.
-	if apiAddr == nil {
+	if len(apiAddrs) == 0 {
 		if details.cannotRunOnClient {
-			return nil, fmt.Errorf("command must be run on the daemon: %v", req.Path)
+			return nil, daemonUnreachable(fmt.Errorf("command must be run on the daemon: %v", req.Path))
 		}
 		return exe, nil
 	}
 
-	// Resolve the API addr.
-	apiAddr, err = resolveAddr(req.Context, apiAddr)
+	if len(overrides) > 0 {
+		return nil, usageError(fmt.Errorf("--%s cannot be used while the daemon is running", corecmds.WithConfigOption))
+	}
+	// the memory of the daemon isn't the command's to limit
+	if cctx.MaxMemory > 0 {
+		return nil, usageError(fmt.Errorf("--%s cannot be used while the daemon is running", corecmds.MaxMemoryOption))
+	}
+
+	// Resolve the API addr, failing over to the next one given with --api
+	// when an endpoint is down.
+	resolveTimeout, err := apiResolveTimeoutOption(req)
 	if err != nil {
-		return nil, err
+		return nil, usageError(err)
+	}
+	apiAddr, resolved, err := selectAPIAddr(req.Context, apiAddrs, resolveTimeout)
+	if err != nil {
+		return nil, daemonUnreachable(err)
 	}
-	network, host, err := manet.DialArgs(apiAddr)
+	network, host, err := manet.DialArgs(resolved)
 	if err != nil {
 		return nil, err
 	}
 
+	// The certificate is checked against the address given, not the one it
+	// resolved to.
+	var tlsConfig *tls.Config
+	if apiUsesTLS(apiAddr) {
+		if tlsConfig, err = apiTLSConfig(req, apiAddr); err != nil {
+			return nil, err
+		}
+	}
+
 	// Construct the executor.
 	opts := []cmdhttp.ClientOpt{
 		cmdhttp.ClientWithAPIPrefix(corehttp.APIPath),
 	}
 
-	// Fallback on a local executor if we (a) have a repo and (b) aren't
-	// forcing a daemon.
-	if !daemonRequested && fsrepo.IsInitialized(cctx.ConfigRoot) {
+	// Fallback on a local executor if we (a) have a repo, (b) aren't
+	// forcing a daemon and (c) weren't told not to.
+	noFallback, _ := req.Options[corecmds.NoFallbackOption].(bool)
+	if !daemonRequested && !noFallback && fsrepo.IsInitialized(cctx.ConfigRoot) {
 		opts = append(opts, cmdhttp.ClientWithFallback(exe))
 	}
 
+	apiTimeout, err := apiTimeoutOption(req)
+	if err != nil {
+		return nil, usageError(err)
+	}
+
+	// streaming commands may only answer once they have something to output
+	var headerTimeout time.Duration
+	if !details.streaming {
+		headerTimeout = apiTimeout
+	}
+	transport := apiTransport(network, host, apiTimeout, headerTimeout, apiRetries(req, details), tlsConfig)
 	switch network {
 	case "tcp", "tcp4", "tcp6":
 	case "unix":
-		path := host
+		if err := checkAPISocket(host); err != nil {
+			return nil, daemonUnreachable(err)
+		}
 		host = "unix"
-		opts = append(opts, cmdhttp.ClientWithHTTPClient(&http.Client{
-			Transport: &http.Transport{
-				DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
-					return net.Dial("unix", path)
-				},
-			},
-		}))
 	default:
 		return nil, fmt.Errorf("unsupported API address: %s", apiAddr)
 	}
+	if retries := retryTransientOption(req, details); retries > 0 {
+		transport = &retryTransport{retries: retries, next: transport}
+	}
+
+	// A request sent again with its idempotency key, by running the command
+	// again, isn't applied twice by the daemon.
+	if key, _ := req.Options[corecmds.IdempotencyKeyOption].(string); key != "" {
+		transport = &idempotencyTransport{key: key, next: transport}
+	}
+	if token := apiAuthToken(req); token != "" {
+		transport = &authTransport{token: token, next: transport}
+	}
+	if secret := apiSecret(); secret != "" {
+		transport = &secretTransport{secret: secret, next: transport}
+	}
+
+	if transport != nil {
+		opts = append(opts, cmdhttp.ClientWithHTTPClient(&http.Client{Transport: transport}))
+	}
 
 	return cmdhttp.NewClient(host, opts...), nil
 }
@@ -326,6 +541,21 @@ func loadConfig(path string) (*config.Config, error) {
 	return fsrepo.ConfigAt(path)
 }
 
+// configLoader returns a loadConfig that applies the --with-config overrides
+// on top of the config read from disk.
+func configLoader(overrides []string) func(string) (*config.Config, error) {
+	if len(overrides) == 0 {
+		return loadConfig
+	}
+	return func(path string) (*config.Config, error) {
+		cfg, err := loadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		return repo.ApplyConfigOverrides(cfg, overrides)
+	}
+}
+
 // startProfiling begins CPU profiling and returns a `stop` function to be
 // executed as late as possible. The stop function captures the memprofile.
 func startProfiling() (func(), error) {
@@ -364,11 +594,17 @@ func writeHeapProfileToFile() error {
 	return pprof.WriteHeapProfile(mprof)
 }
 
-func profileIfEnabled() (func(), error) {
+// profileIfEnabled starts the profiling when IPFS_PROF is set or
+// --profiling is passed in args.
+func profileIfEnabled(args []string) (func(), error) {
+	profiling, err := rootBoolOptionArg(args, corecmds.ProfilingOption)
+	if err != nil {
+		return nil, err
+	}
 	// FIXME this is a temporary hack so profiling of asynchronous operations
 	// works as intended.
-	if os.Getenv(EnvEnableProfiling) != "" {
-		stopProfilingFunc, err := startProfiling() // TODO maybe change this to its own option... profiling makes it slower.
+	if profiling || os.Getenv(EnvEnableProfiling) != "" {
+		stopProfilingFunc, err := startProfiling()
 		if err != nil {
 			return nil, err
 		}
@@ -377,8 +613,10 @@ func profileIfEnabled() (func(), error) {
 	return func() {}, nil
 }
 
-func resolveAddr(ctx context.Context, addr ma.Multiaddr) (ma.Multiaddr, error) {
-	ctx, cancelFunc := context.WithTimeout(ctx, 10*time.Second)
+// resolveAddr returns the addresses addr resolves to, in order, giving up
+// after timeout.
+func resolveAddr(ctx context.Context, addr ma.Multiaddr, timeout time.Duration) ([]ma.Multiaddr, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, timeout)
 	defer cancelFunc()
 
 	addrs, err := dnsResolver.Resolve(ctx, addr)
@@ -390,5 +628,5 @@ func resolveAddr(ctx context.Context, addr ma.Multiaddr) (ma.Multiaddr, error) {
 		return nil, errors.New("non-resolvable API endpoint")
 	}
 
-	return addrs[0], nil
+	return addrs, nil
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/go-ipfs-cmds/cli"
+)
+
+// memCheckInterval is how often the memory use of a command running with
+// --max-memory is checked. Declared as a var for testing purposes.
+var memCheckInterval = 250 * time.Millisecond
+
+// maxMemoryOption returns the --max-memory limit in bytes, 0 if not set.
+func maxMemoryOption(req *cmds.Request) (uint64, error) {
+	s, ok := req.Options[corecmds.MaxMemoryOption].(string)
+	if !ok {
+		return 0, nil
+	}
+	limit, err := humanize.ParseBytes(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s: %s", corecmds.MaxMemoryOption, err)
+	}
+	if limit == 0 {
+		return 0, fmt.Errorf("--%s must be positive", corecmds.MaxMemoryOption)
+	}
+	return limit, nil
+}
+
+// memoryLimitError is the error a command fails with when it goes over
+// --max-memory.
+type memoryLimitError struct {
+	limit, used uint64
+}
+
+func (e *memoryLimitError) Error() string {
+	return fmt.Sprintf("aborted: the command used %s of memory, over the --%s limit of %s",
+		humanize.IBytes(e.used), corecmds.MaxMemoryOption, humanize.IBytes(e.limit))
+}
+
+// memLimitExecutor runs commands locally, canceling them when the heap grows
+// over limit. The check is soft: the command notices when its context is
+// canceled, so memory may keep growing for a little while.
+type memLimitExecutor struct {
+	cmds.Executor
+	limit uint64
+}
+
+func (x *memLimitExecutor) Execute(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+	ctx, cancel := context.WithCancel(req.Context)
+	defer cancel()
+	parent := req.Context
+	req.Context = ctx
+	defer func() { req.Context = parent }()
+
+	w := &memWatcher{limit: x.limit, cancel: cancel}
+	go w.watch(ctx)
+
+	// report the limit rather than the cancellation the command fails with
+	if cre, ok := re.(cli.ResponseEmitter); ok {
+		re = &memLimitEmitter{ResponseEmitter: cre, w: w}
+	}
+
+	err := x.Executor.Execute(req, re, env)
+	if lerr := w.err(); lerr != nil && err != nil {
+		return lerr
+	}
+	return err
+}
+
+type memWatcher struct {
+	limit  uint64
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	exceeded *memoryLimitError
+}
+
+func (w *memWatcher) watch(ctx context.Context) {
+	ticker := time.NewTicker(memCheckInterval)
+	defer ticker.Stop()
+
+	var ms runtime.MemStats
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		runtime.ReadMemStats(&ms)
+		if ms.HeapAlloc <= w.limit {
+			continue
+		}
+		// don't count garbage that wasn't collected yet
+		runtime.GC()
+		runtime.ReadMemStats(&ms)
+		if ms.HeapAlloc <= w.limit {
+			continue
+		}
+
+		log.Debugf("heap of %d bytes over the memory limit, aborting the command", ms.HeapAlloc)
+		w.mu.Lock()
+		w.exceeded = &memoryLimitError{limit: w.limit, used: ms.HeapAlloc}
+		w.mu.Unlock()
+		w.cancel()
+		return
+	}
+}
+
+func (w *memWatcher) err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.exceeded == nil {
+		return nil
+	}
+	return w.exceeded
+}
+
+type memLimitEmitter struct {
+	cli.ResponseEmitter
+	w *memWatcher
+}
+
+func (re *memLimitEmitter) CloseWithError(err error) error {
+	if err != nil {
+		if lerr := re.w.err(); lerr != nil {
+			err = lerr
+		}
+	}
+	return re.ResponseEmitter.CloseWithError(err)
+}
+
+// Type keeps the PostRun of the CLI, which is looked up by emitter type.
+func (re *memLimitEmitter) Type() cmds.PostRunType {
+	if t, ok := re.ResponseEmitter.(interface{ Type() cmds.PostRunType }); ok {
+		return t.Type()
+	}
+	return cmds.CLI
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/go-ipfs-cmds/cli"
+)
+
+// hogExecutor allocates memory until its context is canceled, reporting the
+// context error like a command would.
+type hogExecutor struct {
+	max int // bytes allocated before giving up
+}
+
+func (x *hogExecutor) Execute(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+	var hog [][]byte
+	var err error
+	for size := 0; size < x.max; size += 1 << 20 {
+		if err = req.Context.Err(); err != nil {
+			break
+		}
+		hog = append(hog, make([]byte, 1<<20))
+		time.Sleep(time.Millisecond)
+	}
+	runtime.KeepAlive(hog)
+	return re.CloseWithError(err)
+}
+
+func TestMemoryLimit(t *testing.T) {
+	defer func(d time.Duration) { memCheckInterval = d }(memCheckInterval)
+	memCheckInterval = 5 * time.Millisecond
+
+	req, err := cmds.NewRequest(context.Background(), []string{"add"}, cmds.OptMap{}, nil, nil, Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	re, err := cli.NewResponseEmitter(&stdout, &stderr, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ms runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&ms)
+	limit := ms.HeapAlloc + 32<<20
+
+	exe := &memLimitExecutor{Executor: &hogExecutor{max: 1 << 30}, limit: limit}
+	if err := exe.Execute(req, re, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(stderr.String(), "over the --max-memory limit") {
+		t.Fatalf("expected the memory limit error, got %q", stderr.String())
+	}
+	if re.(cli.ResponseEmitter).Status() == 0 {
+		t.Fatal("expected a failure exit status")
+	}
+	if req.Context != context.Background() {
+		t.Fatal("expected the request context to be restored")
+	}
+
+	// well under the limit, the command runs to completion
+	req.Options = cmds.OptMap{}
+	stderr.Reset()
+	re, _ = cli.NewResponseEmitter(&stdout, &stderr, req)
+	exe = &memLimitExecutor{Executor: &hogExecutor{max: 1 << 20}, limit: limit}
+	if err := exe.Execute(req, re, nil); err != nil {
+		t.Fatal(err)
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("unexpected error: %s", stderr.String())
+	}
+}
+
+func TestMaxMemoryOption(t *testing.T) {
+	limit, err := maxMemoryOption(&cmds.Request{Options: cmds.OptMap{corecmds.MaxMemoryOption: "512MB"}})
+	if err != nil || limit != 512*1000*1000 {
+		t.Fatalf("expected 512MB, got %d, %v", limit, err)
+	}
+	for _, v := range []string{"lots", "0"} {
+		if _, err := maxMemoryOption(&cmds.Request{Options: cmds.OptMap{corecmds.MaxMemoryOption: v}}); err == nil {
+			t.Errorf("%s: expected an error", v)
+		}
+	}
+}
+
+func TestMaxMemoryExecutor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memlimit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	env := &oldcmds.Context{ConfigRoot: dir, MaxMemory: 512 << 20}
+
+	req, err := cmds.NewRequest(context.Background(), []string{"cat"}, cmds.OptMap{}, nil, nil, Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without a daemon, the command is limited where it runs
+	exe, err := makeExecutor(req, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ml, ok := exe.(*memLimitExecutor); !ok || ml.limit != env.MaxMemory {
+		t.Fatalf("expected the command limited to %d bytes, got %T", env.MaxMemory, exe)
+	}
+
+	// the daemon's memory can't be limited
+	if err := ioutil.WriteFile(filepath.Join(dir, "api"), []byte("/ip4/127.0.0.1/tcp/5001"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := makeExecutor(req, env); err == nil || !strings.Contains(err.Error(), corecmds.MaxMemoryOption) {
+		t.Fatalf("expected --%s to be rejected with a daemon, got %v", corecmds.MaxMemoryOption, err)
+	}
+}
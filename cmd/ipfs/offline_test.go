@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestOfflineExecutor(t *testing.T) {
+	// a daemon seems to be running on the repo
+	dir, err := ioutil.TempDir("", "offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "api"), []byte("/ip4/127.0.0.1/tcp/5001"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := &oldcmds.Context{ConfigRoot: dir}
+
+	makeExe := func(path []string, opts cmds.OptMap) (cmds.Executor, error) {
+		req, err := cmds.NewRequest(context.Background(), path, opts, nil, nil, Root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return makeExecutor(req, env)
+	}
+
+	// the executor running the commands in this process
+	isLocal := func(exe cmds.Executor) bool {
+		return reflect.TypeOf(exe) == reflect.TypeOf(cmds.NewExecutor(Root))
+	}
+
+	exe, err := makeExe([]string{"cat"}, cmds.OptMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isLocal(exe) {
+		t.Fatal("expected the daemon to be used")
+	}
+
+	// a running daemon runs the command offline itself
+	for _, opt := range []string{corecmds.OfflineOption, corecmds.LocalOption} {
+		exe, err = makeExe([]string{"cat"}, cmds.OptMap{opt: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isLocal(exe) {
+			t.Fatalf("expected the daemon to be used with --%s", opt)
+		}
+	}
+
+	exe, err = makeExe([]string{"cat"}, cmds.OptMap{corecmds.NoDaemonOption: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isLocal(exe) {
+		t.Fatalf("expected the command to run locally with --%s, got %T", corecmds.NoDaemonOption, exe)
+	}
+
+	if _, err := makeExe([]string{"log"}, cmds.OptMap{corecmds.NoDaemonOption: true}); err == nil {
+		t.Fatalf("expected a command running only on the daemon to fail with --%s", corecmds.NoDaemonOption)
+	}
+	if _, err := makeExe([]string{"cat"}, cmds.OptMap{corecmds.NoDaemonOption: true, corecmds.ApiOption: "/ip4/127.0.0.1/tcp/5001"}); err == nil {
+		t.Fatalf("expected --%s to be rejected with --%s", corecmds.ApiOption, corecmds.NoDaemonOption)
+	}
+}
+
+func TestNoFallbackExecutor(t *testing.T) {
+	// the daemon of the repo is down
+	dir, err := ioutil.TempDir("", "nofallback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	down, downAddr := listenAPI(t)
+	down.Close()
+	for name, data := range map[string]string{"config": "{}", "api": downAddr.String()} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	errLocal := errors.New("ran on the repo")
+	env := &oldcmds.Context{
+		ConfigRoot:    dir,
+		ConstructNode: func() (*core.IpfsNode, error) { return nil, errLocal },
+	}
+
+	run := func(opts cmds.OptMap) error {
+		req, err := cmds.NewRequest(context.Background(), []string{"id"}, opts, nil, nil, Root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exe, err := makeExecutor(req, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the error is either returned or emitted
+		re, res := cmds.NewChanResponsePair(req)
+		emitted := make(chan error, 1)
+		go func() {
+			for {
+				if _, err := res.Next(); err != nil {
+					emitted <- err
+					return
+				}
+			}
+		}()
+		if err := exe.Execute(req, re, env); err != nil {
+			return err
+		}
+		re.Close()
+		if err := <-emitted; err != io.EOF {
+			return err
+		}
+		return nil
+	}
+
+	if err := run(cmds.OptMap{}); err == nil || err.Error() != errLocal.Error() {
+		t.Fatalf("expected the command to fall back on the repo, got %v", err)
+	}
+	err = run(cmds.OptMap{corecmds.NoFallbackOption: true})
+	if err == nil || !strings.Contains(err.Error(), "cannot connect to the api") {
+		t.Fatalf("expected the connection error with --%s, got %v", corecmds.NoFallbackOption, err)
+	}
+}
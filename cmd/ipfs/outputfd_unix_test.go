@@ -0,0 +1,93 @@
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestOutputFdArg(t *testing.T) {
+	for _, tc := range []struct {
+		args  []string
+		fd    int
+		found bool
+		err   bool
+	}{
+		{[]string{"ipfs", "id"}, 0, false, false},
+		{[]string{"ipfs", "--output-fd", "3", "id"}, 3, true, false},
+		{[]string{"ipfs", "id", "--output-fd=4"}, 4, true, false},
+		{[]string{"ipfs", "cat", "--", "--output-fd=4"}, 0, false, false},
+		{[]string{"ipfs", "id", "--output-fd"}, 0, false, true},
+		{[]string{"ipfs", "id", "--output-fd=three"}, 0, false, true},
+		{[]string{"ipfs", "id", "--output-fd=-1"}, 0, false, true},
+	} {
+		fd, found, err := outputFdArg(tc.args)
+		if fd != tc.fd || found != tc.found || (err != nil) != tc.err {
+			t.Errorf("%v: expected (%d, %t, err=%t), got (%d, %t, %v)", tc.args, tc.fd, tc.found, tc.err, fd, found, err)
+		}
+	}
+}
+
+func TestRootBoolOptionArg(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		set  bool
+		err  bool
+	}{
+		{[]string{"ipfs", "daemon"}, false, false},
+		{[]string{"ipfs", "--profiling", "daemon"}, true, false},
+		{[]string{"ipfs", "daemon", "--profiling=true"}, true, false},
+		{[]string{"ipfs", "daemon", "--profiling=false"}, false, false},
+		{[]string{"ipfs", "cat", "--", "--profiling"}, false, false},
+		{[]string{"ipfs", "daemon", "--profiling=yes"}, false, true},
+	} {
+		set, err := rootBoolOptionArg(tc.args, "profiling")
+		if set != tc.set || (err != nil) != tc.err {
+			t.Errorf("%v: expected (%t, err=%t), got (%t, %v)", tc.args, tc.set, tc.err, set, err)
+		}
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	f, err := outputFile([]string{"ipfs", "id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != os.Stdout {
+		t.Fatal("expected stdout without --output-fd")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	f, err = outputFile([]string{"ipfs", "id", fmt.Sprintf("--output-fd=%d", w.Fd())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("result\n"); err != nil {
+		t.Fatal(err)
+	}
+	// closes the write end of the pipe
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "result\n" {
+		t.Fatalf("expected the result on the pipe, got %q", out)
+	}
+
+	// the descriptor is closed now
+	if _, err := outputFile([]string{"ipfs", "id", fmt.Sprintf("--output-fd=%d", w.Fd())}); err == nil {
+		t.Fatal("expected a closed file descriptor to be rejected")
+	}
+}
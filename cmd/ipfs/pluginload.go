@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	loader "github.com/ipfs/go-ipfs/plugin/loader"
+)
+
+// defaultPluginTimeout bounds initializing the plugins when
+// $IPFS_PLUGIN_TIMEOUT isn't set.
+const defaultPluginTimeout = 30 * time.Second
+
+// pluginTimeout returns how long the plugins may take to initialize, as set
+// with $IPFS_PLUGIN_TIMEOUT.
+func pluginTimeout() (time.Duration, error) {
+	s := os.Getenv(EnvPluginTimeout)
+	if s == "" {
+		return defaultPluginTimeout, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid $%s %q, expected a positive duration like 30s", EnvPluginTimeout, s)
+	}
+	return d, nil
+}
+
+// initPlugins runs init, initializing and injecting the plugins named, and
+// gives up when it didn't return within timeout. The plugin loader can't be
+// interrupted: a plugin that timed out may still be running in the
+// background.
+func initPlugins(names []string, timeout time.Duration, init func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- init()
+	}()
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-t.C:
+		return fmt.Errorf("plugins not initialized after %s, one of them may be stuck: %s (set $%s to wait longer)",
+			timeout, strings.Join(names, ", "), EnvPluginTimeout)
+	}
+}
+
+// pluginError names in err the plugins that failed to initialize or inject,
+// as recorded by plugins.
+func pluginError(plugins *loader.PluginLoader, err error) error {
+	var failed []string
+	for _, st := range plugins.Status() {
+		if st.Stage == loader.PluginFailed {
+			failed = append(failed, st.Name)
+		}
+	}
+	if len(failed) == 0 {
+		return err
+	}
+	return fmt.Errorf("plugin %s: %s", strings.Join(failed, ", "), err)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+	dagcmd "github.com/ipfs/go-ipfs/core/commands/dag"
+
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	files "github.com/ipfs/go-ipfs-files"
+	gocar "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func TestPluginTimeout(t *testing.T) {
+	old, had := os.LookupEnv(EnvPluginTimeout)
+	defer func() {
+		if had {
+			os.Setenv(EnvPluginTimeout, old)
+		} else {
+			os.Unsetenv(EnvPluginTimeout)
+		}
+	}()
+
+	os.Unsetenv(EnvPluginTimeout)
+	if d, err := pluginTimeout(); err != nil || d != defaultPluginTimeout {
+		t.Fatalf("expected the default timeout, got %s (%v)", d, err)
+	}
+	os.Setenv(EnvPluginTimeout, "2m")
+	if d, err := pluginTimeout(); err != nil || d != 2*time.Minute {
+		t.Fatalf("expected 2m, got %s (%v)", d, err)
+	}
+	for _, s := range []string{"soon", "0s", "-1s"} {
+		os.Setenv(EnvPluginTimeout, s)
+		if _, err := pluginTimeout(); err == nil {
+			t.Errorf("expected $%s=%s to be rejected", EnvPluginTimeout, s)
+		}
+	}
+}
+
+func TestInitPlugins(t *testing.T) {
+	errInit := errors.New("bad config")
+	if err := initPlugins(nil, time.Second, func() error { return errInit }); err != errInit {
+		t.Fatalf("expected the error of the plugins, got %v", err)
+	}
+	if err := initPlugins(nil, time.Second, func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	start := time.Now()
+	err := initPlugins([]string{"ds-slow", "ipld-git"}, 50*time.Millisecond, func() error {
+		<-stuck
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "ds-slow, ipld-git") || !strings.Contains(err.Error(), EnvPluginTimeout) {
+		t.Fatalf("expected a timeout naming the plugins, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected to give up after 50ms, took %s", elapsed)
+	}
+}
+
+// TestVerifyCarGitBlock checks that 'ipfs dag car verify', not using the
+// repo, still gets the git codec of the preloaded plugins.
+func TestVerifyCarGitBlock(t *testing.T) {
+	path := []string{"dag", "car", "verify"}
+	if details := commandDetails(path); !details.loadsPlugins() {
+		t.Fatal("expected the plugins to be loaded to verify a CAR")
+	}
+	repo, err := ioutil.TempDir("", "verify-car")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repo)
+	if _, err := loadPlugins(repo, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	blob := []byte("blob 5\x00hello")
+	hash, err := mh.Sum(blob, mh.SHA1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cid.NewCidV1(cid.GitRaw, hash)
+	var car bytes.Buffer
+	if err := gocar.WriteHeader(&gocar.CarHeader{Roots: []cid.Cid{c}, Version: 1}, &car); err != nil {
+		t.Fatal(err)
+	}
+	if err := carutil.LdWrite(&car, c.Bytes(), blob); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := files.NewMapDirectory(map[string]files.Node{"file": files.NewBytesFile(car.Bytes())})
+	req, err := cmds.NewRequest(context.Background(), path, cmds.OptMap{}, nil, dir, Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re, res := cmds.NewChanResponsePair(req)
+	go func() {
+		re.CloseWithError(req.Command.Run(req, re, &oldcmds.Context{}))
+	}()
+	v, err := res.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := v.(*dagcmd.CarVerifyOutput)
+	if out.Blocks != 1 || !out.Complete {
+		t.Fatalf("expected the git blob to make a complete CAR, got %+v", out)
+	}
+	if _, err := res.Next(); err != io.EOF {
+		t.Fatalf("expected the verification to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// retryBackoff is the delay before the first retry, doubled on every
+// following one. Declared as a var for testing purposes.
+var retryBackoff = 200 * time.Millisecond
+
+// retryTransientOption returns how many times the request for a command is
+// sent again on transient errors, as requested with --retry-transient. Only
+// idempotent commands are retried.
+func retryTransientOption(req *cmds.Request, details cmdDetails) int {
+	retries, _ := req.Options[corecmds.RetryTransientOption].(int)
+	if retries <= 0 || !details.idempotent {
+		return 0
+	}
+	return retries
+}
+
+// retryTransport sends a request again when sending it fails with a
+// transient error. Only failures before the daemon responded (e.g. failing
+// to reach it) are retried: once there is a response, the command may have
+// produced output and errors are final. Requests with a body are never
+// retried, the body being gone once sent.
+type retryTransport struct {
+	retries int
+	next    http.RoundTripper // http.DefaultTransport if nil
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	backoff := retryBackoff
+	res, err := next.RoundTrip(req)
+	for i := 0; i < t.retries && req.Body == nil && isTransientError(err); i++ {
+		log.Debugf("retrying %s after transient error: %s", req.URL.Path, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-req.Context().Done():
+			return nil, err
+		}
+		backoff *= 2
+
+		res, err = next.RoundTrip(req)
+	}
+	return res, err
+}
+
+// isTransientError reports whether err is a network failure that may go
+// away by itself, such as a timeout or a reset connection.
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
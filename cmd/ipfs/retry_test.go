@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// flakyTransport fails with err the first failures times it sends a request.
+type flakyTransport struct {
+	failures int
+	err      error
+	sent     int
+}
+
+func (t *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.sent++
+	if t.sent <= t.failures {
+		return nil, t.err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Request: req}, nil
+}
+
+func sendWithRetries(t *testing.T, retries int, next *flakyTransport, body string) error {
+	var req *http.Request
+	var err error
+	if body == "" {
+		req, err = http.NewRequest("POST", "http://127.0.0.1:5001/api/v0/cat", nil)
+	} else {
+		req, err = http.NewRequest("POST", "http://127.0.0.1:5001/api/v0/cat", strings.NewReader(body))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = (&retryTransport{retries: retries, next: next}).RoundTrip(req)
+	return err
+}
+
+func TestRetryTransient(t *testing.T) {
+	defer func(b time.Duration) { retryBackoff = b }(retryBackoff)
+	retryBackoff = 0
+
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+
+	// a request is sent again until it gets a response
+	next := &flakyTransport{failures: 2, err: reset}
+	if err := sendWithRetries(t, 3, next, ""); err != nil {
+		t.Fatalf("expected the request to succeed after retrying, got %s", err)
+	}
+	if next.sent != 3 {
+		t.Fatalf("expected 3 sends, got %d", next.sent)
+	}
+
+	// but no more than asked for
+	next = &flakyTransport{failures: 5, err: reset}
+	if err := sendWithRetries(t, 2, next, ""); err == nil {
+		t.Fatal("expected the request to fail once out of retries")
+	}
+	if next.sent != 3 {
+		t.Fatalf("expected 3 sends, got %d", next.sent)
+	}
+
+	// a request whose body was consumed can't be sent again
+	next = &flakyTransport{failures: 1, err: reset}
+	if err := sendWithRetries(t, 3, next, "QmHash"); err == nil {
+		t.Fatal("expected the request with a body to fail")
+	}
+	if next.sent != 1 {
+		t.Fatalf("expected a single send of a request with a body, got %d", next.sent)
+	}
+
+	// permanent errors aren't retried
+	next = &flakyTransport{failures: 1, err: errors.New("x509: certificate signed by unknown authority")}
+	if err := sendWithRetries(t, 3, next, ""); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	if next.sent != 1 {
+		t.Fatalf("expected a single send on a permanent error, got %d", next.sent)
+	}
+}
+
+func TestRetryTransientOption(t *testing.T) {
+	for _, tc := range []struct {
+		path     []string
+		expected int
+	}{
+		{[]string{"cat"}, 3},
+		// a write command is never retried
+		{[]string{"add"}, 0},
+	} {
+		req, err := cmds.NewRequest(context.Background(), tc.path, cmds.OptMap{
+			corecmds.RetryTransientOption: 3,
+		}, nil, nil, Root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retries := retryTransientOption(req, commandDetails(req.Path)); retries != tc.expected {
+			t.Errorf("%v: expected %d retries, got %d", tc.path, tc.expected, retries)
+		}
+	}
+}
@@ -3,6 +3,9 @@ package commands
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 
@@ -10,6 +13,7 @@ import (
 	coreapi "github.com/ipfs/go-ipfs/core/coreapi"
 	loader "github.com/ipfs/go-ipfs/plugin/loader"
 
+	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-ipfs-cmds"
 	config "github.com/ipfs/go-ipfs-config"
 	logging "github.com/ipfs/go-log"
@@ -19,12 +23,19 @@ import (
 
 var log = logging.Logger("command")
 
+// DefaultFlushTimeout is how long Close waits for the node to flush the repo
+// when the context has no FlushTimeout.
+const DefaultFlushTimeout = 30 * time.Second
+
 // Context represents request context
 type Context struct {
 	ConfigRoot string
 	ReqLog     *ReqLog
 
-	Plugins *loader.PluginLoader
+	// Plugins are the plugins loaded, loaded with LoadPlugins by
+	// GetPlugins if nil.
+	Plugins     *loader.PluginLoader
+	LoadPlugins func() (*loader.PluginLoader, error)
 
 	config     *config.Config
 	LoadConfig func(path string) (*config.Config, error)
@@ -33,6 +44,15 @@ type Context struct {
 	api           coreiface.CoreAPI
 	node          *core.IpfsNode
 	ConstructNode func() (*core.IpfsNode, error)
+
+	// FlushTimeout bounds how long Close waits for the node to flush the
+	// repo before closing it. The node isn't closed if the flush times out.
+	FlushTimeout time.Duration
+	// Stderr receives the warnings printed by Close, os.Stderr if nil.
+	Stderr io.Writer
+	// MaxMemory is the soft limit, in bytes, on the heap of the commands
+	// run in this process. Zero means no limit.
+	MaxMemory uint64
 }
 
 // GetConfig returns the config of the current Command execution
@@ -48,6 +68,23 @@ func (c *Context) GetConfig() (*config.Config, error) {
 	return c.config, err
 }
 
+// GetPlugins returns the plugins of the current Command execution
+// context. It may load them with the provided function. Plugins failing to
+// load aren't kept, the error of LoadPlugins being what reports them.
+func (c *Context) GetPlugins() (*loader.PluginLoader, error) {
+	if c.Plugins == nil {
+		if c.LoadPlugins == nil {
+			return nil, errors.New("no plugin loader available")
+		}
+		plugins, err := c.LoadPlugins()
+		if err != nil {
+			return nil, err
+		}
+		c.Plugins = plugins
+	}
+	return c.Plugins, nil
+}
+
 // GetNode returns the node of the current Command execution
 // context. It may construct it with the provided function.
 func (c *Context) GetNode() (*core.IpfsNode, error) {
@@ -129,7 +166,53 @@ func (c *Context) Close() {
 	// Note that this means the underlying req.Context().Node variable is exposed.
 	// this is gross, and should be changed when we extract out the exec Context.
 	if c.node != nil {
+		if !c.flush() {
+			// closing the node would sync the stuck datastore again, or
+			// race the flush still running
+			return
+		}
 		log.Info("Shutting down node...")
 		c.node.Close()
 	}
 }
+
+// flush makes what the command wrote durable before the node is closed,
+// reporting whether it finished. It gives up after FlushTimeout, so a stuck
+// datastore doesn't keep the command from exiting: the flush is left running
+// and the node is then not closed by Close, the process exiting without it.
+func (c *Context) flush() bool {
+	timeout := c.FlushTimeout
+	if timeout <= 0 {
+		timeout = DefaultFlushTimeout
+	}
+	stderr := c.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- flushNode(c.node)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: failed to flush the repo: %s\n", err)
+		}
+		return true
+	case <-time.After(timeout):
+		fmt.Fprintf(stderr, "Warning: flushing the repo did not finish within %s, recent writes may not be durable\n", timeout)
+		return false
+	}
+}
+
+// flushNode writes out the MFS root of n and syncs its datastore.
+func flushNode(n *core.IpfsNode) error {
+	if n.FilesRoot != nil {
+		if err := n.FilesRoot.Flush(); err != nil {
+			return err
+		}
+	}
+	return n.Repo.Datastore().Sync(datastore.NewKey("/"))
+}
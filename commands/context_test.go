@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/ipfs/go-ipfs/core"
+	loader "github.com/ipfs/go-ipfs/plugin/loader"
+	"github.com/ipfs/go-ipfs/repo"
+
+	datastore "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	config "github.com/ipfs/go-ipfs-config"
+)
+
+// syncDatastore records calls to Sync, which block until release is closed,
+// and to Close.
+type syncDatastore struct {
+	datastore.Batching
+	synced  chan datastore.Key
+	release chan struct{}
+	closed  chan struct{}
+}
+
+func (d *syncDatastore) Close() error {
+	close(d.closed)
+	return d.Batching.Close()
+}
+
+func (d *syncDatastore) Sync(prefix datastore.Key) error {
+	d.synced <- prefix
+	<-d.release
+	return d.Batching.Sync(prefix)
+}
+
+func newSyncNode(t *testing.T) (*core.IpfsNode, *syncDatastore) {
+	ds := &syncDatastore{
+		Batching: syncds.MutexWrap(datastore.NewMapDatastore()),
+		synced:   make(chan datastore.Key, 16),
+		release:  make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	r := &repo.Mock{
+		C: config.Config{
+			Identity: config.Identity{
+				PeerID: "QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe", // required by offline node
+			},
+		},
+		D: ds,
+	}
+	n, err := core.NewNode(context.Background(), &core.BuildCfg{Repo: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n, ds
+}
+
+func TestCloseFlushes(t *testing.T) {
+	n, ds := newSyncNode(t)
+	close(ds.release)
+
+	var stderr bytes.Buffer
+	c := &Context{
+		ConstructNode: func() (*core.IpfsNode, error) { return n, nil },
+		Stderr:        &stderr,
+	}
+	if _, err := c.GetNode(); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	select {
+	case k := <-ds.synced:
+		if k != datastore.NewKey("/") {
+			t.Fatalf("expected the whole datastore to be synced, got %s", k)
+		}
+	default:
+		t.Fatal("expected the datastore to be synced before closing")
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("unexpected warning: %s", stderr.String())
+	}
+}
+
+func TestCloseFlushTimeout(t *testing.T) {
+	n, ds := newSyncNode(t)
+	defer n.Close()
+	defer close(ds.release)
+
+	var stderr bytes.Buffer
+	c := &Context{
+		ConstructNode: func() (*core.IpfsNode, error) { return n, nil },
+		FlushTimeout:  50 * time.Millisecond,
+		Stderr:        &stderr,
+	}
+	if _, err := c.GetNode(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	c.Close()
+	if took := time.Since(start); took > 5*time.Second {
+		t.Fatalf("close took %s, expected it to give up after the timeout", took)
+	}
+
+	<-ds.synced
+	if !strings.Contains(stderr.String(), "did not finish within 50ms") {
+		t.Fatalf("expected a timeout warning, got %q", stderr.String())
+	}
+	// the node isn't closed while the flush still runs
+	select {
+	case <-ds.closed:
+		t.Fatal("expected the datastore not to be closed after the flush timed out")
+	default:
+	}
+}
+
+func TestGetPluginsLoadsOnce(t *testing.T) {
+	var loads int
+	fail := true
+	c := &Context{
+		LoadPlugins: func() (*loader.PluginLoader, error) {
+			loads++
+			if fail {
+				return nil, errors.New("plugin failed to inject")
+			}
+			return loader.NewPluginLoader("")
+		},
+	}
+	if c.Plugins != nil || loads != 0 {
+		t.Fatal("plugins loaded before being asked for")
+	}
+
+	if _, err := c.GetPlugins(); err == nil {
+		t.Fatal("expected the plugin loading error")
+	}
+	if c.Plugins != nil {
+		t.Fatal("plugins failing to load were kept")
+	}
+
+	fail = false
+	plugins, err := c.GetPlugins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Plugins != plugins {
+		t.Fatal("expected the loaded plugins on the context")
+	}
+	if loads != 2 {
+		t.Fatalf("expected the plugins loaded once after the failure, loaded %d times", loads)
+	}
+}
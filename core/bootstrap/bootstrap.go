@@ -50,6 +50,9 @@ type BootstrapConfig struct {
 	// for the bootstrap process to use. This makes it possible for clients
 	// to control the peers the process uses at any moment.
 	BootstrapPeers func() []peer.AddrInfo
+
+	// Progress, if set, records the progress of the bootstrap process.
+	Progress *Progress
 }
 
 // DefaultBootstrapConfig specifies default sane parameters for bootstrapping.
@@ -82,13 +85,21 @@ func Bootstrap(id peer.ID, host host.Host, rt routing.Routing, cfg BootstrapConf
 		log.Warn("no bootstrap nodes configured: go-ipfs may have difficulty connecting to the network")
 	}
 
+	if cfg.Progress != nil {
+		cfg.Progress.started(cfg.MinPeerThreshold)
+	}
+
 	// the periodic bootstrap function -- the connection supervisor
 	periodic := func(worker goprocess.Process) {
 		ctx := goprocessctx.OnClosingContext(worker)
 
-		if err := bootstrapRound(ctx, host, cfg); err != nil {
+		err := bootstrapRound(ctx, host, cfg)
+		if err != nil {
 			log.Debugf("%s bootstrap error: %s", id, err)
 		}
+		if cfg.Progress != nil {
+			cfg.Progress.roundDone(len(host.Network().Peers()), err)
+		}
 
 		<-doneWithRound
 	}
@@ -104,6 +115,9 @@ func Bootstrap(id peer.ID, host host.Host, rt routing.Routing, cfg BootstrapConf
 			proc.Close()
 			return nil, err
 		}
+		if cfg.Progress != nil {
+			cfg.Progress.routingBootstrapped()
+		}
 	}
 
 	doneWithRound <- struct{}{}
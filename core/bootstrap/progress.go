@@ -0,0 +1,120 @@
+package bootstrap
+
+import (
+	"sync"
+	"time"
+)
+
+// maxRoutingTableSamples is the number of routing table sizes a Progress
+// keeps, the ones of the last 30 minutes with the default period.
+const maxRoutingTableSamples = 60
+
+// RoutingTableSample is the size of the routing table at some time.
+type RoutingTableSample struct {
+	Time time.Time
+	Size int
+}
+
+// Status is a snapshot of the progress of a bootstrap process.
+type Status struct {
+	Started time.Time
+	// Rounds is the number of bootstrap rounds run, FailedRounds the ones
+	// that couldn't connect to enough bootstrap peers, with LastError.
+	Rounds       int
+	FailedRounds int
+	LastRound    time.Time
+	LastError    string
+	// Connected is the number of peers connected after the last round.
+	Connected int
+	// RoutingBootstrapped is set once the routing system was bootstrapped,
+	// see routing.Routing.Bootstrap.
+	RoutingBootstrapped bool
+	// Complete is set once the routing was bootstrapped and the last round
+	// found enough connected peers and a routing table with peers.
+	Complete bool
+	// RoutingTable are the sizes of the routing table after each round, the
+	// oldest first.
+	RoutingTable []RoutingTableSample
+}
+
+// Progress records the progress of a bootstrap process, see
+// BootstrapConfig.Progress.
+type Progress struct {
+	routingTableSize func() int
+	now              func() time.Time
+
+	mu     sync.Mutex
+	status Status
+	// minPeers is the MinPeerThreshold of the process
+	minPeers int
+}
+
+// NewProgress returns a Progress sampling the size of the routing table with
+// routingTableSize, which may be nil.
+func NewProgress(routingTableSize func() int) *Progress {
+	return &Progress{
+		routingTableSize: routingTableSize,
+		now:              time.Now,
+	}
+}
+
+// Status returns the progress so far.
+func (p *Progress) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.status
+	s.RoutingTable = append([]RoutingTableSample(nil), p.status.RoutingTable...)
+	return s
+}
+
+func (p *Progress) started(minPeers int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = Status{Started: p.now()}
+	p.minPeers = minPeers
+}
+
+func (p *Progress) routingBootstrapped() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.RoutingBootstrapped = true
+	p.updateComplete()
+}
+
+// roundDone records a bootstrap round, after which connected peers are
+// connected.
+func (p *Progress) roundDone(connected int, err error) {
+	size := -1
+	if p.routingTableSize != nil {
+		size = p.routingTableSize()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	p.status.Rounds++
+	p.status.LastRound = now
+	p.status.LastError = ""
+	if err != nil {
+		p.status.LastError = err.Error()
+		p.status.FailedRounds++
+	}
+	p.status.Connected = connected
+	if size >= 0 {
+		p.status.RoutingTable = append(p.status.RoutingTable, RoutingTableSample{Time: now, Size: size})
+		if len(p.status.RoutingTable) > maxRoutingTableSamples {
+			p.status.RoutingTable = p.status.RoutingTable[1:]
+		}
+	}
+	p.updateComplete()
+}
+
+func (p *Progress) updateComplete() {
+	s := &p.status
+	s.Complete = s.RoutingBootstrapped && s.Rounds > 0 && s.Connected >= p.minPeers
+	if n := len(s.RoutingTable); n > 0 && s.RoutingTable[n-1].Size == 0 {
+		s.Complete = false
+	}
+}
@@ -0,0 +1,108 @@
+package bootstrap
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/routing"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+// stubDHT counts the times it was bootstrapped, and reports a routing table
+// with as many peers as connected to its host.
+type stubDHT struct {
+	routing.Routing
+	bootstrapped int32
+}
+
+func (d *stubDHT) Bootstrap(context.Context) error {
+	atomic.AddInt32(&d.bootstrapped, 1)
+	return nil
+}
+
+func TestBootstrapProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn := mocknet.New(ctx)
+	h, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bootstrapPeers []peer.AddrInfo
+	for i := 0; i < 2; i++ {
+		p, err := mn.GenPeer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bootstrapPeers = append(bootstrapPeers, peer.AddrInfo{ID: p.ID(), Addrs: p.Addrs()})
+	}
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	dht := &stubDHT{}
+	progress := NewProgress(func() int { return len(h.Network().Peers()) })
+	cfg := BootstrapConfigWithPeers(bootstrapPeers)
+	cfg.MinPeerThreshold = 2
+	cfg.Period = 20 * time.Millisecond
+	cfg.Progress = progress
+
+	closer, err := Bootstrap(h.ID(), h, dht, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+
+	var s Status
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		s = progress.Status()
+		if s.Rounds >= 3 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("expected bootstrap rounds to be recorded, got %+v", s)
+		}
+	}
+
+	if atomic.LoadInt32(&dht.bootstrapped) != 1 || !s.RoutingBootstrapped {
+		t.Fatal("expected the DHT to be bootstrapped once")
+	}
+	if !s.Complete || s.Connected != 2 || s.FailedRounds != 0 {
+		t.Fatalf("expected the bootstrap to be complete with 2 peers, got %+v", s)
+	}
+	if len(s.RoutingTable) != s.Rounds || s.RoutingTable[len(s.RoutingTable)-1].Size != 2 {
+		t.Fatalf("expected the routing table size after each round, got %v", s.RoutingTable)
+	}
+	if s.Started.IsZero() || s.LastRound.Before(s.Started) {
+		t.Fatalf("unexpected times: started %s, last round %s", s.Started, s.LastRound)
+	}
+}
+
+func TestBootstrapProgressIncomplete(t *testing.T) {
+	p := NewProgress(func() int { return 0 })
+	p.started(4)
+	p.routingBootstrapped()
+	p.roundDone(0, ErrNotEnoughBootstrapPeers)
+
+	s := p.Status()
+	if s.Complete || s.FailedRounds != 1 || s.LastError != ErrNotEnoughBootstrapPeers.Error() {
+		t.Fatalf("expected an incomplete bootstrap with a failed round, got %+v", s)
+	}
+
+	// enough peers, but an empty routing table
+	p.roundDone(4, nil)
+	if s := p.Status(); s.Complete || s.LastError != "" {
+		t.Fatalf("expected the bootstrap to be incomplete with an empty routing table, got %+v", s)
+	}
+
+	for i := 0; i < maxRoutingTableSamples+5; i++ {
+		p.roundDone(4, nil)
+	}
+	if s := p.Status(); len(s.RoutingTable) != maxRoutingTableSamples {
+		t.Fatalf("expected %d routing table samples, got %d", maxRoutingTableSamples, len(s.RoutingTable))
+	}
+}
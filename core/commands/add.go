@@ -7,9 +7,12 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	"github.com/ipfs/go-ipfs/core/coreunix"
 
+	humanize "github.com/dustin/go-humanize"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	files "github.com/ipfs/go-ipfs-files"
 	coreiface "github.com/ipfs/interface-go-ipfs-core"
@@ -22,10 +25,20 @@ import (
 var ErrDepthLimitExceeded = fmt.Errorf("depth limit exceeded")
 
 type AddEvent struct {
-	Name  string
-	Hash  string `json:",omitempty"`
-	Bytes int64  `json:",omitempty"`
-	Size  string `json:",omitempty"`
+	Name   string
+	Hash   string     `json:",omitempty"`
+	Bytes  int64      `json:",omitempty"`
+	Size   string     `json:",omitempty"`
+	Sha256 string     `json:",omitempty"`
+	Phases *AddPhases `json:",omitempty"`
+}
+
+// AddPhases is the time an add spent in each phase, reported last with
+// --bench-phases.
+type AddPhases struct {
+	Chunking time.Duration
+	Hashing  time.Duration
+	Writing  time.Duration
 }
 
 const (
@@ -45,10 +58,19 @@ const (
 	hashOptionName        = "hash"
 	inlineOptionName      = "inline"
 	inlineLimitOptionName = "inline-limit"
+	resumeOptionName      = "resume"
+	benchPhasesOptionName = "bench-phases"
+	alsoSha256OptionName  = "also-sha256"
 )
 
 const adderOutChanSize = 8
 
+// Config keys consulted by 'ipfs add' when the matching flag is not given.
+const (
+	importChunkerConfigKey = "Import.Chunker"
+	importLayoutConfigKey  = "Import.Layout"
+)
+
 var AddCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Add a file or directory to ipfs.",
@@ -75,6 +97,9 @@ You can now refer to the added file in a gateway, like so:
 
   /ipfs/QmaG4FuMqEBnQNn3C8XJ5bpW8kLs7zq2ZXgHptJHbKDDVx/example.jpg
 
+Once done, a summary of the entries added, their size and the time taken is
+printed to stderr, unless '--quiet' or the global '--no-summary' is given.
+
 The chunker option, '-s', specifies the chunking strategy that dictates
 how to break files into blocks. Blocks with same content can
 be deduplicated. Different chunking strategies will produce different
@@ -103,6 +128,52 @@ You can now check what blocks have been created by:
   QmerURi9k4XzKCaaPbsK6BL5pMEjF7PGphjDvkkjDtsVf3 868
   QmQB28iwSriSUSMqG2nXDTLtdPHgWb4rebBrU7Q1j4vxPv 338
 
+The default chunker and layout can be changed in the config with the
+Import.Chunker and Import.Layout ("balanced" or "trickle") keys. The
+'--chunker' and '--trickle' flags take precedence over them:
+
+  > ipfs config Import.Chunker rabin-262144-524288-1048576
+  > ipfs config Import.Layout trickle
+
+An interrupted add of a large file can be resumed with '--resume', which
+records the chunks of files as they are stored. Adding the same file again
+with '--resume', while it is unchanged on disk, only hashes and stores the
+chunks that weren't stored the first time. Like '--nocopy', it relies on the
+file being on the same machine as the daemon, and implies '--raw-leaves'.
+The records of a file are deleted once it is added, when it changes, or when
+it isn't added again for a week.
+
+  > ipfs add --resume big.iso
+  ^C
+  > ipfs add --resume big.iso
+
+Tiny files can be inlined into their CID with '--inline', instead of being
+stored as blocks: the blocks of at most '--inline-limit' bytes (32 by
+default) are addressed by an identity CID holding their content:
+
+  > echo hi > hi.txt
+  > ipfs add --inline --inline-limit 64 hi.txt
+  added bafyaacykbeeaeeqdnbuqugad hi.txt
+
+With '--also-sha256', the plain SHA-256 of the content of each file, as
+printed by sha256sum, is reported after its CID, for systems tracking files
+by their SHA-256. The CIDs are unchanged:
+
+  > echo "hello world" > hello.txt
+  > ipfs add --also-sha256 hello.txt
+  added QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o sha256:a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447 hello.txt
+
+The returned CIDs are printed in base58 for CIDv0, and base32 for CIDv1. The
+global '--cid-base' option picks another multibase, upgrading CIDv0 to CIDv1
+to be able to use it, which is handy where CIDs are case-insensitive, like
+subdomains. Only the output changes, the blocks added are the same:
+
+  > echo "base32 test" > base32-test.txt
+  > ipfs add base32-test.txt
+  added QmS92gwP5AAp6YHyG7QypC81H3ARBmNTbozJ1gTrhy2ASA base32-test.txt
+  > ipfs add --cid-base=base32 base32-test.txt
+  added bafybeibyosqxljd2eptb4ebbtvk7pb4aoxzqa6ttdsflty6rsslz5y6i34 base32-test.txt
+
 Finally, a note on hash determinism. While not guaranteed, adding the same
 file/directory with the same flags will almost always result in the same output
 hash. However, almost all of the flags provided by this command (other than pin,
@@ -127,7 +198,7 @@ only-hash, and progress/status related flags) will change the final hash.
 		cmds.BoolOption(trickleOptionName, "t", "Use trickle-dag format for dag generation."),
 		cmds.BoolOption(onlyHashOptionName, "n", "Only chunk and hash - do not write to disk."),
 		cmds.BoolOption(wrapOptionName, "w", "Wrap files with a directory object."),
-		cmds.StringOption(chunkerOptionName, "s", "Chunking algorithm, size-[bytes], rabin-[min]-[avg]-[max] or buzhash").WithDefault("size-262144"),
+		cmds.StringOption(chunkerOptionName, "s", "Chunking algorithm, size-[bytes], rabin-[min]-[avg]-[max] or buzhash. Default: Import.Chunker from the config, or size-262144."),
 		cmds.BoolOption(pinOptionName, "Pin this object when adding.").WithDefault(true),
 		cmds.BoolOption(rawLeavesOptionName, "Use raw blocks for leaf nodes. (experimental)"),
 		cmds.BoolOption(noCopyOptionName, "Add the file using filestore. Implies raw-leaves. (experimental)"),
@@ -136,6 +207,9 @@ only-hash, and progress/status related flags) will change the final hash.
 		cmds.StringOption(hashOptionName, "Hash function to use. Implies CIDv1 if not sha2-256. (experimental)").WithDefault("sha2-256"),
 		cmds.BoolOption(inlineOptionName, "Inline small blocks into CIDs. (experimental)"),
 		cmds.IntOption(inlineLimitOptionName, "Maximum block size to inline. (experimental)").WithDefault(32),
+		cmds.BoolOption(resumeOptionName, "Checkpoint added files so that an interrupted add resumes where it stopped. Implies raw-leaves. (experimental)"),
+		cmds.BoolOption(benchPhasesOptionName, "Report the time spent chunking, hashing and writing blocks to the datastore."),
+		cmds.BoolOption(alsoSha256OptionName, "Also report the plain SHA-256 of the content of each file, as printed by sha256sum."),
 	},
 	PreRun: func(req *cmds.Request, env cmds.Environment) error {
 		quiet, _ := req.Options[quietOptionName].(bool)
@@ -161,6 +235,13 @@ only-hash, and progress/status related flags) will change the final hash.
 		if err != nil {
 			return err
 		}
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if err := applyImportConfig(req.Options, nd.Repo.GetConfigKey); err != nil {
+			return err
+		}
 
 		progress, _ := req.Options[progressOptionName].(bool)
 		trickle, _ := req.Options[trickleOptionName].(bool)
@@ -176,6 +257,18 @@ only-hash, and progress/status related flags) will change the final hash.
 		hashFunStr, _ := req.Options[hashOptionName].(string)
 		inline, _ := req.Options[inlineOptionName].(bool)
 		inlineLimit, _ := req.Options[inlineLimitOptionName].(int)
+		resume, _ := req.Options[resumeOptionName].(bool)
+
+		if inline && inlineLimit < 0 {
+			return fmt.Errorf("%s option must not be negative", inlineLimitOptionName)
+		}
+
+		if resume {
+			if rbset && !rawblks {
+				return fmt.Errorf("%s option requires '--%s' to be enabled", resumeOptionName, rawLeavesOptionName)
+			}
+			rawblks, rbset = true, true
+		}
 
 		hashFunCode, ok := mh.Names[strings.ToLower(hashFunStr)]
 		if !ok {
@@ -200,8 +293,6 @@ only-hash, and progress/status related flags) will change the final hash.
 			options.Unixfs.Inline(inline),
 			options.Unixfs.InlineLimit(inlineLimit),
 
-			options.Unixfs.Chunker(chunker),
-
 			options.Unixfs.Pin(dopin),
 			options.Unixfs.HashOnly(hash),
 			options.Unixfs.FsCache(fscache),
@@ -211,6 +302,10 @@ only-hash, and progress/status related flags) will change the final hash.
 			options.Unixfs.Silent(silent),
 		}
 
+		if chunker != "" {
+			opts = append(opts, options.Unixfs.Chunker(chunker))
+		}
+
 		if cidVerSet {
 			opts = append(opts, options.Unixfs.CidVersion(cidVer))
 		}
@@ -225,10 +320,28 @@ only-hash, and progress/status related flags) will change the final hash.
 
 		opts = append(opts, nil) // events option placeholder
 
+		addCtx := req.Context
+		if resume {
+			addCtx = coreunix.WithResume(addCtx)
+		}
+		var timings *coreunix.PhaseTimings
+		if benchPhases, _ := req.Options[benchPhasesOptionName].(bool); benchPhases {
+			timings = new(coreunix.PhaseTimings)
+			addCtx = coreunix.WithPhaseTimings(addCtx, timings)
+		}
+
+		alsoSha256, _ := req.Options[alsoSha256OptionName].(bool)
+
 		var added int
 		addit := toadd.Entries()
 		for addit.Next() {
-			_, dir := addit.Node().(files.Directory)
+			node := addit.Node()
+			_, dir := node.(files.Directory)
+			var sums *sha256Sums
+			if alsoSha256 {
+				sums = newSha256Sums()
+				node = sums.wrap(node, "")
+			}
 			errCh := make(chan error, 1)
 			events := make(chan interface{}, adderOutChanSize)
 			opts[len(opts)-1] = options.Unixfs.Events(events)
@@ -236,7 +349,7 @@ only-hash, and progress/status related flags) will change the final hash.
 			go func() {
 				var err error
 				defer close(events)
-				_, err = api.Unixfs().Add(req.Context, addit.Node(), opts...)
+				_, err = api.Unixfs().Add(addCtx, node, opts...)
 				errCh <- err
 			}()
 
@@ -246,9 +359,15 @@ only-hash, and progress/status related flags) will change the final hash.
 					return errors.New("unknown event type")
 				}
 
-				h := ""
+				h, sum := "", ""
 				if output.Path != nil {
 					h = enc.Encode(output.Path.Cid())
+					if sums != nil && dir {
+						sum, _ = sums.get(output.Name)
+					} else if sums != nil {
+						// a file added alone is named after its CID
+						sum, _ = sums.get("")
+					}
 				}
 
 				if !dir && addit.Name() != "" {
@@ -258,10 +377,11 @@ only-hash, and progress/status related flags) will change the final hash.
 				}
 
 				if err := res.Emit(&AddEvent{
-					Name:  output.Name,
-					Hash:  h,
-					Bytes: output.Bytes,
-					Size:  output.Size,
+					Name:   output.Name,
+					Hash:   h,
+					Bytes:  output.Bytes,
+					Size:   output.Size,
+					Sha256: sum,
 				}); err != nil {
 					return err
 				}
@@ -281,6 +401,14 @@ only-hash, and progress/status related flags) will change the final hash.
 			return fmt.Errorf("expected a file argument")
 		}
 
+		if timings != nil {
+			p := timings.Phases()
+			return res.Emit(&AddEvent{Phases: &AddPhases{
+				Chunking: p.Chunking,
+				Hashing:  p.Hashing,
+				Writing:  p.Writing,
+			}})
+		}
 		return nil
 	},
 	PostRun: cmds.PostRunMap{
@@ -288,6 +416,7 @@ only-hash, and progress/status related flags) will change the final hash.
 			sizeChan := make(chan int64, 1)
 			outChan := make(chan interface{})
 			req := res.Request()
+			start := time.Now()
 
 			// Could be slow.
 			go func() {
@@ -309,6 +438,7 @@ only-hash, and progress/status related flags) will change the final hash.
 				quiet = quiet || quieter
 
 				progress, _ := req.Options[progressOptionName].(bool)
+				noSummary, _ := req.Options[NoSummaryOption].(bool)
 
 				var bar *pb.ProgressBar
 				if progress {
@@ -323,6 +453,8 @@ only-hash, and progress/status related flags) will change the final hash.
 				lastFile := ""
 				lastHash := ""
 				var totalProgress, prevFiles, lastBytes int64
+				var added int
+				size := int64(-1)
 
 			LOOP:
 				for {
@@ -336,8 +468,16 @@ only-hash, and progress/status related flags) will change the final hash.
 							break LOOP
 						}
 						output := out.(*AddEvent)
+						if p := output.Phases; p != nil {
+							if progress {
+								fmt.Fprintf(os.Stderr, "\033[2K\r")
+							}
+							fmt.Fprintf(os.Stderr, "chunking %s, hashing %s, writing %s\n", p.Chunking, p.Hashing, p.Writing)
+							continue
+						}
 						if len(output.Hash) > 0 {
 							lastHash = output.Hash
+							added++
 							if quieter {
 								continue
 							}
@@ -348,6 +488,8 @@ only-hash, and progress/status related flags) will change the final hash.
 							}
 							if quiet {
 								fmt.Fprintf(os.Stdout, "%s\n", output.Hash)
+							} else if output.Sha256 != "" {
+								fmt.Fprintf(os.Stdout, "added %s sha256:%s %s\n", output.Hash, output.Sha256, output.Name)
 							} else {
 								fmt.Fprintf(os.Stdout, "added %s %s\n", output.Hash, output.Name)
 							}
@@ -372,7 +514,7 @@ only-hash, and progress/status related flags) will change the final hash.
 						if progress {
 							bar.Update()
 						}
-					case size := <-sizeChan:
+					case size = <-sizeChan:
 						if progress {
 							bar.Total = size
 							bar.ShowPercent = true
@@ -392,6 +534,13 @@ only-hash, and progress/status related flags) will change the final hash.
 					bar.ShowTimeLeft = true
 					bar.Update()
 				}
+
+				if !quiet && !noSummary && added > 0 {
+					if progress {
+						fmt.Fprintln(os.Stderr)
+					}
+					fmt.Fprintln(os.Stderr, addSummary(added, size, time.Since(start)))
+				}
 			}
 
 			if e := res.Error(); e != nil {
@@ -425,3 +574,46 @@ only-hash, and progress/status related flags) will change the final hash.
 	},
 	Type: AddEvent{},
 }
+
+// applyImportConfig fills in the chunker and trickle options from the Import
+// section of the config when they were not passed. Keys that cannot be read
+// are treated as unset.
+func applyImportConfig(opts cmds.OptMap, getKey func(string) (interface{}, error)) error {
+	if _, found := opts[chunkerOptionName]; !found {
+		if v, err := getKey(importChunkerConfigKey); err == nil {
+			chunker, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("%s: expected a string, got %T", importChunkerConfigKey, v)
+			}
+			opts[chunkerOptionName] = chunker
+		}
+	}
+
+	if _, found := opts[trickleOptionName]; !found {
+		if v, err := getKey(importLayoutConfigKey); err == nil {
+			switch v {
+			case "balanced":
+				opts[trickleOptionName] = false
+			case "trickle":
+				opts[trickleOptionName] = true
+			default:
+				return fmt.Errorf("%s: unknown layout %v, expected \"balanced\" or \"trickle\"", importLayoutConfigKey, v)
+			}
+		}
+	}
+	return nil
+}
+
+// addSummary returns the line printed to stderr after the entries added,
+// size being the bytes read, negative when unknown.
+func addSummary(added int, size int64, elapsed time.Duration) string {
+	entries := "entries"
+	if added == 1 {
+		entries = "entry"
+	}
+	summary := fmt.Sprintf("added %d %s", added, entries)
+	if size >= 0 {
+		summary += ", " + humanize.Bytes(uint64(size))
+	}
+	return summary + fmt.Sprintf(" in %s", elapsed.Round(time.Millisecond))
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	files "github.com/ipfs/go-ipfs-files"
+)
+
+// sha256Sums records the plain SHA-256 of the content of the files added,
+// by their path in the add.
+type sha256Sums struct {
+	mu   sync.Mutex
+	sums map[string]string
+}
+
+func newSha256Sums() *sha256Sums {
+	return &sha256Sums{sums: make(map[string]string)}
+}
+
+// get returns the hex SHA-256 of the file added at p, if it was read whole.
+func (s *sha256Sums) get(p string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sum, ok := s.sums[p]
+	return sum, ok
+}
+
+func (s *sha256Sums) set(p, sum string) {
+	s.mu.Lock()
+	s.sums[p] = sum
+	s.mu.Unlock()
+}
+
+// wrap returns nd hashing the content of the regular files under it as they
+// are read, p being the path of nd in the add.
+func (s *sha256Sums) wrap(nd files.Node, p string) files.Node {
+	switch nd := nd.(type) {
+	case *files.Symlink:
+		return nd
+	case files.Directory:
+		return &sha256Dir{Directory: nd, sums: s, path: p}
+	case files.File:
+		f := &sha256File{File: nd, sums: s, path: p, h: sha256.New()}
+		// the filestore needs the path of the file
+		if fi, ok := nd.(files.FileInfo); ok {
+			return &sha256FileInfo{sha256File: f, fi: fi}
+		}
+		return f
+	default:
+		return nd
+	}
+}
+
+type sha256Dir struct {
+	files.Directory
+	sums *sha256Sums
+	path string
+}
+
+func (d *sha256Dir) Entries() files.DirIterator {
+	return &sha256DirIterator{DirIterator: d.Directory.Entries(), dir: d}
+}
+
+type sha256DirIterator struct {
+	files.DirIterator
+	dir *sha256Dir
+}
+
+func (it *sha256DirIterator) Node() files.Node {
+	return it.dir.sums.wrap(it.DirIterator.Node(), path.Join(it.dir.path, it.Name()))
+}
+
+// sha256File hashes the content of a file read from start to end.
+type sha256File struct {
+	files.File
+	sums *sha256Sums
+	path string
+
+	h hash.Hash
+	// broken is set when the file isn't read in order
+	broken bool
+}
+
+func (f *sha256File) Read(b []byte) (int, error) {
+	n, err := f.File.Read(b)
+	if f.h != nil && !f.broken {
+		f.h.Write(b[:n])
+		if err == io.EOF {
+			f.sums.set(f.path, hex.EncodeToString(f.h.Sum(nil)))
+			f.h = nil
+		}
+	}
+	return n, err
+}
+
+func (f *sha256File) Seek(offset int64, whence int) (int64, error) {
+	pos, err := f.File.Seek(offset, whence)
+	if err == nil && pos == 0 {
+		f.h, f.broken = sha256.New(), false
+	} else {
+		f.broken = true
+	}
+	return pos, err
+}
+
+type sha256FileInfo struct {
+	*sha256File
+	fi files.FileInfo
+}
+
+func (f *sha256FileInfo) AbsPath() string {
+	return f.fi.AbsPath()
+}
+
+func (f *sha256FileInfo) Stat() os.FileInfo {
+	return f.fi.Stat()
+}
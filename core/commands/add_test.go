@@ -0,0 +1,330 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	repo "github.com/ipfs/go-ipfs/repo"
+
+	cid "github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	config "github.com/ipfs/go-ipfs-config"
+	files "github.com/ipfs/go-ipfs-files"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func importConfig(cfg map[string]interface{}) func(string) (interface{}, error) {
+	return func(key string) (interface{}, error) {
+		v, ok := cfg[key]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return v, nil
+	}
+}
+
+func TestApplyImportConfig(t *testing.T) {
+	cfg := importConfig(map[string]interface{}{
+		importChunkerConfigKey: "rabin-262144-524288-1048576",
+		importLayoutConfigKey:  "trickle",
+	})
+
+	// config defaults apply when no flags are passed
+	opts := cmds.OptMap{}
+	if err := applyImportConfig(opts, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if opts[chunkerOptionName] != "rabin-262144-524288-1048576" {
+		t.Errorf("expected the configured chunker, got %v", opts[chunkerOptionName])
+	}
+	if opts[trickleOptionName] != true {
+		t.Errorf("expected the configured trickle layout, got %v", opts[trickleOptionName])
+	}
+
+	// flags override the config
+	opts = cmds.OptMap{
+		chunkerOptionName: "size-1024",
+		trickleOptionName: false,
+	}
+	if err := applyImportConfig(opts, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if opts[chunkerOptionName] != "size-1024" {
+		t.Errorf("expected the chunker flag to win, got %v", opts[chunkerOptionName])
+	}
+	if opts[trickleOptionName] != false {
+		t.Errorf("expected the trickle flag to win, got %v", opts[trickleOptionName])
+	}
+
+	// nothing configured leaves the built-in defaults
+	opts = cmds.OptMap{}
+	if err := applyImportConfig(opts, importConfig(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 0 {
+		t.Errorf("expected no options to be set, got %v", opts)
+	}
+
+	// bad values are rejected
+	bad := importConfig(map[string]interface{}{importLayoutConfigKey: "sideways"})
+	if err := applyImportConfig(cmds.OptMap{}, bad); err == nil {
+		t.Error("expected an unknown layout to be rejected")
+	}
+	bad = importConfig(map[string]interface{}{importChunkerConfigKey: 42.0})
+	if err := applyImportConfig(cmds.OptMap{}, bad); err == nil {
+		t.Error("expected a non-string chunker to be rejected")
+	}
+}
+
+// testAdd adds a file named name with content through the add command,
+// returning the root CID printed.
+func testAdd(t *testing.T, n *core.IpfsNode, name, content string, opts cmds.OptMap) (string, error) {
+	events, err := testAddEvents(t, n, testAddFile(name, content), opts)
+	if err != nil {
+		return "", err
+	}
+	var hash string
+	for _, ev := range events {
+		if ev.Hash != "" {
+			hash = ev.Hash
+		}
+	}
+	return hash, nil
+}
+
+// testAddFile returns the directory of a file named name with content.
+func testAddFile(name, content string) files.Directory {
+	return files.NewSliceDirectory([]files.DirEntry{
+		files.FileEntry(name, files.NewBytesFile([]byte(content))),
+	})
+}
+
+// testAddResponse runs the add command on n for file, returning its response.
+func testAddResponse(t *testing.T, n *core.IpfsNode, file files.Directory, opts cmds.OptMap) cmds.Response {
+	env := &oldcmds.Context{
+		ConstructNode: func() (*core.IpfsNode, error) { return n, nil },
+	}
+	req, err := cmds.NewRequest(context.Background(), []string{"add"}, opts, nil, file, Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.FillDefaults(); err != nil {
+		t.Fatal(err)
+	}
+
+	re, res := cmds.NewChanResponsePair(req)
+	go func() {
+		re.CloseWithError(cmds.NewExecutor(Root).Execute(req, re, env))
+	}()
+	return res
+}
+
+// testAddEvents adds file through the add command, returning its output.
+func testAddEvents(t *testing.T, n *core.IpfsNode, file files.Directory, opts cmds.OptMap) ([]*AddEvent, error) {
+	res := testAddResponse(t, n, file, opts)
+	var events []*AddEvent
+	for {
+		v, err := res.Next()
+		if err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		events = append(events, v.(*AddEvent))
+	}
+}
+
+func testAddNode(t *testing.T) *core.IpfsNode {
+	n, err := core.NewNode(context.Background(), &core.BuildCfg{Repo: &repo.Mock{
+		C: config.Config{
+			Identity: config.Identity{
+				PeerID: "QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe", // required by offline node
+			},
+		},
+		D: syncds.MutexWrap(datastore.NewMapDatastore()),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestAddCidBase(t *testing.T) {
+	n := testAddNode(t)
+	defer n.Close()
+
+	add := func(opts cmds.OptMap) string {
+		hash, err := testAdd(t, n, "base32-test.txt", "base32 test\n", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	// CIDv0 are upgraded to be shown in another base, the base32 CID is the
+	// one t0040-add-and-cat.sh expects
+	for _, tc := range []struct {
+		opts     cmds.OptMap
+		expected string
+	}{
+		{cmds.OptMap{}, "QmS92gwP5AAp6YHyG7QypC81H3ARBmNTbozJ1gTrhy2ASA"},
+		{cmds.OptMap{"cid-base": "base32"}, "bafybeibyosqxljd2eptb4ebbtvk7pb4aoxzqa6ttdsflty6rsslz5y6i34"},
+		{cmds.OptMap{"cid-base": "base32", "upgrade-cidv0-in-output": false}, "QmS92gwP5AAp6YHyG7QypC81H3ARBmNTbozJ1gTrhy2ASA"},
+	} {
+		if h := add(tc.opts); h != tc.expected {
+			t.Errorf("%v: expected %s, got %s", tc.opts, tc.expected, h)
+		}
+	}
+}
+
+func TestAddInline(t *testing.T) {
+	n := testAddNode(t)
+	defer n.Close()
+
+	inline := cmds.OptMap{inlineOptionName: true, inlineLimitOptionName: 64}
+	hash, err := testAdd(t, n, "hi.txt", "hi\n", inline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := cid.Decode(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dmh, err := mh.Decode(c.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dmh.Code != mh.IDENTITY || !bytes.Contains(dmh.Digest, []byte("hi\n")) {
+		t.Fatalf("expected an identity CID holding the content, got %s", c)
+	}
+
+	// over the limit, the file is stored as a block
+	hash, err = testAdd(t, n, "big.txt", strings.Repeat("x", 100), inline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := cid.Decode(hash); err != nil || c.Prefix().MhType == mh.IDENTITY {
+		t.Fatalf("expected a hashed CID over the limit, got %s (%v)", hash, err)
+	}
+
+	inline[inlineLimitOptionName] = -1
+	if _, err := testAdd(t, n, "hi.txt", "hi\n", inline); err == nil {
+		t.Fatal("expected a negative limit to be rejected")
+	}
+}
+
+// testAddCLI adds a file named name with content through the add command,
+// returning what its command line client prints to stdout and stderr.
+func testAddCLI(t *testing.T, n *core.IpfsNode, name, content string, opts cmds.OptMap) (string, string) {
+	// the client prints to stdout and stderr
+	var outputs [2]*os.File
+	for i := range outputs {
+		var err error
+		if outputs[i], err = ioutil.TempFile("", "add-output"); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(outputs[i].Name())
+		defer outputs[i].Close()
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = outputs[0], outputs[1]
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+
+	res := testAddResponse(t, n, testAddFile(name, content), opts)
+	if err := AddCmd.PostRun[cmds.CLI](res, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var printed [2]string
+	for i, f := range outputs {
+		b, err := ioutil.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		printed[i] = string(b)
+	}
+	return printed[0], printed[1]
+}
+
+func TestAddSummary(t *testing.T) {
+	n := testAddNode(t)
+	defer n.Close()
+
+	stdout, stderr := testAddCLI(t, n, "hi.txt", "hi\n", cmds.OptMap{})
+	if !strings.HasPrefix(stdout, "added Qm") || !strings.HasSuffix(stdout, " hi.txt\n") {
+		t.Fatalf("expected the added file on stdout, got %q", stdout)
+	}
+	if !strings.HasPrefix(stderr, "added 1 entry, 3 B in ") {
+		t.Fatalf("expected the summary on stderr, got %q", stderr)
+	}
+
+	stdout, stderr = testAddCLI(t, n, "hi.txt", "hi\n", cmds.OptMap{NoSummaryOption: true})
+	if !strings.HasPrefix(stdout, "added Qm") {
+		t.Fatalf("expected the added file on stdout, got %q", stdout)
+	}
+	if stderr != "" {
+		t.Fatalf("expected no summary with --%s, got %q", NoSummaryOption, stderr)
+	}
+
+	stdout, stderr = testAddCLI(t, n, "hi.txt", "hi\n", cmds.OptMap{quietOptionName: true})
+	if !strings.HasPrefix(stdout, "Qm") || stderr != "" {
+		t.Fatalf("expected only the CID with --%s, got %q and %q", quietOptionName, stdout, stderr)
+	}
+}
+
+func TestAddSha256(t *testing.T) {
+	n := testAddNode(t)
+	defer n.Close()
+
+	contents := map[string]string{
+		"hello.txt":     "hello world\n",
+		"dir/empty.txt": "",
+		"dir/big.bin":   strings.Repeat("0123456789abcdef", 64*1024),
+	}
+	file := files.NewSliceDirectory([]files.DirEntry{
+		files.FileEntry("hello.txt", files.NewBytesFile([]byte(contents["hello.txt"]))),
+		files.FileEntry("dir", files.NewSliceDirectory([]files.DirEntry{
+			files.FileEntry("empty.txt", files.NewBytesFile(nil)),
+			files.FileEntry("big.bin", files.NewBytesFile([]byte(contents["dir/big.bin"]))),
+		})),
+	})
+	events, err := testAddEvents(t, n, file, cmds.OptMap{alsoSha256OptionName: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reported := make(map[string]*AddEvent)
+	for _, ev := range events {
+		if ev.Hash != "" {
+			reported[ev.Name] = ev
+		}
+	}
+	for name, content := range contents {
+		ev, ok := reported[name]
+		if !ok {
+			t.Fatalf("%s was not added", name)
+		}
+		sum := sha256.Sum256([]byte(content))
+		if expected := hex.EncodeToString(sum[:]); ev.Sha256 != expected {
+			t.Errorf("%s: expected SHA-256 %s, got %q", name, expected, ev.Sha256)
+		}
+	}
+	if ev := reported["dir"]; ev == nil || ev.Sha256 != "" {
+		t.Errorf("expected no SHA-256 for a directory, got %+v", ev)
+	}
+	if reported["hello.txt"].Hash != "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o" {
+		t.Errorf("expected the CID to be unchanged, got %s", reported["hello.txt"].Hash)
+	}
+}
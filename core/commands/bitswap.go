@@ -10,6 +10,7 @@ import (
 	humanize "github.com/dustin/go-humanize"
 	bitswap "github.com/ipfs/go-bitswap"
 	decision "github.com/ipfs/go-bitswap/decision"
+	cid "github.com/ipfs/go-cid"
 	cidutil "github.com/ipfs/go-cidutil"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	peer "github.com/libp2p/go-libp2p-core/peer"
@@ -37,7 +38,10 @@ var showWantlistCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Show blocks currently on the wantlist.",
 		ShortDescription: `
-Print out all blocks currently on the bitswap wantlist for the local peer.`,
+Print out all blocks currently on the bitswap wantlist for the local peer.
+
+With --peer, print out the blocks that peer is asking us for instead, as
+recorded in its bitswap ledger.`,
 	},
 	Options: []cmds.Option{
 		cmds.StringOption(peerOptionName, "p", "Specify which peer to show wantlist for. Default: self."),
@@ -58,18 +62,15 @@ Print out all blocks currently on the bitswap wantlist for the local peer.`,
 			return e.TypeErr(bs, nd.Exchange)
 		}
 
-		pstr, found := req.Options[peerOptionName].(string)
-		if found {
-			pid, err := peer.Decode(pstr)
+		pid := nd.Identity
+		if pstr, found := req.Options[peerOptionName].(string); found {
+			pid, err = peer.Decode(pstr)
 			if err != nil {
 				return err
 			}
-			if pid != nd.Identity {
-				return cmds.EmitOnce(res, &KeyList{bs.WantlistForPeer(pid)})
-			}
 		}
 
-		return cmds.EmitOnce(res, &KeyList{bs.GetWantlist()})
+		return cmds.EmitOnce(res, &KeyList{wantlistFor(bs, nd.Identity, pid)})
 	},
 	Encoders: cmds.EncoderMap{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *KeyList) error {
@@ -87,6 +88,21 @@ Print out all blocks currently on the bitswap wantlist for the local peer.`,
 	},
 }
 
+// wantlistSource is the part of bitswap read by 'ipfs bitswap wantlist'.
+type wantlistSource interface {
+	GetWantlist() []cid.Cid
+	WantlistForPeer(peer.ID) []cid.Cid
+}
+
+// wantlistFor returns the local wantlist if p is self, otherwise the
+// wantlist p sent us, from its ledger.
+func wantlistFor(bs wantlistSource, self, p peer.ID) []cid.Cid {
+	if p == self {
+		return bs.GetWantlist()
+	}
+	return bs.WantlistForPeer(p)
+}
+
 const (
 	bitswapVerboseOptionName = "verbose"
 	bitswapHumanOptionName   = "human"
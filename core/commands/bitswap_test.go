@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	u "github.com/ipfs/go-ipfs-util"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/test"
+)
+
+// stubLedgers serves a local wantlist and per-peer ledger wantlists.
+type stubLedgers struct {
+	local []cid.Cid
+	peers map[peer.ID][]cid.Cid
+}
+
+func (s *stubLedgers) GetWantlist() []cid.Cid              { return s.local }
+func (s *stubLedgers) WantlistForPeer(p peer.ID) []cid.Cid { return s.peers[p] }
+
+func TestWantlistFor(t *testing.T) {
+	var ids []peer.ID
+	for i := 0; i < 3; i++ {
+		p, err := test.RandPeerID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, p)
+	}
+	self, known, unknown := ids[0], ids[1], ids[2]
+
+	key := func(s string) cid.Cid { return cid.NewCidV0(u.Hash([]byte(s))) }
+	bs := &stubLedgers{
+		local: []cid.Cid{key("mine")},
+		peers: map[peer.ID][]cid.Cid{known: {key("theirs-1"), key("theirs-2")}},
+	}
+
+	if got := wantlistFor(bs, self, self); len(got) != 1 || !got[0].Equals(key("mine")) {
+		t.Fatalf("expected the local wantlist, got %v", got)
+	}
+	got := wantlistFor(bs, self, known)
+	if len(got) != 2 || !got[0].Equals(key("theirs-1")) || !got[1].Equals(key("theirs-2")) {
+		t.Fatalf("expected the peer's wantlist from its ledger, got %v", got)
+	}
+	if got := wantlistFor(bs, self, unknown); len(got) != 0 {
+		t.Fatalf("expected an empty wantlist for a peer without a ledger, got %v", got)
+	}
+}
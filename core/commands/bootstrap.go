@@ -38,6 +38,7 @@ Running 'ipfs bootstrap' with no arguments will run 'ipfs bootstrap list'.
 		"list": bootstrapListCmd,
 		"add":  bootstrapAddCmd,
 		"rm":   bootstrapRemoveCmd,
+		"test": bootstrapTestCmd,
 	},
 }
 
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	host "github.com/libp2p/go-libp2p-core/host"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	ping "github.com/libp2p/go-libp2p/p2p/protocol/ping"
+)
+
+const bootstrapTestTimeoutOptionName = "dial-timeout"
+
+// BootstrapTestResult is the outcome of dialing a single bootstrap peer.
+type BootstrapTestResult struct {
+	Peer      string
+	Addrs     []string
+	Reachable bool
+	// Latency is the round trip time of a ping to the peer, zero when it
+	// doesn't answer pings.
+	Latency time.Duration `json:",omitempty"`
+	Error   string        `json:",omitempty"`
+}
+
+// BootstrapTestOutput is the output of 'ipfs bootstrap test'.
+type BootstrapTestOutput struct {
+	Peers []BootstrapTestResult
+}
+
+var bootstrapTestCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Check which bootstrap peers can be reached.",
+		ShortDescription: `
+'ipfs bootstrap test' dials every peer in the bootstrap list in parallel and
+reports whether it could be reached, and its latency, the round trip time of
+a ping to it: a peer already connected to isn't dialed again, so how long
+connecting took says nothing. Peers that cannot be reached within the
+timeout are reported with the reason the dial failed.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(bootstrapTestTimeoutOptionName, "How long to wait for each peer.").WithDefault("10s"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsOnline {
+			return ErrNotOnline
+		}
+
+		timeoutStr, _ := req.Options[bootstrapTestTimeoutOptionName].(string)
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid timeout: %s", err)
+		}
+
+		cfg, err := nd.Repo.Config()
+		if err != nil {
+			return err
+		}
+		peers, err := cfg.BootstrapPeers()
+		if err != nil {
+			return err
+		}
+
+		results := testBootstrapPeers(req.Context, peers, timeout, nd.PeerHost.Connect, pingPeer(nd.PeerHost))
+		return cmds.EmitOnce(res, &BootstrapTestOutput{Peers: results})
+	},
+	Type: BootstrapTestOutput{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *BootstrapTestOutput) error {
+			tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+			for _, r := range out.Peers {
+				if r.Reachable && r.Latency > 0 {
+					fmt.Fprintf(tw, "%s\treachable\t%s\n", r.Peer, r.Latency.Round(time.Millisecond))
+				} else if r.Reachable {
+					fmt.Fprintf(tw, "%s\treachable\tno answer to ping\n", r.Peer)
+				} else {
+					fmt.Fprintf(tw, "%s\tunreachable\t%s\n", r.Peer, r.Error)
+				}
+			}
+			return tw.Flush()
+		}),
+	},
+}
+
+// pingPeer returns a function measuring the round trip time to a peer
+// connected to h with a ping.
+func pingPeer(h host.Host) func(context.Context, peer.ID) (time.Duration, error) {
+	return func(ctx context.Context, p peer.ID) (time.Duration, error) {
+		// ping.Ping pings until ctx is done
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		res, ok := <-ping.Ping(ctx, h, p)
+		if !ok {
+			return 0, ctx.Err()
+		}
+		return res.RTT, res.Error
+	}
+}
+
+// testBootstrapPeers dials all peers in parallel with connect, and measures
+// the latency of those reached with ping, giving each peer at most timeout.
+// Results are in the same order as peers.
+func testBootstrapPeers(ctx context.Context, peers []peer.AddrInfo, timeout time.Duration, connect func(context.Context, peer.AddrInfo) error, ping func(context.Context, peer.ID) (time.Duration, error)) []BootstrapTestResult {
+	results := make([]BootstrapTestResult, len(peers))
+
+	var wg sync.WaitGroup
+	for i, pi := range peers {
+		addrs := make([]string, len(pi.Addrs))
+		for j, a := range pi.Addrs {
+			addrs[j] = a.String()
+		}
+		results[i] = BootstrapTestResult{Peer: pi.ID.Pretty(), Addrs: addrs}
+
+		wg.Add(1)
+		go func(r *BootstrapTestResult, pi peer.AddrInfo) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			if err := connect(ctx, pi); err != nil {
+				r.Error = fmt.Sprintf("%s: %s", formatDialErrorSummary(categorizeDialError(err)), err)
+				return
+			}
+			r.Reachable = true
+			// connect returns at once when already connected, it can't
+			// tell the latency
+			rtt, err := ping(ctx, pi.ID)
+			if err != nil {
+				log.Debugf("pinging bootstrap peer %s: %s", pi.ID, err)
+				return
+			}
+			r.Latency = rtt
+		}(&results[i], pi)
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/test"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ping "github.com/libp2p/go-libp2p/p2p/protocol/ping"
+)
+
+func TestTestBootstrapPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn := mocknet.New(ctx)
+	self, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reachable, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlinked, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mn.LinkPeers(self.ID(), reachable.ID()); err != nil {
+		t.Fatal(err)
+	}
+	noAddrs, err := test.RandPeerID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peers := []peer.AddrInfo{
+		{ID: reachable.ID(), Addrs: reachable.Addrs()},
+		{ID: unlinked.ID(), Addrs: unlinked.Addrs()},
+		{ID: noAddrs},
+	}
+	// the reachable peer answers pings, and is already connected to
+	ping.NewPingService(reachable)
+	if _, err := mn.ConnectPeers(self.ID(), reachable.ID()); err != nil {
+		t.Fatal(err)
+	}
+	results := testBootstrapPeers(ctx, peers, time.Second, self.Connect, pingPeer(self))
+	if len(results) != len(peers) {
+		t.Fatalf("expected %d results, got %d", len(peers), len(results))
+	}
+
+	for i, r := range results {
+		if r.Peer != peers[i].ID.Pretty() {
+			t.Errorf("result %d: expected peer %s, got %s", i, peers[i].ID, r.Peer)
+		}
+		if expected := i == 0; r.Reachable != expected {
+			t.Errorf("result %d: expected reachable=%t, got %t (%s)", i, expected, r.Reachable, r.Error)
+		}
+		if r.Reachable && (r.Latency <= 0 || r.Error != "") {
+			t.Errorf("result %d: expected a latency and no error, got %v", i, r)
+		}
+		if !r.Reachable && r.Error == "" {
+			t.Errorf("result %d: expected an error", i)
+		}
+	}
+	if len(results[0].Addrs) == 0 {
+		t.Error("expected the dialed addresses to be reported")
+	}
+}
+
+func TestTestBootstrapPeersNoPing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	id, err := test.RandPeerID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	connect := func(context.Context, peer.AddrInfo) error { return nil }
+	noPing := func(context.Context, peer.ID) (time.Duration, error) {
+		return 0, errors.New("protocol not supported")
+	}
+	results := testBootstrapPeers(ctx, []peer.AddrInfo{{ID: id}}, time.Second, connect, noPing)
+	if len(results) != 1 || !results[0].Reachable || results[0].Latency != 0 || results[0].Error != "" {
+		t.Fatalf("expected the peer reachable without a latency, got %+v", results)
+	}
+}
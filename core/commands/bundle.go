@@ -0,0 +1,310 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	e "github.com/ipfs/go-ipfs/core/commands/e"
+
+	bserv "github.com/ipfs/go-blockservice"
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	files "github.com/ipfs/go-ipfs-files"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	ipld "github.com/ipfs/go-ipld-format"
+	mdag "github.com/ipfs/go-merkledag"
+	gocar "github.com/ipld/go-car"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func init() {
+	cbor.RegisterCborType(BundleManifest{})
+}
+
+const (
+	bundlePinOptionName = "pin"
+
+	// bundleVersion is the version of the bundle manifest.
+	bundleVersion = 1
+)
+
+// BundleManifest describes the DAG in a bundle. It is stored as the root
+// block of the bundle's CAR, linking to the root of the DAG, so importing the
+// CAR with 'ipfs dag import' pins the whole DAG as well.
+type BundleManifest struct {
+	Version uint64
+	Root    cid.Cid
+	// Blocks and Size are the number of distinct blocks in the DAG and
+	// their total size, to check the bundle is complete when importing it.
+	Blocks uint64
+	Size   uint64
+}
+
+// BundleImportOutput is the output of 'ipfs bundle import'.
+type BundleImportOutput struct {
+	Root   string
+	Blocks uint64
+	Size   uint64
+	Pinned bool
+}
+
+var BundleCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Write a DAG and its manifest to a self-contained .car file.",
+		ShortDescription: `
+'ipfs bundle <cid> <file>' writes the complete DAG under <cid> to <file>, a
+.car file (see 'ipfs dag export') that can be carried to a node without
+network access and imported with 'ipfs bundle import'.
+
+The bundle starts with a small manifest recording the root of the DAG, and the
+number and total size of its blocks. Missing blocks are fetched from the
+network before the bundle is written.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("cid", true, false, "CID of the root of the DAG to bundle."),
+		cmds.StringArg("file", true, false, "Path of the .car file to write."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		c, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return fmt.Errorf("unable to parse root specification (currently only bare CIDs are supported): %s", err)
+		}
+
+		node, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		ng := mdag.NewSession(req.Context, node.DAG)
+
+		// walk the DAG once to write the manifest, which comes first
+		m, err := bundleManifest(req.Context, ng, c)
+		if err != nil {
+			return err
+		}
+
+		pipeR, pipeW := io.Pipe()
+		go func() {
+			pipeW.CloseWithError(writeBundle(req.Context, ng, m, pipeW))
+		}()
+
+		if err := res.Emit(pipeR); err != nil {
+			pipeR.Close()
+			return err
+		}
+		return nil
+	},
+	PostRun: cmds.PostRunMap{
+		cmds.CLI: func(res cmds.Response, re cmds.ResponseEmitter) error {
+			req := res.Request()
+
+			v, err := res.Next()
+			if err != nil {
+				return err
+			}
+			r, ok := v.(io.Reader)
+			if !ok {
+				return e.New(e.TypeErr(r, v))
+			}
+
+			path := req.Arguments[1]
+			f, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, r); err != nil {
+				f.Close()
+				os.Remove(path)
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stdout, "Saved bundle of %s to %s\n", req.Arguments[0], path)
+			return nil
+		},
+	},
+	Subcommands: map[string]*cmds.Command{
+		"import": bundleImportCmd,
+	},
+}
+
+var bundleImportCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Import a bundle written by 'ipfs bundle'.",
+		ShortDescription: `
+'ipfs bundle import' imports the blocks of a bundle, without reaching out to
+the network, and checks the DAG described by its manifest is complete. With
+--pin, the root of the DAG is pinned recursively.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.FileArg("file", true, false, "The path of the bundle.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(bundlePinOptionName, "Pin the root of the bundled DAG."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		node, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		file := files.FileFromEntry(req.Files.Entries())
+		if file == nil {
+			return errors.New("expected a file handle")
+		}
+		defer file.Close()
+
+		// keep the imported blocks from being collected before they are
+		// pinned
+		defer node.Blockstore.PinLock().Unlock()
+
+		dserv := mdag.NewDAGService(bserv.New(node.Blockstore, offline.Exchange(node.Blockstore)))
+		m, err := importBundle(req.Context, file, dserv)
+		if err != nil {
+			return err
+		}
+
+		out := &BundleImportOutput{
+			Root:   m.Root.String(),
+			Blocks: m.Blocks,
+			Size:   m.Size,
+		}
+		if pin, _ := req.Options[bundlePinOptionName].(bool); pin {
+			nd, err := dserv.Get(req.Context, m.Root)
+			if err != nil {
+				return err
+			}
+			if err := node.Pinning.Pin(req.Context, nd, true); err != nil {
+				return err
+			}
+			if err := node.Pinning.Flush(req.Context); err != nil {
+				return err
+			}
+			out.Pinned = true
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *BundleImportOutput) error {
+			fmt.Fprintf(w, "Imported %s (%d blocks, %d bytes)", out.Root, out.Blocks, out.Size)
+			if out.Pinned {
+				fmt.Fprint(w, ", pinned")
+			}
+			fmt.Fprintln(w)
+			return nil
+		}),
+	},
+	Type: BundleImportOutput{},
+}
+
+// bundleManifest walks the DAG under root and returns its manifest.
+func bundleManifest(ctx context.Context, ng ipld.NodeGetter, root cid.Cid) (*BundleManifest, error) {
+	m := &BundleManifest{Version: bundleVersion, Root: root}
+	getLinks := func(ctx context.Context, c cid.Cid) ([]*ipld.Link, error) {
+		nd, err := ng.Get(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		m.Blocks++
+		m.Size += uint64(len(nd.RawData()))
+		return nd.Links(), nil
+	}
+	if err := mdag.Walk(ctx, getLinks, root, cid.NewSet().Visit); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// writeBundle writes the manifest m and the DAG it describes as a CAR, rooted
+// at the manifest.
+func writeBundle(ctx context.Context, ng ipld.NodeGetter, m *BundleManifest, w io.Writer) error {
+	mnd, err := cbor.WrapObject(m, mh.SHA2_256, -1)
+	if err != nil {
+		return err
+	}
+	return gocar.WriteCar(ctx, &manifestGetter{NodeGetter: ng, manifest: mnd}, []cid.Cid{mnd.Cid()}, w)
+}
+
+// importBundle adds the blocks of the bundle read from r to dserv, and checks
+// the DAG described by its manifest is complete.
+func importBundle(ctx context.Context, r io.Reader, dserv ipld.DAGService) (*BundleManifest, error) {
+	car, err := gocar.NewCarReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if car.Header.Version != 1 || len(car.Header.Roots) != 1 {
+		return nil, errors.New("not a bundle: expected a version 1 .car file with a single root")
+	}
+	manifestCid := car.Header.Roots[0]
+
+	var m *BundleManifest
+	batch := ipld.NewBatch(ctx, dserv)
+	for {
+		block, err := car.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if block.Cid().Equals(manifestCid) {
+			// the manifest is only needed to check the bundle
+			m = new(BundleManifest)
+			if err := cbor.DecodeInto(block.RawData(), m); err != nil {
+				return nil, fmt.Errorf("not a bundle: invalid manifest: %s", err)
+			}
+			continue
+		}
+
+		nd, err := ipld.Decode(block)
+		if err != nil {
+			return nil, err
+		}
+		if err := batch.Add(ctx, nd); err != nil {
+			return nil, err
+		}
+	}
+	if err := batch.Commit(); err != nil {
+		return nil, err
+	}
+
+	if m == nil {
+		return nil, errors.New("not a bundle: the manifest is missing")
+	}
+	if m.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", m.Version)
+	}
+
+	got, err := bundleManifest(ctx, dserv, m.Root)
+	if err != nil {
+		return nil, fmt.Errorf("incomplete bundle: %s", err)
+	}
+	if got.Blocks != m.Blocks || got.Size != m.Size {
+		return nil, fmt.Errorf("incomplete bundle: the DAG has %d blocks (%d bytes), the manifest lists %d (%d bytes)",
+			got.Blocks, got.Size, m.Blocks, m.Size)
+	}
+	return m, nil
+}
+
+// manifestGetter serves the manifest of a bundle, which isn't stored, along
+// with the nodes of the DAG.
+type manifestGetter struct {
+	ipld.NodeGetter
+	manifest ipld.Node
+}
+
+func (g *manifestGetter) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	if c.Equals(g.manifest.Cid()) {
+		return g.manifest, nil
+	}
+	return g.NodeGetter.Get(ctx, c)
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	bserv "github.com/ipfs/go-blockservice"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	ipld "github.com/ipfs/go-ipld-format"
+	mdag "github.com/ipfs/go-merkledag"
+)
+
+func newBundleDAGService() (ipld.DAGService, blockstore.Blockstore) {
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	return mdag.NewDAGService(bserv.New(bs, offline.Exchange(bs))), bs
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src, _ := newBundleDAGService()
+
+	// a root with a raw leaf and a subtree sharing that leaf
+	leaf := mdag.NewRawNode([]byte("shared leaf"))
+	sub := mdag.NodeWithData([]byte("sub"))
+	root := mdag.NodeWithData([]byte("root"))
+	if err := sub.AddNodeLink("leaf", leaf); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddNodeLink("sub", sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddNodeLink("leaf", leaf); err != nil {
+		t.Fatal(err)
+	}
+	dagNodes := []ipld.Node{leaf, sub, root}
+	if err := src.AddMany(ctx, dagNodes); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := bundleManifest(ctx, src, root.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var size uint64
+	for _, nd := range dagNodes {
+		size += uint64(len(nd.RawData()))
+	}
+	if m.Blocks != 3 || m.Size != size {
+		t.Fatalf("expected 3 blocks of %d bytes, got %+v", size, m)
+	}
+
+	var buf bytes.Buffer
+	if err := writeBundle(ctx, src, m, &buf); err != nil {
+		t.Fatal(err)
+	}
+	bundle := buf.Bytes()
+
+	// import into a fresh repo
+	dst, dstBlocks := newBundleDAGService()
+	got, err := importBundle(ctx, bytes.NewReader(bundle), dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Root.Equals(root.Cid()) || got.Blocks != m.Blocks || got.Size != m.Size {
+		t.Fatalf("expected manifest %+v, got %+v", m, got)
+	}
+	for _, nd := range dagNodes {
+		if has, err := dstBlocks.Has(nd.Cid()); err != nil || !has {
+			t.Fatalf("block %s missing after import", nd.Cid())
+		}
+	}
+	// the manifest itself isn't kept
+	keys, err := dstBlocks.AllKeysChan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for range keys {
+		n++
+	}
+	if n != len(dagNodes) {
+		t.Fatalf("expected %d blocks in the fresh repo, got %d", len(dagNodes), n)
+	}
+}
+
+func TestBundleIncomplete(t *testing.T) {
+	ctx := context.Background()
+	src, _ := newBundleDAGService()
+
+	root := mdag.NodeWithData([]byte("root"))
+	if err := src.Add(ctx, root); err != nil {
+		t.Fatal(err)
+	}
+	m, err := bundleManifest(ctx, src, root.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a manifest listing a block the bundle doesn't carry
+	m.Blocks++
+	var buf bytes.Buffer
+	if err := writeBundle(ctx, src, m, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, _ := newBundleDAGService()
+	_, err = importBundle(ctx, &buf, dst)
+	if err == nil || !strings.Contains(err.Error(), "incomplete bundle") {
+		t.Fatalf("expected an incomplete bundle error, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	reqstats "github.com/ipfs/go-ipfs/core/reqstats"
+
+	cid "github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	coreiface "github.com/ipfs/interface-go-ipfs-core"
+	options "github.com/ipfs/interface-go-ipfs-core/options"
+	path "github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+// Statuses of the CIDs handled by 'ipfs cache prewarm'.
+const (
+	prewarmPinned        = "pinned"
+	prewarmKept          = "kept"
+	prewarmAlreadyPinned = "already pinned"
+	prewarmReleased      = "released"
+	prewarmFailed        = "failed"
+)
+
+const (
+	prewarmTopOptionName          = "top"
+	prewarmFetchTimeoutOptionName = "fetch-timeout"
+)
+
+// prewarmPinsKey is where the CIDs pinned by 'ipfs cache prewarm' are kept,
+// to release them once they are not among the most requested anymore.
+var prewarmPinsKey = datastore.NewKey("/local/cache/prewarm")
+
+// CachePrewarmOutput is what 'ipfs cache prewarm' did with a CID.
+type CachePrewarmOutput struct {
+	Cid      string
+	Requests uint64 `json:",omitempty"`
+	Status   string
+	Error    string `json:",omitempty"`
+}
+
+var CacheCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Manage the content cached by the node.",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"prewarm": cachePrewarmCmd,
+	},
+}
+
+var cachePrewarmCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Fetch and pin the content most requested from the gateway.",
+		ShortDescription: `
+'ipfs cache prewarm' fetches the CIDs most requested from the gateway of the
+daemon, and pins them so they stay in the cache:
+
+  > ipfs cache prewarm --top 100
+  pinned          QmYCvbfNbCwFR45HiNP45rwJgvatpiW38D961L5qAhUM5Y (1204 requests)
+  already pinned  QmcXx6Vb6rp3qDrSZq5sv5HPwbjsMaJ6ZkVrcLy2F1DQZ4 (980 requests)
+  released        QmSiTko9JZyabH56y2fussEt1A5oDqsFXB3CkvAqraFryz
+
+The pins are temporary: the next run releases the pins of the previous one
+not in the top anymore. CIDs already pinned otherwise are left alone.
+
+Requests are counted by the daemon since it started, favoring the recent ones
+once many CIDs were requested.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.IntOption(prewarmTopOptionName, "Number of most requested CIDs to pin.").WithDefault(100),
+		cmds.StringOption(prewarmFetchTimeoutOptionName, "Time to fetch and pin each CID.").WithDefault("5m"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+		if nd.RequestStats == nil {
+			return fmt.Errorf("request statistics are not available")
+		}
+
+		top, _ := req.Options[prewarmTopOptionName].(int)
+		if top <= 0 {
+			return cmds.Errorf(cmds.ErrClient, "--%s must be positive", prewarmTopOptionName)
+		}
+		timeoutStr, _ := req.Options[prewarmFetchTimeoutOptionName].(string)
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid --%s: %s", prewarmFetchTimeoutOptionName, err)
+		}
+
+		return prewarm(req.Context, api, nd.Repo.Datastore(), nd.RequestStats.Top(top), timeout, func(out *CachePrewarmOutput) error {
+			return res.Emit(out)
+		})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *CachePrewarmOutput) error {
+			fmt.Fprintf(w, "%-15s %s", out.Status, out.Cid)
+			if out.Requests > 0 {
+				fmt.Fprintf(w, " (%d requests)", out.Requests)
+			}
+			if out.Error != "" {
+				fmt.Fprintf(w, ": %s", out.Error)
+			}
+			fmt.Fprintln(w)
+			return nil
+		}),
+	},
+	Type: CachePrewarmOutput{},
+}
+
+// prewarm fetches and pins the CIDs of top, each within timeout, and
+// releases the pins of the previous run no longer in top. The CIDs it holds
+// pinned are recorded in ds when it returns, even interrupted, so that no pin
+// is left behind.
+func prewarm(ctx context.Context, api coreiface.CoreAPI, ds datastore.Datastore, top []reqstats.Entry, timeout time.Duration, emit func(*CachePrewarmOutput) error) (err error) {
+	previous, err := loadPrewarmPins(ds)
+	if err != nil {
+		return err
+	}
+
+	// the pins held, those of the previous run until released
+	held := make(map[cid.Cid]struct{}, len(previous))
+	for c := range previous {
+		held[c] = struct{}{}
+	}
+	defer func() {
+		if serr := storePrewarmPins(ds, held); err == nil {
+			err = serr
+		}
+	}()
+
+	pinned := make(map[cid.Cid]struct{})
+	for _, e := range top {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		out := &CachePrewarmOutput{Cid: e.Cid.String(), Requests: e.Requests}
+		p := path.IpfsPath(e.Cid)
+
+		if _, ours := previous[e.Cid]; ours {
+			out.Status = prewarmKept
+			pinned[e.Cid] = struct{}{}
+		} else if _, isPinned, err := api.Pin().IsPinned(ctx, p, options.Pin.IsPinned.Recursive()); err == nil && isPinned {
+			out.Status = prewarmAlreadyPinned
+		} else {
+			pctx, cancel := context.WithTimeout(ctx, timeout)
+			err := api.Pin().Add(pctx, p, options.Pin.Recursive(true))
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				out.Status = prewarmFailed
+				out.Error = err.Error()
+			} else {
+				out.Status = prewarmPinned
+				pinned[e.Cid] = struct{}{}
+				held[e.Cid] = struct{}{}
+			}
+		}
+		if err := emit(out); err != nil {
+			return err
+		}
+	}
+
+	for c := range previous {
+		if _, found := pinned[c]; found {
+			continue
+		}
+		out := &CachePrewarmOutput{Cid: c.String(), Status: prewarmReleased}
+		// the pin may have been removed by hand since
+		if _, isPinned, err := api.Pin().IsPinned(ctx, path.IpfsPath(c), options.Pin.IsPinned.Recursive()); err == nil && isPinned {
+			if err := api.Pin().Rm(ctx, path.IpfsPath(c), options.Pin.RmRecursive(true)); err != nil {
+				out.Status = prewarmFailed
+				out.Error = err.Error()
+			}
+		}
+		// a pin failing to be released stays held, to try again next time
+		if out.Status == prewarmReleased {
+			delete(held, c)
+		}
+		if err := emit(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadPrewarmPins(ds datastore.Datastore) (map[cid.Cid]struct{}, error) {
+	pins := make(map[cid.Cid]struct{})
+	b, err := ds.Get(prewarmPinsKey)
+	if err == datastore.ErrNotFound {
+		return pins, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var strs []string
+	if err := json.Unmarshal(b, &strs); err != nil {
+		return nil, fmt.Errorf("reading the prewarmed pins: %s", err)
+	}
+	for _, s := range strs {
+		c, err := cid.Decode(s)
+		if err != nil {
+			return nil, fmt.Errorf("reading the prewarmed pins: %s", err)
+		}
+		pins[c] = struct{}{}
+	}
+	return pins, nil
+}
+
+func storePrewarmPins(ds datastore.Datastore, pins map[cid.Cid]struct{}) error {
+	cids := make([]cid.Cid, 0, len(pins))
+	for c := range pins {
+		cids = append(cids, c)
+	}
+	sortCids(cids)
+	strs := make([]string, len(cids))
+	for i, c := range cids {
+		strs[i] = c.String()
+	}
+
+	b, err := json.Marshal(strs)
+	if err != nil {
+		return err
+	}
+	return ds.Put(prewarmPinsKey, b)
+}
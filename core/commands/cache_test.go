@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	coreapi "github.com/ipfs/go-ipfs/core/coreapi"
+	coremock "github.com/ipfs/go-ipfs/core/mock"
+	reqstats "github.com/ipfs/go-ipfs/core/reqstats"
+
+	cid "github.com/ipfs/go-cid"
+	files "github.com/ipfs/go-ipfs-files"
+	coreiface "github.com/ipfs/interface-go-ipfs-core"
+	options "github.com/ipfs/interface-go-ipfs-core/options"
+	path "github.com/ipfs/interface-go-ipfs-core/path"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+func TestCachePrewarm(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn := mocknet.New(ctx)
+	cache, err := coremock.MockPublicNode(ctx, mn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	origin, err := coremock.MockPublicNode(ctx, mn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer origin.Close()
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mn.ConnectAllButSelf(); err != nil {
+		t.Fatal(err)
+	}
+
+	cacheAPI, err := coreapi.NewCoreAPI(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	originAPI, err := coreapi.NewCoreAPI(origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the content is on the origin node, except a pinned by hand on the cache
+	add := func(api coreiface.CoreAPI, i int) cid.Cid {
+		data := bytes.Repeat([]byte(fmt.Sprintf("content %d\n", i)), 1000)
+		p, err := api.Unixfs().Add(ctx, files.NewBytesFile(data), options.Unixfs.Pin(false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.Cid()
+	}
+	a, b, c := add(originAPI, 1), add(originAPI, 2), add(originAPI, 3)
+	local := add(cacheAPI, 4)
+	if err := cacheAPI.Pin().Add(ctx, path.IpfsPath(local)); err != nil {
+		t.Fatal(err)
+	}
+
+	// synthetic request stats: a, local, b then c
+	stats := reqstats.New()
+	for cid, n := range map[cid.Cid]int{a: 5, local: 4, b: 3, c: 1} {
+		for i := 0; i < n; i++ {
+			stats.Record(cid)
+		}
+	}
+
+	run := func(top int) map[cid.Cid]string {
+		statuses := make(map[cid.Cid]string)
+		err := prewarm(ctx, cacheAPI, cache.Repo.Datastore(), stats.Top(top), 10*time.Second, func(out *CachePrewarmOutput) error {
+			c, err := cid.Decode(out.Cid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Error != "" {
+				t.Errorf("%s: %s", out.Cid, out.Error)
+			}
+			statuses[c] = out.Status
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return statuses
+	}
+	isPinned := func(c cid.Cid) bool {
+		_, pinned, err := cacheAPI.Pin().IsPinned(ctx, path.IpfsPath(c), options.Pin.IsPinned.Recursive())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pinned
+	}
+
+	statuses := run(3)
+	expected := map[cid.Cid]string{a: prewarmPinned, local: prewarmAlreadyPinned, b: prewarmPinned}
+	if fmt.Sprint(statuses) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, statuses)
+	}
+	for _, k := range []cid.Cid{a, b} {
+		if has, err := cache.Blockstore.Has(k); err != nil || !has {
+			t.Errorf("expected %s to be fetched", k)
+		}
+		if !isPinned(k) {
+			t.Errorf("expected %s to be pinned", k)
+		}
+	}
+	if has, _ := cache.Blockstore.Has(c); has {
+		t.Errorf("expected %s, not in the top, not to be fetched", c)
+	}
+
+	// c becomes popular, b isn't in the top anymore
+	for i := 0; i < 10; i++ {
+		stats.Record(c)
+	}
+	statuses = run(3)
+	expected = map[cid.Cid]string{c: prewarmPinned, a: prewarmKept, local: prewarmAlreadyPinned, b: prewarmReleased}
+	if fmt.Sprint(statuses) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, statuses)
+	}
+	if isPinned(b) || !isPinned(a) || !isPinned(c) {
+		t.Error("expected b to be released, a and c to be pinned")
+	}
+	// the pin made by hand is left alone
+	statuses = run(1)
+	if !isPinned(local) || isPinned(a) || !isPinned(c) {
+		t.Errorf("expected only the prewarmed pins to be released, got %v", statuses)
+	}
+
+	// a run canceled once a is pinned still records the pin
+	rctx, rcancel := context.WithCancel(ctx)
+	defer rcancel()
+	err = prewarm(rctx, cacheAPI, cache.Repo.Datastore(), stats.Top(3), 10*time.Second, func(out *CachePrewarmOutput) error {
+		if out.Cid == a.String() && out.Status == prewarmPinned {
+			rcancel()
+		}
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("expected the run to be canceled, got %v", err)
+	}
+	if !isPinned(a) {
+		t.Fatal("expected a to be pinned before the cancellation")
+	}
+	pins, err := loadPrewarmPins(cache.Repo.Datastore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pins[a]; !ok {
+		t.Fatalf("expected the pin of a to be recorded, got %v", pins)
+	}
+	if _, ok := pins[c]; !ok {
+		t.Fatalf("expected the pin of c to still be recorded, got %v", pins)
+	}
+	// and released by the next run
+	run(1)
+	if isPinned(a) || !isPinned(c) {
+		t.Error("expected the pin of the canceled run to be released")
+	}
+}
@@ -20,11 +20,12 @@ var CidCmd = &cmds.Command{
 		Tagline: "Convert and discover properties of CIDs",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"format": cidFmtCmd,
-		"base32": base32Cmd,
-		"bases":  basesCmd,
-		"codecs": codecsCmd,
-		"hashes": hashesCmd,
+		"format":     cidFmtCmd,
+		"base32":     base32Cmd,
+		"bases":      basesCmd,
+		"codecs":     codecsCmd,
+		"hashes":     hashesCmd,
+		"hash-bench": hashBenchCmd,
 	},
 }
 
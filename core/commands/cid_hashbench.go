@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	mhash "github.com/multiformats/go-multihash"
+)
+
+const (
+	hashBenchHashOptionName     = "hash"
+	hashBenchSizeOptionName     = "size"
+	hashBenchDurationOptionName = "duration"
+)
+
+// defaultBenchHashes are the functions benchmarked by 'ipfs cid hash-bench'
+// when none are given, one or two lengths per family.
+var defaultBenchHashes = []string{
+	"sha1",
+	"sha2-256",
+	"sha2-512",
+	"dbl-sha2-256",
+	"sha3-256",
+	"sha3-512",
+	"keccak-256",
+	"blake2b-256",
+	"blake2b-512",
+	"blake2s-256",
+}
+
+// HashBenchResult is the throughput of a single hash function.
+type HashBenchResult struct {
+	Name string
+	Code uint64
+	MBps float64
+}
+
+// HashBenchOutput is the output of 'ipfs cid hash-bench'.
+type HashBenchOutput struct {
+	Size    int
+	Results []HashBenchResult
+}
+
+var hashBenchCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Measure the throughput of hash functions.",
+		ShortDescription: `
+'ipfs cid hash-bench' repeatedly hashes a buffer of random data with each
+hash function and reports the throughput in MB/s, fastest first. Use --hash
+to pick the functions, any name listed by 'ipfs cid hashes' is accepted.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringsOption(hashBenchHashOptionName, "Hash function to benchmark. May be given multiple times."),
+		cmds.IntOption(hashBenchSizeOptionName, "Size of the hashed buffer in bytes.").WithDefault(1 << 20),
+		cmds.StringOption(hashBenchDurationOptionName, "How long to hash with each function.").WithDefault("200ms"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		names, _ := req.Options[hashBenchHashOptionName].([]string)
+		if len(names) == 0 {
+			names = defaultBenchHashes
+		}
+		size, _ := req.Options[hashBenchSizeOptionName].(int)
+		if size <= 0 {
+			return cmds.Errorf(cmds.ErrClient, "buffer size must be positive")
+		}
+		durStr, _ := req.Options[hashBenchDurationOptionName].(string)
+		dur, err := time.ParseDuration(durStr)
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid duration: %s", err)
+		}
+
+		codes := make([]uint64, len(names))
+		for i, name := range names {
+			code, ok := mhash.Names[name]
+			if !ok {
+				return cmds.Errorf(cmds.ErrClient, "unknown hash function %q", name)
+			}
+			codes[i] = code
+		}
+
+		buf := make([]byte, size)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+
+		results, err := hashBench(buf, codes, dur)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &HashBenchOutput{Size: size, Results: results})
+	},
+	Type: HashBenchOutput{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *HashBenchOutput) error {
+			tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+			for _, r := range out.Results {
+				fmt.Fprintf(tw, "%s\t%.1f MB/s\n", r.Name, r.MBps)
+			}
+			return tw.Flush()
+		}),
+	},
+}
+
+// hashBench hashes buf with each function for about dur, and returns their
+// throughput sorted fastest first.
+func hashBench(buf []byte, codes []uint64, dur time.Duration) ([]HashBenchResult, error) {
+	results := make([]HashBenchResult, 0, len(codes))
+	for _, code := range codes {
+		var (
+			n       int
+			elapsed time.Duration
+			start   = time.Now()
+		)
+		for n == 0 || elapsed < dur {
+			if _, err := mhash.Sum(buf, code, -1); err != nil {
+				return nil, fmt.Errorf("%s: %s", mhash.Codes[code], err)
+			}
+			n++
+			elapsed = time.Since(start)
+		}
+
+		results = append(results, HashBenchResult{
+			Name: mhash.Codes[code],
+			Code: code,
+			MBps: float64(n*len(buf)) / elapsed.Seconds() / 1e6,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].MBps > results[j].MBps
+	})
+	return results, nil
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	mhash "github.com/multiformats/go-multihash"
+)
+
+func TestHashBench(t *testing.T) {
+	codes := make([]uint64, len(defaultBenchHashes))
+	for i, name := range defaultBenchHashes {
+		code, ok := mhash.Names[name]
+		if !ok {
+			t.Fatalf("unknown default hash function %q", name)
+		}
+		codes[i] = code
+	}
+
+	results, err := hashBench(make([]byte, 1024), codes, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(codes) {
+		t.Fatalf("expected %d results, got %d", len(codes), len(results))
+	}
+
+	seen := make(map[string]bool)
+	for i, r := range results {
+		if r.MBps <= 0 {
+			t.Errorf("%s: expected a positive rate, got %f", r.Name, r.MBps)
+		}
+		if mhash.Codes[r.Code] != r.Name {
+			t.Errorf("%s: name doesn't match code %d", r.Name, r.Code)
+		}
+		if i > 0 && r.MBps > results[i-1].MBps {
+			t.Errorf("expected results sorted fastest first")
+		}
+		seen[r.Name] = true
+	}
+	for _, name := range defaultBenchHashes {
+		if !seen[name] {
+			t.Errorf("missing result for %s", name)
+		}
+	}
+}
+
+func TestHashBenchUnsupported(t *testing.T) {
+	if _, err := hashBench([]byte("x"), []uint64{mhash.Names["blake2s-128"]}, time.Millisecond); err == nil {
+		t.Fatal("expected a length without an implementation to fail")
+	}
+}
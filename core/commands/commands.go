@@ -43,8 +43,12 @@ type Command struct {
 	Name        string
 	Subcommands []Command
 	Options     []Option
+	// NoRepo is set, with --no-repo, on the commands that run without a
+	// repo. The other commands listed lead to such commands.
+	NoRepo bool `json:",omitempty"`
 
-	showOpts bool
+	showOpts   bool
+	onlyNoRepo bool
 }
 
 type Option struct {
@@ -52,7 +56,8 @@ type Option struct {
 }
 
 const (
-	flagsOptionName = "flags"
+	flagsOptionName  = "flags"
+	noRepoOptionName = "no-repo"
 )
 
 // CommandsCmd takes in a root command,
@@ -80,6 +85,47 @@ func CommandsCmd(root *cmds.Command) *cmds.Command {
 	}
 }
 
+// ClientCommandsCmd returns CommandsCmd(root), also listing with --no-repo
+// only the commands noRepo reports as running without a repo, which only
+// the client knows.
+func ClientCommandsCmd(root *cmds.Command, noRepo func(path []string) bool) *cmds.Command {
+	cmd := CommandsCmd(root)
+	cmd.Helptext.ShortDescription = `
+Lists all available commands (and subcommands) and exits.
+
+With --no-repo, only the commands that run without a repo are listed, like
+'ipfs cid base32', to know which ones work in environments without any.
+`
+	cmd.Options = append(cmd.Options, cmds.BoolOption(noRepoOptionName, "Only show the commands that run without a repo"))
+	run := cmd.Run
+	cmd.Run = func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		if only, _ := req.Options[noRepoOptionName].(bool); !only {
+			return run(req, res, env)
+		}
+		rootCmd := cmd2outputCmd("ipfs", root)
+		rootCmd.showOpts, _ = req.Options[flagsOptionName].(bool)
+		rootCmd.onlyNoRepo = true
+		markNoRepo(&rootCmd, nil, noRepo)
+		return cmds.EmitOnce(res, &rootCmd)
+	}
+	return cmd
+}
+
+// markNoRepo sets NoRepo on cmd and its subcommands running without a repo,
+// cmd being at path, and drops the subcommands that don't lead to any. It
+// reports whether cmd is kept.
+func markNoRepo(cmd *Command, path []string, noRepo func(path []string) bool) bool {
+	cmd.NoRepo = noRepo(path)
+	subs := cmd.Subcommands[:0]
+	for _, sub := range cmd.Subcommands {
+		if markNoRepo(&sub, append(path[:len(path):len(path)], sub.Name), noRepo) {
+			subs = append(subs, sub)
+		}
+	}
+	cmd.Subcommands = subs
+	return cmd.NoRepo || len(subs) > 0
+}
+
 func cmd2outputCmd(name string, cmd *cmds.Command) Command {
 	opts := make([]Option, len(cmd.Options))
 	for i, opt := range cmd.Options {
@@ -102,9 +148,16 @@ func cmd2outputCmd(name string, cmd *cmds.Command) Command {
 func cmdPathStrings(cmd *Command, showOptions bool) []string {
 	var cmds []string
 
+	onlyNoRepo := cmd.onlyNoRepo
 	var recurse func(prefix string, cmd *Command)
 	recurse = func(prefix string, cmd *Command) {
 		newPrefix := prefix + cmd.Name
+		if onlyNoRepo && !cmd.NoRepo {
+			for _, sub := range cmd.Subcommands {
+				recurse(newPrefix+" ", &sub)
+			}
+			return
+		}
 		cmds = append(cmds, newPrefix)
 		if prefix != "" && showOptions {
 			for _, options := range cmd.Options {
@@ -26,6 +26,7 @@ func TestROCommands(t *testing.T) {
 		"/dag/get",
 		"/dag/resolve",
 		"/dns",
+		"/dns/resolve",
 		"/get",
 		"/ls",
 		"/name",
@@ -85,39 +86,68 @@ func TestCommands(t *testing.T) {
 		"/bootstrap/list",
 		"/bootstrap/rm",
 		"/bootstrap/rm/all",
+		"/bootstrap/test",
+		"/bundle",
+		"/bundle/import",
+		"/cache",
+		"/cache/prewarm",
 		"/cat",
 		"/commands",
 		"/config",
+		"/config/check-addrs",
+		"/config/diff",
 		"/config/edit",
 		"/config/replace",
 		"/config/show",
+		"/config/try",
 		"/config/profile",
 		"/config/profile/apply",
 		"/dag",
 		"/dag/get",
 		"/dag/export",
+		"/dag/car",
+		"/dag/car/verify",
+		"/dag/car/index",
+		"/dag/car/delta",
 		"/dag/put",
 		"/dag/import",
 		"/dag/resolve",
+		"/dag/walk",
 		"/dht",
 		"/dht/findpeer",
 		"/dht/findprovs",
 		"/dht/get",
 		"/dht/provide",
+		"/dht/announce-log",
+		"/dht/reprovide-stats",
+		"/dht/bootstrap-status",
+		"/dht/routingtable",
+		"/dht/routingtable/export",
+		"/dht/routingtable/import",
 		"/dht/put",
 		"/dht/query",
 		"/diag",
+		"/diag/autonat",
 		"/diag/cmds",
 		"/diag/cmds/clear",
 		"/diag/cmds/set-time",
+		"/diag/collect",
+		"/diag/fds",
+		"/diag/goroutines",
+		"/diag/replay",
 		"/diag/sys",
 		"/dns",
+		"/dns/resolve",
+		"/events",
+		"/events/subscribe",
 		"/file",
 		"/file/ls",
 		"/files",
 		"/files/chcid",
 		"/files/cp",
+		"/files/export-tar",
 		"/files/flush",
+		"/files/import-tar",
 		"/files/ls",
 		"/files/mkdir",
 		"/files/mv",
@@ -143,6 +173,7 @@ func TestCommands(t *testing.T) {
 		"/ls",
 		"/mount",
 		"/name",
+		"/name/convert",
 		"/name/publish",
 		"/name/pubsub",
 		"/name/pubsub/state",
@@ -172,11 +203,14 @@ func TestCommands(t *testing.T) {
 		"/p2p/stream/ls",
 		"/pin",
 		"/pin/add",
+		"/pin/diff",
 		"/ping",
 		"/pin/ls",
 		"/pin/rm",
 		"/pin/update",
 		"/pin/verify",
+		"/plugin",
+		"/plugin/export",
 		"/pubsub",
 		"/pubsub/ls",
 		"/pubsub/peers",
@@ -185,8 +219,18 @@ func TestCommands(t *testing.T) {
 		"/refs",
 		"/refs/local",
 		"/repo",
+		"/repo/codec-stats",
+		"/repo/dedup-savings",
+		"/repo/top",
+		"/repo/orphans",
+		"/repo/replication",
+		"/repo/pin-load-bench",
+		"/repo/cold-blocks",
+		"/repo/datastore-stress",
 		"/repo/fsck",
 		"/repo/gc",
+		"/repo/lock",
+		"/repo/lock/status",
 		"/repo/stat",
 		"/repo/verify",
 		"/repo/version",
@@ -201,12 +245,23 @@ func TestCommands(t *testing.T) {
 		"/swarm/addrs",
 		"/swarm/addrs/listen",
 		"/swarm/addrs/local",
+		"/swarm/addrs/effective",
 		"/swarm/connect",
+		"/swarm/denylist",
+		"/swarm/denylist/reload",
 		"/swarm/disconnect",
 		"/swarm/filters",
 		"/swarm/filters/add",
 		"/swarm/filters/rm",
+		"/swarm/limit",
+		"/swarm/limit/set",
+		"/swarm/limit/rm",
+		"/swarm/limit/ls",
+		"/swarm/negotiate",
 		"/swarm/peers",
+		"/swarm/relays",
+		"/swarm/simulate-partition",
+		"/swarm/transport-params",
 		"/tar",
 		"/tar/add",
 		"/tar/cat",
@@ -221,6 +276,7 @@ func TestCommands(t *testing.T) {
 		"/cid/codecs",
 		"/cid/bases",
 		"/cid/hashes",
+		"/cid/hash-bench",
 	}
 
 	cmdSet := make(map[string]struct{})
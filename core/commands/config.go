@@ -60,10 +60,13 @@ Set the value of the 'Datastore.Path' key:
 `,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"show":    configShowCmd,
-		"edit":    configEditCmd,
-		"replace": configReplaceCmd,
-		"profile": configProfileCmd,
+		"show":        configShowCmd,
+		"edit":        configEditCmd,
+		"replace":     configReplaceCmd,
+		"profile":     configProfileCmd,
+		"check-addrs": configCheckAddrsCmd,
+		"try":         configTryCmd,
+		"diff":        configDiffCmd,
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("key", true, false, "The key of the config entry (e.g. \"Addresses.API\")."),
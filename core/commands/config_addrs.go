@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	"github.com/ipfs/go-ipfs-cmds"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// Status values reported by 'ipfs config check-addrs'.
+const (
+	addrStatusOK          = "ok"
+	addrStatusInUse       = "in-use"
+	addrStatusInvalid     = "invalid"
+	addrStatusUnavailable = "unavailable"
+)
+
+// AddrCheck is the result of trying to bind a single configured address.
+type AddrCheck struct {
+	Kind   string
+	Addr   string
+	Status string
+	Error  string `json:",omitempty"`
+}
+
+// AddrCheckOutput is the output of 'ipfs config check-addrs'.
+type AddrCheckOutput struct {
+	Addrs []AddrCheck
+}
+
+var configCheckAddrsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Check that the configured listen addresses can be bound.",
+		ShortDescription: `
+'ipfs config check-addrs' tries to bind every address listed in
+Addresses.API, Addresses.Gateway and Addresses.Swarm, and reports the ones
+that are invalid or already in use. The node is not started.
+
+It should be run while the daemon is stopped, otherwise the addresses held
+by the daemon will be reported as in use.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		cfg, err := cmdenv.GetConfig(env)
+		if err != nil {
+			return err
+		}
+
+		var out AddrCheckOutput
+		check := func(kind string, addrs []string) {
+			for _, a := range addrs {
+				out.Addrs = append(out.Addrs, checkAddr(kind, a))
+			}
+		}
+		check("API", cfg.Addresses.API)
+		check("Gateway", cfg.Addresses.Gateway)
+		check("Swarm", cfg.Addresses.Swarm)
+
+		return cmds.EmitOnce(res, &out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *AddrCheckOutput) error {
+			tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+			for _, c := range out.Addrs {
+				if c.Error != "" {
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Kind, c.Addr, c.Status, c.Error)
+				} else {
+					fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Kind, c.Addr, c.Status)
+				}
+			}
+			return tw.Flush()
+		}),
+	},
+	Type: AddrCheckOutput{},
+}
+
+// checkAddr parses addr and tries to bind it, releasing it straight away.
+func checkAddr(kind, addr string) AddrCheck {
+	c := AddrCheck{Kind: kind, Addr: addr, Status: addrStatusOK}
+
+	maddr, err := ma.NewMultiaddr(addr)
+	if err != nil {
+		c.Status = addrStatusInvalid
+		c.Error = err.Error()
+		return c
+	}
+
+	if err := tryBind(maddr); err != nil {
+		c.Error = err.Error()
+		if errors.Is(err, syscall.EADDRINUSE) {
+			c.Status = addrStatusInUse
+		} else {
+			c.Status = addrStatusUnavailable
+		}
+	}
+	return c
+}
+
+// tryBind binds the transport part of maddr. Addresses carried over UDP
+// (e.g. QUIC) are bound as a packet listener.
+func tryBind(maddr ma.Multiaddr) error {
+	// Only keep the network/transport part, e.g. /ip4/0.0.0.0/udp/4001 for
+	// /ip4/0.0.0.0/udp/4001/quic.
+	var transport ma.Multiaddr
+	ma.ForEach(maddr, func(c ma.Component) bool {
+		if transport == nil {
+			transport = &c
+		} else {
+			transport = transport.Encapsulate(&c)
+		}
+		switch c.Protocol().Code {
+		case ma.P_TCP, ma.P_UDP, ma.P_UNIX:
+			return false
+		}
+		return true
+	})
+
+	network, host, err := manet.DialArgs(transport)
+	if err != nil {
+		return err
+	}
+
+	switch network {
+	case "udp", "udp4", "udp6":
+		l, err := net.ListenPacket(network, host)
+		if err != nil {
+			return err
+		}
+		return l.Close()
+	default:
+		l, err := net.Listen(network, host)
+		if err != nil {
+			return err
+		}
+		return l.Close()
+	}
+}
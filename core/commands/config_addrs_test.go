@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestCheckAddr(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	for _, tc := range []struct {
+		addr   string
+		status string
+	}{
+		{"/ip4/127.0.0.1/tcp/0", addrStatusOK},
+		{"/ip4/127.0.0.1/udp/0/quic", addrStatusOK},
+		{"/ip4/127.0.0.1/tcp/" + strconv.Itoa(port), addrStatusInUse},
+		{"/ip4/127.0.0.1/tcp/" + strconv.Itoa(port) + "/ws", addrStatusInUse},
+		{"/ip4/127.0.0.1/tcp", addrStatusInvalid},
+		{"/dns4/example.com/tcp/80", addrStatusUnavailable},
+	} {
+		c := checkAddr("Swarm", tc.addr)
+		if c.Status != tc.status {
+			t.Errorf("%s: expected status %q, got %q (%s)", tc.addr, tc.status, c.Status, c.Error)
+		}
+	}
+}
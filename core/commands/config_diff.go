@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	"github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	"github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/go-ipfs-config"
+)
+
+// ConfigDiffEntry is a config key whose value differs between the running
+// node and the config file. A nil value means the key is unset on that side.
+type ConfigDiffEntry struct {
+	Key     string
+	Running interface{}
+	Disk    interface{}
+}
+
+// ConfigDiffOutput is the output of 'ipfs config diff'.
+type ConfigDiffOutput struct {
+	Entries []ConfigDiffEntry
+}
+
+var configDiffCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Compare the running config with the config file.",
+		ShortDescription: `
+'ipfs config diff' compares the config the running node is using with the
+config file in the repo, and lists the keys whose values differ. The two
+diverge when the file is edited while the daemon runs, or when the daemon
+was started with --with-config.
+
+Each differing key is printed with the value on disk (-) and the running
+value (+). The private key is never compared.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		cfgRoot, err := cmdenv.GetConfigRoot(env)
+		if err != nil {
+			return err
+		}
+
+		running, err := nd.Repo.Config()
+		if err != nil {
+			return err
+		}
+		entries, err := configDiff(running, cfgRoot)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &ConfigDiffOutput{Entries: entries})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *ConfigDiffOutput) error {
+			for _, e := range out.Entries {
+				fmt.Fprintf(w, "%s\n- %s\n+ %s\n", e.Key, formatConfigValue(e.Disk), formatConfigValue(e.Running))
+			}
+			return nil
+		}),
+	},
+	Type: ConfigDiffOutput{},
+}
+
+// configDiff compares running with the config file in repoPath.
+func configDiff(running *config.Config, repoPath string) ([]ConfigDiffEntry, error) {
+	disk, err := fsrepo.ConfigAt(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	return diffConfigs(running, disk)
+}
+
+// diffConfigs lists the leaf keys whose values differ between running and
+// disk, sorted by key.
+func diffConfigs(running, disk *config.Config) ([]ConfigDiffEntry, error) {
+	runningMap, err := flatConfig(running)
+	if err != nil {
+		return nil, err
+	}
+	diskMap, err := flatConfig(disk)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []ConfigDiffEntry{}
+	for k, rv := range runningMap {
+		if dv, ok := diskMap[k]; !ok || !reflect.DeepEqual(rv, dv) {
+			entries = append(entries, ConfigDiffEntry{Key: k, Running: rv, Disk: dv})
+		}
+	}
+	for k, dv := range diskMap {
+		if _, ok := runningMap[k]; !ok {
+			entries = append(entries, ConfigDiffEntry{Key: k, Disk: dv})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+// flatConfig maps every leaf of cfg to its dotted key, without the private
+// key.
+func flatConfig(cfg *config.Config) (map[string]interface{}, error) {
+	m, err := config.ToMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	flat := make(map[string]interface{})
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+			for k, sv := range sub {
+				walk(prefix+"."+k, sv)
+			}
+			return
+		}
+		flat[strings.TrimPrefix(prefix, ".")] = v
+	}
+	walk("", m)
+	delete(flat, config.IdentityTag+"."+config.PrivKeyTag)
+	return flat, nil
+}
+
+func formatConfigValue(v interface{}) string {
+	if v == nil {
+		return "(unset)"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
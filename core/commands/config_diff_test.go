@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	config "github.com/ipfs/go-ipfs-config"
+	serialize "github.com/ipfs/go-ipfs-config/serialize"
+)
+
+func TestConfigDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk := &config.Config{}
+	disk.Identity.PrivKey = "secret"
+	disk.Swarm.ConnMgr.HighWater = 900
+	disk.Addresses.API = []string{"/ip4/127.0.0.1/tcp/5001"}
+	if err := serialize.WriteConfigFile(filepath.Join(dir, config.DefaultConfigFile), disk); err != nil {
+		t.Fatal(err)
+	}
+
+	running, err := fsrepo.ConfigAt(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := configDiff(running, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no differences before a runtime change, got %v", entries)
+	}
+
+	// change the running config only
+	running.Swarm.ConnMgr.HighWater = 1000
+	running.Addresses.API = append(running.Addresses.API, "/ip4/127.0.0.1/tcp/5002")
+	running.Identity.PrivKey = "other secret"
+
+	entries, err = configDiff(running, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 differences, got %v", entries)
+	}
+	if e := entries[0]; e.Key != "Addresses.API" || formatConfigValue(e.Disk) != `"/ip4/127.0.0.1/tcp/5001"` ||
+		formatConfigValue(e.Running) != `["/ip4/127.0.0.1/tcp/5001","/ip4/127.0.0.1/tcp/5002"]` {
+		t.Errorf("unexpected entry %v", e)
+	}
+	if e := entries[1]; e.Key != "Swarm.ConnMgr.HighWater" || formatConfigValue(e.Disk) != "900" || formatConfigValue(e.Running) != "1000" {
+		t.Errorf("unexpected entry %v", e)
+	}
+}
@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	"github.com/ipfs/go-ipfs/repo/common"
+
+	"github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/go-ipfs-config"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Outcomes reported by 'ipfs config try'.
+const (
+	configTryHot     = "hot"
+	configTryRestart = "restart"
+	configTryInvalid = "invalid"
+)
+
+// hotConfigKeys lists the config keys (and key prefixes) that the daemon
+// re-reads from the repo each time they are used, so changing them takes
+// effect without a restart. Everything else is only read on startup.
+var hotConfigKeys = []string{
+	"Mounts",
+	"Experimental.Libp2pStreamMounting",
+}
+
+// isHotConfigKey reports whether a change to key is picked up by a running
+// daemon.
+func isHotConfigKey(key string) bool {
+	for _, k := range hotConfigKeys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigTryOutput is the output of 'ipfs config try'.
+type ConfigTryOutput struct {
+	Key    string
+	Value  interface{}
+	Result string
+	Reason string `json:",omitempty"`
+}
+
+var configTryCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Check whether a config change would be accepted, without applying it.",
+		ShortDescription: `
+'ipfs config try' validates a config change against the node's current
+config and reports one of:
+
+  hot       the change is valid and a running daemon picks it up immediately
+  restart   the change is valid but only takes effect after a restart
+  invalid   the change would be rejected
+
+Nothing is written to the config file.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("key", true, false, "The key of the config entry (e.g. \"Addresses.API\")."),
+		cmds.StringArg("value", true, false, "The value to try setting the config entry to."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		key, valueStr := req.Arguments[0], req.Arguments[1]
+
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		cfg, err := nd.Repo.Config()
+		if err != nil {
+			return err
+		}
+
+		var value interface{} = valueStr
+		if parseJSON, _ := req.Options[configJSONOptionName].(bool); parseJSON {
+			if err := json.Unmarshal([]byte(valueStr), &value); err != nil {
+				return fmt.Errorf("failed to unmarshal json. %s", err)
+			}
+		} else if isbool, _ := req.Options[configBoolOptionName].(bool); isbool {
+			value = valueStr == "true"
+		}
+
+		out := &ConfigTryOutput{Key: key, Value: value}
+		out.Result, out.Reason = tryConfigChange(cfg, key, value)
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *ConfigTryOutput) error {
+			if out.Reason != "" {
+				_, err := fmt.Fprintf(w, "%s: %s\n", out.Result, out.Reason)
+				return err
+			}
+			_, err := fmt.Fprintln(w, out.Result)
+			return err
+		}),
+	},
+	Type: ConfigTryOutput{},
+}
+
+// tryConfigChange applies key=value to a copy of cfg and classifies the
+// change. The reason is only set for invalid changes.
+func tryConfigChange(cfg *config.Config, key string, value interface{}) (result, reason string) {
+	switch strings.ToLower(key) {
+	case "identity", "identity.privkey":
+		return configTryInvalid, "cannot show or change private key through API"
+	}
+
+	mapconf, err := config.ToMap(cfg)
+	if err != nil {
+		return configTryInvalid, err.Error()
+	}
+
+	// Strings given on the command line are coerced to the type of the
+	// current value, like 'ipfs config <key> <value>' does.
+	if s, ok := value.(string); ok {
+		if old, err := common.MapGetKV(mapconf, key); err == nil {
+			switch old.(type) {
+			case bool:
+				if value, err = strconv.ParseBool(s); err != nil {
+					return configTryInvalid, fmt.Sprintf("expected a boolean: %s", err)
+				}
+			case float64:
+				if value, err = strconv.ParseFloat(s, 64); err != nil {
+					return configTryInvalid, fmt.Sprintf("expected a number: %s", err)
+				}
+			}
+		}
+	}
+
+	if err := common.MapSetKV(mapconf, key, value); err != nil {
+		return configTryInvalid, err.Error()
+	}
+	newCfg, err := config.FromMap(mapconf)
+	if err != nil {
+		return configTryInvalid, err.Error()
+	}
+
+	// Unknown keys are silently dropped when decoding, check that the value
+	// survived the round trip.
+	newMap, err := config.ToMap(newCfg)
+	if err != nil {
+		return configTryInvalid, err.Error()
+	}
+	if _, err := common.MapGetKV(newMap, key); err != nil {
+		return configTryInvalid, fmt.Sprintf("unknown config key %q", key)
+	}
+
+	if err := validateConfigAddrs(newCfg); err != nil {
+		return configTryInvalid, err.Error()
+	}
+
+	if isHotConfigKey(key) {
+		return configTryHot, ""
+	}
+	return configTryRestart, ""
+}
+
+// validateConfigAddrs checks that every configured address is a valid
+// multiaddr.
+func validateConfigAddrs(cfg *config.Config) error {
+	for field, addrs := range map[string][]string{
+		"Addresses.API":        cfg.Addresses.API,
+		"Addresses.Gateway":    cfg.Addresses.Gateway,
+		"Addresses.Swarm":      cfg.Addresses.Swarm,
+		"Addresses.Announce":   cfg.Addresses.Announce,
+		"Addresses.NoAnnounce": cfg.Addresses.NoAnnounce,
+	} {
+		for _, a := range addrs {
+			if _, err := ma.NewMultiaddr(a); err != nil {
+				return fmt.Errorf("%s: invalid multiaddr %q: %s", field, a, err)
+			}
+		}
+	}
+
+	if _, err := cfg.BootstrapPeers(); err != nil {
+		return fmt.Errorf("Bootstrap: %s", err)
+	}
+	return nil
+}
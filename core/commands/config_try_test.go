@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"testing"
+
+	config "github.com/ipfs/go-ipfs-config"
+)
+
+func TestTryConfigChange(t *testing.T) {
+	cfg := &config.Config{}
+
+	for _, tc := range []struct {
+		key    string
+		value  interface{}
+		result string
+	}{
+		{"Mounts.IPFS", "/mnt/ipfs", configTryHot},
+		{"Experimental.Libp2pStreamMounting", "true", configTryHot},
+		{"Swarm.ConnMgr.HighWater", "900", configTryRestart},
+		{"Addresses.API", []interface{}{"/ip4/127.0.0.1/tcp/5002"}, configTryRestart},
+		{"Gateway.NoFetch", true, configTryRestart},
+		{"Addresses.API", []interface{}{"not a multiaddr"}, configTryInvalid},
+		{"Swarm.ConnMgr.HighWater", "lots", configTryInvalid},
+		{"Experimental.Libp2pStreamMounting", "maybe", configTryInvalid},
+		{"Bootstrap", []interface{}{"/ip4/1.2.3.4/tcp/4001"}, configTryInvalid},
+		{"No.Such.Key", "x", configTryInvalid},
+		{"Identity.PrivKey", "x", configTryInvalid},
+	} {
+		result, reason := tryConfigChange(cfg, tc.key, tc.value)
+		if result != tc.result {
+			t.Errorf("%s=%v: expected %q, got %q (%s)", tc.key, tc.value, tc.result, result, reason)
+		}
+		if (result == configTryInvalid) != (reason != "") {
+			t.Errorf("%s=%v: unexpected reason %q for result %q", tc.key, tc.value, reason, result)
+		}
+	}
+
+	if cfg.Mounts.IPFS != "" || cfg.Swarm.ConnMgr.HighWater != 0 {
+		t.Fatal("trying a config change must not modify the config")
+	}
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"encoding/csv"
+	"io"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// CSV is the encoding selected with --enc=csv. List-style commands support
+// it to export their output to spreadsheets.
+const CSV cmds.EncodingType = "csv"
+
+// csvRowsFunc returns the rows of a value emitted by a command.
+type csvRowsFunc func(req *cmds.Request, v interface{}) ([][]string, error)
+
+// makeCSVEncoder returns an encoder for the CSV encoding, writing header
+// before the rows of the first value. Streaming commands emit several values,
+// their rows all go in the same table.
+func makeCSVEncoder(header []string, rows csvRowsFunc) cmds.EncoderFunc {
+	return func(req *cmds.Request) func(io.Writer) cmds.Encoder {
+		return func(w io.Writer) cmds.Encoder {
+			return &csvEncoder{
+				req:    req,
+				w:      csv.NewWriter(w),
+				header: header,
+				rows:   rows,
+			}
+		}
+	}
+}
+
+type csvEncoder struct {
+	req         *cmds.Request
+	w           *csv.Writer
+	header      []string
+	rows        csvRowsFunc
+	wroteHeader bool
+}
+
+func (e *csvEncoder) Encode(v interface{}) error {
+	rows, err := e.rows(e.req, v)
+	if err != nil {
+		return err
+	}
+
+	if !e.wroteHeader {
+		if err := e.w.Write(e.header); err != nil {
+			return err
+		}
+		e.wroteHeader = true
+	}
+	if err := e.w.WriteAll(rows); err != nil {
+		return err
+	}
+	return e.w.Error()
+}
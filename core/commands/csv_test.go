@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	inet "github.com/libp2p/go-libp2p-core/network"
+)
+
+func TestSwarmPeersCSV(t *testing.T) {
+	var buf bytes.Buffer
+	req := &cmds.Request{Options: cmds.OptMap{}}
+	enc := swarmPeersCmd.Encoders[CSV](req)(&buf)
+
+	err := enc.Encode(&connInfos{Peers: []connInfo{
+		{
+			Addr:      "/ip4/203.0.113.7/tcp/4001",
+			Peer:      "QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ",
+			Latency:   "12ms",
+			Direction: inet.DirOutbound,
+			Streams:   []streamInfo{{"/ipfs/bitswap/1.1.0"}, {"/ipfs/kad/1.0.0"}},
+		},
+		{
+			Addr: "/ip4/198.51.100.1/tcp/4001",
+			Peer: "QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe",
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Addr,Peer,Latency,Muxer,Direction,Streams\n" +
+		"/ip4/203.0.113.7/tcp/4001,QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ,12ms,,outbound,/ipfs/bitswap/1.1.0 /ipfs/kad/1.0.0\n" +
+		"/ip4/198.51.100.1/tcp/4001,QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe,,,,\n"
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestPinLsStreamCSV(t *testing.T) {
+	var buf bytes.Buffer
+	req := &cmds.Request{Options: cmds.OptMap{pinStreamOptionName: true}}
+	enc := listPinCmd.Encoders[CSV](req)(&buf)
+
+	for _, p := range []PinLsObject{
+		{Cid: "QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ", Type: "recursive"},
+		{Cid: "QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe", Type: "direct"},
+	} {
+		if err := enc.Encode(&PinLsOutputWrapper{PinLsObject: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the header is only written once
+	expected := "Cid,Type\n" +
+		"QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ,recursive\n" +
+		"QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe,direct\n"
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	if err := enc.Encode("not a pin"); err == nil {
+		t.Fatal("expected an error encoding the wrong type")
+	}
+}
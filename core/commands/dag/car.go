@@ -0,0 +1,221 @@
+package dagcmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	files "github.com/ipfs/go-ipfs-files"
+	ipld "github.com/ipfs/go-ipld-format"
+	gocar "github.com/ipld/go-car"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// CarVerifyOutput is the result of 'ipfs dag car verify'.
+type CarVerifyOutput struct {
+	Roots  []cid.Cid
+	Blocks int
+	// MissingRoots are the roots not in the CAR.
+	MissingRoots []cid.Cid `json:",omitempty"`
+	// Missing are the blocks linked to but not in the CAR.
+	Missing []CarMissingBlock `json:",omitempty"`
+	// Corrupt are the blocks whose data doesn't match their CID.
+	Corrupt []cid.Cid `json:",omitempty"`
+	// Unreachable is the number of blocks not reachable from the roots.
+	Unreachable int
+	// Complete is set when the CAR holds every block of the dags of its
+	// roots, uncorrupted.
+	Complete bool
+	// Ordered is set when the blocks are in the order of a depth-first
+	// traversal of the roots, each block once, as 'ipfs dag export' writes
+	// them.
+	Ordered bool
+}
+
+// CarMissingBlock is a block linked to but not in a CAR.
+type CarMissingBlock struct {
+	Cid    cid.Cid
+	Parent cid.Cid
+}
+
+var DagCarCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Inspect CAR files.",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"verify": DagCarVerifyCmd,
+		"index":  DagCarIndexCmd,
+		"delta":  DagCarDeltaCmd,
+	},
+}
+
+var DagCarVerifyCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Check that a CAR file is complete and ordered.",
+		ShortDescription: `
+'ipfs dag car verify' checks that a CAR file holds its roots and every block
+they link to, with data matching their CID, and whether the blocks are in
+depth-first traversal order, as 'ipfs dag export' writes them:
+
+  > ipfs dag car verify site.car
+  1 root, 214 blocks
+  complete: yes
+  ordered: yes
+
+The command fails if the CAR is incomplete. It doesn't need the repo nor the
+daemon.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.FileArg("file", true, false, "CAR file to verify.").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		it := req.Files.Entries()
+		if !it.Next() {
+			if it.Err() != nil {
+				return it.Err()
+			}
+			return errors.New("expected a CAR file")
+		}
+		file := files.FileFromEntry(it)
+		if file == nil {
+			return errors.New("expected a file handle")
+		}
+		defer file.Close()
+
+		out, err := verifyCar(file)
+		if err != nil {
+			return err
+		}
+		if err := res.Emit(out); err != nil {
+			return err
+		}
+		if !out.Complete {
+			return errors.New("the CAR is incomplete")
+		}
+		return nil
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *CarVerifyOutput) error {
+			roots := "roots"
+			if len(out.Roots) == 1 {
+				roots = "root"
+			}
+			fmt.Fprintf(w, "%d %s, %d blocks\n", len(out.Roots), roots, out.Blocks)
+			for _, c := range out.MissingRoots {
+				fmt.Fprintf(w, "missing root %s\n", c)
+			}
+			for _, m := range out.Missing {
+				fmt.Fprintf(w, "missing %s, linked from %s\n", m.Cid, m.Parent)
+			}
+			for _, c := range out.Corrupt {
+				fmt.Fprintf(w, "corrupt %s\n", c)
+			}
+			if out.Unreachable > 0 {
+				fmt.Fprintf(w, "%d blocks not reachable from the roots\n", out.Unreachable)
+			}
+			fmt.Fprintf(w, "complete: %s\n", yesNo(out.Complete))
+			fmt.Fprintf(w, "ordered: %s\n", yesNo(out.Ordered))
+			return nil
+		}),
+	},
+	Type: CarVerifyOutput{},
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// verifyCar reads the CAR r and checks its blocks against its roots.
+func verifyCar(r io.Reader) (*CarVerifyOutput, error) {
+	car, err := gocar.NewCarReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if car.Header.Version != 1 {
+		return nil, errors.New("only car files version 1 supported at present")
+	}
+
+	out := &CarVerifyOutput{Roots: car.Header.Roots}
+	// the blocks in the order of the CAR, and their links
+	var order []cid.Cid
+	links := make(map[cid.Cid][]cid.Cid)
+	for {
+		block, err := car.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		out.Blocks++
+		c := block.Cid()
+		order = append(order, c)
+		if _, dup := links[c]; dup {
+			continue
+		}
+
+		if sum, err := c.Prefix().Sum(block.RawData()); err != nil || !sum.Equals(c) {
+			out.Corrupt = append(out.Corrupt, c)
+			links[c] = nil
+			continue
+		}
+		nd, err := ipld.Decode(block)
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s: %s", c, err)
+		}
+		for _, l := range nd.Links() {
+			links[c] = append(links[c], l.Cid)
+		}
+		if links[c] == nil {
+			links[c] = []cid.Cid{}
+		}
+	}
+
+	// the blocks in depth-first order from the roots, each once
+	var traversal []cid.Cid
+	visited := make(map[cid.Cid]bool)
+	var visit func(c, parent cid.Cid)
+	visit = func(c, parent cid.Cid) {
+		if visited[c] {
+			return
+		}
+		visited[c] = true
+		children, found := links[c]
+		if !found {
+			// the content of identity CIDs is in the CID itself
+			if c.Prefix().MhType == mh.IDENTITY {
+				return
+			}
+			if parent.Defined() {
+				out.Missing = append(out.Missing, CarMissingBlock{Cid: c, Parent: parent})
+			} else {
+				out.MissingRoots = append(out.MissingRoots, c)
+			}
+			return
+		}
+		traversal = append(traversal, c)
+		for _, child := range children {
+			visit(child, c)
+		}
+	}
+	for _, root := range out.Roots {
+		visit(root, cid.Undef)
+	}
+
+	for c := range links {
+		if !visited[c] {
+			out.Unreachable++
+		}
+	}
+	out.Complete = len(out.MissingRoots) == 0 && len(out.Missing) == 0 && len(out.Corrupt) == 0
+	out.Ordered = len(order) == len(traversal)
+	for i := 0; out.Ordered && i < len(order); i++ {
+		out.Ordered = order[i] == traversal[i]
+	}
+	return out, nil
+}
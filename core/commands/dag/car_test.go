@@ -0,0 +1,72 @@
+package dagcmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	mdtest "github.com/ipfs/go-merkledag/test"
+	gocar "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+)
+
+// writeCar writes a CAR with roots and the given nodes, in order.
+func writeCar(t *testing.T, roots []cid.Cid, nodes ...ipld.Node) *bytes.Buffer {
+	var buf bytes.Buffer
+	if err := gocar.WriteHeader(&gocar.CarHeader{Roots: roots, Version: 1}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, nd := range nodes {
+		if err := carutil.LdWrite(&buf, nd.Cid().Bytes(), nd.RawData()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &buf
+}
+
+func TestVerifyCar(t *testing.T) {
+	ds := mdtest.Mock()
+	nodes := buildDAG(t, ds)
+	root := nodes["root"].Cid()
+
+	var exported bytes.Buffer
+	if err := gocar.WriteCar(context.Background(), ds, []cid.Cid{root}, &exported); err != nil {
+		t.Fatal(err)
+	}
+	out, err := verifyCar(&exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Complete || !out.Ordered || out.Blocks != 5 || out.Unreachable != 0 {
+		t.Fatalf("expected the exported CAR to be complete and ordered, got %+v", out)
+	}
+
+	// c is missing
+	out, err = verifyCar(writeCar(t, []cid.Cid{root}, nodes["root"], nodes["a"], nodes["d"], nodes["b"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Complete || len(out.Missing) != 1 || out.Missing[0].Cid != nodes["c"].Cid() || out.Missing[0].Parent != nodes["a"].Cid() {
+		t.Fatalf("expected c to be missing from a, got %+v", out)
+	}
+
+	// complete, but the leaves first
+	out, err = verifyCar(writeCar(t, []cid.Cid{root}, nodes["c"], nodes["d"], nodes["a"], nodes["b"], nodes["root"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Complete || out.Ordered {
+		t.Fatalf("expected a complete CAR out of order, got %+v", out)
+	}
+
+	// the root is missing, b isn't reachable
+	out, err = verifyCar(writeCar(t, []cid.Cid{nodes["a"].Cid(), root}, nodes["a"], nodes["c"], nodes["d"], nodes["b"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Complete || len(out.MissingRoots) != 1 || out.MissingRoots[0] != root || out.Unreachable != 1 {
+		t.Fatalf("expected the root to be missing, got %+v", out)
+	}
+}
@@ -0,0 +1,143 @@
+package dagcmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	ipld "github.com/ipfs/go-ipld-format"
+	mdag "github.com/ipfs/go-merkledag"
+	gocar "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+)
+
+var DagCarDeltaCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Export the blocks of a dag missing from an older version of it.",
+		ShortDescription: `
+'ipfs dag car delta' streams a CAR rooted at <new-root> holding only the blocks
+of the dag of <new-root> that are not in the dag of <old-root>, to bring a node
+holding the old dag up to date without sending it the whole new one:
+
+  > ipfs dag car delta QmOld QmNew site-update.car
+  > ipfs dag import site-update.car   # on the node holding QmOld
+
+The CAR is written to <output> if given, else on stdout. The subtrees of the
+new dag found in the old one are skipped without being traversed, so both
+dags should be complete in the repo. The blocks are in depth-first traversal
+order of the new dag.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("old-root", true, false, "CID of the dag the receiver already has."),
+		cmds.StringArg("new-root", true, false, "CID of the dag to bring the receiver to."),
+		cmds.StringArg("output", false, false, "File to write the CAR to, instead of stdout."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		var roots [2]cid.Cid
+		for i := range roots {
+			c, err := cid.Decode(req.Arguments[i])
+			if err != nil {
+				return cmds.Errorf(cmds.ErrClient, "invalid root %q: %s", req.Arguments[i], err)
+			}
+			roots[i] = c
+		}
+
+		node, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		pipeR, pipeW := io.Pipe()
+		errCh := make(chan error, 1)
+		go func() {
+			ses := mdag.NewSession(req.Context, node.DAG)
+			_, err := writeCarDelta(req.Context, ses, roots[0], roots[1], pipeW)
+			pipeW.CloseWithError(err)
+			errCh <- err
+		}()
+
+		if err := res.Emit(pipeR); err != nil {
+			pipeR.Close()
+			return err
+		}
+		return <-errCh
+	},
+	PostRun: cmds.PostRunMap{
+		cmds.CLI: func(res cmds.Response, re cmds.ResponseEmitter) error {
+			args := res.Request().Arguments
+			if len(args) < 3 {
+				return cmds.Copy(re, res)
+			}
+
+			f, err := os.Create(args[2])
+			if err != nil {
+				return re.CloseWithError(err)
+			}
+			// don't leave a partial CAR behind
+			fail := func(err error) error {
+				f.Close()
+				os.Remove(f.Name())
+				return re.CloseWithError(err)
+			}
+			for {
+				v, err := res.Next()
+				if err == io.EOF {
+					if err := f.Close(); err != nil {
+						return fail(err)
+					}
+					return re.Close()
+				} else if err != nil {
+					return fail(err)
+				}
+				r, ok := v.(io.Reader)
+				if !ok {
+					return fail(fmt.Errorf("expected a CAR stream, got %T", v))
+				}
+				if _, err := io.Copy(f, r); err != nil {
+					return fail(err)
+				}
+			}
+		},
+	},
+}
+
+// writeCarDelta writes to w a CAR rooted at newRoot with the blocks of its
+// dag not in the dag of oldRoot, returning how many it wrote.
+func writeCarDelta(ctx context.Context, ng ipld.NodeGetter, oldRoot, newRoot cid.Cid, w io.Writer) (int, error) {
+	old := cid.NewSet()
+	if err := mdag.Walk(ctx, mdag.GetLinksWithDAG(ng), oldRoot, old.Visit); err != nil {
+		return 0, fmt.Errorf("traversing the old dag: %s", err)
+	}
+
+	if err := gocar.WriteHeader(&gocar.CarHeader{Roots: []cid.Cid{newRoot}, Version: 1}, w); err != nil {
+		return 0, err
+	}
+
+	// the subtrees of the old dag are in the old dag as a whole
+	visited := cid.NewSet()
+	visit := func(c cid.Cid) bool {
+		return !old.Has(c) && visited.Visit(c)
+	}
+	var written int
+	writeAndGetLinks := func(ctx context.Context, c cid.Cid) ([]*ipld.Link, error) {
+		nd, err := ng.Get(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		if err := carutil.LdWrite(w, c.Bytes(), nd.RawData()); err != nil {
+			return nil, err
+		}
+		written++
+		return nd.Links(), nil
+	}
+	if err := mdag.Walk(ctx, writeAndGetLinks, newRoot, visit); err != nil {
+		return written, fmt.Errorf("traversing the new dag: %s", err)
+	}
+	return written, nil
+}
@@ -0,0 +1,112 @@
+package dagcmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	mdag "github.com/ipfs/go-merkledag"
+	mdtest "github.com/ipfs/go-merkledag/test"
+	gocar "github.com/ipld/go-car"
+)
+
+func TestCarDelta(t *testing.T) {
+	ctx := context.Background()
+	src := mdtest.Mock()
+	oldNodes := buildDAG(t, src)
+
+	// the new dag keeps a, drops b, and adds e linking d and the new f
+	add := func(name string, children ...ipld.Node) *mdag.ProtoNode {
+		nd := mdag.NodeWithData([]byte(name))
+		for _, c := range children {
+			if err := nd.AddNodeLink(c.Cid().String(), c); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := src.Add(ctx, nd); err != nil {
+			t.Fatal(err)
+		}
+		return nd
+	}
+	f := add("f")
+	e := add("e", oldNodes["d"], f)
+	newRoot := add("root2", oldNodes["a"], e)
+
+	var car bytes.Buffer
+	n, err := writeCarDelta(ctx, src, oldNodes["root"].Cid(), newRoot.Cid(), &car)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected root2, e and f in the delta, got %d blocks", n)
+	}
+
+	// a node holding the old dag imports the delta
+	dst := mdtest.Mock()
+	for _, nd := range oldNodes {
+		if err := dst.Add(ctx, nd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cr, err := gocar.NewCarReader(&car)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cr.Header.Roots) != 1 || cr.Header.Roots[0] != newRoot.Cid() {
+		t.Fatalf("expected the delta to be rooted at the new root, got %v", cr.Header.Roots)
+	}
+	var order []cid.Cid
+	for {
+		block, err := cr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		order = append(order, block.Cid())
+		nd, err := ipld.Decode(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dst.Add(ctx, nd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []cid.Cid{newRoot.Cid(), e.Cid(), f.Cid()}
+	for i := range expected {
+		if i >= len(order) || order[i] != expected[i] {
+			t.Fatalf("expected the blocks %v in traversal order, got %v", expected, order)
+		}
+	}
+
+	// the whole new dag is now there
+	var visited int
+	err = mdag.Walk(ctx, mdag.GetLinksWithDAG(dst), newRoot.Cid(), func(c cid.Cid) bool {
+		visited++
+		return true
+	})
+	if err != nil {
+		t.Fatalf("expected the delta and the old dag to make up the new dag: %s", err)
+	}
+	// root2, a, c, d, e, d, f
+	if visited != 7 {
+		t.Fatalf("expected to visit 7 nodes of the new dag, got %d", visited)
+	}
+}
+
+func TestCarDeltaSameRoot(t *testing.T) {
+	ds := mdtest.Mock()
+	root := buildDAG(t, ds)["root"].Cid()
+
+	var car bytes.Buffer
+	n, err := writeCarDelta(context.Background(), ds, root, root, &car)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("expected an empty delta between a dag and itself, got %d blocks", n)
+	}
+}
@@ -0,0 +1,169 @@
+package dagcmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	files "github.com/ipfs/go-ipfs-files"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	gocar "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// carIndexSortedCodec is the multicodec of the CARv2 sorted index format.
+const carIndexSortedCodec = 0x0400
+
+var DagCarIndexCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Index the blocks of a CAR file for random access.",
+		ShortDescription: `
+'ipfs dag car index' writes on stdout an index of the blocks of a CAR file,
+mapping the multihash of each block to the offset of its section in the CAR,
+to read any block without scanning the CAR:
+
+  > ipfs dag car index site.car > site.car.idx
+
+The index is in the CARv2 sorted index format, the offsets being from the
+start of the CAR. It is built in memory, and written once the whole CAR is
+read. It doesn't need the repo nor the daemon.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.FileArg("file", true, false, "CAR file to index.").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		it := req.Files.Entries()
+		if !it.Next() {
+			if it.Err() != nil {
+				return it.Err()
+			}
+			return errors.New("expected a CAR file")
+		}
+		file := files.FileFromEntry(it)
+		if file == nil {
+			return errors.New("expected a file handle")
+		}
+		defer file.Close()
+
+		idx, err := indexCar(file)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := idx.marshal(&buf); err != nil {
+			return err
+		}
+		return res.Emit(&buf)
+	},
+}
+
+// carIndex maps the digests of the multihashes of the blocks of a CAR to the
+// offsets of their section, bucketed by digest length, as in the CARv2 sorted
+// index format.
+type carIndex map[uint32][]carIndexEntry
+
+type carIndexEntry struct {
+	digest []byte
+	offset uint64
+}
+
+// indexCar reads the CAR r and indexes its blocks. The first section of the
+// blocks found several times is indexed.
+func indexCar(r io.Reader) (carIndex, error) {
+	br := bufio.NewReader(r)
+	header, err := carutil.LdRead(br)
+	if err != nil {
+		return nil, err
+	}
+	var ch gocar.CarHeader
+	if err := cbor.DecodeInto(header, &ch); err != nil {
+		return nil, fmt.Errorf("invalid header: %s", err)
+	}
+	if ch.Version != 1 {
+		return nil, errors.New("only car files version 1 supported at present")
+	}
+
+	idx := make(carIndex)
+	seen := make(map[string]bool)
+	offset := sectionSize(header)
+	for {
+		section, err := carutil.LdRead(br)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		c, _, err := carutil.ReadCid(section)
+		if err != nil {
+			return nil, fmt.Errorf("invalid section at offset %d: %s", offset, err)
+		}
+		dmh, err := mh.Decode(c.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if !seen[string(dmh.Digest)] {
+			seen[string(dmh.Digest)] = true
+			width := uint32(len(dmh.Digest)) + 8
+			idx[width] = append(idx[width], carIndexEntry{digest: dmh.Digest, offset: offset})
+		}
+		offset += sectionSize(section)
+	}
+
+	for _, entries := range idx {
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i].digest, entries[j].digest) < 0
+		})
+	}
+	return idx, nil
+}
+
+// sectionSize returns the size of the CAR section holding data, with its
+// length prefix.
+func sectionSize(data []byte) uint64 {
+	var buf [binary.MaxVarintLen64]byte
+	return uint64(binary.PutUvarint(buf[:], uint64(len(data))) + len(data))
+}
+
+// marshal writes idx in the CARv2 sorted index format: the codec, the number
+// of buckets, then each bucket by increasing width, with its width, its size
+// in bytes and its entries, sorted.
+func (idx carIndex) marshal(w io.Writer) error {
+	var codec [binary.MaxVarintLen64]byte
+	if _, err := w.Write(codec[:binary.PutUvarint(codec[:], carIndexSortedCodec)]); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, int32(len(idx))); err != nil {
+		return err
+	}
+
+	widths := make([]uint32, 0, len(idx))
+	for width := range idx {
+		widths = append(widths, width)
+	}
+	sort.Slice(widths, func(i, j int) bool { return widths[i] < widths[j] })
+	for _, width := range widths {
+		entries := idx[width]
+		if err := binary.Write(w, binary.LittleEndian, width); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, int64(len(entries))*int64(width)); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if _, err := w.Write(e.digest); err != nil {
+				return err
+			}
+			if err := binary.Write(w, binary.LittleEndian, e.offset); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
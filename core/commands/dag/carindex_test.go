@@ -0,0 +1,127 @@
+package dagcmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	mdtest "github.com/ipfs/go-merkledag/test"
+	gocar "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func TestIndexCar(t *testing.T) {
+	ds := mdtest.Mock()
+	nodes := buildDAG(t, ds)
+	root := nodes["root"].Cid()
+
+	var car bytes.Buffer
+	if err := gocar.WriteCar(context.Background(), ds, []cid.Cid{root}, &car); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := indexCar(bytes.NewReader(car.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := idx.marshal(&buf); err != nil {
+		t.Fatal(err)
+	}
+	idx, err = readCarIndex(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, nd := range nodes {
+		offset, ok := idx.offset(nd.Cid())
+		if !ok {
+			t.Fatalf("%s is not indexed", name)
+		}
+		if offset >= uint64(car.Len()) {
+			t.Fatalf("%s: offset %d is past the end of the CAR", name, offset)
+		}
+		c, data, err := carutil.ReadNode(bufio.NewReader(bytes.NewReader(car.Bytes()[offset:])))
+		if err != nil {
+			t.Fatalf("%s: reading the block at offset %d: %s", name, offset, err)
+		}
+		if !c.Equals(nd.Cid()) || !bytes.Equal(data, nd.RawData()) {
+			t.Fatalf("%s: expected its block at offset %d, got %s", name, offset, c)
+		}
+	}
+
+	other, err := cid.NewPrefixV1(cid.Raw, mh.SHA2_256).Sum([]byte("not in the CAR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.offset(other); ok {
+		t.Fatal("expected a block not in the CAR not to be indexed")
+	}
+}
+
+// readCarIndex reads an index written by marshal.
+func readCarIndex(r io.Reader) (carIndex, error) {
+	br := bufio.NewReader(r)
+	codec, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if codec != carIndexSortedCodec {
+		return nil, fmt.Errorf("unsupported index codec 0x%x", codec)
+	}
+	var buckets int32
+	if err := binary.Read(br, binary.LittleEndian, &buckets); err != nil {
+		return nil, err
+	}
+
+	idx := make(carIndex)
+	for i := int32(0); i < buckets; i++ {
+		var width uint32
+		var size int64
+		if err := binary.Read(br, binary.LittleEndian, &width); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(br, binary.LittleEndian, &size); err != nil {
+			return nil, err
+		}
+		if width <= 8 || size < 0 || size%int64(width) != 0 {
+			return nil, fmt.Errorf("invalid index bucket of width %d and size %d", width, size)
+		}
+		entries := make([]carIndexEntry, 0, size/int64(width))
+		for n := int64(0); n < size; n += int64(width) {
+			entry := make([]byte, width)
+			if _, err := io.ReadFull(br, entry); err != nil {
+				return nil, err
+			}
+			entries = append(entries, carIndexEntry{
+				digest: entry[:width-8],
+				offset: binary.LittleEndian.Uint64(entry[width-8:]),
+			})
+		}
+		idx[width] = entries
+	}
+	return idx, nil
+}
+
+// offset returns the offset of the section of the block c in the CAR.
+func (idx carIndex) offset(c cid.Cid) (uint64, bool) {
+	dmh, err := mh.Decode(c.Hash())
+	if err != nil {
+		return 0, false
+	}
+	entries := idx[uint32(len(dmh.Digest))+8]
+	i := sort.Search(len(entries), func(i int) bool {
+		return bytes.Compare(entries[i].digest, dmh.Digest) >= 0
+	})
+	if i == len(entries) || !bytes.Equal(entries[i].digest, dmh.Digest) {
+		return 0, false
+	}
+	return entries[i].offset, true
+}
@@ -54,6 +54,8 @@ to deprecate and replace the existing 'ipfs object' command moving forward.
 		"resolve": DagResolveCmd,
 		"import":  DagImportCmd,
 		"export":  DagExportCmd,
+		"walk":    DagWalkCmd,
+		"car":     DagCarCmd,
 	},
 }
 
@@ -0,0 +1,157 @@
+package dagcmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	ipld "github.com/ipfs/go-ipld-format"
+	path "github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+const (
+	streamOptionName   = "stream"
+	maxDepthOptionName = "max-depth"
+)
+
+// WalkLink is a link of a node visited by 'dag walk'.
+type WalkLink struct {
+	Name string `json:",omitempty"`
+	Cid  cid.Cid
+}
+
+// WalkOutput is a node visited by 'dag walk'.
+type WalkOutput struct {
+	Cid   cid.Cid
+	Depth int
+	Size  uint64
+	Links []WalkLink
+}
+
+var DagWalkCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Walk a dag and list the nodes it is made of.",
+		ShortDescription: `
+'ipfs dag walk' traverses the dag below <ref> depth-first and outputs every
+node, with its depth, block size and links, in the order it was visited.
+Nodes reachable through several paths are only output once, with the depth
+they were first reached at.
+
+With --stream, nodes are output as soon as they are visited instead of once
+the walk completes. --max-depth limits how far below the root the walk goes;
+the root is at depth 0.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("ref", true, false, "The dag to walk.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(streamOptionName, "s", "Output nodes as they are visited."),
+		cmds.IntOption(maxDepthOptionName, "Only walk this many levels below the root, -1 for no limit.").WithDefault(-1),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		rp, err := api.ResolvePath(req.Context, path.New(req.Arguments[0]))
+		if err != nil {
+			return err
+		}
+
+		stream, _ := req.Options[streamOptionName].(bool)
+		maxDepth, _ := req.Options[maxDepthOptionName].(int)
+
+		if stream {
+			return walkDAG(req.Context, api.Dag(), rp.Cid(), maxDepth, func(out *WalkOutput) error {
+				return res.Emit(out)
+			})
+		}
+
+		var visited []*WalkOutput
+		err = walkDAG(req.Context, api.Dag(), rp.Cid(), maxDepth, func(out *WalkOutput) error {
+			visited = append(visited, out)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, out := range visited {
+			if err := res.Emit(out); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *WalkOutput) error {
+			enc, err := cmdenv.GetLowLevelCidEncoder(req)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "%d %s %d %d\n", out.Depth, enc.Encode(out.Cid), out.Size, len(out.Links))
+			return err
+		}),
+	},
+	Type: WalkOutput{},
+}
+
+// walkDAG visits the dag below root depth-first, calling visit on each node
+// before its children. Each node is visited once, at the depth it is first
+// reached at. Nodes deeper than maxDepth are not fetched; a negative maxDepth
+// means no limit. A node reached again closer to the root has its children
+// walked again, so that those the limit cut off the first time are visited.
+// The walk stops at the first error returned by visit, or when ctx is done.
+func walkDAG(ctx context.Context, ng ipld.NodeGetter, root cid.Cid, maxDepth int, visit func(*WalkOutput) error) error {
+	// the smallest depth each node was reached at
+	seen := make(map[cid.Cid]int)
+
+	var walk func(c cid.Cid, depth int) error
+	walk = func(c cid.Cid, depth int) error {
+		prev, visited := seen[c]
+		if visited && (maxDepth < 0 || depth >= prev) {
+			return nil
+		}
+		seen[c] = depth
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		nd, err := ng.Get(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		links := nd.Links()
+		if !visited {
+			out := &WalkOutput{
+				Cid:   c,
+				Depth: depth,
+				Size:  uint64(len(nd.RawData())),
+				Links: make([]WalkLink, len(links)),
+			}
+			for i, l := range links {
+				out.Links[i] = WalkLink{Name: l.Name, Cid: l.Cid}
+			}
+			if err := visit(out); err != nil {
+				return err
+			}
+		}
+
+		if maxDepth >= 0 && depth >= maxDepth {
+			return nil
+		}
+		for _, l := range links {
+			if err := walk(l.Cid, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(root, 0)
+}
@@ -0,0 +1,152 @@
+package dagcmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	mdag "github.com/ipfs/go-merkledag"
+	mdtest "github.com/ipfs/go-merkledag/test"
+)
+
+// buildDAG adds the dag
+//
+//	root
+//	├── a
+//	│   ├── c
+//	│   └── d
+//	└── b
+//	    └── d
+//
+// and returns its nodes by name.
+func buildDAG(t *testing.T, ds ipld.DAGService) map[string]*mdag.ProtoNode {
+	nodes := make(map[string]*mdag.ProtoNode)
+	mk := func(name string, children ...string) {
+		nd := mdag.NodeWithData([]byte(name))
+		for _, c := range children {
+			if err := nd.AddNodeLink(c, nodes[c]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := ds.Add(context.Background(), nd); err != nil {
+			t.Fatal(err)
+		}
+		nodes[name] = nd
+	}
+	mk("c")
+	mk("d")
+	mk("a", "c", "d")
+	mk("b", "d")
+	mk("root", "a", "b")
+	return nodes
+}
+
+func TestWalkDAG(t *testing.T) {
+	ds := mdtest.Mock()
+	nodes := buildDAG(t, ds)
+	root := nodes["root"].Cid()
+
+	for _, tc := range []struct {
+		maxDepth int
+		order    []string
+		depths   []int
+	}{
+		{-1, []string{"root", "a", "c", "d", "b"}, []int{0, 1, 2, 2, 1}},
+		{1, []string{"root", "a", "b"}, []int{0, 1, 1}},
+		{0, []string{"root"}, []int{0}},
+	} {
+		var visited []*WalkOutput
+		err := walkDAG(context.Background(), ds, root, tc.maxDepth, func(out *WalkOutput) error {
+			visited = append(visited, out)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(visited) != len(tc.order) {
+			t.Fatalf("max-depth %d: expected %d nodes, got %d", tc.maxDepth, len(tc.order), len(visited))
+		}
+		for i, name := range tc.order {
+			nd := nodes[name]
+			out := visited[i]
+			if !out.Cid.Equals(nd.Cid()) || out.Depth != tc.depths[i] {
+				t.Errorf("max-depth %d: expected %s at depth %d in position %d, got %s at depth %d",
+					tc.maxDepth, name, tc.depths[i], i, out.Cid, out.Depth)
+			}
+			if out.Size != uint64(len(nd.RawData())) || len(out.Links) != len(nd.Links()) {
+				t.Errorf("max-depth %d: wrong size or links for %s: %v", tc.maxDepth, name, out)
+			}
+		}
+	}
+}
+
+func TestWalkDAGMaxDepthDiamond(t *testing.T) {
+	ds := mdtest.Mock()
+	mk := func(name string, children ...*mdag.ProtoNode) *mdag.ProtoNode {
+		nd := mdag.NodeWithData([]byte(name))
+		for _, c := range children {
+			if err := nd.AddNodeLink(string(c.Data()), c); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := ds.Add(context.Background(), nd); err != nil {
+			t.Fatal(err)
+		}
+		return nd
+	}
+	// x is at depth 2 below a, where the walk first reaches it, and at
+	// depth 1 below root
+	y := mk("y")
+	x := mk("x", y)
+	a := mk("a", x)
+	root := mk("root", a, x)
+
+	var visited []*WalkOutput
+	err := walkDAG(context.Background(), ds, root.Cid(), 2, func(out *WalkOutput) error {
+		visited = append(visited, out)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		nd    *mdag.ProtoNode
+		depth int
+	}{{root, 0}, {a, 1}, {x, 2}, {y, 2}}
+	if len(visited) != len(expected) {
+		t.Fatalf("expected %d nodes, got %d", len(expected), len(visited))
+	}
+	for i, e := range expected {
+		if !visited[i].Cid.Equals(e.nd.Cid()) || visited[i].Depth != e.depth {
+			t.Errorf("expected %s at depth %d in position %d, got %s at depth %d",
+				e.nd.Data(), e.depth, i, visited[i].Cid, visited[i].Depth)
+		}
+	}
+}
+
+func TestWalkDAGCancel(t *testing.T) {
+	ds := mdtest.Mock()
+	nodes := buildDAG(t, ds)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var visited []cid.Cid
+	err := walkDAG(ctx, ds, nodes["root"].Cid(), -1, func(out *WalkOutput) error {
+		visited = append(visited, out.Cid)
+		if len(visited) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the walk to be canceled, got %v", err)
+	}
+	if len(visited) != 2 {
+		t.Fatalf("expected the walk to stop after 2 nodes, visited %d", len(visited))
+	}
+}
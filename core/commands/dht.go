@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
 	"time"
 
 	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	e "github.com/ipfs/go-ipfs/core/commands/e"
 
 	cid "github.com/ipfs/go-cid"
 	cmds "github.com/ipfs/go-ipfs-cmds"
@@ -33,12 +35,16 @@ var DhtCmd = &cmds.Command{
 	},
 
 	Subcommands: map[string]*cmds.Command{
-		"query":     queryDhtCmd,
-		"findprovs": findProvidersDhtCmd,
-		"findpeer":  findPeerDhtCmd,
-		"get":       getValueDhtCmd,
-		"put":       putValueDhtCmd,
-		"provide":   provideRefDhtCmd,
+		"query":            queryDhtCmd,
+		"findprovs":        findProvidersDhtCmd,
+		"findpeer":         findPeerDhtCmd,
+		"get":              getValueDhtCmd,
+		"put":              putValueDhtCmd,
+		"provide":          provideRefDhtCmd,
+		"announce-log":     announceLogDhtCmd,
+		"reprovide-stats":  reprovideStatsDhtCmd,
+		"bootstrap-status": bootstrapStatusDhtCmd,
+		"routingtable":     routingTableDhtCmd,
 	},
 }
 
@@ -126,12 +132,22 @@ var queryDhtCmd = &cmds.Command{
 
 const (
 	numProvidersOptionName = "num-providers"
+
+	defaultNumProviders = 20
 )
 
 var findProvidersDhtCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline:          "Find peers that can provide a specific value, given a key.",
 		ShortDescription: "Outputs a list of newline-delimited provider Peer IDs.",
+		LongDescription: `
+Outputs a list of newline-delimited provider Peer IDs as they are found.
+
+The search stops once --num-providers providers have been found. Pass
+--num-providers=0 to keep searching until the DHT has no more providers
+to offer. With --verbose, a final line reports whether the requested
+number of providers was reached or the search was exhausted.
+`,
 	},
 
 	Arguments: []cmds.Argument{
@@ -139,7 +155,7 @@ var findProvidersDhtCmd = &cmds.Command{
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption(dhtVerboseOptionName, "v", "Print extra information."),
-		cmds.IntOption(numProvidersOptionName, "n", "The number of providers to find.").WithDefault(20),
+		cmds.IntOption(numProvidersOptionName, "n", "The number of providers to find, 0 for no limit.").WithDefault(defaultNumProviders),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		n, err := cmdenv.GetNode(env)
@@ -152,8 +168,8 @@ var findProvidersDhtCmd = &cmds.Command{
 		}
 
 		numProviders, _ := req.Options[numProvidersOptionName].(int)
-		if numProviders < 1 {
-			return fmt.Errorf("number of providers must be greater than 0")
+		if numProviders < 0 {
+			return fmt.Errorf("number of providers must not be negative")
 		}
 
 		c, err := cid.Parse(req.Arguments[0])
@@ -165,17 +181,9 @@ var findProvidersDhtCmd = &cmds.Command{
 		ctx, cancel := context.WithCancel(req.Context)
 		ctx, events := routing.RegisterForQueryEvents(ctx)
 
-		pchan := n.Routing.FindProvidersAsync(ctx, c, numProviders)
-
 		go func() {
 			defer cancel()
-			for p := range pchan {
-				np := p
-				routing.PublishQueryEvent(ctx, &routing.QueryEvent{
-					Type:      routing.Provider,
-					Responses: []*peer.AddrInfo{&np},
-				})
-			}
+			streamProviders(ctx, n.Routing, c, numProviders)
 		}()
 		for e := range events {
 			if err := res.Emit(e); err != nil {
@@ -186,10 +194,16 @@ var findProvidersDhtCmd = &cmds.Command{
 		return nil
 	},
 	Encoders: cmds.EncoderMap{
+		CSV: makeCSVEncoder([]string{"Peer", "Addrs"}, providersCSVRows),
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *routing.QueryEvent) error {
 			pfm := pfuncMap{
 				routing.FinalPeer: func(obj *routing.QueryEvent, out io.Writer, verbose bool) error {
-					if verbose {
+					if !verbose {
+						return nil
+					}
+					if obj.Extra != "" {
+						fmt.Fprintf(out, "* %s\n", obj.Extra)
+					} else {
 						fmt.Fprintf(out, "* closest peer %s\n", obj.ID)
 					}
 					return nil
@@ -216,6 +230,61 @@ var findProvidersDhtCmd = &cmds.Command{
 	Type: routing.QueryEvent{},
 }
 
+// providersCSVRows returns a row per provider found, other query events are
+// left out.
+func providersCSVRows(req *cmds.Request, v interface{}) ([][]string, error) {
+	ev, ok := v.(*routing.QueryEvent)
+	if !ok {
+		return nil, e.TypeErr(ev, v)
+	}
+	if ev.Type != routing.Provider {
+		return nil, nil
+	}
+
+	rows := make([][]string, 0, len(ev.Responses))
+	for _, prov := range ev.Responses {
+		addrs := make([]string, len(prov.Addrs))
+		for i, a := range prov.Addrs {
+			addrs[i] = a.String()
+		}
+		rows = append(rows, []string{prov.ID.Pretty(), strings.Join(addrs, " ")})
+	}
+	return rows, nil
+}
+
+// streamProviders publishes a provider query event on ctx for every provider
+// of c found by r, stopping once numProviders providers have been found (0
+// means no limit). It then publishes a final event whose Extra field reports
+// whether the requested count was reached or the search was exhausted.
+func streamProviders(ctx context.Context, r routing.ContentRouting, c cid.Cid, numProviders int) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	found := 0
+	for p := range r.FindProvidersAsync(ctx, c, numProviders) {
+		np := p
+		routing.PublishQueryEvent(ctx, &routing.QueryEvent{
+			Type:      routing.Provider,
+			Responses: []*peer.AddrInfo{&np},
+		})
+		found++
+		if numProviders > 0 && found >= numProviders {
+			break
+		}
+	}
+
+	var summary string
+	if numProviders > 0 && found >= numProviders {
+		summary = fmt.Sprintf("found the requested %d providers", numProviders)
+	} else {
+		summary = fmt.Sprintf("search exhausted after %d providers", found)
+	}
+	routing.PublishQueryEvent(ctx, &routing.QueryEvent{
+		Type:  routing.FinalPeer,
+		Extra: summary,
+	})
+}
+
 const (
 	recursiveOptionName = "recursive"
 )
@@ -277,13 +346,19 @@ var provideRefDhtCmd = &cmds.Command{
 		ctx, cancel := context.WithCancel(req.Context)
 		ctx, events := routing.RegisterForQueryEvents(ctx)
 
+		// the announcements are recorded like those of the reprovider
+		var r routing.ContentRouting = nd.Routing
+		if nd.AnnounceLog != nil {
+			r = nd.AnnounceLog.Router(r)
+		}
+
 		var provideErr error
 		go func() {
 			defer cancel()
 			if rec {
-				provideErr = provideKeysRec(ctx, nd.Routing, nd.DAG, cids)
+				provideErr = provideKeysRec(ctx, r, nd.DAG, cids)
 			} else {
-				provideErr = provideKeys(ctx, nd.Routing, cids)
+				provideErr = provideKeys(ctx, r, cids)
 			}
 			if provideErr != nil {
 				routing.PublishQueryEvent(ctx, &routing.QueryEvent{
@@ -319,7 +394,7 @@ var provideRefDhtCmd = &cmds.Command{
 	Type: routing.QueryEvent{},
 }
 
-func provideKeys(ctx context.Context, r routing.Routing, cids []cid.Cid) error {
+func provideKeys(ctx context.Context, r routing.ContentRouting, cids []cid.Cid) error {
 	for _, c := range cids {
 		err := r.Provide(ctx, c, true)
 		if err != nil {
@@ -329,7 +404,7 @@ func provideKeys(ctx context.Context, r routing.Routing, cids []cid.Cid) error {
 	return nil
 }
 
-func provideKeysRec(ctx context.Context, r routing.Routing, dserv ipld.DAGService, cids []cid.Cid) error {
+func provideKeysRec(ctx context.Context, r routing.ContentRouting, dserv ipld.DAGService, cids []cid.Cid) error {
 	provided := cid.NewSet()
 	for _, c := range cids {
 		kset := cid.NewSet()
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ipfs/go-ipfs/announcelog"
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+const (
+	announceLogFollowOptionName = "follow"
+)
+
+// AnnounceLogEntry is a provide announcement reported by
+// 'ipfs dht announce-log'.
+type AnnounceLogEntry struct {
+	Cid   string
+	Time  time.Time
+	Peers []string
+	Error string `json:",omitempty"`
+}
+
+var announceLogDhtCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the provider announcements made by this node.",
+		ShortDescription: `
+Outputs the most recent provider announcements (provides and reprovides)
+made by this node, oldest first, along with the peers that answered while
+each announcement was routed. With --follow, keeps streaming announcements
+as they are made.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(announceLogFollowOptionName, "f", "Keep streaming new announcements."),
+		cmds.BoolOption(dhtVerboseOptionName, "v", "Print the peers of each announcement."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		if nd.AnnounceLog == nil {
+			return errors.New("provider announcements are not recorded with Experimental.StrategicProviding enabled")
+		}
+
+		emit := func(a announcelog.Announcement) error {
+			e := &AnnounceLogEntry{
+				Cid:   a.Cid.String(),
+				Time:  a.Time,
+				Peers: make([]string, len(a.Peers)),
+			}
+			for i, p := range a.Peers {
+				e.Peers[i] = p.Pretty()
+			}
+			if a.Err != nil {
+				e.Error = a.Err.Error()
+			}
+			return res.Emit(e)
+		}
+
+		follow, _ := req.Options[announceLogFollowOptionName].(bool)
+		if !follow {
+			for _, a := range nd.AnnounceLog.Recent() {
+				if err := emit(a); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		recent, announcements := nd.AnnounceLog.Subscribe(req.Context)
+		for _, a := range recent {
+			if err := emit(a); err != nil {
+				return err
+			}
+		}
+		for a := range announcements {
+			if err := emit(a); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *AnnounceLogEntry) error {
+			fmt.Fprintf(w, "%s %s %d peers", out.Time.Format(time.RFC3339), out.Cid, len(out.Peers))
+			if out.Error != "" {
+				fmt.Fprintf(w, " (%s)", out.Error)
+			}
+			fmt.Fprintln(w)
+
+			if verbose, _ := req.Options[dhtVerboseOptionName].(bool); verbose {
+				for _, p := range out.Peers {
+					fmt.Fprintf(w, "\t%s\n", p)
+				}
+			}
+			return nil
+		}),
+	},
+	Type: AnnounceLogEntry{},
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// BootstrapStatusOutput is the output of 'ipfs dht bootstrap-status'.
+type BootstrapStatusOutput struct {
+	Started             time.Time
+	Rounds              int
+	FailedRounds        int
+	LastRound           time.Time
+	LastError           string `json:",omitempty"`
+	Connected           int
+	RoutingBootstrapped bool
+	Complete            bool
+	// RoutingTable are the sizes of the routing table after the last
+	// rounds, the oldest first.
+	RoutingTable []RoutingTableSize
+}
+
+// RoutingTableSize is the size of the routing table at some time.
+type RoutingTableSize struct {
+	Time time.Time
+	Size int
+}
+
+var bootstrapStatusDhtCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the progress of the bootstrap process.",
+		ShortDescription: `
+Outputs the number of bootstrap rounds run since the daemon started, and how
+many failed to connect to enough bootstrap peers, the peers connected after
+the last round, and the size of the DHT routing table after the last rounds.
+
+The bootstrap is complete once the DHT was bootstrapped and the last round
+found enough peers connected and a routing table with peers. Rounds keep
+running every 30s afterwards, to reconnect when peers are lost.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsOnline {
+			return ErrNotOnline
+		}
+		if nd.BootstrapProgress == nil {
+			return errors.New("the node was not bootstrapped")
+		}
+
+		s := nd.BootstrapProgress.Status()
+		out := &BootstrapStatusOutput{
+			Started:             s.Started,
+			Rounds:              s.Rounds,
+			FailedRounds:        s.FailedRounds,
+			LastRound:           s.LastRound,
+			LastError:           s.LastError,
+			Connected:           s.Connected,
+			RoutingBootstrapped: s.RoutingBootstrapped,
+			Complete:            s.Complete,
+		}
+		for _, sample := range s.RoutingTable {
+			out.RoutingTable = append(out.RoutingTable, RoutingTableSize{Time: sample.Time, Size: sample.Size})
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *BootstrapStatusOutput) error {
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			if out.Complete {
+				fmt.Fprintf(tw, "Status:\tcomplete\n")
+			} else {
+				fmt.Fprintf(tw, "Status:\tin progress\n")
+			}
+			fmt.Fprintf(tw, "Started:\t%s\n", out.Started.Format(time.RFC3339))
+			fmt.Fprintf(tw, "Rounds:\t%d (%d failed)\n", out.Rounds, out.FailedRounds)
+			if out.Rounds > 0 {
+				fmt.Fprintf(tw, "Last round:\t%s\n", out.LastRound.Format(time.RFC3339))
+			}
+			if out.LastError != "" {
+				fmt.Fprintf(tw, "Last error:\t%s\n", out.LastError)
+			}
+			fmt.Fprintf(tw, "Connected peers:\t%d\n", out.Connected)
+			fmt.Fprintf(tw, "DHT bootstrapped:\t%t\n", out.RoutingBootstrapped)
+			if len(out.RoutingTable) > 0 {
+				fmt.Fprintf(tw, "Routing table:\n")
+				for _, s := range out.RoutingTable {
+					fmt.Fprintf(tw, "  %s\t%d peers\n", s.Time.Format(time.RFC3339), s.Size)
+				}
+			}
+			return tw.Flush()
+		}),
+	},
+	Type: BootstrapStatusOutput{},
+}
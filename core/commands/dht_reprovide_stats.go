@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	"github.com/ipfs/go-ipfs/reprovidestats"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// ReprovideStatsOutput is the output of 'ipfs dht reprovide-stats'.
+type ReprovideStatsOutput struct {
+	Queued            int
+	Running           bool
+	Provided          int
+	Remaining         int
+	Rate              float64
+	LastCycle         int
+	LastCycleDuration time.Duration
+	CycleEstimate     time.Duration
+}
+
+var reprovideStatsDhtCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the progress of the reprovider.",
+		ShortDescription: `
+Outputs the number of CIDs waiting in the provide queue, the progress of the
+running reprovide cycle, the rate at which CIDs are reprovided (in CIDs per
+second) and the estimated time to complete a full reprovide cycle at that
+rate. The estimates are based on the last complete cycle, and are not
+available until the node has reprovided at least once.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		if nd.ReprovideStats == nil {
+			return errors.New("reprovides are not tracked with Experimental.StrategicProviding enabled")
+		}
+
+		stats, err := nd.ReprovideStats.Stats()
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, reprovideStatsOutput(stats))
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *ReprovideStatsOutput) error {
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			fmt.Fprintf(tw, "Queued:\t%d\n", out.Queued)
+			if out.Running {
+				fmt.Fprintf(tw, "Reproviding:\t%d done, %d remaining\n", out.Provided, out.Remaining)
+			} else {
+				fmt.Fprintf(tw, "Reproviding:\tidle\n")
+			}
+			if out.LastCycleDuration > 0 {
+				fmt.Fprintf(tw, "Last cycle:\t%d CIDs in %s\n", out.LastCycle, out.LastCycleDuration)
+			}
+			fmt.Fprintf(tw, "Rate:\t%.2f CIDs/s\n", out.Rate)
+			if out.CycleEstimate > 0 {
+				fmt.Fprintf(tw, "Full cycle:\t%s\n", out.CycleEstimate.Round(time.Second))
+			} else {
+				fmt.Fprintf(tw, "Full cycle:\tunknown\n")
+			}
+			return tw.Flush()
+		}),
+	},
+	Type: ReprovideStatsOutput{},
+}
+
+func reprovideStatsOutput(s reprovidestats.Stats) *ReprovideStatsOutput {
+	return &ReprovideStatsOutput{
+		Queued:            s.Queued,
+		Running:           s.Running,
+		Provided:          s.Provided,
+		Remaining:         s.Remaining,
+		Rate:              s.Rate,
+		LastCycle:         s.LastCycle,
+		LastCycleDuration: s.LastCycleDuration,
+		CycleEstimate:     s.CycleEstimate,
+	}
+}
@@ -0,0 +1,253 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	core "github.com/ipfs/go-ipfs/core"
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// routingTableImportConcurrency is the number of peers of a snapshot
+	// dialed at once.
+	routingTableImportConcurrency = 16
+	// routingTableImportDialTimeout bounds connecting to a peer of a
+	// snapshot, most of them likely gone for an old one.
+	routingTableImportDialTimeout = 10 * time.Second
+)
+
+// RoutingTableSnapshot is a saved routing table: the peers in it, and the
+// addresses they were known at.
+type RoutingTableSnapshot struct {
+	Peers []RoutingTablePeer
+}
+
+// RoutingTablePeer is a peer of a RoutingTableSnapshot.
+type RoutingTablePeer struct {
+	ID    string
+	Addrs []string
+}
+
+// RoutingTableImportError is why connecting to a peer of a snapshot failed.
+type RoutingTableImportError struct {
+	Peer  string
+	Error string
+}
+
+// RoutingTableImportOutput is the output of 'ipfs dht routingtable import'.
+type RoutingTableImportOutput struct {
+	Peers     int
+	Connected int
+	Failed    []RoutingTableImportError `json:",omitempty"`
+	// RoutingTable is the size of the routing table right after the peers
+	// were connected to, before the DHT added them all.
+	RoutingTable int
+}
+
+var routingTableDhtCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Manage the DHT routing table.",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"export": routingTableExportDhtCmd,
+		"import": routingTableImportDhtCmd,
+	},
+}
+
+var routingTableExportDhtCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Save the DHT routing table to a snapshot.",
+		ShortDescription: `
+'ipfs dht routingtable export' writes the peers of the DHT routing table, and
+the addresses they are known at, as a snapshot to seed a routing table from
+with 'ipfs dht routingtable import' or 'ipfs daemon --import-routing-table':
+
+  ipfs dht routingtable export > routingtable.json
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsOnline {
+			return ErrNotOnline
+		}
+		if nd.DHT == nil {
+			return ErrNotDHT
+		}
+
+		peers := append(nd.DHT.WAN.RoutingTable().ListPeers(), nd.DHT.LAN.RoutingTable().ListPeers()...)
+		return cmds.EmitOnce(res, routingTableSnapshot(peers, nd.Peerstore.Addrs))
+	},
+	Type: RoutingTableSnapshot{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *RoutingTableSnapshot) error {
+			return json.NewEncoder(w).Encode(out)
+		}),
+	},
+}
+
+var routingTableImportDhtCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Seed the DHT routing table from a saved snapshot.",
+		ShortDescription: `
+'ipfs dht routingtable import' connects to the peers of a routing table
+snapshot, so a freshly started daemon gets a populated routing table without
+waiting for the DHT to find peers by itself. The peers connected to that speak
+the DHT are added to the routing table.
+
+The snapshot is JSON listing the peers and their addresses:
+
+  {"Peers":[{"ID":"QmPeer...","Addrs":["/ip4/1.2.3.4/tcp/4001"]}]}
+
+Peers that can't be reached are reported and skipped. The size of the routing
+table is read right after connecting to the peers, before the DHT added them
+all, so it can be lower than it ends up.
+
+Snapshots are saved with 'ipfs dht routingtable export'.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.FileArg("file", true, false, "The routing table snapshot.").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsOnline {
+			return ErrNotOnline
+		}
+		if nd.DHT == nil {
+			return ErrNotDHT
+		}
+
+		file, err := cmdenv.GetFileArg(req.Files.Entries())
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		peers, err := ReadRoutingTableSnapshot(file)
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid routing table snapshot: %s", err)
+		}
+		return cmds.EmitOnce(res, ImportRoutingTable(req.Context, nd, peers))
+	},
+	Type: RoutingTableImportOutput{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *RoutingTableImportOutput) error {
+			for _, f := range out.Failed {
+				fmt.Fprintf(w, "failed to connect to %s: %s\n", f.Peer, f.Error)
+			}
+			fmt.Fprintf(w, "connected to %d of %d peers, %d in the routing table\n", out.Connected, out.Peers, out.RoutingTable)
+			return nil
+		}),
+	},
+}
+
+// routingTableSnapshot returns the snapshot of a routing table of peers,
+// known at the addresses returned by addrs, sorted by ID.
+func routingTableSnapshot(peers []peer.ID, addrs func(peer.ID) []ma.Multiaddr) *RoutingTableSnapshot {
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+	snapshot := &RoutingTableSnapshot{Peers: []RoutingTablePeer{}}
+	for i, p := range peers {
+		// a peer can be in both the WAN and LAN routing tables
+		if i > 0 && p == peers[i-1] {
+			continue
+		}
+		rp := RoutingTablePeer{ID: p.Pretty(), Addrs: []string{}}
+		for _, a := range addrs(p) {
+			rp.Addrs = append(rp.Addrs, a.String())
+		}
+		snapshot.Peers = append(snapshot.Peers, rp)
+	}
+	return snapshot
+}
+
+// ReadRoutingTableSnapshot reads the peers of a RoutingTableSnapshot from r.
+func ReadRoutingTableSnapshot(r io.Reader) ([]peer.AddrInfo, error) {
+	var snapshot RoutingTableSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	peers := make([]peer.AddrInfo, 0, len(snapshot.Peers))
+	for _, p := range snapshot.Peers {
+		id, err := peer.Decode(p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer ID %q: %s", p.ID, err)
+		}
+		pi := peer.AddrInfo{ID: id}
+		for _, a := range p.Addrs {
+			addr, err := ma.NewMultiaddr(a)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q of %s: %s", a, p.ID, err)
+			}
+			pi.Addrs = append(pi.Addrs, addr)
+		}
+		peers = append(peers, pi)
+	}
+	return peers, nil
+}
+
+// ImportRoutingTable connects nd to peers, read from a routing table
+// snapshot, for its DHT to add them to its routing table.
+func ImportRoutingTable(ctx context.Context, nd *core.IpfsNode, peers []peer.AddrInfo) *RoutingTableImportOutput {
+	// don't dial ourselves, were we in the snapshot
+	var others []peer.AddrInfo
+	for _, pi := range peers {
+		if pi.ID != nd.Identity {
+			others = append(others, pi)
+		}
+	}
+
+	out := importRoutingTable(ctx, others, nd.PeerHost.Connect)
+	out.RoutingTable = nd.DHT.WAN.RoutingTable().Size() + nd.DHT.LAN.RoutingTable().Size()
+	return out
+}
+
+// importRoutingTable connects to peers with connect, a few at a time, and
+// reports which it failed to connect to, in the order of peers.
+func importRoutingTable(ctx context.Context, peers []peer.AddrInfo, connect func(context.Context, peer.AddrInfo) error) *RoutingTableImportOutput {
+	errs := make([]error, len(peers))
+	sem := make(chan struct{}, routingTableImportConcurrency)
+	var wg sync.WaitGroup
+	for i, pi := range peers {
+		wg.Add(1)
+		go func(i int, pi peer.AddrInfo) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+
+			dialCtx, cancel := context.WithTimeout(ctx, routingTableImportDialTimeout)
+			defer cancel()
+			errs[i] = connect(dialCtx, pi)
+		}(i, pi)
+	}
+	wg.Wait()
+
+	out := &RoutingTableImportOutput{Peers: len(peers)}
+	for i, err := range errs {
+		if err != nil {
+			out.Failed = append(out.Failed, RoutingTableImportError{Peer: peers[i].ID.Pretty(), Error: err.Error()})
+			continue
+		}
+		out.Connected++
+	}
+	return out
+}
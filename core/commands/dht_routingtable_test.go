@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/test"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestRoutingTableImport(t *testing.T) {
+	var ids []peer.ID
+	var entries []string
+	for i := 0; i < 3; i++ {
+		id := test.RandPeerIDFatal(t)
+		ids = append(ids, id)
+		entries = append(entries, fmt.Sprintf(`{"ID":%q,"Addrs":["/ip4/10.0.0.%d/tcp/4001"]}`, id.Pretty(), i+1))
+	}
+	peers, err := ReadRoutingTableSnapshot(strings.NewReader(`{"Peers":[` + strings.Join(entries, ",") + `]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 3 {
+		t.Fatalf("expected 3 peers in the snapshot, got %d", len(peers))
+	}
+
+	var mu sync.Mutex
+	attempts := make(map[peer.ID]string)
+	connect := func(ctx context.Context, pi peer.AddrInfo) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(pi.Addrs) != 1 {
+			t.Errorf("expected the address of %s from the snapshot, got %v", pi.ID, pi.Addrs)
+		} else {
+			attempts[pi.ID] = pi.Addrs[0].String()
+		}
+		if pi.ID == ids[1] {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	out := importRoutingTable(context.Background(), peers, connect)
+	for i, id := range ids {
+		if want := fmt.Sprintf("/ip4/10.0.0.%d/tcp/4001", i+1); attempts[id] != want {
+			t.Errorf("expected a connection attempt to %s at %s, got %q", id, want, attempts[id])
+		}
+	}
+	if out.Peers != 3 || out.Connected != 2 {
+		t.Fatalf("expected 2 of 3 peers connected, got %+v", out)
+	}
+	if len(out.Failed) != 1 || out.Failed[0].Peer != ids[1].Pretty() || out.Failed[0].Error != "connection refused" {
+		t.Fatalf("expected the failure of %s, got %+v", ids[1], out.Failed)
+	}
+}
+
+func TestRoutingTableSnapshotInvalid(t *testing.T) {
+	for _, snapshot := range []string{
+		`not json`,
+		`{"Peers":[{"ID":"not a peer"}]}`,
+		`{"Peers":[{"Addrs":["/ip4/10.0.0.1/tcp/4001"]}]}`,
+		`{"Peers":[{"ID":"QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ","Addrs":["not an address"]}]}`,
+	} {
+		if _, err := ReadRoutingTableSnapshot(strings.NewReader(snapshot)); err == nil {
+			t.Errorf("expected snapshot %s to be invalid", snapshot)
+		}
+	}
+}
+
+func TestRoutingTableExport(t *testing.T) {
+	a, b := test.RandPeerIDFatal(t), test.RandPeerIDFatal(t)
+	addrs := func(p peer.ID) []ma.Multiaddr {
+		if p == a {
+			return []ma.Multiaddr{ma.StringCast("/ip4/10.0.0.1/tcp/4001")}
+		}
+		return nil
+	}
+
+	// b is in both the WAN and LAN routing tables
+	snapshot := routingTableSnapshot([]peer.ID{b, a, b}, addrs)
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	peers, err := ReadRoutingTableSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("expected the 2 peers exported once each, got %v", peers)
+	}
+	for _, pi := range peers {
+		if len(pi.Addrs) != len(addrs(pi.ID)) {
+			t.Errorf("expected %s exported with its addresses %v, got %v", pi.ID, addrs(pi.ID), pi.Addrs)
+		}
+	}
+}
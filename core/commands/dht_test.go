@@ -1,11 +1,16 @@
 package commands
 
 import (
+	"context"
+	"strings"
 	"testing"
 
 	"github.com/ipfs/go-ipfs/namesys"
 
+	cid "github.com/ipfs/go-cid"
 	ipns "github.com/ipfs/go-ipns"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	routing "github.com/libp2p/go-libp2p-core/routing"
 	"github.com/libp2p/go-libp2p-core/test"
 )
 
@@ -32,3 +37,74 @@ func TestKeyTranslation(t *testing.T) {
 		t.Fatal("keys didnt match!")
 	}
 }
+
+type stubProviderRouting struct {
+	providers []peer.AddrInfo
+}
+
+func (r *stubProviderRouting) Provide(context.Context, cid.Cid, bool) error {
+	return nil
+}
+
+// FindProvidersAsync deliberately ignores count and returns every provider
+// it knows about.
+func (r *stubProviderRouting) FindProvidersAsync(ctx context.Context, _ cid.Cid, _ int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		for _, p := range r.providers {
+			select {
+			case out <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func collectProviders(r routing.ContentRouting, numProviders int) (int, string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx, events := routing.RegisterForQueryEvents(ctx)
+
+	go func() {
+		defer cancel()
+		streamProviders(ctx, r, cid.NewCidV1(cid.Raw, nil), numProviders)
+	}()
+
+	var found int
+	var summary string
+	for e := range events {
+		switch e.Type {
+		case routing.Provider:
+			found++
+		case routing.FinalPeer:
+			summary = e.Extra
+		}
+	}
+	return found, summary
+}
+
+func TestStreamProviders(t *testing.T) {
+	r := &stubProviderRouting{}
+	for i := 0; i < 2*defaultNumProviders+5; i++ {
+		r.providers = append(r.providers, peer.AddrInfo{ID: test.RandPeerIDFatal(t)})
+	}
+
+	found, summary := collectProviders(r, defaultNumProviders+10)
+	if found != defaultNumProviders+10 {
+		t.Fatalf("expected %d providers, got %d", defaultNumProviders+10, found)
+	}
+	if !strings.Contains(summary, "found the requested") {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+
+	found, summary = collectProviders(r, 0)
+	if found != len(r.providers) {
+		t.Fatalf("expected %d providers, got %d", len(r.providers), found)
+	}
+	if !strings.Contains(summary, "exhausted") {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+}
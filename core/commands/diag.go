@@ -10,7 +10,12 @@ var DiagCmd = &cmds.Command{
 	},
 
 	Subcommands: map[string]*cmds.Command{
-		"sys":  sysDiagCmd,
-		"cmds": ActiveReqsCmd,
+		"sys":        sysDiagCmd,
+		"cmds":       ActiveReqsCmd,
+		"goroutines": goroutinesDiagCmd,
+		"collect":    diagCollectCmd,
+		"autonat":    diagAutoNATCmd,
+		"replay":     diagReplayCmd,
+		"fds":        fdsDiagCmd,
 	},
 }
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	event "github.com/libp2p/go-libp2p-core/event"
+	host "github.com/libp2p/go-libp2p-core/host"
+	network "github.com/libp2p/go-libp2p-core/network"
+)
+
+// DiagAutoNATOutput is the output of 'ipfs diag autonat'.
+type DiagAutoNATOutput struct {
+	Reachability string
+}
+
+var diagAutoNATCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the node's AutoNAT reachability verdict.",
+		ShortDescription: `
+'ipfs diag autonat' reports whether AutoNAT found the node to be publicly
+reachable. AutoNAT asks other peers to dial the node back, the verdict is
+Public or Private once enough of these probes agree, Unknown before that.
+
+The AutoNAT client doesn't expose the probes themselves, only its verdict.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsOnline {
+			return ErrNotOnline
+		}
+
+		out, err := autoNATStatus(nd.PeerHost)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *DiagAutoNATOutput) error {
+			fmt.Fprintf(w, "Reachability: %s\n", out.Reachability)
+			return nil
+		}),
+	},
+	Type: DiagAutoNATOutput{},
+}
+
+// autoNATEventWait bounds the wait for the last reachability event, which the
+// event bus hands to new subscribers from another goroutine.
+const autoNATEventWait = 100 * time.Millisecond
+
+// autoNATStatus returns the AutoNAT state of h. The verdict is the last
+// reachability event: the emitter is stateful, so subscribing delivers it,
+// and no event means AutoNAT didn't decide yet.
+func autoNATStatus(h host.Host) (*DiagAutoNATOutput, error) {
+	sub, err := h.EventBus().Subscribe(new(event.EvtLocalReachabilityChanged))
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Close()
+
+	reachability := network.ReachabilityUnknown
+	select {
+	case e := <-sub.Out():
+		reachability = e.(event.EvtLocalReachabilityChanged).Reachability
+	case <-time.After(autoNATEventWait):
+	}
+
+	return &DiagAutoNATOutput{Reachability: reachability.String()}, nil
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	eventbus "github.com/libp2p/go-eventbus"
+	event "github.com/libp2p/go-libp2p-core/event"
+	network "github.com/libp2p/go-libp2p-core/network"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+func TestAutoNATStatus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h, err := mocknet.New(ctx).GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing decided yet
+	out, err := autoNATStatus(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Reachability != "Unknown" {
+		t.Fatalf("expected an unknown verdict, got %+v", out)
+	}
+
+	em, err := h.EventBus().Emitter(new(event.EvtLocalReachabilityChanged), eventbus.Stateful)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer em.Close()
+	if err := em.Emit(event.EvtLocalReachabilityChanged{Reachability: network.ReachabilityPrivate}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err = autoNATStatus(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Reachability != "Private" {
+		t.Fatalf("expected the Private verdict, got %s", out.Reachability)
+	}
+}
@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime/pprof"
+	"time"
+
+	version "github.com/ipfs/go-ipfs"
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	"github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	config "github.com/ipfs/go-ipfs-config"
+	lwriter "github.com/ipfs/go-log/writer"
+)
+
+const diagCollectLogTimeOptionName = "log-time"
+
+// redactedValue replaces secrets in the collected config.
+const redactedValue = "<redacted>"
+
+// secretConfigKey matches the config keys whose values are never collected,
+// spelled in any case and with or without an underscore (e.g. api_key, ApiKey).
+var secretConfigKey = regexp.MustCompile(`(?i)priv(ate)?_?key|api_?key|access_?key|secret|passw(or)?d|token|authorization|credential`)
+
+// diagFile is a single file of the diagnostics archive.
+type diagFile struct {
+	Name string
+	Data []byte
+}
+
+var diagCollectCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Bundle diagnostics of the node into a .tar.gz archive.",
+		ShortDescription: `
+'ipfs diag collect' writes a .tar.gz archive to stdout, meant to be attached
+to bug reports. It contains:
+
+  config.json      the config, with the private key and other secrets redacted
+  version.json     the ipfs, repo and go versions, and system information
+  plugins.json     the loaded plugins
+  goroutines.txt   the stack traces of all goroutines
+  heap.pprof       a heap profile
+  eventlog.txt     the event log, recorded for --log-time
+  swarm.json       connected peers and bandwidth totals, when online
+  errors.txt       anything that could not be collected
+
+  > ipfs diag collect > diag.tar.gz
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(diagCollectLogTimeOptionName, "How long to record the event log for.").WithDefault("3s"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		cctx := env.(*oldcmds.Context)
+
+		logTimeStr, _ := req.Options[diagCollectLogTimeOptionName].(string)
+		logTime, err := time.ParseDuration(logTimeStr)
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid log time: %s", err)
+		}
+
+		var plugins interface{}
+		if cctx.Plugins != nil {
+			plugins = cctx.Plugins.Plugins()
+		}
+
+		files := collectDiagnostics(req.Context, nd, plugins, logTime)
+
+		var buf bytes.Buffer
+		if err := writeDiagArchive(&buf, files); err != nil {
+			return err
+		}
+		return res.Emit(&buf)
+	},
+}
+
+// collectDiagnostics gathers the files of the diagnostics archive. Failing
+// to collect one of them doesn't stop the others, the errors are listed in
+// errors.txt instead.
+func collectDiagnostics(ctx context.Context, nd *core.IpfsNode, plugins interface{}, logTime time.Duration) []diagFile {
+	var (
+		files []diagFile
+		errs  bytes.Buffer
+	)
+	add := func(name string, collect func() ([]byte, error)) {
+		data, err := collect()
+		if err != nil {
+			fmt.Fprintf(&errs, "%s: %s\n", name, err)
+			return
+		}
+		files = append(files, diagFile{Name: name, Data: data})
+	}
+	asJSON := func(v interface{}) ([]byte, error) {
+		return json.MarshalIndent(v, "", "  ")
+	}
+
+	add("config.json", func() ([]byte, error) {
+		cfg, err := nd.Repo.Config()
+		if err != nil {
+			return nil, err
+		}
+		m, err := config.ToMap(cfg)
+		if err != nil {
+			return nil, err
+		}
+		redactSecrets(m)
+		return asJSON(m)
+	})
+	add("version.json", func() ([]byte, error) {
+		info := map[string]interface{}{
+			"ipfs_version": version.CurrentVersionNumber,
+			"ipfs_commit":  version.CurrentCommit,
+			"repo_version": fsrepo.RepoVersion,
+		}
+		// keep what could be gathered, a missing disk doesn't hide the rest
+		for _, f := range []func(map[string]interface{}) error{runtimeInfo, diskSpaceInfo, memInfo} {
+			if err := f(info); err != nil {
+				fmt.Fprintf(&errs, "version.json: %s\n", err)
+			}
+		}
+		return asJSON(info)
+	})
+	add("plugins.json", func() ([]byte, error) {
+		return asJSON(plugins)
+	})
+	add("goroutines.txt", func() ([]byte, error) {
+		var buf bytes.Buffer
+		err := pprof.Lookup("goroutine").WriteTo(&buf, 2)
+		return buf.Bytes(), err
+	})
+	add("heap.pprof", func() ([]byte, error) {
+		var buf bytes.Buffer
+		err := pprof.Lookup("heap").WriteTo(&buf, 0)
+		return buf.Bytes(), err
+	})
+	add("eventlog.txt", func() ([]byte, error) {
+		return recordEventLog(ctx, logTime), nil
+	})
+	if nd.IsOnline {
+		add("swarm.json", func() ([]byte, error) {
+			return asJSON(swarmDiagnostics(nd))
+		})
+	}
+
+	if errs.Len() > 0 {
+		files = append(files, diagFile{Name: "errors.txt", Data: errs.Bytes()})
+	}
+	return files
+}
+
+// redactSecrets replaces the values of secret looking keys in v, at any
+// depth, including the maps in arrays like the mounts of Datastore.Spec.
+func redactSecrets(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range v {
+			if secretConfigKey.MatchString(k) {
+				v[k] = redactedValue
+				continue
+			}
+			redactSecrets(sub)
+		}
+	case []interface{}:
+		for _, sub := range v {
+			redactSecrets(sub)
+		}
+	}
+}
+
+// recordEventLog returns the event log written during d.
+func recordEventLog(ctx context.Context, d time.Duration) []byte {
+	if d <= 0 {
+		return nil
+	}
+
+	r, w := io.Pipe()
+	lwriter.WriterGroup.AddWriter(w)
+
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&buf, r)
+		close(done)
+	}()
+
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+	// the writer group drops writers once they fail
+	w.Close()
+	<-done
+	return buf.Bytes()
+}
+
+func swarmDiagnostics(nd *core.IpfsNode) map[string]interface{} {
+	var conns []map[string]string
+	for _, c := range nd.PeerHost.Network().Conns() {
+		conns = append(conns, map[string]string{
+			"peer":      c.RemotePeer().Pretty(),
+			"addr":      c.RemoteMultiaddr().String(),
+			"direction": c.Stat().Direction.String(),
+		})
+	}
+
+	out := map[string]interface{}{
+		"peers":       len(nd.PeerHost.Network().Peers()),
+		"connections": conns,
+	}
+	if nd.Reporter != nil {
+		out["bandwidth"] = nd.Reporter.GetBandwidthTotals()
+	}
+	return out
+}
+
+// writeDiagArchive writes files as a .tar.gz archive.
+func writeDiagArchive(w io.Writer, files []diagFile) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+
+	now := time.Now()
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    f.Name,
+			Mode:    0644,
+			Size:    int64(len(f.Data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	core "github.com/ipfs/go-ipfs/core"
+	repo "github.com/ipfs/go-ipfs/repo"
+
+	datastore "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	config "github.com/ipfs/go-ipfs-config"
+)
+
+func TestDiagCollect(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "diag-collect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("IPFS_PATH", os.Getenv("IPFS_PATH"))
+	os.Setenv("IPFS_PATH", dir)
+
+	const secret = "c2VjcmV0LXByaXZhdGUta2V5"
+	r := &repo.Mock{
+		C: config.Config{
+			Identity: config.Identity{
+				PeerID: "QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe", // required by offline node
+			},
+			API: config.API{
+				HTTPHeaders: map[string][]string{
+					"Authorization": {"Bearer " + secret},
+				},
+			},
+		},
+		D: syncds.MutexWrap(datastore.NewMapDatastore()),
+	}
+	n, err := core.NewNode(ctx, &core.BuildCfg{Repo: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	// set after construction, the node would try to decode it
+	r.C.Identity.PrivKey = secret
+
+	var buf bytes.Buffer
+	files := collectDiagnostics(ctx, n, []string{"some-plugin"}, 0)
+	if err := writeDiagArchive(&buf, files); err != nil {
+		t.Fatal(err)
+	}
+
+	gzr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gzr)
+	entries := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[hdr.Name] = data
+	}
+
+	for _, name := range []string{"config.json", "version.json", "plugins.json", "goroutines.txt", "heap.pprof", "eventlog.txt"} {
+		if _, ok := entries[name]; !ok {
+			t.Errorf("archive is missing %s", name)
+		}
+	}
+	if _, ok := entries["swarm.json"]; ok {
+		t.Error("offline node should not have swarm.json")
+	}
+	if errs, ok := entries["errors.txt"]; ok {
+		t.Errorf("unexpected errors: %s", errs)
+	}
+
+	for name, data := range entries {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("%s leaks a secret", name)
+		}
+	}
+
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(entries["config.json"], &cfg); err != nil {
+		t.Fatal(err)
+	}
+	ident := cfg["Identity"].(map[string]interface{})
+	if ident["PrivKey"] != redactedValue {
+		t.Errorf("PrivKey not redacted: %v", ident["PrivKey"])
+	}
+	if ident["PeerID"] != "QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe" {
+		t.Errorf("PeerID should be kept, got %v", ident["PeerID"])
+	}
+	if !strings.Contains(string(entries["plugins.json"]), "some-plugin") {
+		t.Errorf("plugins.json doesn't list the plugins: %s", entries["plugins.json"])
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	m := map[string]interface{}{
+		"PrivKey": "key",
+		"Name":    "node",
+		"Nested": map[string]interface{}{
+			"ApiToken": "token",
+			"Password": 1234,
+			"Port":     4001,
+		},
+		"Remote": map[string]interface{}{
+			"ApiKey":       "key",
+			"access_key":   "key",
+			"PrivateKey":   "key",
+			"Passwd":       "pass",
+			"Credentials":  "creds",
+			"KeyspaceSize": 64,
+		},
+		"Spec": map[string]interface{}{
+			"mounts": []interface{}{
+				map[string]interface{}{"mountpoint": "/blocks", "accessKey": "key", "secretKey": "secret"},
+				"not a map",
+			},
+		},
+	}
+	redactSecrets(m)
+
+	if m["PrivKey"] != redactedValue || m["Name"] != "node" {
+		t.Errorf("unexpected top level: %v", m)
+	}
+	nested := m["Nested"].(map[string]interface{})
+	if nested["ApiToken"] != redactedValue || nested["Password"] != redactedValue || nested["Port"] != 4001 {
+		t.Errorf("unexpected nested level: %v", nested)
+	}
+	remote := m["Remote"].(map[string]interface{})
+	for _, k := range []string{"ApiKey", "access_key", "PrivateKey", "Passwd", "Credentials"} {
+		if remote[k] != redactedValue {
+			t.Errorf("%s not redacted: %v", k, remote[k])
+		}
+	}
+	if remote["KeyspaceSize"] != 64 {
+		t.Errorf("KeyspaceSize redacted: %v", remote["KeyspaceSize"])
+	}
+	mount := m["Spec"].(map[string]interface{})["mounts"].([]interface{})[0].(map[string]interface{})
+	if mount["accessKey"] != redactedValue || mount["secretKey"] != redactedValue || mount["mountpoint"] != "/blocks" {
+		t.Errorf("unexpected mount: %v", mount)
+	}
+}
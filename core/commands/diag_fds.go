@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// FDsOutput is the output of 'ipfs diag fds'.
+type FDsOutput struct {
+	// Open is the number of file descriptors open in the process.
+	Open int
+	// SoftLimit and HardLimit are the limits on the file descriptors of
+	// the process, as set with ulimit -n and ulimit -Hn.
+	SoftLimit uint64
+	HardLimit uint64
+	// Sockets, Pipes and Files are the open file descriptors by type,
+	// Other the rest, like devices and directories.
+	Sockets int
+	Pipes   int
+	Files   int
+	Other   int
+}
+
+var fdsDiagCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Report the file descriptors in use and their limits.",
+		ShortDescription: `
+'ipfs diag fds' prints how many file descriptors the daemon (or this process
+when the daemon is not running) has open, out of its limits, by type:
+
+  > ipfs diag fds
+  open: 1024 of 8192 (hard limit 1048576)
+  sockets: 961
+  pipes: 12
+  files: 40
+  other: 11
+
+A daemon running out of file descriptors fails to accept connections and to
+open its datastore: raise the soft limit, or set IPFS_FD_MAX before starting
+it. Only supported on Unix systems.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		out, err := fdUsage()
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *FDsOutput) error {
+			fmt.Fprintf(w, "open: %d of %d (hard limit %d)\n", out.Open, out.SoftLimit, out.HardLimit)
+			fmt.Fprintf(w, "sockets: %d\n", out.Sockets)
+			fmt.Fprintf(w, "pipes: %d\n", out.Pipes)
+			fmt.Fprintf(w, "files: %d\n", out.Files)
+			fmt.Fprintf(w, "other: %d\n", out.Other)
+			return nil
+		}),
+	},
+	Type: FDsOutput{},
+}
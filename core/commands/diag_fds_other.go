@@ -0,0 +1,12 @@
+// +build !darwin,!linux,!netbsd,!openbsd
+
+package commands
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func fdUsage() (*FDsOutput, error) {
+	return nil, fmt.Errorf("file descriptor usage is not supported on %s", runtime.GOOS)
+}
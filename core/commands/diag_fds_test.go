@@ -0,0 +1,57 @@
+// +build darwin linux netbsd openbsd
+
+package commands
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestFDUsage(t *testing.T) {
+	before, err := fdUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		f, err := ioutil.TempFile("", "diag-fds")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	out, err := fdUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Open < before.Open+6 {
+		t.Fatalf("expected at least %d open file descriptors, got %d", before.Open+6, out.Open)
+	}
+	if out.Open != out.Sockets+out.Pipes+out.Files+out.Other {
+		t.Fatalf("expected the types to add up to the open file descriptors, got %+v", out)
+	}
+	if out.Files < before.Files+3 || out.Sockets < before.Sockets+1 || out.Pipes < before.Pipes+2 {
+		t.Fatalf("expected 3 more files, 1 more socket and 2 more pipes than %+v, got %+v", before, out)
+	}
+	if out.SoftLimit == 0 || out.HardLimit < out.SoftLimit {
+		t.Fatalf("implausible limits: soft %d, hard %d", out.SoftLimit, out.HardLimit)
+	}
+	if uint64(out.Open) > out.SoftLimit {
+		t.Fatalf("more file descriptors open (%d) than the soft limit (%d)", out.Open, out.SoftLimit)
+	}
+}
@@ -0,0 +1,53 @@
+// +build darwin linux netbsd openbsd
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	unix "golang.org/x/sys/unix"
+)
+
+// fdUsage counts the file descriptors open in the process by type, listing
+// them from /proc/self/fd or, where there is no procfs, /dev/fd.
+func fdUsage() (*FDsOutput, error) {
+	var rlimit unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_NOFILE, &rlimit); err != nil {
+		return nil, err
+	}
+	out := &FDsOutput{SoftLimit: uint64(rlimit.Cur), HardLimit: uint64(rlimit.Max)}
+
+	dir := "/proc/self/fd"
+	if _, err := os.Stat(dir); err != nil {
+		dir = "/dev/fd"
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		fd, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		// the descriptor listing the directory is closed by now
+		var st unix.Stat_t
+		if err := unix.Fstat(fd, &st); err != nil {
+			continue
+		}
+		out.Open++
+		switch uint32(st.Mode) & unix.S_IFMT {
+		case unix.S_IFSOCK:
+			out.Sockets++
+		case unix.S_IFIFO:
+			out.Pipes++
+		case unix.S_IFREG:
+			out.Files++
+		default:
+			out.Other++
+		}
+	}
+	return out, nil
+}
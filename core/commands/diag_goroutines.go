@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime/pprof"
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+const (
+	goroutinesFilterOptionName = "filter"
+)
+
+// GoroutinesOutput is the output of 'ipfs diag goroutines'.
+type GoroutinesOutput struct {
+	Total      int
+	Goroutines []string
+}
+
+var goroutinesDiagCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Dump the stack traces of all goroutines.",
+		ShortDescription: `
+'ipfs diag goroutines' prints the stack trace of every goroutine of the
+daemon (or of this process when the daemon is not running).
+
+With --filter, only the goroutines whose stack trace contains one of the
+given substrings are printed, e.g. '--filter bitswap' or
+'--filter go-bitswap.(*Bitswap).'.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringsOption(goroutinesFilterOptionName, "Only show goroutines whose stack contains this substring. May be given multiple times."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		var buf bytes.Buffer
+		if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+			return err
+		}
+
+		filters, _ := req.Options[goroutinesFilterOptionName].([]string)
+		goroutines := splitGoroutineDump(buf.String())
+		return cmds.EmitOnce(res, &GoroutinesOutput{
+			Total:      len(goroutines),
+			Goroutines: filterGoroutines(goroutines, filters),
+		})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *GoroutinesOutput) error {
+			for _, g := range out.Goroutines {
+				fmt.Fprintf(w, "%s\n\n", g)
+			}
+			fmt.Fprintf(w, "%d of %d goroutines\n", len(out.Goroutines), out.Total)
+			return nil
+		}),
+	},
+	Type: GoroutinesOutput{},
+}
+
+// splitGoroutineDump splits a debug=2 goroutine profile into the stack
+// traces of the individual goroutines.
+func splitGoroutineDump(dump string) []string {
+	var goroutines []string
+	for _, g := range strings.Split(dump, "\n\n") {
+		if g = strings.TrimSpace(g); g != "" {
+			goroutines = append(goroutines, g)
+		}
+	}
+	return goroutines
+}
+
+// filterGoroutines returns the goroutines whose stack trace contains any of
+// the filters, or all of them when no filter is given.
+func filterGoroutines(goroutines []string, filters []string) []string {
+	if len(filters) == 0 {
+		return goroutines
+	}
+
+	var out []string
+	for _, g := range goroutines {
+		for _, f := range filters {
+			if strings.Contains(g, f) {
+				out = append(out, g)
+				break
+			}
+		}
+	}
+	return out
+}
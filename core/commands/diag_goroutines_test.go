@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+const testGoroutineDump = `goroutine 1 [running]:
+main.main()
+	/go/src/main.go:10 +0x20
+
+goroutine 21 [select]:
+github.com/ipfs/go-bitswap.(*Bitswap).provideWorker(0xc0001)
+	/go/pkg/mod/github.com/ipfs/go-bitswap@v0.2.19/workers.go:150 +0x1a0
+created by github.com/ipfs/go-bitswap.(*Bitswap).startWorkers
+	/go/pkg/mod/github.com/ipfs/go-bitswap@v0.2.19/workers.go:30 +0x80
+
+goroutine 35 [IO wait]:
+internal/poll.runtime_pollWait(0x7f, 0x72)
+	/usr/local/go/src/runtime/netpoll.go:203 +0x55
+github.com/libp2p/go-libp2p-swarm.(*Swarm).AddListenAddr.func2()
+	/go/pkg/mod/github.com/libp2p/go-libp2p-swarm@v0.2.4/swarm_listen.go:92 +0x100
+
+goroutine 40 [chan receive]:
+github.com/ipfs/go-bitswap/internal/messagequeue.(*MessageQueue).runQueue(0xc0002)
+	/go/pkg/mod/github.com/ipfs/go-bitswap@v0.2.19/internal/messagequeue/messagequeue.go:383 +0x2b0
+`
+
+func TestFilterGoroutines(t *testing.T) {
+	goroutines := splitGoroutineDump(testGoroutineDump)
+	if len(goroutines) != 4 {
+		t.Fatalf("expected 4 goroutines, got %d", len(goroutines))
+	}
+
+	cases := []struct {
+		filters  []string
+		expected []string
+	}{
+		{nil, []string{"goroutine 1 ", "goroutine 21 ", "goroutine 35 ", "goroutine 40 "}},
+		{[]string{"bitswap"}, []string{"goroutine 21 ", "goroutine 40 "}},
+		{[]string{"go-bitswap.(*Bitswap)."}, []string{"goroutine 21 "}},
+		{[]string{"messagequeue", "go-libp2p-swarm"}, []string{"goroutine 35 ", "goroutine 40 "}},
+		{[]string{"no-such-package"}, nil},
+	}
+	for _, c := range cases {
+		got := filterGoroutines(goroutines, c.filters)
+		if len(got) != len(c.expected) {
+			t.Errorf("filters %v: expected %d goroutines, got %d", c.filters, len(c.expected), len(got))
+			continue
+		}
+		for i, g := range got {
+			if !strings.HasPrefix(g, c.expected[i]) {
+				t.Errorf("filters %v: goroutine %d: expected %q..., got %q", c.filters, i, c.expected[i], g)
+			}
+		}
+	}
+}
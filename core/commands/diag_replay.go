@@ -0,0 +1,312 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	cmdhttp "github.com/ipfs/go-ipfs-cmds/http"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+const (
+	replayRateOptionName           = "rate"
+	replayTargetOptionName         = "target"
+	replayAllowMutationsOptionName = "allow-mutations"
+)
+
+// ReplayOutput is the output of 'ipfs diag replay'.
+type ReplayOutput struct {
+	Requests int
+	Failed   int
+	// Errors holds the first error of every failing command.
+	Errors   map[string]string `json:",omitempty"`
+	Duration time.Duration
+	// Rate is the achieved rate, in requests per second.
+	Rate float64
+}
+
+var diagReplayCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Replay logged commands against a daemon, for load testing.",
+		ShortDescription: `
+'ipfs diag replay' sends the commands of a request log, as output by
+'ipfs diag cmds --enc=json', to a daemon at a steady rate. Requests are sent
+on schedule, without waiting for the previous ones to complete:
+
+  > ipfs diag cmds --enc=json > requests.json
+  > ipfs diag replay requests.json --rate 50/s --target /ip4/10.0.0.2/tcp/5001
+  replayed 1200 requests in 24.01s (49.98/s), 0 failed
+
+The rate is a number of requests per second, minute or hour, like '50/s' or
+'600/m'. The target is a multiaddr or a URL, the local daemon by default.
+
+Only the commands of the read-only API, the one served by read-only gateways,
+are replayed by default. Others (add, pin, files, ...) may change the state of
+the node and require --allow-mutations. Commands sending files can't be
+replayed, their content isn't logged.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.FileArg("logfile", true, false, "Request log to replay.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(replayRateOptionName, "Rate of requests, as <n>/s, <n>/m or <n>/h.").WithDefault("10/s"),
+		cmds.StringOption(replayTargetOptionName, "API address of the daemon to load, a multiaddr or URL. Default: the local daemon."),
+		cmds.BoolOption(replayAllowMutationsOptionName, "Replay commands that change the state of the node."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		interval, err := parseReplayRate(req.Options[replayRateOptionName].(string))
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid --%s: %s", replayRateOptionName, err)
+		}
+		allowMutations, _ := req.Options[replayAllowMutationsOptionName].(bool)
+
+		target, _ := req.Options[replayTargetOptionName].(string)
+		if target == "" {
+			cfgRoot, err := cmdenv.GetConfigRoot(env)
+			if err != nil {
+				return err
+			}
+			apiAddr, err := fsrepo.APIAddr(cfgRoot)
+			if err != nil {
+				return fmt.Errorf("no --%s given and the local daemon's address is unknown: %s", replayTargetOptionName, err)
+			}
+			naddr, err := manet.ToNetAddr(apiAddr)
+			if err != nil {
+				return err
+			}
+			target = naddr.String()
+		}
+		base, err := remoteAPIURL(target)
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid --%s: %s", replayTargetOptionName, err)
+		}
+		if !strings.HasPrefix(base, "http://") {
+			return cmds.Errorf(cmds.ErrClient, "invalid --%s: only http targets are supported", replayTargetOptionName)
+		}
+
+		it := req.Files.Entries()
+		if !it.Next() {
+			if it.Err() != nil {
+				return it.Err()
+			}
+			return fmt.Errorf("no log file given")
+		}
+		f, ok := it.Node().(io.Reader)
+		if !ok {
+			return cmds.Errorf(cmds.ErrClient, "expected a log file, got a directory")
+		}
+		entries, err := readReplayLog(f, allowMutations)
+		if err != nil {
+			return err
+		}
+
+		exe := cmdhttp.NewClient(base, cmdhttp.ClientWithAPIPrefix("/api/v0"))
+		return cmds.EmitOnce(res, replayRequests(req.Context, exe, entries, interval))
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *ReplayOutput) error {
+			fmt.Fprintf(w, "replayed %d requests in %s (%.2f/s), %d failed\n", out.Requests, out.Duration.Round(10*time.Millisecond), out.Rate, out.Failed)
+			failing := make([]string, 0, len(out.Errors))
+			for c := range out.Errors {
+				failing = append(failing, c)
+			}
+			sort.Strings(failing)
+			for _, c := range failing {
+				fmt.Fprintf(w, "  %s: %s\n", c, out.Errors[c])
+			}
+			return nil
+		}),
+	},
+	Type: ReplayOutput{},
+}
+
+// parseReplayRate returns the interval between requests for a rate given as
+// <n>/s, <n>/m or <n>/h.
+func parseReplayRate(s string) (time.Duration, error) {
+	i := strings.LastIndex(s, "/")
+	if i < 0 {
+		return 0, fmt.Errorf("expected <n>/s, <n>/m or <n>/h, got %q", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || !(n > 0) || math.IsInf(n, 1) {
+		return 0, fmt.Errorf("invalid number of requests %q", s[:i])
+	}
+	per, err := time.ParseDuration("1" + s[i+1:])
+	if err != nil || (per != time.Second && per != time.Minute && per != time.Hour) {
+		return 0, fmt.Errorf("invalid unit %q, expected s, m or h", s[i+1:])
+	}
+	// a ticker needs a positive interval
+	interval := time.Duration(float64(per) / n)
+	if interval <= 0 {
+		return 0, fmt.Errorf("rate %q too high, at most one request per nanosecond", s)
+	}
+	return interval, nil
+}
+
+// replayEntry is a logged command to replay.
+type replayEntry struct {
+	path    []string
+	options cmds.OptMap
+	args    []string
+}
+
+// readReplayLog reads a request log, the JSON output of 'ipfs diag cmds'.
+// Unknown commands are rejected, and so are the ones missing from the
+// read-only API, which may change the state of the node, unless
+// allowMutations is set.
+func readReplayLog(r io.Reader, allowMutations bool) ([]replayEntry, error) {
+	var log []*cmds.ReqLogEntry
+	if err := json.NewDecoder(r).Decode(&log); err != nil {
+		return nil, fmt.Errorf("reading the request log: %s", err)
+	}
+
+	var mutating []string
+	entries := make([]replayEntry, 0, len(log))
+	for _, e := range log {
+		path := strings.Split(e.Command, "/")
+		if _, err := Root.Get(path); err != nil {
+			return nil, fmt.Errorf("request %d: unknown command %q", e.ID, e.Command)
+		}
+		if _, err := RootRO.Get(path); err != nil && !allowMutations {
+			mutating = append(mutating, e.Command)
+		}
+
+		// options were decoded from JSON, numbers as floats: pass them as
+		// strings for the request to parse them according to their type
+		opts := make(cmds.OptMap, len(e.Options))
+		for k, v := range e.Options {
+			switch v := v.(type) {
+			case float64:
+				opts[k] = strconv.FormatFloat(v, 'f', -1, 64)
+			case []interface{}:
+				strs := make([]string, len(v))
+				for i, s := range v {
+					strs[i] = fmt.Sprint(s)
+				}
+				opts[k] = strs
+			default:
+				opts[k] = v
+			}
+		}
+		entries = append(entries, replayEntry{path: path, options: opts, args: e.Args})
+	}
+
+	if len(mutating) > 0 {
+		return nil, fmt.Errorf("the log has commands outside the read-only API (%s), pass --%s to replay them",
+			strings.Join(dedupStrings(mutating), ", "), replayAllowMutationsOptionName)
+	}
+	return entries, nil
+}
+
+func dedupStrings(strs []string) []string {
+	seen := make(map[string]struct{}, len(strs))
+	out := strs[:0]
+	for _, s := range strs {
+		if _, ok := seen[s]; !ok {
+			seen[s] = struct{}{}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// replayRequests sends entries with exe, one every interval, and waits for
+// their completion.
+func replayRequests(ctx context.Context, exe cmds.Executor, entries []replayEntry, interval time.Duration) *ReplayOutput {
+	out := &ReplayOutput{Errors: map[string]string{}}
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	fail := func(command string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		out.Failed++
+		if _, found := out.Errors[command]; !found {
+			out.Errors[command] = err.Error()
+		}
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+loop:
+	for i, e := range entries {
+		if i > 0 {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				break loop
+			}
+		}
+		out.Requests++
+
+		wg.Add(1)
+		go func(e replayEntry) {
+			defer wg.Done()
+			command := strings.Join(e.path, "/")
+			req, err := cmds.NewRequest(ctx, e.path, e.options, e.args, nil, Root)
+			if err != nil {
+				fail(command, err)
+				return
+			}
+			re := &discardEmitter{}
+			if err := exe.Execute(req, re, nil); err != nil {
+				fail(command, err)
+			} else if re.err != nil {
+				fail(command, re.err)
+			}
+		}(e)
+	}
+	wg.Wait()
+
+	out.Duration = time.Since(start)
+	if out.Duration > 0 {
+		out.Rate = float64(out.Requests) / out.Duration.Seconds()
+	}
+	return out
+}
+
+// discardEmitter drops the values emitted by a command, keeping its error.
+type discardEmitter struct {
+	err error
+}
+
+func (re *discardEmitter) Emit(v interface{}) error {
+	// streamed outputs have to be read for the command to complete
+	if r, ok := v.(io.Reader); ok {
+		_, err := io.Copy(ioutil.Discard, r)
+		return err
+	}
+	return nil
+}
+
+func (re *discardEmitter) Close() error {
+	return nil
+}
+
+func (re *discardEmitter) CloseWithError(err error) error {
+	if err != nil && err != io.EOF {
+		re.err = err
+	}
+	return nil
+}
+
+func (re *discardEmitter) SetLength(uint64) {}
+
+var _ cmds.ResponseEmitter = (*discardEmitter)(nil)
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cmdhttp "github.com/ipfs/go-ipfs-cmds/http"
+)
+
+func TestDiagReplay(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests = map[string][]string{}
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path] = append(requests[r.URL.Path], r.URL.RawQuery)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}\n"))
+	}))
+	defer ts.Close()
+
+	log := `[
+  {"Command": "version", "Options": {"number": true}, "Args": null, "ID": 1},
+  {"Command": "block/stat", "Options": {"encoding": "json"}, "Args": ["QmS92gwP5AAp6YHyG7QypC81H3ARBmNTbozJ1gTrhy2ASA"], "ID": 2},
+  {"Command": "cat", "Options": {"length": 10}, "Args": ["/ipfs/QmS92gwP5AAp6YHyG7QypC81H3ARBmNTbozJ1gTrhy2ASA"], "ID": 3},
+  {"Command": "version", "Options": {}, "Args": null, "ID": 4},
+  {"Command": "block/stat", "Options": {}, "Args": ["QmS92gwP5AAp6YHyG7QypC81H3ARBmNTbozJ1gTrhy2ASA"], "ID": 5}
+]`
+	entries, err := readReplayLog(strings.NewReader(log), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interval, err := parseReplayRate("50/s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval != 20*time.Millisecond {
+		t.Fatalf("expected an interval of 20ms at 50/s, got %s", interval)
+	}
+
+	exe := cmdhttp.NewClient(ts.URL, cmdhttp.ClientWithAPIPrefix("/api/v0"))
+	out := replayRequests(context.Background(), exe, entries, interval)
+	if out.Requests != 5 || out.Failed != 0 {
+		t.Fatalf("expected 5 requests and no failure, got %d requests, %d failed: %v", out.Requests, out.Failed, out.Errors)
+	}
+	// the last request is sent 4 intervals after the first one
+	if out.Duration < 4*interval {
+		t.Fatalf("5 requests at 50/s took %s, expected at least %s", out.Duration, 4*interval)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if n := len(requests["/api/v0/version"]); n != 2 {
+		t.Errorf("expected 2 version requests, got %d", n)
+	}
+	if n := len(requests["/api/v0/block/stat"]); n != 2 {
+		t.Errorf("expected 2 block/stat requests, got %d", n)
+	}
+	if q := requests["/api/v0/cat"]; len(q) != 1 || !strings.Contains(q[0], "length=10") {
+		t.Errorf("expected a cat request with length=10, got %v", q)
+	}
+}
+
+func TestDiagReplayMutations(t *testing.T) {
+	log := `[
+  {"Command": "pin/add", "Options": {}, "Args": ["/ipfs/QmS92gwP5AAp6YHyG7QypC81H3ARBmNTbozJ1gTrhy2ASA"], "ID": 1},
+  {"Command": "version", "Options": {}, "Args": null, "ID": 2}
+]`
+	_, err := readReplayLog(strings.NewReader(log), false)
+	if err == nil || !strings.Contains(err.Error(), "pin/add") || !strings.Contains(err.Error(), "--allow-mutations") {
+		t.Fatalf("expected pin/add to require --allow-mutations, got %v", err)
+	}
+	entries, err := readReplayLog(strings.NewReader(log), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(entries))
+	}
+
+	if _, err := readReplayLog(strings.NewReader(`[{"Command": "nope", "ID": 1}]`), true); err == nil {
+		t.Fatal("expected an error for an unknown command")
+	}
+}
+
+func TestParseReplayRate(t *testing.T) {
+	for s, expected := range map[string]time.Duration{
+		"10/s":  100 * time.Millisecond,
+		"600/m": 100 * time.Millisecond,
+		"2/h":   30 * time.Minute,
+	} {
+		if d, err := parseReplayRate(s); err != nil || d != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", s, expected, d, err)
+		}
+	}
+	for _, s := range []string{"10", "0/s", "-1/s", "10/d", "x/s", "NaN/s", "Inf/s", "1e10/s"} {
+		if _, err := parseReplayRate(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
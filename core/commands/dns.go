@@ -13,6 +13,7 @@ import (
 
 const (
 	dnsRecursiveOptionName = "recursive"
+	dnsTraceOptionName     = "trace"
 )
 
 var DNSCmd = &cmds.Command{
@@ -82,4 +83,90 @@ The resolver can recursively resolve:
 		}),
 	},
 	Type: ncmd.ResolvedPath{},
+	Subcommands: map[string]*cmds.Command{
+		"resolve": dnsResolveCmd,
+	},
+}
+
+// DNSResolveOutput is the output of 'ipfs dns resolve'.
+type DNSResolveOutput struct {
+	Lookups []namesys.DNSLinkLookup `json:",omitempty"`
+	Path    string
+	Cid     string `json:",omitempty"`
+}
+
+var dnsResolveCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Resolve a DNSLink, following chains of DNSLinks.",
+		ShortDescription: `
+'ipfs dns resolve' looks up the DNSLink of a domain, in the TXT records of
+_dnslink.<domain> and then <domain>, follows DNSLinks pointing to
+/ipns/<other-domain>, and outputs the final path and the CID it starts with.
+
+With --trace, every TXT lookup is shown with the raw records found, which
+helps site operators check their DNSLink setup:
+
+  > ipfs dns resolve --trace docs.ipfs.io
+  _dnslink.docs.ipfs.io.
+    dnslink=/ipns/ipfs.io
+    -> /ipns/ipfs.io
+  _dnslink.ipfs.io.
+    dnslink=/ipfs/QmRzTuh2Lpuz7Gr39stNr6mTFdqAghsZec1JoUnfySUzcy
+    -> /ipfs/QmRzTuh2Lpuz7Gr39stNr6mTFdqAghsZec1JoUnfySUzcy
+  /ipfs/QmRzTuh2Lpuz7Gr39stNr6mTFdqAghsZec1JoUnfySUzcy
+  cid: QmRzTuh2Lpuz7Gr39stNr6mTFdqAghsZec1JoUnfySUzcy
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("domain-name", true, false, "The domain-name name to resolve.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(dnsTraceOptionName, "Show every TXT lookup and its records."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		trace, _ := req.Options[dnsTraceOptionName].(bool)
+
+		lookups, p, err := namesys.NewDNSResolver().Trace(req.Context, req.Arguments[0])
+		out := &DNSResolveOutput{Path: p.String()}
+		if trace {
+			out.Lookups = lookups
+		}
+		if err != nil {
+			if trace && len(lookups) > 0 {
+				// show where the chain broke
+				if err := res.Emit(out); err != nil {
+					return err
+				}
+			}
+			return err
+		}
+
+		if segs := p.Segments(); len(segs) > 1 && segs[0] == "ipfs" {
+			out.Cid = segs[1]
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *DNSResolveOutput) error {
+			for _, l := range out.Lookups {
+				fmt.Fprintln(w, l.Name)
+				for _, r := range l.Records {
+					fmt.Fprintf(w, "  %s\n", r)
+				}
+				if l.Link != "" {
+					fmt.Fprintf(w, "  -> %s\n", l.Link)
+				} else {
+					fmt.Fprintf(w, "  error: %s\n", l.Error)
+				}
+			}
+			if out.Path != "" {
+				fmt.Fprintln(w, out.Path)
+			}
+			if out.Cid != "" {
+				fmt.Fprintf(w, "cid: %s\n", out.Cid)
+			}
+			return nil
+		}),
+	},
+	Type: DNSResolveOutput{},
 }
@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	event "github.com/libp2p/go-libp2p-core/event"
+)
+
+// eventTypes maps the names accepted by 'ipfs events subscribe' to the
+// event-bus types they select.
+var eventTypes = map[string]interface{}{
+	"peer-connectedness":   new(event.EvtPeerConnectednessChanged),
+	"peer-identified":      new(event.EvtPeerIdentificationCompleted),
+	"peer-identify-failed": new(event.EvtPeerIdentificationFailed),
+	"peer-protocols":       new(event.EvtPeerProtocolsUpdated),
+	"local-addresses":      new(event.EvtLocalAddressesUpdated),
+	"local-protocols":      new(event.EvtLocalProtocolsUpdated),
+	"local-reachability":   new(event.EvtLocalReachabilityChanged),
+}
+
+// EventOutput is a single event-bus event as emitted by 'ipfs events subscribe'.
+type EventOutput struct {
+	Type  string
+	Event interface{}
+}
+
+var EventsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Interact with the node's event bus.",
+		ShortDescription: `
+'ipfs events' exposes the internal events emitted by the node (peer
+connections, reachability changes, ...).
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"subscribe": eventsSubscribeCmd,
+	},
+}
+
+var eventsSubscribeCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Stream events from the node's event bus.",
+		ShortDescription: `
+'ipfs events subscribe' streams the selected event-bus events until the
+request is cancelled. When no type is given, all known event types are
+streamed.
+`,
+		LongDescription: `
+'ipfs events subscribe' streams the selected event-bus events until the
+request is cancelled. When no type is given, all known event types are
+streamed.
+
+Known event types:
+
+  peer-connectedness     A peer connected or disconnected.
+  peer-identified        The identify protocol completed with a peer.
+  peer-identify-failed   The identify protocol failed with a peer.
+  peer-protocols         A peer's supported protocols changed.
+  local-addresses        The node's listen addresses changed.
+  local-protocols        The node's supported protocols changed.
+  local-reachability     The node's reachability (public/private) changed.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("type", false, true, "Event types to subscribe to."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		if !nd.IsOnline {
+			return ErrNotOnline
+		}
+
+		names := req.Arguments
+		if len(names) == 0 {
+			names = eventTypeNames()
+		}
+
+		return subscribeEvents(req.Context, nd.PeerHost.EventBus(), names, func(out *EventOutput) error {
+			return res.Emit(out)
+		})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *EventOutput) error {
+			data, err := json.Marshal(out.Event)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "%s %s\n", out.Type, data)
+			return err
+		}),
+	},
+	Type: EventOutput{},
+}
+
+// subscribeEvents subscribes to the named event types on bus and calls emit
+// for every event received until ctx is cancelled.
+func subscribeEvents(ctx context.Context, bus event.Bus, names []string, emit func(*EventOutput) error) error {
+	types := make([]interface{}, 0, len(names))
+	byType := make(map[reflect.Type]string, len(names))
+	for _, name := range names {
+		typ, ok := eventTypes[name]
+		if !ok {
+			return cmds.Errorf(cmds.ErrClient, "unknown event type %q, expected one of: %s", name, strings.Join(eventTypeNames(), ", "))
+		}
+		types = append(types, typ)
+		byType[reflect.TypeOf(typ).Elem()] = name
+	}
+
+	sub, err := bus.Subscribe(types)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case evt, ok := <-sub.Out():
+			if !ok {
+				return nil
+			}
+			out := &EventOutput{
+				Type:  byType[reflect.TypeOf(evt)],
+				Event: evt,
+			}
+			if err := emit(out); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func eventTypeNames() []string {
+	names := make([]string, 0, len(eventTypes))
+	for name := range eventTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
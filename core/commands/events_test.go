@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	eventbus "github.com/libp2p/go-eventbus"
+	event "github.com/libp2p/go-libp2p-core/event"
+	network "github.com/libp2p/go-libp2p-core/network"
+)
+
+func TestSubscribeEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bus := eventbus.NewBus()
+	em, err := bus.Emitter(new(event.EvtLocalReachabilityChanged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer em.Close()
+
+	outs := make(chan *EventOutput, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- subscribeEvents(ctx, bus, []string{"local-reachability"}, func(out *EventOutput) error {
+			select {
+			case outs <- out:
+			default:
+			}
+			return nil
+		})
+	}()
+
+	// The subscription is registered asynchronously, keep emitting until
+	// the subscriber sees an event.
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case out := <-outs:
+			if out.Type != "local-reachability" {
+				t.Fatalf("expected type local-reachability, got %q", out.Type)
+			}
+			evt, ok := out.Event.(event.EvtLocalReachabilityChanged)
+			if !ok {
+				t.Fatalf("unexpected event %T", out.Event)
+			}
+			if evt.Reachability != network.ReachabilityPublic {
+				t.Fatalf("expected public reachability, got %s", evt.Reachability)
+			}
+			cancel()
+			if err := <-errCh; err != nil {
+				t.Fatal(err)
+			}
+			return
+		case <-ticker.C:
+			if err := em.Emit(event.EvtLocalReachabilityChanged{Reachability: network.ReachabilityPublic}); err != nil {
+				t.Fatal(err)
+			}
+		case <-ctx.Done():
+			t.Fatal("subscriber never received the event")
+		}
+	}
+}
+
+func TestSubscribeEventsUnknownType(t *testing.T) {
+	err := subscribeEvents(context.Background(), eventbus.NewBus(), []string{"no-such-event"}, func(*EventOutput) error {
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected an error for an unknown event type")
+	}
+}
@@ -11,6 +11,28 @@ import (
 	cmds "github.com/ipfs/go-ipfs-cmds"
 )
 
+// ExternalCommand returns a command running binary with the arguments it is
+// given. It is used for the commands registered in the ExternalCommands
+// section of the config, mapping a command name to a binary.
+func ExternalCommand(name, binary string) *cmds.Command {
+	return &cmds.Command{
+		Helptext: cmds.HelpText{
+			Tagline: fmt.Sprintf("Run %s (external command).", binary),
+			ShortDescription: fmt.Sprintf(`
+'ipfs %s' is registered in the ExternalCommands section of the config, it
+runs %s with the arguments given after the command name.
+`, name, binary),
+		},
+		Arguments: []cmds.Argument{
+			cmds.StringArg("args", false, true, "Arguments for subcommand."),
+		},
+		External: true,
+		Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+			return runExternal(res, binary, req.Arguments)
+		},
+	}
+}
+
 func ExternalBinary(instructions string) *cmds.Command {
 	return &cmds.Command{
 		Arguments: []cmds.Argument{
@@ -35,41 +57,46 @@ func ExternalBinary(instructions string) *cmds.Command {
 				return fmt.Errorf("%s not installed", binname)
 			}
 
-			r, w := io.Pipe()
+			return runExternal(res, binname, req.Arguments)
+		},
+	}
+}
 
-			cmd := exec.Command(binname, req.Arguments...)
+// runExternal runs binname with args, emitting its combined output.
+func runExternal(res cmds.ResponseEmitter, binname string, args []string) error {
+	r, w := io.Pipe()
 
-			// TODO: make commands lib be able to pass stdin through daemon
-			//cmd.Stdin = req.Stdin()
-			cmd.Stdin = io.LimitReader(nil, 0)
-			cmd.Stdout = w
-			cmd.Stderr = w
+	cmd := exec.Command(binname, args...)
 
-			// setup env of child program
-			osenv := os.Environ()
+	// TODO: make commands lib be able to pass stdin through daemon
+	//cmd.Stdin = req.Stdin()
+	cmd.Stdin = io.LimitReader(nil, 0)
+	cmd.Stdout = w
+	cmd.Stderr = w
 
-			cmd.Env = osenv
+	// setup env of child program
+	osenv := os.Environ()
 
-			err = cmd.Start()
-			if err != nil {
-				return fmt.Errorf("failed to start subcommand: %s", err)
-			}
+	cmd.Env = osenv
 
-			errC := make(chan error)
+	err := cmd.Start()
+	if err != nil {
+		return fmt.Errorf("failed to start subcommand: %s", err)
+	}
 
-			go func() {
-				var err error
-				defer func() { errC <- err }()
-				err = cmd.Wait()
-				w.Close()
-			}()
+	errC := make(chan error)
 
-			err = res.Emit(r)
-			if err != nil {
-				return err
-			}
+	go func() {
+		var err error
+		defer func() { errC <- err }()
+		err = cmd.Wait()
+		w.Close()
+	}()
 
-			return <-errC
-		},
+	err = res.Emit(r)
+	if err != nil {
+		return err
 	}
+
+	return <-errC
 }
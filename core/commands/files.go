@@ -69,16 +69,18 @@ operations.
 		cmds.BoolOption(filesFlushOptionName, "f", "Flush target and ancestors after write.").WithDefault(true),
 	},
 	Subcommands: map[string]*cmds.Command{
-		"read":  filesReadCmd,
-		"write": filesWriteCmd,
-		"mv":    filesMvCmd,
-		"cp":    filesCpCmd,
-		"ls":    filesLsCmd,
-		"mkdir": filesMkdirCmd,
-		"stat":  filesStatCmd,
-		"rm":    filesRmCmd,
-		"flush": filesFlushCmd,
-		"chcid": filesChcidCmd,
+		"read":       filesReadCmd,
+		"write":      filesWriteCmd,
+		"mv":         filesMvCmd,
+		"cp":         filesCpCmd,
+		"ls":         filesLsCmd,
+		"mkdir":      filesMkdirCmd,
+		"stat":       filesStatCmd,
+		"rm":         filesRmCmd,
+		"flush":      filesFlushCmd,
+		"chcid":      filesChcidCmd,
+		"export-tar": filesExportTarCmd,
+		"import-tar": filesImportTarCmd,
 	},
 }
 
@@ -98,9 +100,10 @@ type statOutput struct {
 	CumulativeSize uint64
 	Blocks         int
 	Type           string
-	WithLocality   bool   `json:",omitempty"`
-	Local          bool   `json:",omitempty"`
-	SizeLocal      uint64 `json:",omitempty"`
+	WithLocality   bool      `json:",omitempty"`
+	Local          bool      `json:",omitempty"`
+	SizeLocal      uint64    `json:",omitempty"`
+	Hamt           *HamtStat `json:",omitempty"`
 }
 
 const (
@@ -112,6 +115,7 @@ Type: <type>`
 	filesFormatOptionName    = "format"
 	filesSizeOptionName      = "size"
 	filesWithLocalOptionName = "with-local"
+	filesHamtOptionName      = "hamt"
 )
 
 var filesStatCmd = &cmds.Command{
@@ -128,6 +132,7 @@ var filesStatCmd = &cmds.Command{
 		cmds.BoolOption(filesHashOptionName, "Print only hash. Implies '--format=<hash>'. Conflicts with other format options."),
 		cmds.BoolOption(filesSizeOptionName, "Print only size. Implies '--format=<cumulsize>'. Conflicts with other format options."),
 		cmds.BoolOption(filesWithLocalOptionName, "Compute the amount of the dag that is local, and if possible the total size"),
+		cmds.BoolOption(filesHamtOptionName, "Report the HAMT sharding of a directory: fanout, number of shards and entries."),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 
@@ -179,6 +184,13 @@ var filesStatCmd = &cmds.Command{
 			return err
 		}
 
+		if hamtStat, _ := req.Options[filesHamtOptionName].(bool); hamtStat {
+			o.Hamt, err = statHamt(req.Context, node.DAG, nd)
+			if err != nil {
+				return err
+			}
+		}
+
 		if !withLocal {
 			return cmds.EmitOnce(res, o)
 		}
@@ -205,6 +217,10 @@ var filesStatCmd = &cmds.Command{
 
 			fmt.Fprintln(w, s)
 
+			if out.Hamt != nil {
+				writeHamtStat(w, out.Hamt)
+			}
+
 			if out.WithLocality {
 				fmt.Fprintf(w, "Local: %s of %s (%.2f%%)\n",
 					humanize.Bytes(out.SizeLocal),
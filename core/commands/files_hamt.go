@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	ipld "github.com/ipfs/go-ipld-format"
+	dag "github.com/ipfs/go-merkledag"
+	ft "github.com/ipfs/go-unixfs"
+	hamt "github.com/ipfs/go-unixfs/hamt"
+)
+
+// HamtStat describes the layout of a directory, as reported by
+// 'ipfs files stat --hamt'.
+type HamtStat struct {
+	Sharded bool
+	// Fanout is the width of the shards, HashType the hash function used
+	// to place entries.
+	Fanout   uint64 `json:",omitempty"`
+	HashType uint64 `json:",omitempty"`
+	// Shards is the number of shard nodes, including the root one, and
+	// Depth the number of levels of shards.
+	Shards  int `json:",omitempty"`
+	Depth   int `json:",omitempty"`
+	Entries int
+}
+
+// statHamt returns the layout of the directory nd, walking all its shards
+// if it's sharded.
+func statHamt(ctx context.Context, dserv ipld.DAGService, nd ipld.Node) (*HamtStat, error) {
+	pn, ok := nd.(*dag.ProtoNode)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a directory", nd.Cid())
+	}
+	fsn, err := ft.FSNodeFromBytes(pn.Data())
+	if err != nil {
+		return nil, err
+	}
+
+	switch fsn.Type() {
+	case ft.TDirectory:
+		return &HamtStat{Entries: len(pn.Links())}, nil
+	case ft.THAMTShard:
+	default:
+		return nil, fmt.Errorf("%s is not a directory", nd.Cid())
+	}
+
+	out := &HamtStat{
+		Sharded:  true,
+		Fanout:   fsn.Fanout(),
+		HashType: fsn.HashType(),
+	}
+	// links to sub-shards are named after their index only, links to
+	// entries have the entry name after the index
+	padLen := len(fmt.Sprintf("%X", fsn.Fanout()-1))
+
+	var walk func(nd ipld.Node, depth int) error
+	walk = func(nd ipld.Node, depth int) error {
+		// the HAMT implementation validates the shard
+		if _, err := hamt.NewHamtFromDag(dserv, nd); err != nil {
+			return fmt.Errorf("invalid shard %s: %s", nd.Cid(), err)
+		}
+		out.Shards++
+		if depth > out.Depth {
+			out.Depth = depth
+		}
+
+		for _, lnk := range nd.Links() {
+			if len(lnk.Name) > padLen {
+				out.Entries++
+				continue
+			}
+			child, err := lnk.GetNode(ctx, dserv)
+			if err != nil {
+				return err
+			}
+			if err := walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(nd, 1); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func writeHamtStat(w io.Writer, s *HamtStat) {
+	if !s.Sharded {
+		fmt.Fprintf(w, "Sharded: false\nEntries: %d\n", s.Entries)
+		return
+	}
+	fmt.Fprintf(w, "Sharded: true\nFanout: %d\nHashType: 0x%x\nShards: %d\nDepth: %d\nEntries: %d\n",
+		s.Fanout, s.HashType, s.Shards, s.Depth, s.Entries)
+}
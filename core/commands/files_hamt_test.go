@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	repo "github.com/ipfs/go-ipfs/repo"
+
+	datastore "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	config "github.com/ipfs/go-ipfs-config"
+	ft "github.com/ipfs/go-unixfs"
+	hamt "github.com/ipfs/go-unixfs/hamt"
+)
+
+func TestFilesStatHamt(t *testing.T) {
+	ctx := context.Background()
+	n, err := core.NewNode(ctx, &core.BuildCfg{Repo: &repo.Mock{
+		C: config.Config{
+			Identity: config.Identity{
+				PeerID: "QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe", // required by offline node
+			},
+		},
+		D: syncds.MutexWrap(datastore.NewMapDatastore()),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	env := &oldcmds.Context{
+		ConstructNode: func() (*core.IpfsNode, error) { return n, nil },
+	}
+
+	// enough entries for some of them to collide in the root shard and
+	// be moved to sub-shards
+	const entries = 1000
+	empty := ft.EmptyDirNode()
+	if err := n.DAG.Add(ctx, empty); err != nil {
+		t.Fatal(err)
+	}
+	shard, err := hamt.NewShard(n.DAG, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < entries; i++ {
+		if err := shard.Set(ctx, fmt.Sprintf("entry-%d", i), empty); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := shard.Node()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stat := func(c fmt.Stringer) *HamtStat {
+		req, err := cmds.NewRequest(ctx, []string{"files", "stat"}, cmds.OptMap{filesHamtOptionName: true}, []string{"/ipfs/" + c.String()}, nil, Root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := req.FillDefaults(); err != nil {
+			t.Fatal(err)
+		}
+		re, res := cmds.NewChanResponsePair(req)
+		go func() {
+			re.CloseWithError(cmds.NewExecutor(Root).Execute(req, re, env))
+		}()
+		v, err := res.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v.(*statOutput).Hamt
+	}
+
+	s := stat(root.Cid())
+	if !s.Sharded || s.Fanout != 256 || s.HashType != hamt.HashMurmur3 {
+		t.Fatalf("expected a murmur3 shard with a fanout of 256, got %+v", s)
+	}
+	if s.Entries != entries {
+		t.Errorf("expected %d entries, got %d", entries, s.Entries)
+	}
+	if s.Shards < 2 || s.Depth < 2 {
+		t.Errorf("expected sub-shards, got %d shards over %d levels", s.Shards, s.Depth)
+	}
+
+	s = stat(empty.Cid())
+	if s.Sharded || s.Entries != 0 {
+		t.Errorf("expected an empty unsharded directory, got %+v", s)
+	}
+}
@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	gopath "path"
+
+	"github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	files "github.com/ipfs/go-ipfs-files"
+	"github.com/ipfs/go-mfs"
+	iface "github.com/ipfs/interface-go-ipfs-core"
+	path "github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+// FilesImportTarOutput is an entry imported by 'ipfs files import-tar'.
+type FilesImportTarOutput struct {
+	Path string
+	Hash string
+}
+
+var filesExportTarCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Export a file or directory of MFS as a tar archive.",
+		ShortDescription: `
+'ipfs files export-tar' writes the given MFS path as a tar archive, to the
+given file or to stdout. A file or directory is archived under its name,
+the root directory as its entries:
+
+  > ipfs files export-tar /docs docs.tar
+
+The archive can be imported back with 'ipfs files import-tar'.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("path", true, false, "Path of the file or directory to export."),
+		cmds.StringArg("output", false, false, "File to write the archive to, stdout if omitted."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		p, err := checkPath(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		fsn, err := mfs.Lookup(nd.FilesRoot, p)
+		if err != nil {
+			return err
+		}
+		// flushes the pending changes below p
+		node, err := fsn.GetNode()
+		if err != nil {
+			return err
+		}
+		f, err := api.Unixfs().Get(req.Context, path.IpfsPath(node.Cid()))
+		if err != nil {
+			return err
+		}
+
+		piper, pipew := io.Pipe()
+		go func() {
+			pipew.CloseWithError(writeMfsTar(pipew, f, gopath.Base(p)))
+		}()
+		if err := res.Emit(piper); err != nil {
+			// unblock the writer, nothing reads the archive anymore
+			piper.CloseWithError(err)
+			return err
+		}
+		return nil
+	},
+	PostRun: cmds.PostRunMap{
+		cmds.CLI: func(res cmds.Response, re cmds.ResponseEmitter) error {
+			req := res.Request()
+			if len(req.Arguments) < 2 {
+				return cmds.Copy(re, res)
+			}
+
+			v, err := res.Next()
+			if err != nil {
+				return err
+			}
+			r, ok := v.(io.Reader)
+			if !ok {
+				return fmt.Errorf("unexpected type: %T", v)
+			}
+
+			out, err := os.Create(req.Arguments[1])
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, r); err != nil {
+				out.Close()
+				return err
+			}
+			return out.Close()
+		},
+	},
+}
+
+// writeMfsTar writes f to w as a tar archive, under name, or as its entries
+// for the root directory.
+func writeMfsTar(w io.Writer, f files.Node, name string) error {
+	tw, err := files.NewTarWriter(w)
+	if err != nil {
+		return err
+	}
+
+	if dir, ok := f.(files.Directory); ok && name == "/" {
+		it := dir.Entries()
+		for it.Next() {
+			if err := tw.WriteFile(it.Node(), it.Name()); err != nil {
+				return err
+			}
+		}
+		if it.Err() != nil {
+			return it.Err()
+		}
+	} else if err := tw.WriteFile(f, name); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+var filesImportTarCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Import a tar archive into MFS.",
+		ShortDescription: `
+'ipfs files import-tar' adds the files of a tar archive, like the ones of
+'ipfs files export-tar', and extracts them in the given MFS directory,
+created if missing:
+
+  > ipfs files import-tar /restored docs.tar
+  added QmPZ9gcCEpqKTo6aq61g2nXGUhM4iCL3ewB6LDXZCtioEB /restored/docs/README
+  added QmQy6xmJhrcC5QLboAcGFcAE1tC8CrwDVkrHdEYJkLscrQ /restored/docs/index.html
+
+Directories already in MFS are merged with the archive, files are never
+overwritten. Only the directories, regular files and symlinks of the archive
+are imported, its other entries, like hard links, are skipped.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("path", true, false, "Directory to extract the archive in."),
+		cmds.FileArg("archive", true, false, "Tar archive to import.").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		dest, err := checkPath(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		flush, _ := req.Options[filesFlushOptionName].(bool)
+
+		r, err := cmdenv.GetFileArg(req.Files.Entries())
+		if err != nil {
+			return err
+		}
+
+		// keep a garbage collection from removing the added files before
+		// they are in MFS
+		defer nd.Blockstore.PinLock().Unlock()
+
+		err = importMfsTar(req.Context, api, nd.FilesRoot, dest, r, func(out *FilesImportTarOutput) error {
+			return res.Emit(out)
+		})
+		if err != nil {
+			return err
+		}
+		if flush {
+			if _, err := mfs.FlushPath(req.Context, nd.FilesRoot, dest); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *FilesImportTarOutput) error {
+			_, err := fmt.Fprintf(w, "added %s %s\n", out.Hash, out.Path)
+			return err
+		}),
+	},
+	Type: FilesImportTarOutput{},
+}
+
+// importMfsTar adds the entries of the tar archive r and puts them under the
+// directory dest of root.
+func importMfsTar(ctx context.Context, api iface.CoreAPI, root *mfs.Root, dest string, r io.Reader, emit func(*FilesImportTarOutput) error) error {
+	if err := mfs.Mkdir(root, dest, mfs.MkdirOpts{Mkparents: true}); err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// the archive can't reach outside of dest
+		p := gopath.Join(dest, gopath.Clean("/"+hdr.Name))
+		if p == dest {
+			continue
+		}
+
+		var f files.Node
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := mfs.Mkdir(root, p, mfs.MkdirOpts{Mkparents: true}); err != nil {
+				return fmt.Errorf("%s: %s", p, err)
+			}
+			continue
+		case tar.TypeReg, tar.TypeRegA:
+			f = files.NewReaderFile(tr)
+		case tar.TypeSymlink:
+			f = files.NewLinkFile(hdr.Linkname, nil)
+		default:
+			// hard links, devices, pax global headers...
+			flog.Infof("%s: skipping unsupported tar entry type %q", hdr.Name, hdr.Typeflag)
+			continue
+		}
+
+		added, err := api.Unixfs().Add(ctx, f)
+		if err != nil {
+			return fmt.Errorf("%s: %s", p, err)
+		}
+		node, err := api.Dag().Get(ctx, added.Cid())
+		if err != nil {
+			return err
+		}
+		if err := mfs.Mkdir(root, gopath.Dir(p), mfs.MkdirOpts{Mkparents: true}); err != nil {
+			return fmt.Errorf("%s: %s", p, err)
+		}
+		if err := mfs.PutNode(root, p, node); err != nil {
+			return fmt.Errorf("%s: %s", p, err)
+		}
+		if err := emit(&FilesImportTarOutput{Path: p, Hash: added.Cid().String()}); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	gopath "path"
+	"testing"
+
+	core "github.com/ipfs/go-ipfs/core"
+	"github.com/ipfs/go-ipfs/core/coreapi"
+	repo "github.com/ipfs/go-ipfs/repo"
+
+	datastore "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	config "github.com/ipfs/go-ipfs-config"
+	files "github.com/ipfs/go-ipfs-files"
+	"github.com/ipfs/go-mfs"
+	path "github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+func TestFilesTarRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	n, err := core.NewNode(ctx, &core.BuildCfg{Repo: &repo.Mock{
+		C: config.Config{
+			Identity: config.Identity{
+				PeerID: "QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe", // required by offline node
+			},
+		},
+		D: syncds.MutexWrap(datastore.NewMapDatastore()),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	api, err := coreapi.NewCoreAPI(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := api.Unixfs().Add(ctx, files.NewMapDirectory(map[string]files.Node{
+		"README": files.NewBytesFile([]byte("read me")),
+		"guides": files.NewMapDirectory(map[string]files.Node{
+			"intro.md": files.NewBytesFile([]byte("# intro")),
+			"empty":    files.NewMapDirectory(nil),
+		}),
+		"latest": files.NewLinkFile("guides/intro.md", nil),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docsNode, err := api.ResolveNode(ctx, docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mfs.Mkdir(n.FilesRoot, "/site", mfs.MkdirOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mfs.PutNode(n.FilesRoot, "/site/docs", docsNode); err != nil {
+		t.Fatal(err)
+	}
+
+	export := func(p string) *bytes.Buffer {
+		fsn, err := mfs.Lookup(n.FilesRoot, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nd, err := fsn.GetNode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := api.Unixfs().Get(ctx, path.IpfsPath(nd.Cid()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := writeMfsTar(&buf, f, gopath.Base(p)); err != nil {
+			t.Fatal(err)
+		}
+		return &buf
+	}
+	checkSame := func(p string) {
+		fsn, err := mfs.Lookup(n.FilesRoot, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nd, err := fsn.GetNode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nd.Cid() != docsNode.Cid() {
+			t.Fatalf("expected %s to be imported as %s, got %s", p, docsNode.Cid(), nd.Cid())
+		}
+	}
+
+	var imported []string
+	err = importMfsTar(ctx, api, n.FilesRoot, "/restored", export("/site/docs"), func(out *FilesImportTarOutput) error {
+		imported = append(imported, out.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSame("/restored/docs")
+	if len(imported) != 3 {
+		t.Fatalf("expected the 2 files and the symlink to be imported, got %v", imported)
+	}
+
+	// the root directory is archived as its entries
+	noop := func(*FilesImportTarOutput) error { return nil }
+	if err := importMfsTar(ctx, api, n.FilesRoot, "/full", export("/"), noop); err != nil {
+		t.Fatal(err)
+	}
+	checkSame("/full/site/docs")
+	checkSame("/full/restored/docs")
+
+	// files are not overwritten
+	if err := importMfsTar(ctx, api, n.FilesRoot, "/restored", export("/site/docs"), noop); err == nil {
+		t.Fatal("expected importing over existing files to fail")
+	}
+
+	// the entries that can't be imported are skipped
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": "archived"}},
+		{Typeflag: tar.TypeReg, Name: "file", Size: 4, Mode: 0644},
+		{Typeflag: tar.TypeLink, Name: "hardlink", Linkname: "file"},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			tw.Write([]byte("data"))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	imported = nil
+	err = importMfsTar(ctx, api, n.FilesRoot, "/skipping", &buf, func(out *FilesImportTarOutput) error {
+		imported = append(imported, out.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 1 || imported[0] != "/skipping/file" {
+		t.Fatalf("expected only the regular file to be imported, got %v", imported)
+	}
+}
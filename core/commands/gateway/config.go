@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	corehttp "github.com/ipfs/go-ipfs/core/corehttp"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	config "github.com/ipfs/go-ipfs-config"
+)
+
+// ConfigOutput is the output of 'ipfs gateway config'.
+type ConfigOutput struct {
+	Addresses    []string
+	Writable     bool
+	NoFetch      bool
+	NoDNSLink    bool
+	RootRedirect string `json:",omitempty"`
+	PathPrefixes []string
+	// Headers are the headers set on the responses, the CORS defaults
+	// included.
+	Headers map[string][]string
+	// PublicGateways are the gateways recognized by their hostname, the
+	// default ones included.
+	PublicGateways map[string]config.GatewaySpec
+	Handlers       []corehttp.GatewayHandler
+}
+
+var configCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the effective gateway configuration.",
+		ShortDescription: `
+'ipfs gateway config' reports how the daemon serves its gateway with the
+current config: the addresses, the settings of the Gateway section, the
+headers set on the responses, the public gateways recognized by their
+hostname, with the defaults applied, and the handlers registered for each
+path.
+
+The gateway is reported writable as set by Gateway.Writable, which the
+'--writable' option of 'ipfs daemon' overrides.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		cfg, err := cmdenv.GetConfig(env)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, gatewayConfig(cfg))
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *ConfigOutput) error {
+			return writeGatewayConfig(w, out)
+		}),
+	},
+	Type: ConfigOutput{},
+}
+
+// gatewayConfig returns the gateway configuration effective with cfg.
+func gatewayConfig(cfg *config.Config) *ConfigOutput {
+	out := &ConfigOutput{
+		Addresses:      cfg.Addresses.Gateway,
+		Writable:       cfg.Gateway.Writable,
+		NoFetch:        cfg.Gateway.NoFetch,
+		NoDNSLink:      cfg.Gateway.NoDNSLink,
+		RootRedirect:   cfg.Gateway.RootRedirect,
+		PathPrefixes:   cfg.Gateway.PathPrefixes,
+		Headers:        corehttp.GatewayHeaders(cfg),
+		PublicGateways: corehttp.KnownGateways(cfg),
+		Handlers:       corehttp.GatewayHandlers(cfg, cfg.Gateway.Writable),
+	}
+	if out.Addresses == nil {
+		out.Addresses = []string{}
+	}
+	if out.PathPrefixes == nil {
+		out.PathPrefixes = []string{}
+	}
+	return out
+}
+
+func writeGatewayConfig(w io.Writer, out *ConfigOutput) error {
+	fmt.Fprintf(w, "addresses: %s\n", strings.Join(out.Addresses, ", "))
+	fmt.Fprintf(w, "writable: %t\n", out.Writable)
+	fmt.Fprintf(w, "no fetch: %t\n", out.NoFetch)
+	fmt.Fprintf(w, "no dnslink: %t\n", out.NoDNSLink)
+	if out.RootRedirect != "" {
+		fmt.Fprintf(w, "root redirect: %s\n", out.RootRedirect)
+	}
+	if len(out.PathPrefixes) > 0 {
+		fmt.Fprintf(w, "path prefixes: %s\n", strings.Join(out.PathPrefixes, ", "))
+	}
+
+	fmt.Fprintln(w, "\nheaders:")
+	for _, h := range sortedKeys(out.Headers) {
+		fmt.Fprintf(w, "  %s: %s\n", h, strings.Join(out.Headers[h], ", "))
+	}
+
+	fmt.Fprintln(w, "\npublic gateways:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	hostnames := make([]string, 0, len(out.PublicGateways))
+	for h := range out.PublicGateways {
+		hostnames = append(hostnames, h)
+	}
+	sort.Strings(hostnames)
+	for _, h := range hostnames {
+		gw := out.PublicGateways[h]
+		mode := "paths"
+		if gw.UseSubdomains {
+			mode = "subdomains"
+		}
+		dnslink := ""
+		if gw.NoDNSLink {
+			dnslink = "\tno dnslink"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s%s\n", h, mode, strings.Join(gw.Paths, " "), dnslink)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nhandlers:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, h := range out.Handlers {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", h.Path, h.Option, h.Description)
+	}
+	return tw.Flush()
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
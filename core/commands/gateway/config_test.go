@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	config "github.com/ipfs/go-ipfs-config"
+)
+
+func TestGatewayConfigSubdomain(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Addresses.Gateway = []string{"/ip4/127.0.0.1/tcp/8080"}
+	cfg.Gateway.NoDNSLink = true
+	cfg.Gateway.HTTPHeaders = map[string][]string{"x-served-by": {"gw1"}}
+	cfg.Gateway.PublicGateways = map[string]*config.GatewaySpec{
+		"example.com": {Paths: []string{"/ipfs", "/ipns"}, UseSubdomains: true},
+		// removes a default gateway
+		"dweb.link": nil,
+	}
+
+	out := gatewayConfig(cfg)
+	if !reflect.DeepEqual(out.Addresses, []string{"/ip4/127.0.0.1/tcp/8080"}) || out.Writable || !out.NoDNSLink {
+		t.Fatalf("unexpected settings %+v", out)
+	}
+	if gw, ok := out.PublicGateways["example.com"]; !ok || !gw.UseSubdomains || !reflect.DeepEqual(gw.Paths, []string{"/ipfs", "/ipns"}) {
+		t.Fatalf("expected the subdomain gateway example.com, got %+v (found: %t)", gw, ok)
+	}
+	if _, ok := out.PublicGateways["dweb.link"]; ok {
+		t.Fatal("expected dweb.link to be removed")
+	}
+	if gw, ok := out.PublicGateways["localhost"]; !ok || !gw.UseSubdomains {
+		t.Fatal("expected the default subdomain gateway localhost")
+	}
+	if v := out.Headers["X-Served-By"]; !reflect.DeepEqual(v, []string{"gw1"}) {
+		t.Fatalf("expected the configured header, got %v", v)
+	}
+	if v := out.Headers["Access-Control-Allow-Origin"]; !reflect.DeepEqual(v, []string{"*"}) {
+		t.Fatalf("expected the default CORS origin, got %v", v)
+	}
+
+	var paths []string
+	for _, h := range out.Handlers {
+		paths = append(paths, h.Path+" "+h.Option)
+	}
+	expected := []string{"/ HostnameOption", "/ipfs/ GatewayOption", "/ipns/ GatewayOption", "/version VersionOption", "/api/v0/ CommandsROOption"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("expected handlers %v, got %v", expected, paths)
+	}
+
+	var buf bytes.Buffer
+	if err := writeGatewayConfig(&buf, out); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"no dnslink: true", "example.com      subdomains  /ipfs /ipns", "X-Served-By: gw1", "/ipfs/    GatewayOption"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected %q in the output:\n%s", s, buf.String())
+		}
+	}
+}
+
+func TestGatewayConfigHandlers(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Gateway.Writable = true
+	cfg.Gateway.RootRedirect = "/ipfs/QmWebsite"
+	cfg.Experimental.P2pHttpProxy = true
+
+	handlers := gatewayConfig(cfg).Handlers
+	var options []string
+	for _, h := range handlers {
+		options = append(options, h.Option)
+	}
+	expected := []string{"HostnameOption", "GatewayOption", "GatewayOption", "VersionOption", "CommandsROOption", "P2PProxyOption", "RedirectOption"}
+	if !reflect.DeepEqual(options, expected) {
+		t.Fatalf("expected handlers %v, got %v", expected, options)
+	}
+	if !strings.HasPrefix(handlers[1].Description, "writable") {
+		t.Fatalf("expected a writable gateway, got %q", handlers[1].Description)
+	}
+}
@@ -0,0 +1,127 @@
+// Package gateway implements the 'ipfs gateway' commands. It lives outside of
+// package commands because it builds on corehttp, which itself depends on
+// package commands.
+package gateway
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	corehttp "github.com/ipfs/go-ipfs/core/corehttp"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// BenchOutput is the output of 'ipfs gateway bench'.
+type BenchOutput struct {
+	Path   string
+	Status int
+	Bytes  int64
+	// TTFB is the time until the first byte of the response was received.
+	TTFB time.Duration
+	// Total is the time until the whole response body was received.
+	Total time.Duration
+}
+
+var GatewayCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Interact with the HTTP gateway.",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"bench":  benchCmd,
+		"config": configCmd,
+	},
+}
+
+var benchCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Measure how fast the gateway serves a path.",
+		ShortDescription: `
+'ipfs gateway bench' requests a path through an in-process gateway, as the
+daemon would serve it, and reports the time to first byte and the total
+time taken to receive the response.
+
+It uses the repo directly and so cannot be run while the daemon is running.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("path", true, false, "The path to request, e.g. /ipfs/<cid>/file."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		p := req.Arguments[0]
+		if !strings.HasPrefix(p, "/ipfs/") && !strings.HasPrefix(p, "/ipns/") {
+			return cmds.Errorf(cmds.ErrClient, "path must start with /ipfs/ or /ipns/")
+		}
+
+		mux := http.NewServeMux()
+		if _, err := corehttp.GatewayOption(false, "/ipfs", "/ipns")(n, nil, mux); err != nil {
+			return err
+		}
+
+		out, err := bench(mux, p)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *BenchOutput) error {
+			fmt.Fprintf(w, "status: %d\n", out.Status)
+			fmt.Fprintf(w, "bytes:  %d\n", out.Bytes)
+			fmt.Fprintf(w, "ttfb:   %s\n", out.TTFB)
+			fmt.Fprintf(w, "total:  %s\n", out.Total)
+			return nil
+		}),
+	},
+	Type: BenchOutput{},
+}
+
+// bench serves h over a local HTTP server and times a GET request for path.
+func bench(h http.Handler, path string) (*BenchOutput, error) {
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	var start, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	start = time.Now()
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	n, err := io.Copy(ioutil.Discard, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	end := time.Now()
+
+	return &BenchOutput{
+		Path:   path,
+		Status: resp.StatusCode,
+		Bytes:  n,
+		TTFB:   firstByte.Sub(start),
+		Total:  end.Sub(start),
+	}, nil
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/ipfs/go-ipfs/core"
+	coreapi "github.com/ipfs/go-ipfs/core/coreapi"
+	corehttp "github.com/ipfs/go-ipfs/core/corehttp"
+	repo "github.com/ipfs/go-ipfs/repo"
+
+	datastore "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	config "github.com/ipfs/go-ipfs-config"
+	files "github.com/ipfs/go-ipfs-files"
+)
+
+func TestBenchTimings(t *testing.T) {
+	const delay = 50 * time.Millisecond
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+		time.Sleep(delay)
+		w.Write([]byte(" world"))
+	})
+
+	out, err := bench(h, "/ipfs/whatever")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", out.Status)
+	}
+	if out.Bytes != int64(len("hello world")) {
+		t.Fatalf("expected %d bytes, got %d", len("hello world"), out.Bytes)
+	}
+	if out.TTFB < delay {
+		t.Fatalf("expected a TTFB of at least %s, got %s", delay, out.TTFB)
+	}
+	if out.Total < out.TTFB+delay {
+		t.Fatalf("expected the total time (%s) to include the second delay after the TTFB (%s)", out.Total, out.TTFB)
+	}
+}
+
+func TestBenchGateway(t *testing.T) {
+	ctx := context.Background()
+
+	r := &repo.Mock{
+		C: config.Config{
+			Identity: config.Identity{
+				PeerID: "QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe", // required by offline node
+			},
+		},
+		D: syncds.MutexWrap(datastore.NewMapDatastore()),
+	}
+	n, err := core.NewNode(ctx, &core.BuildCfg{Repo: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	api, err := coreapi.NewCoreAPI(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Repeat("known content\n", 1000)
+	p, err := api.Unixfs().Add(ctx, files.NewBytesFile([]byte(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	if _, err := corehttp.GatewayOption(false, "/ipfs", "/ipns")(n, nil, mux); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := bench(mux, p.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", out.Status)
+	}
+	if out.Bytes != int64(len(content)) {
+		t.Fatalf("expected %d bytes, got %d", len(content), out.Bytes)
+	}
+	if out.TTFB <= 0 || out.Total < out.TTFB {
+		t.Fatalf("inconsistent timings: ttfb %s, total %s", out.TTFB, out.Total)
+	}
+
+	out, err = bench(mux, "/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Status == http.StatusOK {
+		t.Fatal("expected a missing path to fail")
+	}
+}
@@ -0,0 +1,126 @@
+package name
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	isd "github.com/jbenet/go-is-domain"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	mbase "github.com/multiformats/go-multibase"
+)
+
+// Kinds of IPNS names, as reported by 'ipfs name convert'.
+const (
+	nameTypeKey     = "key"
+	nameTypeDNSLink = "dnslink"
+)
+
+// ConvertOutput is the output of 'ipfs name convert': the forms of an IPNS
+// name. Names of keys have the peer ID and CID forms, DNSLink names only have
+// a path.
+type ConvertOutput struct {
+	Name   string
+	Type   string
+	PeerID string `json:",omitempty"`
+	// Base36 and Base32 are the CIDv1 of the key, with the libp2p-key
+	// codec. Base36 is the one fitting in a DNS label with all keys.
+	Base36 string `json:",omitempty"`
+	Base32 string `json:",omitempty"`
+	Path   string
+}
+
+var ConvertCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Convert an IPNS name between its forms.",
+		ShortDescription: `
+'ipfs name convert' parses an IPNS name in any accepted form, with or without
+the /ipns/ prefix, and prints its other forms. The name of a key is a peer ID,
+or the CID of the key, in base36 or base32, with the libp2p-key codec:
+
+  > ipfs name convert k2k4r8l8ib3lr79fb4053byk44024i9biu9wb8fsty4xlehfqtlsd8dd
+  peer ID: QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe
+  base36:  k2k4r8l8ib3lr79fb4053byk44024i9biu9wb8fsty4xlehfqtlsd8dd
+  base32:  bafzbeici7y4y7bwmecey4kjqmfeth4arqartkbkspdur452bv4ucweglue
+  path:    /ipns/QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe
+
+CIDs of keys with another codec, like the dag-pb ones shown before the
+libp2p-key codec existed, are accepted too.
+
+DNSLink names are domains, they are not converted: the key they point to
+depends on their DNS records, see 'ipfs name resolve' and 'ipfs dns'.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, false, "IPNS name to convert."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		out, err := convertName(req.Arguments[0])
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, err.Error())
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *ConvertOutput) error {
+			if out.Type == nameTypeDNSLink {
+				fmt.Fprintf(w, "DNSLink: %s\n", strings.TrimPrefix(out.Path, "/ipns/"))
+				fmt.Fprintf(w, "path:    %s\n", out.Path)
+				return nil
+			}
+			fmt.Fprintf(w, "peer ID: %s\n", out.PeerID)
+			fmt.Fprintf(w, "base36:  %s\n", out.Base36)
+			fmt.Fprintf(w, "base32:  %s\n", out.Base32)
+			fmt.Fprintf(w, "path:    %s\n", out.Path)
+			return nil
+		}),
+	},
+	Type: ConvertOutput{},
+}
+
+// convertName returns the forms of the IPNS name, the name of a key or a
+// DNSLink domain.
+func convertName(name string) (*ConvertOutput, error) {
+	s := strings.TrimPrefix(name, "/ipns/")
+	if s == "" {
+		return nil, fmt.Errorf("empty IPNS name")
+	}
+
+	id, err := peer.Decode(s)
+	if err != nil {
+		// a key CID with another codec than libp2p-key
+		if c, cerr := cid.Decode(s); cerr == nil {
+			id, err = peer.IDFromBytes(c.Hash())
+		}
+	}
+	if err != nil {
+		if isd.IsDomain(s) {
+			return &ConvertOutput{
+				Name: name,
+				Type: nameTypeDNSLink,
+				Path: "/ipns/" + s,
+			}, nil
+		}
+		return nil, fmt.Errorf("%q is neither a peer ID, the CID of a key nor a DNSLink domain", name)
+	}
+
+	c := peer.ToCid(id)
+	base36, err := c.StringOfBase(mbase.Base36)
+	if err != nil {
+		return nil, err
+	}
+	base32, err := c.StringOfBase(mbase.Base32)
+	if err != nil {
+		return nil, err
+	}
+	return &ConvertOutput{
+		Name:   name,
+		Type:   nameTypeKey,
+		PeerID: id.Pretty(),
+		Base36: base36,
+		Base32: base32,
+		Path:   "/ipns/" + id.Pretty(),
+	}, nil
+}
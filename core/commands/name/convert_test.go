@@ -0,0 +1,75 @@
+package name
+
+import (
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	mbase "github.com/multiformats/go-multibase"
+)
+
+func TestConvertName(t *testing.T) {
+	for _, expected := range []ConvertOutput{
+		{
+			// RSA key, sha256 peer ID
+			PeerID: "QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe",
+			Base36: "k2k4r8l8ib3lr79fb4053byk44024i9biu9wb8fsty4xlehfqtlsd8dd",
+			Base32: "bafzbeici7y4y7bwmecey4kjqmfeth4arqartkbkspdur452bv4ucweglue",
+		},
+		{
+			// ed25519 key, identity peer ID
+			PeerID: "12D3KooWD3eckifWpRn9wQpMG9R9hX3sD158z7EqHWmweQAJU5SA",
+			Base36: "k51qzi5uqu5dhdmyb9bd18pypu2wp5lpv2xnskfmrqa4lb5knqryrotb05e7or",
+			Base32: "bafzaajaiaejcal72gwuz2or47oyxxn6b3rkwdmmkrxgkjxzy3rqt5kczyn7lcm3l",
+		},
+	} {
+		id, err := peer.Decode(expected.PeerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the CIDv1 with the dag-pb codec, as shown before libp2p-key
+		dagPb, err := cid.NewCidV1(cid.DagProtobuf, []byte(id)).StringOfBase(mbase.Base32)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{
+			expected.PeerID,
+			expected.Base36,
+			expected.Base32,
+			"/ipns/" + expected.PeerID,
+			"/ipns/" + expected.Base36,
+			dagPb,
+		} {
+			out, err := convertName(name)
+			if err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+			if out.Name != name || out.Type != nameTypeKey {
+				t.Errorf("%s: expected a key name, got %+v", name, out)
+			}
+			if out.PeerID != expected.PeerID || out.Base36 != expected.Base36 || out.Base32 != expected.Base32 {
+				t.Errorf("%s: expected %+v, got %+v", name, expected, out)
+			}
+			if out.Path != "/ipns/"+expected.PeerID {
+				t.Errorf("%s: expected the path /ipns/%s, got %s", name, expected.PeerID, out.Path)
+			}
+		}
+	}
+
+	for _, name := range []string{"docs.ipfs.io", "/ipns/docs.ipfs.io"} {
+		out, err := convertName(name)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if out.Type != nameTypeDNSLink || out.Path != "/ipns/docs.ipfs.io" || out.PeerID != "" {
+			t.Errorf("%s: expected a DNSLink name, got %+v", name, out)
+		}
+	}
+
+	for _, name := range []string{"", "/ipns/", "not a name", "QmNotAPeerID"} {
+		if _, err := convertName(name); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+}
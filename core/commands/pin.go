@@ -1,27 +1,34 @@
 package commands
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	bserv "github.com/ipfs/go-blockservice"
 	cid "github.com/ipfs/go-cid"
 	cidenc "github.com/ipfs/go-cidutil/cidenc"
+	ds "github.com/ipfs/go-datastore"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	files "github.com/ipfs/go-ipfs-files"
 	dag "github.com/ipfs/go-merkledag"
 	verifcid "github.com/ipfs/go-verifcid"
 	coreiface "github.com/ipfs/interface-go-ipfs-core"
 	options "github.com/ipfs/interface-go-ipfs-core/options"
 	"github.com/ipfs/interface-go-ipfs-core/path"
+	gocar "github.com/ipld/go-car"
 
 	core "github.com/ipfs/go-ipfs/core"
 	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
 	e "github.com/ipfs/go-ipfs/core/commands/e"
+	pinmeta "github.com/ipfs/go-ipfs/pinmeta"
 )
 
 var PinCmd = &cmds.Command{
@@ -35,6 +42,7 @@ var PinCmd = &cmds.Command{
 		"ls":     listPinCmd,
 		"verify": verifyPinCmd,
 		"update": updatePinCmd,
+		"diff":   diffPinCmd,
 	},
 }
 
@@ -44,26 +52,53 @@ type PinOutput struct {
 
 type AddPinOutput struct {
 	Pins     []string
-	Progress int `json:",omitempty"`
+	Progress int             `json:",omitempty"`
+	Failures []PinAddFailure `json:",omitempty"`
+}
+
+// PinAddFailure is a path that could not be pinned by 'ipfs pin add
+// --from-file' or '--from-car'.
+type PinAddFailure struct {
+	Path  string
+	Error string
 }
 
 const (
 	pinRecursiveOptionName = "recursive"
 	pinProgressOptionName  = "progress"
+	pinKeepGoingOptionName = "keep-going"
+	pinFromFileOptionName  = "from-file"
+	pinFromCarOptionName   = "from-car"
 )
 
 var addPinCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline:          "Pin objects to local storage.",
 		ShortDescription: "Stores an IPFS object(s) from a given path locally to disk.",
+		LongDescription: `
+Stores an IPFS object(s) from a given path locally to disk.
+
+Many objects can be pinned at once with --from-file, which reads a file
+listing one path or CID per line (blank lines and lines starting with '#'
+are ignored), or with --from-car, which pins the roots of a .car file. The
+blocks themselves are not imported from the .car file, use 'ipfs dag import'
+for that. In both cases, paths that cannot be pinned are reported and the
+others are still pinned, and no paths are read from stdin.
+
+  > ipfs pin add --from-file cids.txt
+  > ipfs pin add --from-car backup.car
+`,
 	},
 
 	Arguments: []cmds.Argument{
-		cmds.StringArg("ipfs-path", true, true, "Path to object(s) to be pinned.").EnableStdin(),
+		cmds.StringArg("ipfs-path", false, true, "Path to object(s) to be pinned.").EnableStdin(),
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption(pinRecursiveOptionName, "r", "Recursively pin the object linked to by the specified object(s).").WithDefault(true),
 		cmds.BoolOption(pinProgressOptionName, "Show progress"),
+		cmds.BoolOption(pinKeepGoingOptionName, "Report the paths that cannot be pinned and keep pinning the others. Implied by --from-file and --from-car."),
+		cmds.StringOption(pinFromFileOptionName, "Pin the paths listed in a file, one per line."),
+		cmds.StringOption(pinFromCarOptionName, "Pin the roots of a .car file."),
 	},
 	Type: AddPinOutput{},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
@@ -72,13 +107,23 @@ var addPinCmd = &cmds.Command{
 			return err
 		}
 
+		for _, opt := range []string{pinFromFileOptionName, pinFromCarOptionName} {
+			if _, found := req.Options[opt]; found {
+				return cmds.Errorf(cmds.ErrClient, "--%s can only be used from the command line", opt)
+			}
+		}
+
 		// set recursive flag
 		recursive, _ := req.Options[pinRecursiveOptionName].(bool)
 		showProgress, _ := req.Options[pinProgressOptionName].(bool)
+		keepGoing, _ := req.Options[pinKeepGoingOptionName].(bool)
 
 		if err := req.ParseBodyArgs(); err != nil {
 			return err
 		}
+		if len(req.Arguments) == 0 {
+			return cmds.Errorf(cmds.ErrClient, "argument \"ipfs-path\" is required")
+		}
 
 		enc, err := cmdenv.GetCidEncoder(req)
 		if err != nil {
@@ -86,26 +131,30 @@ var addPinCmd = &cmds.Command{
 		}
 
 		if !showProgress {
-			added, err := pinAddMany(req.Context, api, enc, req.Arguments, recursive)
+			added, failures, err := pinAddMany(req.Context, api, enc, req.Arguments, recursive, keepGoing)
 			if err != nil {
 				return err
 			}
 
-			return cmds.EmitOnce(res, &AddPinOutput{Pins: added})
+			if err := cmds.EmitOnce(res, &AddPinOutput{Pins: added, Failures: failures}); err != nil {
+				return err
+			}
+			return pinFailuresErr(failures, len(req.Arguments))
 		}
 
 		v := new(dag.ProgressTracker)
 		ctx := v.DeriveContext(req.Context)
 
 		type pinResult struct {
-			pins []string
-			err  error
+			pins     []string
+			failures []PinAddFailure
+			err      error
 		}
 
 		ch := make(chan pinResult, 1)
 		go func() {
-			added, err := pinAddMany(ctx, api, enc, req.Arguments, recursive)
-			ch <- pinResult{pins: added, err: err}
+			added, failures, err := pinAddMany(ctx, api, enc, req.Arguments, recursive, keepGoing)
+			ch <- pinResult{pins: added, failures: failures, err: err}
 		}()
 
 		ticker := time.NewTicker(500 * time.Millisecond)
@@ -123,7 +172,10 @@ var addPinCmd = &cmds.Command{
 						return err
 					}
 				}
-				return res.Emit(&AddPinOutput{Pins: val.pins})
+				if err := res.Emit(&AddPinOutput{Pins: val.pins, Failures: val.failures}); err != nil {
+					return err
+				}
+				return pinFailuresErr(val.failures, len(req.Arguments))
 			case <-ticker.C:
 				if err := res.Emit(&AddPinOutput{Progress: v.Value()}); err != nil {
 					return err
@@ -147,6 +199,9 @@ var addPinCmd = &cmds.Command{
 			for _, k := range out.Pins {
 				fmt.Fprintf(w, "pinned %s %s\n", k, pintype)
 			}
+			for _, f := range out.Failures {
+				fmt.Fprintf(w, "failed to pin %s: %s\n", f.Path, f.Error)
+			}
 
 			return nil
 		}),
@@ -180,21 +235,86 @@ var addPinCmd = &cmds.Command{
 	},
 }
 
-func pinAddMany(ctx context.Context, api coreiface.CoreAPI, enc cidenc.Encoder, paths []string, recursive bool) ([]string, error) {
-	added := make([]string, len(paths))
-	for i, b := range paths {
-		rp, err := api.ResolvePath(ctx, path.New(b))
+// pinAddMany pins paths in order. It stops at the first failure unless
+// keepGoing is set, in which case failures are returned alongside the pins
+// that succeeded.
+func pinAddMany(ctx context.Context, api coreiface.CoreAPI, enc cidenc.Encoder, paths []string, recursive, keepGoing bool) ([]string, []PinAddFailure, error) {
+	added := make([]string, 0, len(paths))
+	var failures []PinAddFailure
+	for _, b := range paths {
+		err := func() error {
+			rp, err := api.ResolvePath(ctx, path.New(b))
+			if err != nil {
+				return err
+			}
+
+			if err := api.Pin().Add(ctx, rp, options.Pin.Recursive(recursive)); err != nil {
+				return err
+			}
+			added = append(added, enc.Encode(rp.Cid()))
+			return nil
+		}()
 		if err != nil {
-			return nil, err
+			if !keepGoing || ctx.Err() != nil {
+				return nil, nil, err
+			}
+			failures = append(failures, PinAddFailure{Path: b, Error: err.Error()})
 		}
+	}
+
+	return added, failures, nil
+}
+
+// pinFailuresErr summarizes failures out of total pins, if any.
+func pinFailuresErr(failures []PinAddFailure, total int) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed to pin %d of %d paths", len(failures), total)
+}
+
+// readPinListFile reads the paths listed in fname, one per line. Blank
+// lines and lines starting with '#' are skipped.
+func readPinListFile(fname string) ([]string, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-		if err := api.Pin().Add(ctx, rp, options.Pin.Recursive(recursive)); err != nil {
-			return nil, err
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
-		added[i] = enc.Encode(rp.Cid())
+		paths = append(paths, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %s", fname, err)
 	}
+	return paths, nil
+}
+
+// readCarRoots returns the roots listed in the header of the .car file
+// fname.
+func readCarRoots(fname string) ([]string, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-	return added, nil
+	header, err := gocar.ReadHeader(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %s", fname, err)
+	}
+	roots := make([]string, len(header.Roots))
+	for i, c := range header.Roots {
+		roots[i] = c.String()
+	}
+	return roots, nil
 }
 
 var rmPinCmd = &cmds.Command{
@@ -262,6 +382,7 @@ const (
 	pinTypeOptionName   = "type"
 	pinQuietOptionName  = "quiet"
 	pinStreamOptionName = "stream"
+	pinSinceOptionName  = "since"
 )
 
 var listPinCmd = &cmds.Command{
@@ -291,6 +412,10 @@ With arguments, the command fails if any of the arguments is not a pinned
 object. And if --type=<type> is additionally used, the command will also fail
 if any of the arguments is not of the specified type.
 
+Use --since=<duration> (e.g. "1h", "30m") to only list direct and recursive
+pins added within that duration. Indirect pins, and pins added before pin
+times were recorded, are not listed when --since is used.
+
 Example:
 	$ echo "hello" | ipfs add -q
 	QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN
@@ -315,6 +440,7 @@ Example:
 		cmds.StringOption(pinTypeOptionName, "t", "The type of pinned keys to list. Can be \"direct\", \"indirect\", \"recursive\", or \"all\".").WithDefault("all"),
 		cmds.BoolOption(pinQuietOptionName, "q", "Write just hashes of objects."),
 		cmds.BoolOption(pinStreamOptionName, "s", "Enable streaming of pins as they are discovered."),
+		cmds.StringOption(pinSinceOptionName, "Only list direct and recursive pins added within this duration (e.g. \"1h\")."),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		api, err := cmdenv.GetApi(env, req)
@@ -343,6 +469,20 @@ Example:
 			}
 		}
 
+		if sinceStr, _ := req.Options[pinSinceOptionName].(string); sinceStr != "" {
+			since, err := time.ParseDuration(sinceStr)
+			if err != nil {
+				return cmds.Errorf(cmds.ErrClient, "invalid --%s duration: %s", pinSinceOptionName, err)
+			}
+
+			n, err := cmdenv.GetNode(env)
+			if err != nil {
+				return err
+			}
+
+			emit = filterPinsSince(n.Repo.Datastore(), time.Now().Add(-since), emit)
+		}
+
 		if len(req.Arguments) > 0 {
 			err = pinLsKeys(req, typeStr, api, emit)
 		} else {
@@ -362,6 +502,7 @@ Example:
 	},
 	Type: &PinLsOutputWrapper{},
 	Encoders: cmds.EncoderMap{
+		CSV: makeCSVEncoder([]string{"Cid", "Type"}, pinLsCSVRows),
 		cmds.JSON: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *PinLsOutputWrapper) error {
 			stream, _ := req.Options[pinStreamOptionName].(bool)
 
@@ -399,6 +540,28 @@ Example:
 	},
 }
 
+func pinLsCSVRows(req *cmds.Request, v interface{}) ([][]string, error) {
+	out, ok := v.(*PinLsOutputWrapper)
+	if !ok {
+		return nil, e.TypeErr(out, v)
+	}
+
+	if stream, _ := req.Options[pinStreamOptionName].(bool); stream {
+		return [][]string{{out.PinLsObject.Cid, out.PinLsObject.Type}}, nil
+	}
+
+	keys := make([]string, 0, len(out.PinLsList.Keys))
+	for k := range out.PinLsList.Keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rows := make([][]string, len(keys))
+	for i, k := range keys {
+		rows[i] = []string{k, out.PinLsList.Keys[k].Type}
+	}
+	return rows, nil
+}
+
 // PinLsOutputWrapper is the output type of the pin ls command.
 // Pin ls needs to output two different type depending on if it's streamed or not.
 // We use this to bypass the cmds lib refusing to have interface{}
@@ -423,6 +586,74 @@ type PinLsObject struct {
 	Type string `json:",omitempty"`
 }
 
+// ReadPinAddLists reads on the client the paths listed in the files given to
+// 'ipfs pin add' with --from-file and --from-car, and makes them the body of
+// req, one per line, in place of stdin: they are sent to the daemon in the
+// request body, not in its URL. It must be called before req is executed,
+// and does nothing for other commands.
+func ReadPinAddLists(req *cmds.Request) error {
+	if req.Command != addPinCmd {
+		return nil
+	}
+
+	var paths []string
+	bulk := false
+	if fname, found := req.Options[pinFromFileOptionName].(string); found {
+		listed, err := readPinListFile(fname)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, listed...)
+		delete(req.Options, pinFromFileOptionName)
+		bulk = true
+	}
+	if fname, found := req.Options[pinFromCarOptionName].(string); found {
+		roots, err := readCarRoots(fname)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, roots...)
+		delete(req.Options, pinFromCarOptionName)
+		bulk = true
+	}
+	if !bulk {
+		return nil
+	}
+
+	req.Options[pinKeepGoingOptionName] = true
+	req.Files = files.NewMapDirectory(map[string]files.Node{
+		"stdin": files.NewBytesFile([]byte(strings.Join(paths, "\n"))),
+	})
+	return nil
+}
+
+// filterPinsSince wraps emit so that only direct and recursive pins added
+// after cutoff are passed through.
+func filterPinsSince(d ds.Datastore, cutoff time.Time, emit func(value interface{}) error) func(value interface{}) error {
+	return func(v interface{}) error {
+		obj := v.(*PinLsOutputWrapper)
+		switch obj.PinLsObject.Type {
+		case "direct", "recursive":
+		default:
+			return nil
+		}
+
+		c, err := cid.Decode(obj.PinLsObject.Cid)
+		if err != nil {
+			return err
+		}
+
+		added, ok, err := pinmeta.AddedAt(d, c)
+		if err != nil {
+			return err
+		}
+		if !ok || added.Before(cutoff) {
+			return nil
+		}
+		return emit(v)
+	}
+}
+
 func pinLsKeys(req *cmds.Request, typeStr string, api coreiface.CoreAPI, emit func(value interface{}) error) error {
 	enc, err := cmdenv.GetCidEncoder(req)
 	if err != nil {
@@ -0,0 +1,254 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	options "github.com/ipfs/interface-go-ipfs-core/options"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// PinDiffOutput is the output of 'ipfs pin diff'.
+type PinDiffOutput struct {
+	// LocalOnly are the pins of this node missing on the other one,
+	// RemoteOnly the other way around.
+	LocalOnly  []string
+	RemoteOnly []string
+	Common     int
+}
+
+var diffPinCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Compare the pins of this node with another node.",
+		ShortDescription: `
+'ipfs pin diff' compares the pins of this node with the pins of the node
+serving the HTTP API at the given address, and lists the pins only found on
+either side. The address is a multiaddr or a URL:
+
+  > ipfs pin diff /ip4/10.0.0.2/tcp/5001
+  local  QmYCvbfNbCwFR45HiNP45rwJgvatpiW38D961L5qAhUM5Y
+  remote QmcXx6Vb6rp3qDrSZq5sv5HPwbjsMaJ6ZkVrcLy2F1DQZ4
+  12 pins in common
+
+The other node is asked for its whole pinset in a single request, streamed,
+rather than once for every local pin. CIDs are compared by content, a pin
+listed as CIDv0 on one side and CIDv1 on the other is in common.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("other-node-api", true, false, "HTTP API address of the other node."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(pinTypeOptionName, "t", "The type of pins to compare. Can be \"direct\" or \"recursive\".").WithDefault("recursive"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+		enc, err := cmdenv.GetCidEncoder(req)
+		if err != nil {
+			return err
+		}
+
+		typeStr, _ := req.Options[pinTypeOptionName].(string)
+		switch typeStr {
+		case "direct", "recursive":
+		default:
+			return cmds.Errorf(cmds.ErrClient, "invalid type '%s', must be one of {direct, recursive}", typeStr)
+		}
+
+		base, err := remoteAPIURL(req.Arguments[0])
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid API address: %s", err)
+		}
+
+		opt, err := options.Pin.Ls.Type(typeStr)
+		if err != nil {
+			return err
+		}
+		pins, err := api.Pin().Ls(req.Context, opt)
+		if err != nil {
+			return err
+		}
+		var local []cid.Cid
+		for p := range pins {
+			if err := p.Err(); err != nil {
+				return err
+			}
+			local = append(local, p.Path().Cid())
+		}
+
+		remote, err := remotePins(req.Context, http.DefaultClient, base, typeStr)
+		if err != nil {
+			return fmt.Errorf("listing the pins of %s: %s", base, err)
+		}
+
+		localOnly, remoteOnly, common := diffPins(local, remote)
+		out := &PinDiffOutput{
+			LocalOnly:  make([]string, len(localOnly)),
+			RemoteOnly: make([]string, len(remoteOnly)),
+			Common:     common,
+		}
+		for i, c := range localOnly {
+			out.LocalOnly[i] = enc.Encode(c)
+		}
+		for i, c := range remoteOnly {
+			out.RemoteOnly[i] = enc.Encode(c)
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *PinDiffOutput) error {
+			for _, c := range out.LocalOnly {
+				fmt.Fprintf(w, "local  %s\n", c)
+			}
+			for _, c := range out.RemoteOnly {
+				fmt.Fprintf(w, "remote %s\n", c)
+			}
+			fmt.Fprintf(w, "%d pins in common\n", out.Common)
+			return nil
+		}),
+	},
+	Type: PinDiffOutput{},
+}
+
+// remoteAPIURL returns the base URL of the HTTP API at addr, a multiaddr or
+// a URL.
+func remoteAPIURL(addr string) (string, error) {
+	if strings.HasPrefix(addr, "/") {
+		maddr, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			return "", err
+		}
+		naddr, err := manet.ToNetAddr(maddr)
+		if err != nil {
+			return "", err
+		}
+		return "http://" + naddr.String(), nil
+	}
+
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// remotePins lists the pins of type typeStr of the node serving the HTTP API
+// at base, with a single streamed 'pin ls' request. Nodes that don't stream
+// pins answer with the whole list at once, both forms are accepted.
+func remotePins(ctx context.Context, client *http.Client, base, typeStr string) ([]cid.Cid, error) {
+	q := url.Values{
+		pinTypeOptionName:   {typeStr},
+		pinStreamOptionName: {"true"},
+	}
+	req, err := http.NewRequest(http.MethodPost, base+"/api/v0/pin/ls?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		var e cmds.Error
+		if json.Unmarshal(body, &e) == nil && e.Message != "" {
+			return nil, fmt.Errorf("%s", e.Message)
+		}
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var strs []string
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var out PinLsOutputWrapper
+		if err := dec.Decode(&out); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if out.PinLsObject.Cid != "" {
+			strs = append(strs, out.PinLsObject.Cid)
+		}
+		for c := range out.PinLsList.Keys {
+			strs = append(strs, c)
+		}
+	}
+	// errors past the headers come in a trailer
+	if e := resp.Trailer.Get("X-Stream-Error"); e != "" {
+		return nil, fmt.Errorf("%s", e)
+	}
+
+	pins := make([]cid.Cid, 0, len(strs))
+	for _, s := range strs {
+		c, err := cid.Decode(s)
+		if err != nil {
+			return nil, err
+		}
+		pins = append(pins, c)
+	}
+	return pins, nil
+}
+
+// diffPins returns the pins only in local, only in remote, sorted, and the
+// number of pins in both. Pins are compared by multihash, ignoring the CID
+// version.
+func diffPins(local, remote []cid.Cid) (localOnly, remoteOnly []cid.Cid, common int) {
+	inRemote := make(map[string]struct{}, len(remote))
+	for _, c := range remote {
+		inRemote[string(c.Hash())] = struct{}{}
+	}
+
+	inLocal := make(map[string]struct{}, len(local))
+	for _, c := range local {
+		k := string(c.Hash())
+		if _, dup := inLocal[k]; dup {
+			continue
+		}
+		inLocal[k] = struct{}{}
+		if _, found := inRemote[k]; found {
+			common++
+		} else {
+			localOnly = append(localOnly, c)
+		}
+	}
+	for _, c := range remote {
+		k := string(c.Hash())
+		if _, found := inLocal[k]; !found {
+			inLocal[k] = struct{}{}
+			remoteOnly = append(remoteOnly, c)
+		}
+	}
+
+	sortCids(localOnly)
+	sortCids(remoteOnly)
+	return localOnly, remoteOnly, common
+}
+
+func sortCids(cids []cid.Cid) {
+	sort.Slice(cids, func(i, j int) bool {
+		return cids[i].String() < cids[j].String()
+	})
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func testPinCid(t *testing.T, s string) cid.Cid {
+	h, err := mh.Sum([]byte(s), mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV0(h)
+}
+
+func TestPinDiff(t *testing.T) {
+	a, b, c, d := testPinCid(t, "a"), testPinCid(t, "b"), testPinCid(t, "c"), testPinCid(t, "d")
+
+	// the remote lists b as CIDv1, it's still the same pin
+	remoteSet := []cid.Cid{cid.NewCidV1(cid.DagProtobuf, b.Hash()), c, d}
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/api/v0/pin/ls" || r.URL.Query().Get("type") != "recursive" || r.URL.Query().Get("stream") != "true" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		enc := json.NewEncoder(w)
+		for _, c := range remoteSet {
+			enc.Encode(&PinLsOutputWrapper{PinLsObject: PinLsObject{Cid: c.String(), Type: "recursive"}})
+		}
+	}))
+	defer ts.Close()
+
+	base, err := remoteAPIURL(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := remotePins(context.Background(), ts.Client(), base, "recursive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected the pinset in a single request, got %d", requests)
+	}
+
+	localOnly, remoteOnly, common := diffPins([]cid.Cid{a, b, c}, remote)
+	if len(localOnly) != 1 || !localOnly[0].Equals(a) {
+		t.Fatalf("expected only %s to be local, got %v", a, localOnly)
+	}
+	if len(remoteOnly) != 1 || !remoteOnly[0].Equals(d) {
+		t.Fatalf("expected only %s to be remote, got %v", d, remoteOnly)
+	}
+	if common != 2 {
+		t.Fatalf("expected 2 pins in common, got %d", common)
+	}
+}
+
+func TestRemotePinsLegacyList(t *testing.T) {
+	a := testPinCid(t, "a")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&PinLsOutputWrapper{PinLsList: PinLsList{
+			Keys: map[string]PinLsType{a.String(): {Type: "recursive"}},
+		}})
+	}))
+	defer ts.Close()
+
+	remote, err := remotePins(context.Background(), ts.Client(), ts.URL, "recursive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remote) != 1 || !remote[0].Equals(a) {
+		t.Fatalf("expected [%s], got %v", a, remote)
+	}
+}
+
+func TestRemoteAPIURL(t *testing.T) {
+	for addr, expected := range map[string]string{
+		"/ip4/127.0.0.1/tcp/5001": "http://127.0.0.1:5001",
+		"10.0.0.2:5001":           "http://10.0.0.2:5001",
+		"https://node.example/":   "https://node.example",
+	} {
+		u, err := remoteAPIURL(addr)
+		if err != nil || u != expected {
+			t.Errorf("%s: expected %s, got %q, %v", addr, expected, u, err)
+		}
+	}
+	for _, addr := range []string{"/ip4/127.0.0.1/udp/5001/quic", "ftp://node.example"} {
+		if _, err := remoteAPIURL(addr); err == nil {
+			t.Errorf("%s: expected an error", addr)
+		}
+	}
+}
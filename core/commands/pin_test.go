@@ -0,0 +1,226 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/ipfs/go-ipfs/core"
+	coreapi "github.com/ipfs/go-ipfs/core/coreapi"
+	repo "github.com/ipfs/go-ipfs/repo"
+
+	cid "github.com/ipfs/go-cid"
+	cidenc "github.com/ipfs/go-cidutil/cidenc"
+	datastore "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	config "github.com/ipfs/go-ipfs-config"
+	files "github.com/ipfs/go-ipfs-files"
+	mdag "github.com/ipfs/go-merkledag"
+	gocar "github.com/ipld/go-car"
+)
+
+func TestPinAddFromFile(t *testing.T) {
+	ctx := context.Background()
+
+	r := &repo.Mock{
+		C: config.Config{
+			Identity: config.Identity{
+				PeerID: "QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe", // required by offline node
+			},
+		},
+		D: syncds.MutexWrap(datastore.NewMapDatastore()),
+	}
+	n, err := core.NewNode(ctx, &core.BuildCfg{Repo: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	api, err := coreapi.NewCoreAPI(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cids []string
+	for _, content := range []string{"one", "two"} {
+		p, err := api.Unixfs().Add(ctx, files.NewBytesFile([]byte(content)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cids = append(cids, p.Cid().String())
+	}
+
+	dir, err := ioutil.TempDir("", "pin-from-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "cids.txt")
+	list := "# pins\n" + cids[0] + "\n\nnot-a-cid\n" + cids[1] + "\n"
+	if err := ioutil.WriteFile(fname, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := readPinListFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(paths, " ") != strings.Join([]string{cids[0], "not-a-cid", cids[1]}, " ") {
+		t.Fatalf("unexpected paths read from the list: %v", paths)
+	}
+
+	// without keep-going, the first failure stops everything
+	if _, _, err := pinAddMany(ctx, api, cidenc.Default(), paths, true, false); err == nil {
+		t.Fatal("expected pinning an invalid path to fail")
+	}
+
+	added, failures, err := pinAddMany(ctx, api, cidenc.Default(), paths, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(added, " ") != strings.Join(cids, " ") {
+		t.Errorf("expected the valid cids to be pinned, got %v", added)
+	}
+	if len(failures) != 1 || failures[0].Path != "not-a-cid" || failures[0].Error == "" {
+		t.Errorf("expected the invalid cid to be reported, got %v", failures)
+	}
+	if err := pinFailuresErr(failures, len(paths)); err == nil || err.Error() != "failed to pin 1 of 3 paths" {
+		t.Errorf("unexpected summary error: %v", err)
+	}
+
+	for _, s := range cids {
+		c, err := cid.Decode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, pinned, err := n.Pinning.IsPinned(ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pinned {
+			t.Errorf("expected %s to be pinned", c)
+		}
+	}
+}
+
+func TestReadCarRoots(t *testing.T) {
+	root := mdag.NodeWithData([]byte("root"))
+
+	var buf bytes.Buffer
+	if err := gocar.WriteHeader(&gocar.CarHeader{Roots: []cid.Cid{root.Cid()}, Version: 1}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ioutil.TempFile("", "roots.car")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	roots, err := readCarRoots(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || roots[0] != root.Cid().String() {
+		t.Fatalf("expected the car root, got %v", roots)
+	}
+}
+
+func TestFilterPinsSince(t *testing.T) {
+	d := syncds.MutexWrap(datastore.NewMapDatastore())
+	now := time.Now()
+	cutoff := now.Add(-time.Hour)
+
+	newCid := func(data string) cid.Cid {
+		return mdag.NodeWithData([]byte(data)).Cid()
+	}
+	// recordAdded records c as pinned at added, like pinmeta does
+	recordAdded := func(c cid.Cid, added time.Time) {
+		buf, err := added.UTC().MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Put(datastore.NewKey("/local/pins/added").ChildString(c.String()), buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldRecursive, newRecursive, newDirect, indirect, unrecorded :=
+		newCid("old recursive"), newCid("new recursive"), newCid("new direct"), newCid("indirect"), newCid("unrecorded")
+	recordAdded(oldRecursive, now.Add(-2*time.Hour))
+	recordAdded(newRecursive, now)
+	recordAdded(newDirect, now)
+	recordAdded(indirect, now)
+
+	var emitted []string
+	emit := filterPinsSince(d, cutoff, func(v interface{}) error {
+		emitted = append(emitted, v.(*PinLsOutputWrapper).PinLsObject.Cid)
+		return nil
+	})
+	for _, p := range []PinLsObject{
+		{Cid: oldRecursive.String(), Type: "recursive"},
+		{Cid: newRecursive.String(), Type: "recursive"},
+		{Cid: newDirect.String(), Type: "direct"},
+		{Cid: indirect.String(), Type: "indirect"},
+		{Cid: unrecorded.String(), Type: "direct"},
+	} {
+		if err := emit(&PinLsOutputWrapper{PinLsObject: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{newRecursive.String(), newDirect.String()}
+	if strings.Join(emitted, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected only the direct and recursive pins added since the cutoff %v, got %v", want, emitted)
+	}
+}
+
+func TestReadPinAddLists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pin-from-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "cids.txt")
+	listed := []string{"QmTFauExutTsy4XP6JbMFcw2Wa9645HJt2bTqL6qYDCKfe", "/ipns/example.com"}
+	if err := ioutil.WriteFile(fname, []byte(strings.Join(listed, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := cmds.NewRequest(context.Background(), []string{"pin", "add"}, cmds.OptMap{pinFromFileOptionName: fname}, []string{"/ipfs/QmArg"}, nil, Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ReadPinAddLists(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := req.Options[pinFromFileOptionName]; found {
+		t.Fatal("expected the file to be read on the client")
+	}
+	if keepGoing, _ := req.Options[pinKeepGoingOptionName].(bool); !keepGoing {
+		t.Fatal("expected --from-file to imply keep-going")
+	}
+
+	// the listed paths are in the body, not in the arguments of the URL
+	if err := req.Command.CheckArguments(req); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(req.Arguments, " ") != "/ipfs/QmArg" {
+		t.Fatalf("expected only the argument given on the command line, got %v", req.Arguments)
+	}
+	if err := req.ParseBodyArgs(); err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]string{"/ipfs/QmArg"}, listed...); strings.Join(req.Arguments, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected the listed paths in the body, got %v", req.Arguments)
+	}
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+	"github.com/ipfs/go-ipfs/plugin/loader"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// PluginExportOutput is the output of 'ipfs plugin export'.
+type PluginExportOutput struct {
+	Plugins []loader.PluginInfo
+}
+
+var PluginCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Inspect plugins.",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"export": pluginExportCmd,
+	},
+}
+
+var pluginExportCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Export the loaded plugins and their configuration.",
+		ShortDescription: `
+'ipfs plugin export' outputs the name, version and origin of every loaded
+plugin, along with its configuration from the Plugins.Plugins section of
+the config. Plugins built into the binary have no path.
+
+Use '--enc=json' to get output that can be used to reproduce the setup.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		plugins, err := env.(*oldcmds.Context).GetPlugins()
+		if err != nil {
+			return err
+		}
+
+		return cmds.EmitOnce(res, &PluginExportOutput{Plugins: plugins.Plugins()})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *PluginExportOutput) error {
+			tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+			for _, p := range out.Plugins {
+				path := p.Path
+				if path == "" {
+					path = "(built-in)"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Name, p.Version, path)
+			}
+			return tw.Flush()
+		}),
+	},
+	Type: PluginExportOutput{},
+}
@@ -16,9 +16,12 @@ import (
 	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 
+	bserv "github.com/ipfs/go-blockservice"
 	cid "github.com/ipfs/go-cid"
 	bstore "github.com/ipfs/go-ipfs-blockstore"
 	cmds "github.com/ipfs/go-ipfs-cmds"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	dag "github.com/ipfs/go-merkledag"
 )
 
 type RepoVersion struct {
@@ -34,11 +37,20 @@ var RepoCmd = &cmds.Command{
 	},
 
 	Subcommands: map[string]*cmds.Command{
-		"stat":    repoStatCmd,
-		"gc":      repoGcCmd,
-		"fsck":    repoFsckCmd,
-		"version": repoVersionCmd,
-		"verify":  repoVerifyCmd,
+		"stat":             repoStatCmd,
+		"gc":               repoGcCmd,
+		"fsck":             repoFsckCmd,
+		"version":          repoVersionCmd,
+		"verify":           repoVerifyCmd,
+		"dedup-savings":    repoDedupSavingsCmd,
+		"top":              repoTopCmd,
+		"codec-stats":      repoCodecStatsCmd,
+		"orphans":          repoOrphansCmd,
+		"replication":      repoReplicationCmd,
+		"lock":             repoLockCmd,
+		"pin-load-bench":   repoPinLoadBenchCmd,
+		"cold-blocks":      repoColdBlocksCmd,
+		"datastore-stress": repoDatastoreStressCmd,
 	},
 }
 
@@ -210,6 +222,268 @@ Version         string The repo version.
 	},
 }
 
+// DedupSavingsOutput is the output of the "repo dedup-savings" command.
+type DedupSavingsOutput struct {
+	corerepo.DedupStat
+	RepoSize uint64
+}
+
+var repoDedupSavingsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Report how much space block deduplication saves.",
+		ShortDescription: `
+'ipfs repo dedup-savings' walks all pinned DAGs and compares their logical
+size (the sum of their block sizes, counting blocks shared between or within
+DAGs once per reference) with the size of the distinct blocks actually
+stored. It outputs:
+
+LogicalSize     int Size in bytes of the pinned DAGs without deduplication.
+UniqueSize      int Size in bytes of the distinct pinned blocks.
+Savings         int Bytes saved by deduplication.
+Ratio           float LogicalSize divided by UniqueSize.
+RepoSize        int Size in bytes that the repo is currently taking.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(repoHumanOptionName, "H", "Print sizes in human readable format (e.g., 1K 234M 2G)"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		bs := bserv.New(n.Blockstore, offline.Exchange(n.Blockstore))
+		stat, err := corerepo.DedupSavings(req.Context, n.Pinning, dag.NewDAGService(bs), n.Blockstore)
+		if err != nil {
+			return err
+		}
+
+		sizeStat, err := corerepo.RepoSize(req.Context, n)
+		if err != nil {
+			return err
+		}
+
+		return cmds.EmitOnce(res, &DedupSavingsOutput{
+			DedupStat: stat,
+			RepoSize:  sizeStat.RepoSize,
+		})
+	},
+	Type: &DedupSavingsOutput{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *DedupSavingsOutput) error {
+			wtr := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+			defer wtr.Flush()
+
+			human, _ := req.Options[repoHumanOptionName].(bool)
+
+			printSize := func(name string, size uint64) {
+				sizeStr := fmt.Sprintf("%d", size)
+				if human {
+					sizeStr = humanize.Bytes(size)
+				}
+
+				fmt.Fprintf(wtr, "%s:\t%s\n", name, sizeStr)
+			}
+
+			printSize("LogicalSize", out.LogicalSize)
+			printSize("UniqueSize", out.UniqueSize)
+			printSize("Savings", out.Savings)
+			fmt.Fprintf(wtr, "Ratio:\t%.2f\n", out.Ratio)
+			printSize("RepoSize", out.RepoSize)
+
+			return nil
+		}),
+	},
+}
+
+const (
+	repoTopNumOptionName    = "n"
+	repoTopBlocksOptionName = "blocks"
+)
+
+// RepoTopEntry is a single object reported by "repo top".
+type RepoTopEntry struct {
+	Cid  string
+	Size uint64
+}
+
+// RepoTopOutput is the output of the "repo top" command. Entries are sorted
+// by size, largest first.
+type RepoTopOutput struct {
+	Entries []RepoTopEntry
+}
+
+var repoTopCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the largest objects in the repo.",
+		ShortDescription: `
+'ipfs repo top' lists the largest recursively pinned DAGs, largest first.
+The size of a DAG is the total size of its distinct blocks. With --blocks,
+the largest individual blocks in the repo are listed instead, pinned or not.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.IntOption(repoTopNumOptionName, "Number of objects to list.").WithDefault(20),
+		cmds.BoolOption(repoTopBlocksOptionName, "List the largest blocks instead of pinned DAGs."),
+		cmds.BoolOption(repoHumanOptionName, "H", "Print sizes in human readable format (e.g., 1K 234M 2G)"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		num, _ := req.Options[repoTopNumOptionName].(int)
+		if num < 1 {
+			return cmds.Errorf(cmds.ErrClient, "--%s must be at least 1", repoTopNumOptionName)
+		}
+
+		enc, err := cmdenv.GetCidEncoder(req)
+		if err != nil {
+			return err
+		}
+
+		var top []corerepo.SizedCid
+		if blocks, _ := req.Options[repoTopBlocksOptionName].(bool); blocks {
+			top, err = corerepo.LargestBlocks(req.Context, n.Blockstore, num)
+		} else {
+			bs := bserv.New(n.Blockstore, offline.Exchange(n.Blockstore))
+			top, err = corerepo.LargestPins(req.Context, n.Pinning, dag.NewDAGService(bs), n.Blockstore, num)
+		}
+		if err != nil {
+			return err
+		}
+
+		out := &RepoTopOutput{Entries: make([]RepoTopEntry, len(top))}
+		for i, e := range top {
+			out.Entries[i] = RepoTopEntry{Cid: enc.Encode(e.Cid), Size: e.Size}
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Type: &RepoTopOutput{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *RepoTopOutput) error {
+			wtr := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+			defer wtr.Flush()
+
+			human, _ := req.Options[repoHumanOptionName].(bool)
+			for _, e := range out.Entries {
+				sizeStr := fmt.Sprintf("%d", e.Size)
+				if human {
+					sizeStr = humanize.Bytes(e.Size)
+				}
+				fmt.Fprintf(wtr, "%s\t%s\n", sizeStr, e.Cid)
+			}
+			return nil
+		}),
+	},
+}
+
+// RepoOrphan is a DAG root reported by the "repo orphans" command.
+type RepoOrphan struct {
+	Cid string
+}
+
+var repoOrphansCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the roots of DAGs that are neither pinned nor in MFS.",
+		ShortDescription: `
+'ipfs repo orphans' lists the roots of the DAGs stored in the repo that are
+not pinned and not referenced from MFS (see 'ipfs files'). These DAGs are
+the ones the next 'ipfs repo gc' would remove. Content being added while the
+command runs may be listed before it gets pinned.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		enc, err := cmdenv.GetCidEncoder(req)
+		if err != nil {
+			return err
+		}
+
+		// keep a garbage collection from removing blocks under us
+		defer n.Blockstore.PinLock().Unlock()
+
+		roots, err := corerepo.BestEffortRoots(n.FilesRoot)
+		if err != nil {
+			return err
+		}
+		bs := bserv.New(n.Blockstore, offline.Exchange(n.Blockstore))
+		orphans, err := corerepo.Orphans(req.Context, n.Pinning, dag.NewDAGService(bs), n.Blockstore, roots)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range orphans {
+			if err := res.Emit(&RepoOrphan{Cid: enc.Encode(c)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: RepoOrphan{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *RepoOrphan) error {
+			_, err := fmt.Fprintln(w, out.Cid)
+			return err
+		}),
+	},
+}
+
+// CodecStatsOutput is the output of the "repo codec-stats" command.
+type CodecStatsOutput struct {
+	Codecs []corerepo.CodecStat
+}
+
+var repoCodecStatsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Count blocks and their sizes per codec.",
+		ShortDescription: `
+'ipfs repo codec-stats' walks every block in the repo and reports, for each
+multicodec (raw, protobuf, cbor...), the number of blocks and their total
+size in bytes, largest first.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(repoHumanOptionName, "H", "Print sizes in human readable format (e.g., 1K 234M 2G)"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		stats, err := corerepo.CodecStats(req.Context, n.Blockstore)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &CodecStatsOutput{Codecs: stats})
+	},
+	Type: &CodecStatsOutput{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *CodecStatsOutput) error {
+			wtr := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+			defer wtr.Flush()
+
+			human, _ := req.Options[repoHumanOptionName].(bool)
+			fmt.Fprintf(wtr, "Codec\tBlocks\tSize\n")
+			for _, st := range out.Codecs {
+				sizeStr := fmt.Sprintf("%d", st.Size)
+				if human {
+					sizeStr = humanize.Bytes(st.Size)
+				}
+				fmt.Fprintf(wtr, "%s\t%d\t%s\n", st.Codec, st.NumBlocks, sizeStr)
+			}
+			return nil
+		}),
+	},
+}
+
 var repoFsckCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Remove repo lockfiles.",
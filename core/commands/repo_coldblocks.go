@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	"github.com/ipfs/go-ipfs/thirdparty/atimebs"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+const coldBlocksOlderThanOptionName = "older-than"
+
+// ColdBlockOutput is a block listed by 'ipfs repo cold-blocks'.
+type ColdBlockOutput struct {
+	Cid string
+	// LastAccess is nil for the blocks with no access recorded.
+	LastAccess *time.Time `json:",omitempty"`
+}
+
+var repoColdBlocksCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the blocks not accessed for some time.",
+		ShortDescription: `
+'ipfs repo cold-blocks' lists the blocks of the repo not put or read within
+the given window, the least recently accessed first, to plan which ones to
+evict from a cache:
+
+  > ipfs repo cold-blocks --older-than 7d
+  QmPZ9gcCEpqKTo6aq61g2nXGUhM4iCL3ewB6LDXZCtioEB never
+  QmQy6xmJhrcC5QLboAcGFcAE1tC8CrwDVkrHdEYJkLscrQ 2020-03-02T10:04:51Z
+
+The access times are only recorded while the daemon runs with
+'--track-block-access', and at most once an hour for each block. Blocks
+without recorded access, like the ones stored before, are listed as never
+accessed.
+
+The window is a duration like '36h', or a number of days like '7d'.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(coldBlocksOlderThanOptionName, "List the blocks not accessed within this window, e.g. 7d or 36h.").WithDefault("7d"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		s, _ := req.Options[coldBlocksOlderThanOptionName].(string)
+		window, err := parseAge(s)
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid --%s: %s", coldBlocksOlderThanOptionName, err)
+		}
+
+		cold, err := atimebs.Cold(req.Context, n.Blockstore, n.Repo.Datastore(), time.Now().Add(-window))
+		if err != nil {
+			return err
+		}
+		for _, b := range cold {
+			out := &ColdBlockOutput{Cid: b.Cid.String()}
+			if !b.LastAccess.IsZero() {
+				t := b.LastAccess.UTC()
+				out.LastAccess = &t
+			}
+			if err := res.Emit(out); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: ColdBlockOutput{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *ColdBlockOutput) error {
+			last := "never"
+			if out.LastAccess != nil {
+				last = out.LastAccess.Format(time.RFC3339)
+			}
+			_, err := fmt.Fprintf(w, "%s %s\n", out.Cid, last)
+			return err
+		}),
+	},
+}
+
+// parseAge parses a positive duration, also accepting a number of days like
+// "7d".
+func parseAge(s string) (time.Duration, error) {
+	var d time.Duration
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days %q", days)
+		}
+		d = time.Duration(n * float64(24*time.Hour))
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%q is not positive", s)
+	}
+	return d, nil
+}
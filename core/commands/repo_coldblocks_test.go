@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	for s, expected := range map[string]time.Duration{
+		"7d":   7 * 24 * time.Hour,
+		"1.5d": 36 * time.Hour,
+		"36h":  36 * time.Hour,
+		"90m":  90 * time.Minute,
+	} {
+		if d, err := parseAge(s); err != nil || d != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", s, expected, d, err)
+		}
+	}
+	for _, s := range []string{"", "d", "weekd", "0d", "-2h", "7"} {
+		if _, err := parseAge(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
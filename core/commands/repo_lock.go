@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+const repoLockBreakStaleOptionName = "break-stale"
+
+// RepoLockStatus is the output of 'ipfs repo lock status'.
+type RepoLockStatus struct {
+	fsrepo.LockStatus
+	// Removed is set when a stale lock was removed with --break-stale.
+	Removed bool
+}
+
+var repoLockCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Inspect the repo lock.",
+		ShortDescription: `
+'ipfs repo lock' inspects the lock taken by the process using the repo.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"status": repoLockStatusCmd,
+	},
+}
+
+var repoLockStatusCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show whether the repo lock is held by a live process.",
+		ShortDescription: `
+'ipfs repo lock status' reports whether the repo lock file exists, the process
+holding it and whether that process is alive.
+
+A lock naming a process that exited, after a crash for example, is stale and
+keeps the repo from being opened. Pass --break-stale to remove it. Locks held
+by a live process are never removed.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(repoLockBreakStaleOptionName, "Remove the lock if its process is gone."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		cfgRoot, err := cmdenv.GetConfigRoot(env)
+		if err != nil {
+			return err
+		}
+
+		breakStale, _ := req.Options[repoLockBreakStaleOptionName].(bool)
+		if !breakStale {
+			st, err := fsrepo.ReadLockStatus(cfgRoot)
+			if err != nil {
+				return err
+			}
+			return cmds.EmitOnce(res, &RepoLockStatus{LockStatus: *st})
+		}
+
+		st, err := fsrepo.BreakStaleLock(cfgRoot)
+		switch err {
+		case nil:
+			return cmds.EmitOnce(res, &RepoLockStatus{LockStatus: *st, Removed: true})
+		case fsrepo.ErrLockNotStale:
+			return cmds.EmitOnce(res, &RepoLockStatus{LockStatus: *st})
+		default:
+			return err
+		}
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *RepoLockStatus) error {
+			fmt.Fprintf(w, "lock file: %s\n", out.Path)
+			switch {
+			case !out.Exists:
+				fmt.Fprintln(w, "not locked")
+				return nil
+			case out.PID == 0:
+				fmt.Fprintln(w, "not held")
+				return nil
+			case out.Alive:
+				fmt.Fprintf(w, "held by process %d (alive)\n", out.PID)
+				return nil
+			}
+
+			fmt.Fprintf(w, "stale: process %d is gone\n", out.PID)
+			if out.Removed {
+				fmt.Fprintln(w, "removed the stale lock")
+			} else {
+				fmt.Fprintf(w, "run with --%s to remove it\n", repoLockBreakStaleOptionName)
+			}
+			return nil
+		}),
+	},
+	Type: RepoLockStatus{},
+}
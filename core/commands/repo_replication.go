@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	routing "github.com/libp2p/go-libp2p-core/routing"
+)
+
+const (
+	replicationQueryTimeoutOptionName = "query-timeout"
+)
+
+// ReplicationOutput is the output of 'ipfs repo replication'.
+type ReplicationOutput struct {
+	Cid string
+	// Local is set when this node holds the block as well.
+	Local bool
+	// Holders are the other peers found providing the block through
+	// content routing, or answering they have it when asked, Replicas their
+	// number.
+	Holders  []string
+	Replicas int
+	// Asked is the number of connected peers asked whether they have the
+	// block.
+	Asked int
+}
+
+var repoReplicationCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Estimate how many peers hold a block.",
+		ShortDescription: `
+'ipfs repo replication' looks for the peers holding the given block, to
+roughly assess how durable it is. Providers are looked up through content
+routing (the DHT), and the connected peers are asked over bitswap whether
+they have the block (without fetching it), the search stopping after
+--query-timeout.
+
+Peers that hold the block without announcing it and aren't connected can't
+be found, neither can connected peers not answering in time: the count is a
+lower bound.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("cid", true, false, "CID of the block to look for."),
+	},
+	Options: []cmds.Option{
+		cmds.IntOption(numProvidersOptionName, "n", "The number of providers to find through content routing, 0 for no limit.").WithDefault(defaultNumProviders),
+		cmds.StringOption(replicationQueryTimeoutOptionName, "How long to look for holders.").WithDefault("30s"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		if !n.IsOnline {
+			return ErrNotOnline
+		}
+
+		c, err := cid.Parse(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		numProviders, _ := req.Options[numProvidersOptionName].(int)
+		if numProviders < 0 {
+			return cmds.Errorf(cmds.ErrClient, "--%s must not be negative", numProvidersOptionName)
+		}
+
+		timeoutStr, _ := req.Options[replicationQueryTimeoutOptionName].(string)
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid --%s: %s", replicationQueryTimeoutOptionName, err)
+		}
+
+		ctx, cancel := context.WithTimeout(req.Context, timeout)
+		defer cancel()
+
+		var connected []peer.ID
+		var has func(context.Context, cid.Cid, []peer.ID) []peer.ID
+		if n.HaveProber != nil {
+			connected = n.PeerHost.Network().Peers()
+			has = n.HaveProber.Has
+		}
+		holders := findHolders(ctx, c, n.Routing, n.Identity, numProviders, connected, has)
+
+		local, err := n.Blockstore.Has(c)
+		if err != nil {
+			return err
+		}
+
+		out := &ReplicationOutput{
+			Cid:      c.String(),
+			Local:    local,
+			Holders:  make([]string, len(holders)),
+			Replicas: len(holders),
+			Asked:    len(connected),
+		}
+		for i, p := range holders {
+			out.Holders[i] = p.Pretty()
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *ReplicationOutput) error {
+			fmt.Fprintf(w, "%s is held by %d other peers (%d connected peers asked)\n", out.Cid, out.Replicas, out.Asked)
+			if out.Local {
+				fmt.Fprintln(w, "and by this node")
+			}
+			for _, p := range out.Holders {
+				fmt.Fprintf(w, "\t%s\n", p)
+			}
+			return nil
+		}),
+	},
+	Type: ReplicationOutput{},
+}
+
+// findHolders looks for the peers holding c, other than self, among the
+// providers found through r, up to maxProviders (0 for no limit), and among
+// the connected peers answering has. It returns once both searches are done
+// or ctx expires, with the holders sorted.
+func findHolders(ctx context.Context, c cid.Cid, r routing.ContentRouting, self peer.ID, maxProviders int, connected []peer.ID, has func(context.Context, cid.Cid, []peer.ID) []peer.ID) []peer.ID {
+	var haves []peer.ID
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if has != nil {
+			haves = has(ctx, c, connected)
+		}
+	}()
+
+	holders := make(map[peer.ID]struct{})
+	if r != nil {
+		for pi := range r.FindProvidersAsync(ctx, c, maxProviders) {
+			if pi.ID != self {
+				holders[pi.ID] = struct{}{}
+			}
+		}
+	}
+	<-done
+	for _, p := range haves {
+		if p != self {
+			holders[p] = struct{}{}
+		}
+	}
+
+	out := make([]peer.ID, 0, len(holders))
+	for p := range holders {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
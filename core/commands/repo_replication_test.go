@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	core "github.com/ipfs/go-ipfs/core"
+	coremock "github.com/ipfs/go-ipfs/core/mock"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	u "github.com/ipfs/go-ipfs-util"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	routing "github.com/libp2p/go-libp2p-core/routing"
+	"github.com/libp2p/go-libp2p-core/test"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+// stubProviders reports a known set of providers.
+type stubProviders struct {
+	routing.ContentRouting
+	providers []peer.ID
+}
+
+func (r *stubProviders) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	ch := make(chan peer.AddrInfo)
+	go func() {
+		defer close(ch)
+		for i, p := range r.providers {
+			if count > 0 && i >= count {
+				return
+			}
+			select {
+			case ch <- peer.AddrInfo{ID: p}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func TestFindHolders(t *testing.T) {
+	peers := make([]peer.ID, 3)
+	for i := range peers {
+		peers[i] = test.RandPeerIDFatal(t)
+	}
+	self := peers[0]
+	c := cid.NewCidV0(u.Hash([]byte("replicated")))
+
+	// peer 2 is announced twice
+	r := &stubProviders{providers: []peer.ID{self, peers[1], peers[2], peers[2]}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	holders := findHolders(ctx, c, r, self, 0, nil, nil)
+	expected := map[peer.ID]bool{peers[1]: true, peers[2]: true}
+	if len(holders) != len(expected) {
+		t.Fatalf("expected %d holders, got %v", len(expected), holders)
+	}
+	for _, p := range holders {
+		if !expected[p] {
+			t.Fatalf("unexpected holder %s", p)
+		}
+	}
+
+	// the search stops after maxProviders
+	if holders = findHolders(ctx, c, r, self, 1, nil, nil); len(holders) != 0 {
+		t.Fatalf("expected self to be skipped and the search to stop after one provider, got %v", holders)
+	}
+
+	// the connected peers answering they have the block are holders too
+	extra := test.RandPeerIDFatal(t)
+	connected := []peer.ID{peers[1], extra}
+	var asked []peer.ID
+	has := func(ctx context.Context, hc cid.Cid, ps []peer.ID) []peer.ID {
+		if !hc.Equals(c) {
+			t.Errorf("expected the connected peers asked for %s, got %s", c, hc)
+		}
+		asked = ps
+		return []peer.ID{peers[1], extra}
+	}
+	holders = findHolders(ctx, c, r, self, 0, connected, has)
+	if len(asked) != 2 {
+		t.Fatalf("expected the connected peers to be asked, got %v", asked)
+	}
+	if len(holders) != 3 {
+		t.Fatalf("expected the providers and the connected holder, got %v", holders)
+	}
+}
+
+func TestHaveProber(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn := mocknet.New(ctx)
+	var nodes []*core.IpfsNode
+	for i := 0; i < 3; i++ {
+		n, err := coremock.MockPublicNode(ctx, mn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer n.Close()
+		nodes = append(nodes, n)
+	}
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mn.ConnectAllButSelf(); err != nil {
+		t.Fatal(err)
+	}
+	asker, holder, other := nodes[0], nodes[1], nodes[2]
+
+	blk := blocks.NewBlock([]byte("replicated"))
+	if err := holder.Blockstore.Put(blk); err != nil {
+		t.Fatal(err)
+	}
+
+	qctx, qcancel := context.WithTimeout(ctx, 10*time.Second)
+	defer qcancel()
+	holders := asker.HaveProber.Has(qctx, blk.Cid(), []peer.ID{holder.Identity, other.Identity})
+	if len(holders) != 1 || holders[0] != holder.Identity {
+		t.Fatalf("expected only %s to have the block, got %v", holder.Identity, holders)
+	}
+	if qctx.Err() != nil {
+		t.Fatal("expected both peers to answer before the timeout")
+	}
+	if has, _ := asker.Blockstore.Has(blk.Cid()); has {
+		t.Fatal("expected the block not to be fetched")
+	}
+}
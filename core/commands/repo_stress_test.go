@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	dssync "github.com/ipfs/go-datastore/sync"
+)
+
+func TestStressDatastore(t *testing.T) {
+	d := dssync.MutexWrap(ds.NewMapDatastore())
+	out, err := stressDatastore(context.Background(), d, 4, 200, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Errors != 0 || out.ErrorRate != 0 {
+		t.Fatalf("expected no errors, got %d: %s", out.Errors, out.FirstError)
+	}
+	if out.Writers != 4 || out.Ops != 200 || out.Duration <= 0 || out.OpsPerSecond <= 0 {
+		t.Fatalf("unexpected metrics %+v", out)
+	}
+	for name, l := range map[string]DatastoreStressLatency{"put": out.Put, "get": out.Get} {
+		if l.Max <= 0 || l.P50 > l.P99 || l.P99 > l.Max || l.Mean > l.Max {
+			t.Errorf("unexpected %s latencies %+v", name, l)
+		}
+	}
+
+	res, err := d.Query(dsq.Query{KeysOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, err := res.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 0 {
+		t.Fatalf("expected the blocks written to be removed, %d left", len(left))
+	}
+}
+
+func TestStressLatency(t *testing.T) {
+	var ls []time.Duration
+	for i := 100; i > 0; i-- {
+		ls = append(ls, time.Duration(i)*time.Millisecond)
+	}
+	l := stressLatency(ls)
+	expected := DatastoreStressLatency{Mean: 50500 * time.Microsecond, P50: 50 * time.Millisecond, P99: 99 * time.Millisecond, Max: 100 * time.Millisecond}
+	if l != expected {
+		t.Fatalf("expected %+v, got %+v", expected, l)
+	}
+	if (stressLatency(nil) != DatastoreStressLatency{}) {
+		t.Fatal("expected no latencies without operations")
+	}
+}
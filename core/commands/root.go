@@ -23,12 +23,32 @@ const (
 	LocalOption   = "local" // DEPRECATED: use OfflineOption
 	OfflineOption = "offline"
 	ApiOption     = "api"
+
+	WithConfigOption        = "with-config"
+	ApiTimeoutOption        = "api-timeout"
+	ApiResolveTimeoutOption = "api-resolve-timeout"
+	ApiCACertOption         = "api-cacert"
+	ApiAuthOption           = "api-auth"
+	ApiRetryOption          = "api-retry"
+	RetryTransientOption    = "retry-transient"
+	IdempotencyKeyOption    = "idempotency-key"
+	OutputFdOption          = "output-fd"
+	DeadlineOption          = "deadline"
+	FlushTimeoutOption      = "flush-timeout"
+	MaxMemoryOption         = "max-memory"
+	// ProfilingOption isn't named "profile", taken by 'ipfs init'.
+	ProfilingOption     = "profiling"
+	NoSummaryOption     = "no-summary"
+	NoFallbackOption    = "no-fallback"
+	ErrorFormatOption   = "error-format"
+	NoDaemonOption      = "no-daemon"
+	DisablePluginOption = "disable-plugin"
 )
 
 var Root = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline:  "Global p2p merkle-dag filesystem.",
-		Synopsis: "ipfs [--config=<config> | -c] [--debug | -D] [--help] [-h] [--api=<api>] [--offline] [--cid-base=<base>] [--upgrade-cidv0-in-output] [--encoding=<encoding> | --enc] [--timeout=<timeout>] <command> ...",
+		Synopsis: "ipfs [--config=<config> | -c] [--debug | -D] [--help] [-h] [--api=<api>] [--api-timeout=<duration>] [--api-resolve-timeout=<duration>] [--api-cacert=<file>] [--api-auth=<token>] [--api-retry=<n>] [--with-config=<key>=<value>] [--retry-transient=<n>] [--idempotency-key=<key>] [--output-fd=<fd>] [--offline] [--no-daemon] [--cid-base=<base>] [--upgrade-cidv0-in-output] [--encoding=<encoding> | --enc] [--timeout=<timeout>] [--deadline=<time>] [--flush-timeout=<duration>] [--max-memory=<size>] [--profiling] [--no-summary] [--no-fallback] [--error-format=<format>] [--disable-plugin=<name>] <command> ...",
 		Subcommands: `
 BASIC COMMANDS
   init          Initialize ipfs local configuration
@@ -87,6 +107,9 @@ The CLI will exit with one of the following values:
 
 0     Successful execution.
 1     Failed executions.
+2     Invalid command line, like a missing argument or an unknown option.
+3     Unknown command.
+4     The daemon couldn't be reached.
 `,
 	},
 	Options: []cmds.Option{
@@ -96,7 +119,25 @@ The CLI will exit with one of the following values:
 		cmds.BoolOption(cmds.OptShortHelp, "Show a short version of the command help text."),
 		cmds.BoolOption(LocalOption, "L", "Run the command locally, instead of using the daemon. DEPRECATED: use --offline."),
 		cmds.BoolOption(OfflineOption, "Run the command offline."),
-		cmds.StringOption(ApiOption, "Use a specific API instance (defaults to /ip4/127.0.0.1/tcp/5001)"),
+		cmds.BoolOption(NoDaemonOption, "Run the command on the repo directly, never using the daemon. Fails while a daemon holds the repo lock."),
+		cmds.StringOption(ApiOption, "Use a specific API instance (defaults to $IPFS_API, else /ip4/127.0.0.1/tcp/5001). Several instances may be given, separated by commas: the first one accepting connections is used."),
+		cmds.StringOption(ApiTimeoutOption, "Give up on the daemon API when connecting to it, or waiting for a command not streaming its output to start answering, takes longer than this (e.g. 30s). Default: no timeout."),
+		cmds.StringOption(ApiResolveTimeoutOption, "Give up on resolving the daemon API address, and connecting to the addresses it resolves to, after this long (e.g. 30s). Default: 10s."),
+		cmds.StringOption(ApiCACertOption, "PEM file of the CA certificates to trust for an API reached over TLS, like /dns4/example.com/tcp/443/https. Default: the system CAs."),
+		cmds.StringOption(ApiAuthOption, "Bearer token to authenticate to the daemon API with. Default: $IPFS_API_AUTH."),
+		cmds.IntOption(ApiRetryOption, "Connect to the daemon API again up to this many times, waiting longer every time, while it refuses connections or, holding the repo lock, hasn't written its API file yet, e.g. while it starts."),
+		cmds.IntOption(RetryTransientOption, "Send read-only commands to the daemon again up to this many times when sending them fails with a transient network error."),
+		cmds.StringOption(IdempotencyKeyOption, "Key identifying this request to the daemon, which applies a request at most once per key: running the command again with the same key, e.g. after losing the connection, gets the response of the first run."),
+		cmds.IntOption(OutputFdOption, "Write the command output to this inherited file descriptor instead of stdout."),
+		cmds.StringOption(DeadlineOption, "Fail the command if it hasn't finished by this time, given as an RFC 3339 timestamp (e.g. 2024-01-01T00:00:00Z)."),
+		cmds.StringOption(FlushTimeoutOption, "How long to wait for the repo to be flushed before exiting, when running without a daemon (e.g. 10s). Default: 30s."),
+		cmds.StringOption(MaxMemoryOption, "Abort the command if its memory use goes over this size (e.g. 512MB). Can't be used while the daemon is running."),
+		cmds.BoolOption(ProfilingOption, "Profile the command, as with IPFS_PROF set."),
+		cmds.BoolOption(NoSummaryOption, "Don't print the summary following the results of a command, like the one of 'ipfs add'."),
+		cmds.BoolOption(NoFallbackOption, "Fail when the daemon whose API file is in the repo can't be reached, instead of running the command on the repo directly."),
+		cmds.StringOption(ErrorFormatOption, "Format of the errors printed on stderr: text, or json like the API errors ({\"Message\":...,\"Code\":...,\"Type\":\"error\"}). Default: text."),
+		cmds.StringsOption(DisablePluginOption, "Don't load the plugin of this name (case-insensitive), e.g. a datastore plugin keeping the daemon from starting. May be given multiple times."),
+		cmds.StringsOption(WithConfigOption, "Override a config value for this invocation only, as <key>=<value> (e.g. Gateway.NoFetch=true). The config file is not modified. May be given multiple times."),
 
 		// global options, added to every command
 		cmdenv.OptionCidBase,
@@ -115,6 +156,8 @@ var rootSubcommands = map[string]*cmds.Command{
 	"add":       AddCmd,
 	"bitswap":   BitswapCmd,
 	"block":     BlockCmd,
+	"bundle":    BundleCmd,
+	"cache":     CacheCmd,
 	"cat":       CatCmd,
 	"commands":  CommandsDaemonCmd,
 	"files":     FilesCmd,
@@ -129,6 +172,7 @@ var rootSubcommands = map[string]*cmds.Command{
 	"dht":       DhtCmd,
 	"diag":      DiagCmd,
 	"dns":       DNSCmd,
+	"events":    EventsCmd,
 	"id":        IDCmd,
 	"key":       KeyCmd,
 	"log":       LogCmd,
@@ -138,6 +182,7 @@ var rootSubcommands = map[string]*cmds.Command{
 	"object":    ocmd.ObjectCmd,
 	"pin":       PinCmd,
 	"ping":      PingCmd,
+	"plugin":    PluginCmd,
 	"p2p":       P2PCmd,
 	"refs":      RefsCmd,
 	"resolve":   ResolveCmd,
@@ -1,14 +1,29 @@
 package commands
 
 import (
+	"context"
+	"time"
+
 	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
 
 	"github.com/ipfs/go-ipfs-cmds"
 )
 
+const shutdownDrainOptionName = "drain"
+
 var daemonShutdownCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Shut down the ipfs daemon",
+		ShortDescription: `
+With --drain, the daemon stops accepting new API and gateway requests,
+waits up to the given grace period for the ones in flight to complete, and
+then shuts down. The command returns as soon as draining has started.
+
+  > ipfs shutdown --drain 30s
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(shutdownDrainOptionName, "Finish in-flight requests before shutting down, waiting at most this long (e.g. \"30s\")."),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		nd, err := cmdenv.GetNode(env)
@@ -20,6 +35,29 @@ var daemonShutdownCmd = &cmds.Command{
 			return cmds.Errorf(cmds.ErrClient, "daemon not running")
 		}
 
+		if graceStr, found := req.Options[shutdownDrainOptionName].(string); found {
+			grace, err := time.ParseDuration(graceStr)
+			if err != nil {
+				return cmds.Errorf(cmds.ErrClient, "invalid drain period: %s", err)
+			}
+
+			// This request is in flight too, so wait for it in the
+			// background and let it return first.
+			go func() {
+				if nd.Drainer != nil {
+					ctx, cancel := context.WithTimeout(context.Background(), grace)
+					if err := nd.Drainer.Drain(ctx); err != nil {
+						log.Warnf("shutting down with requests still in flight after %s", grace)
+					}
+					cancel()
+				}
+				if err := nd.Close(); err != nil {
+					log.Error("error while shutting down ipfs daemon:", err)
+				}
+			}()
+			return nil
+		}
+
 		if err := nd.Close(); err != nil {
 			log.Error("error while shutting down ipfs daemon:", err)
 		}
@@ -7,11 +7,13 @@ import (
 	"io"
 	"path"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	commands "github.com/ipfs/go-ipfs/commands"
 	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	e "github.com/ipfs/go-ipfs/core/commands/e"
 	repo "github.com/ipfs/go-ipfs/repo"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 
@@ -19,6 +21,7 @@ import (
 	config "github.com/ipfs/go-ipfs-config"
 	inet "github.com/libp2p/go-libp2p-core/network"
 	peer "github.com/libp2p/go-libp2p-core/peer"
+	pstore "github.com/libp2p/go-libp2p-core/peerstore"
 	ma "github.com/multiformats/go-multiaddr"
 	madns "github.com/multiformats/go-multiaddr-dns"
 	mamask "github.com/whyrusleeping/multiaddr-filter"
@@ -46,11 +49,17 @@ ipfs peers in the internet.
 `,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"addrs":      swarmAddrsCmd,
-		"connect":    swarmConnectCmd,
-		"disconnect": swarmDisconnectCmd,
-		"filters":    swarmFiltersCmd,
-		"peers":      swarmPeersCmd,
+		"addrs":              swarmAddrsCmd,
+		"connect":            swarmConnectCmd,
+		"denylist":           swarmDenylistCmd,
+		"disconnect":         swarmDisconnectCmd,
+		"filters":            swarmFiltersCmd,
+		"limit":              swarmLimitCmd,
+		"negotiate":          swarmNegotiateCmd,
+		"peers":              swarmPeersCmd,
+		"relays":             swarmRelaysCmd,
+		"simulate-partition": swarmSimulatePartitionCmd,
+		"transport-params":   swarmTransportParamsCmd,
 	},
 }
 
@@ -59,6 +68,8 @@ const (
 	swarmStreamsOptionName   = "streams"
 	swarmLatencyOptionName   = "latency"
 	swarmDirectionOptionName = "direction"
+	swarmAgentsOptionName    = "agents"
+	swarmByAgentOptionName   = "by-agent"
 )
 
 var swarmPeersCmd = &cmds.Command{
@@ -66,6 +77,16 @@ var swarmPeersCmd = &cmds.Command{
 		Tagline: "List peers with open connections.",
 		ShortDescription: `
 'ipfs swarm peers' lists the set of peers this node is connected to.
+
+With --agents, each peer is listed with the agent version it told with the
+identify protocol, like 'go-ipfs/0.5.0/', and the JSON output also holds the
+protocols it supports and the number of peers per agent version. With
+--by-agent, only the number of peers per agent version is listed:
+
+  > ipfs swarm peers --by-agent
+  41 go-ipfs/0.5.0/
+  12 go-ipfs/0.4.23/
+  3 unknown
 `,
 	},
 	Options: []cmds.Option{
@@ -73,6 +94,8 @@ var swarmPeersCmd = &cmds.Command{
 		cmds.BoolOption(swarmStreamsOptionName, "Also list information about open streams for each peer"),
 		cmds.BoolOption(swarmLatencyOptionName, "Also list information about latency to each peer"),
 		cmds.BoolOption(swarmDirectionOptionName, "Also list information about the direction of connection"),
+		cmds.BoolOption(swarmAgentsOptionName, "Also list the agent version and protocols of each peer"),
+		cmds.BoolOption(swarmByAgentOptionName, "List the number of peers per agent version instead of the peers"),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		api, err := cmdenv.GetApi(env, req)
@@ -84,12 +107,24 @@ var swarmPeersCmd = &cmds.Command{
 		latency, _ := req.Options[swarmLatencyOptionName].(bool)
 		streams, _ := req.Options[swarmStreamsOptionName].(bool)
 		direction, _ := req.Options[swarmDirectionOptionName].(bool)
+		byAgent, _ := req.Options[swarmByAgentOptionName].(bool)
+		agents, _ := req.Options[swarmAgentsOptionName].(bool)
+		agents = agents || byAgent
 
 		conns, err := api.Swarm().Peers(req.Context)
 		if err != nil {
 			return err
 		}
 
+		var ps pstore.Peerstore
+		if agents {
+			n, err := cmdenv.GetNode(env)
+			if err != nil {
+				return err
+			}
+			ps = n.Peerstore
+		}
+
 		var out connInfos
 		for _, c := range conns {
 			ci := connInfo{
@@ -97,6 +132,10 @@ var swarmPeersCmd = &cmds.Command{
 				Peer: c.ID().Pretty(),
 			}
 
+			if agents {
+				ci.Agent, ci.Protocols = peerAgent(ps, c.ID())
+			}
+
 			if verbose || direction {
 				// set direction
 				ci.Direction = c.Direction()
@@ -129,10 +168,21 @@ var swarmPeersCmd = &cmds.Command{
 		}
 
 		sort.Sort(&out)
+		if agents {
+			out.Agents = groupAgents(out.Peers)
+		}
 		return cmds.EmitOnce(res, &out)
 	},
 	Encoders: cmds.EncoderMap{
+		CSV: makeCSVEncoder([]string{"Addr", "Peer", "Latency", "Muxer", "Direction", "Streams"}, swarmPeersCSVRows),
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, ci *connInfos) error {
+			if byAgent, _ := req.Options[swarmByAgentOptionName].(bool); byAgent {
+				for _, g := range ci.Agents {
+					fmt.Fprintf(w, "%d %s\n", g.Peers, g.Agent)
+				}
+				return nil
+			}
+
 			pipfs := ma.ProtocolWithCode(ma.P_IPFS).Name
 			for _, info := range ci.Peers {
 				fmt.Fprintf(w, "%s/%s/%s", info.Addr, pipfs, info.Peer)
@@ -143,6 +193,10 @@ var swarmPeersCmd = &cmds.Command{
 				if info.Direction != inet.DirUnknown {
 					fmt.Fprintf(w, " %s", directionString(info.Direction))
 				}
+
+				if info.Agent != "" {
+					fmt.Fprintf(w, " %s", info.Agent)
+				}
 				fmt.Fprintln(w)
 
 				for _, s := range info.Streams {
@@ -171,6 +225,9 @@ type connInfo struct {
 	Muxer     string
 	Direction inet.Direction
 	Streams   []streamInfo
+	// Agent and Protocols are the ones identify recorded, with --agents.
+	Agent     string   `json:",omitempty"`
+	Protocols []string `json:",omitempty"`
 }
 
 func (ci *connInfo) Less(i, j int) bool {
@@ -187,6 +244,8 @@ func (ci *connInfo) Swap(i, j int) {
 
 type connInfos struct {
 	Peers []connInfo
+	// Agents are the number of peers per agent version, with --agents.
+	Agents []AgentGroup `json:",omitempty"`
 }
 
 func (ci connInfos) Less(i, j int) bool {
@@ -201,6 +260,27 @@ func (ci connInfos) Swap(i, j int) {
 	ci.Peers[i], ci.Peers[j] = ci.Peers[j], ci.Peers[i]
 }
 
+func swarmPeersCSVRows(req *cmds.Request, v interface{}) ([][]string, error) {
+	ci, ok := v.(*connInfos)
+	if !ok {
+		return nil, e.TypeErr(ci, v)
+	}
+
+	rows := make([][]string, 0, len(ci.Peers))
+	for _, info := range ci.Peers {
+		protos := make([]string, len(info.Streams))
+		for i, s := range info.Streams {
+			protos[i] = s.Protocol
+		}
+		direction := ""
+		if info.Direction != inet.DirUnknown {
+			direction = directionString(info.Direction)
+		}
+		rows = append(rows, []string{info.Addr, info.Peer, info.Latency, info.Muxer, direction, strings.Join(protos, " ")})
+	}
+	return rows, nil
+}
+
 // directionString transfers to string
 func directionString(d inet.Direction) string {
 	switch d {
@@ -221,8 +301,9 @@ var swarmAddrsCmd = &cmds.Command{
 `,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"local":  swarmAddrsLocalCmd,
-		"listen": swarmAddrsListenCmd,
+		"local":     swarmAddrsLocalCmd,
+		"listen":    swarmAddrsListenCmd,
+		"effective": swarmAddrsEffectiveCmd,
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		api, err := cmdenv.GetApi(env, req)
@@ -378,7 +459,7 @@ ipfs swarm connect /ip4/104.131.131.82/tcp/4001/p2p/QmaCpDMGvV2BGHeYERUEnRQAwe3N
 
 			err := api.Swarm().Connect(req.Context, pi)
 			if err != nil {
-				return fmt.Errorf("%s failure: %s", output[i], err)
+				return fmt.Errorf("%s failure (%s): %s", output[i], formatDialErrorSummary(categorizeDialError(err)), err)
 			}
 			output[i] += " success"
 		}
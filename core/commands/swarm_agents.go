@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"sort"
+
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	pstore "github.com/libp2p/go-libp2p-core/peerstore"
+)
+
+// unknownAgent stands for the agent of the peers identify didn't tell.
+const unknownAgent = "unknown"
+
+// AgentGroup is the number of connected peers running an agent version.
+type AgentGroup struct {
+	Agent string
+	Peers int
+}
+
+// peerAgent returns the agent version and protocols identify recorded for p
+// in ps.
+func peerAgent(ps pstore.Peerstore, p peer.ID) (string, []string) {
+	agent := unknownAgent
+	if v, err := ps.Get(p, "AgentVersion"); err == nil {
+		if s, ok := v.(string); ok && s != "" {
+			agent = s
+		}
+	}
+	protos, err := ps.GetProtocols(p)
+	if err != nil {
+		protos = nil
+	}
+	sort.Strings(protos)
+	return agent, protos
+}
+
+// groupAgents counts the peers of conns by agent version, the most common
+// first. A peer connected to more than once is counted once.
+func groupAgents(conns []connInfo) []AgentGroup {
+	counts := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, ci := range conns {
+		if seen[ci.Peer] {
+			continue
+		}
+		seen[ci.Peer] = true
+		counts[ci.Agent]++
+	}
+	groups := make([]AgentGroup, 0, len(counts))
+	for agent, n := range counts {
+		groups = append(groups, AgentGroup{Agent: agent, Peers: n})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Peers != groups[j].Peers {
+			return groups[i].Peers > groups[j].Peers
+		}
+		return groups[i].Agent < groups[j].Agent
+	})
+	return groups
+}
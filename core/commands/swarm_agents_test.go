@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/test"
+	"github.com/libp2p/go-libp2p-peerstore/pstoremem"
+)
+
+func TestGroupAgents(t *testing.T) {
+	ps := pstoremem.NewPeerstore()
+	agents := []string{"go-ipfs/0.5.0/", "go-ipfs/0.4.23/", "go-ipfs/0.5.0/", "js-ipfs/0.41.0", "go-ipfs/0.5.0/", "go-ipfs/0.4.23/", ""}
+
+	var peers []connInfo
+	for i, agent := range agents {
+		p, err := test.RandPeerID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the last peer didn't identify itself
+		if i < len(agents)-1 {
+			if err := ps.Put(p, "AgentVersion", agent); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := ps.AddProtocols(p, "/ipfs/kad/1.0.0", "/ipfs/bitswap/1.2.0"); err != nil {
+			t.Fatal(err)
+		}
+		ci := connInfo{Peer: p.Pretty()}
+		ci.Agent, ci.Protocols = peerAgent(ps, p)
+		peers = append(peers, ci)
+	}
+
+	// a peer connected to twice is counted once
+	peers = append(peers, peers[0])
+
+	if peers[0].Agent != "go-ipfs/0.5.0/" {
+		t.Fatalf("expected the recorded agent, got %q", peers[0].Agent)
+	}
+	if expected := []string{"/ipfs/bitswap/1.2.0", "/ipfs/kad/1.0.0"}; !reflect.DeepEqual(peers[0].Protocols, expected) {
+		t.Fatalf("expected protocols %v, got %v", expected, peers[0].Protocols)
+	}
+
+	expected := []AgentGroup{
+		{Agent: "go-ipfs/0.5.0/", Peers: 3},
+		{Agent: "go-ipfs/0.4.23/", Peers: 2},
+		{Agent: "js-ipfs/0.41.0", Peers: 1},
+		{Agent: unknownAgent, Peers: 1},
+	}
+	if groups := groupAgents(peers); !reflect.DeepEqual(groups, expected) {
+		t.Fatalf("expected groups %v, got %v", expected, groups)
+	}
+
+	// peers not in the peerstore are unknown
+	agent, protos := peerAgent(ps, peer.ID("unknown"))
+	if agent != unknownAgent || len(protos) != 0 {
+		t.Fatalf("expected an unknown agent, got %q %v", agent, protos)
+	}
+}
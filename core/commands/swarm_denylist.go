@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	"github.com/ipfs/go-ipfs/core/node/libp2p"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+)
+
+// DenylistReloadOutput is the output of 'ipfs swarm denylist reload'.
+type DenylistReloadOutput struct {
+	Peers        int
+	Ranges       int
+	Disconnected []string
+}
+
+var swarmDenylistCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Manage the connection denylist.",
+		ShortDescription: `
+The denylist is read from the 'denylist' file in the repo when the node
+starts. Each line holds a peer ID, an IP address or an IP range, either in
+CIDR (10.0.0.0/8) or multiaddr filter (/ip4/10.0.0.0/ipcidr/8) notation.
+Blank lines and lines starting with '#' are ignored.
+
+Connections from and to denylisted peers and addresses are refused.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"reload": swarmDenylistReloadCmd,
+	},
+}
+
+var swarmDenylistReloadCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Reload the connection denylist.",
+		ShortDescription: `
+'ipfs swarm denylist reload' re-reads the denylist file from the repo and
+closes the connections to peers that are now denied.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		if n.PeerHost == nil || n.ConnGater == nil {
+			return ErrNotOnline
+		}
+
+		data, err := n.Repo.Denylist()
+		if err != nil {
+			return err
+		}
+		dl, err := libp2p.ParseDenylist(data)
+		if err != nil {
+			return err
+		}
+		n.ConnGater.SetDenylist(dl)
+
+		out := &DenylistReloadOutput{
+			Peers:  len(dl.Peers),
+			Ranges: len(dl.Ranges),
+		}
+
+		net := n.PeerHost.Network()
+		disconnected := make(map[peer.ID]struct{})
+		for _, c := range net.Conns() {
+			p := c.RemotePeer()
+			if _, done := disconnected[p]; done {
+				continue
+			}
+			if n.ConnGater.PeerDenied(p) || n.ConnGater.AddrDenied(c.RemoteMultiaddr()) {
+				if err := net.ClosePeer(p); err != nil {
+					return err
+				}
+				disconnected[p] = struct{}{}
+				out.Disconnected = append(out.Disconnected, p.Pretty())
+			}
+		}
+
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *DenylistReloadOutput) error {
+			fmt.Fprintf(w, "denying %d peers and %d address ranges\n", out.Peers, out.Ranges)
+			for _, p := range out.Disconnected {
+				fmt.Fprintf(w, "disconnected %s\n", p)
+			}
+			return nil
+		}),
+	},
+	Type: DenylistReloadOutput{},
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+
+	swarm "github.com/libp2p/go-libp2p-swarm"
+)
+
+// Categories used to summarize dial failures.
+const (
+	dialErrTimeout          = "timeout"
+	dialErrRefused          = "refused"
+	dialErrNoGoodAddresses  = "no good addresses"
+	dialErrProtocolMismatch = "protocol mismatch"
+	dialErrResourceLimit    = "resource limit"
+	dialErrOther            = "other"
+)
+
+// categorizeDialError returns the number of failures per category found in
+// err. For swarm dial errors, every per-address failure is counted.
+func categorizeDialError(err error) map[string]int {
+	counts := make(map[string]int)
+
+	var derr *swarm.DialError
+	if errors.As(err, &derr) && len(derr.DialErrors) > 0 {
+		for _, te := range derr.DialErrors {
+			counts[dialErrCategory(te.Cause)]++
+		}
+		if derr.Skipped > 0 {
+			counts[dialErrOther] += derr.Skipped
+		}
+		return counts
+	}
+
+	counts[dialErrCategory(err)]++
+	return counts
+}
+
+func dialErrCategory(err error) string {
+	if err == nil {
+		return dialErrOther
+	}
+
+	switch {
+	case errors.Is(err, swarm.ErrNoAddresses), errors.Is(err, swarm.ErrNoGoodAddresses):
+		return dialErrNoGoodAddresses
+	case errors.Is(err, swarm.ErrDialTimeout), errors.Is(err, context.DeadlineExceeded), os.IsTimeout(err):
+		return dialErrTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return dialErrRefused
+	case errors.Is(err, syscall.EMFILE), errors.Is(err, syscall.ENFILE), errors.Is(err, syscall.ENOBUFS):
+		return dialErrResourceLimit
+	}
+
+	// Transport errors aren't always typed, fall back to their message.
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "no good addresses"), strings.Contains(msg, "no addresses"):
+		return dialErrNoGoodAddresses
+	case strings.Contains(msg, "timeout"), strings.Contains(msg, "timed out"):
+		return dialErrTimeout
+	case strings.Contains(msg, "connection refused"):
+		return dialErrRefused
+	case strings.Contains(msg, "protocol not supported"), strings.Contains(msg, "failed to negotiate"),
+		strings.Contains(msg, "no transport for protocol"):
+		return dialErrProtocolMismatch
+	case strings.Contains(msg, "too many open files"), strings.Contains(msg, "resource limit"):
+		return dialErrResourceLimit
+	}
+	return dialErrOther
+}
+
+// formatDialErrorSummary renders category counts, most frequent first, e.g.
+// "3 refused, 1 timeout".
+func formatDialErrorSummary(counts map[string]int) string {
+	cats := make([]string, 0, len(counts))
+	for c := range counts {
+		cats = append(cats, c)
+	}
+	sort.Slice(cats, func(i, j int) bool {
+		if counts[cats[i]] != counts[cats[j]] {
+			return counts[cats[i]] > counts[cats[j]]
+		}
+		return cats[i] < cats[j]
+	})
+
+	parts := make([]string, len(cats))
+	for i, c := range cats {
+		parts[i] = fmt.Sprintf("%d %s", counts[c], c)
+	}
+	return strings.Join(parts, ", ")
+}
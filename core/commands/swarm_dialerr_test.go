@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	swarm "github.com/libp2p/go-libp2p-swarm"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestCategorizeDialError(t *testing.T) {
+	addr := ma.StringCast("/ip4/127.0.0.1/tcp/4001")
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+	derr := &swarm.DialError{
+		DialErrors: []swarm.TransportError{
+			{Address: addr, Cause: refused},
+			{Address: addr, Cause: refused},
+			{Address: addr, Cause: context.DeadlineExceeded},
+			{Address: addr, Cause: errors.New("failed to negotiate security protocol: protocol not supported")},
+			{Address: addr, Cause: os.NewSyscallError("socket", syscall.EMFILE)},
+			{Address: addr, Cause: errors.New("something else")},
+		},
+	}
+
+	for _, tc := range []struct {
+		err      error
+		expected map[string]int
+		summary  string
+	}{
+		{
+			err: derr,
+			expected: map[string]int{
+				dialErrRefused:          2,
+				dialErrTimeout:          1,
+				dialErrProtocolMismatch: 1,
+				dialErrResourceLimit:    1,
+				dialErrOther:            1,
+			},
+			summary: "2 refused, 1 other, 1 protocol mismatch, 1 resource limit, 1 timeout",
+		},
+		{
+			err:      &swarm.DialError{Cause: swarm.ErrNoGoodAddresses},
+			expected: map[string]int{dialErrNoGoodAddresses: 1},
+			summary:  "1 no good addresses",
+		},
+		{
+			err:      swarm.ErrDialTimeout,
+			expected: map[string]int{dialErrTimeout: 1},
+			summary:  "1 timeout",
+		},
+	} {
+		counts := categorizeDialError(tc.err)
+		if len(counts) != len(tc.expected) {
+			t.Errorf("expected %v, got %v", tc.expected, counts)
+			continue
+		}
+		for cat, n := range tc.expected {
+			if counts[cat] != n {
+				t.Errorf("expected %d %q failures, got %d", n, cat, counts[cat])
+			}
+		}
+		if s := formatDialErrorSummary(counts); s != tc.summary {
+			t.Errorf("expected summary %q, got %q", tc.summary, s)
+		}
+	}
+}
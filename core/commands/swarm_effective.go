@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Sources of an advertised address, as reported by 'ipfs swarm addrs effective'.
+const (
+	addrSourceListen   = "listen"
+	addrSourceAnnounce = "announce"
+	addrSourceRelay    = "relay"
+	addrSourceObserved = "observed"
+)
+
+// EffectiveAddr is an address the node advertises, and where it comes from.
+type EffectiveAddr struct {
+	Addr   string
+	Source string
+}
+
+// EffectiveAddrsOutput is the output of 'ipfs swarm addrs effective'.
+type EffectiveAddrsOutput struct {
+	Configured []string
+	Advertised []EffectiveAddr
+}
+
+var swarmAddrsEffectiveCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the addresses the node advertises, and where they come from.",
+		ShortDescription: `
+'ipfs swarm addrs effective' lists the swarm addresses from the config next
+to the addresses the node currently advertises to other peers. These often
+differ: wildcard listen addresses are expanded to the interface addresses,
+Addresses.NoAnnounce filters some out, and addresses seen by other peers or
+mapped on the NAT, as well as relay addresses, are added.
+
+Each advertised address is reported with its source:
+
+  listen     an interface address the node listens on
+  announce   listed in Addresses.Announce
+  relay      reachable through a relay
+  observed   seen by other peers or mapped on the NAT
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsOnline {
+			return ErrNotOnline
+		}
+
+		cfg, err := nd.Repo.Config()
+		if err != nil {
+			return err
+		}
+		announce, err := parseMultiaddrs(cfg.Addresses.Announce)
+		if err != nil {
+			return err
+		}
+		listen, err := nd.PeerHost.Network().InterfaceListenAddresses()
+		if err != nil {
+			return err
+		}
+
+		return cmds.EmitOnce(res, &EffectiveAddrsOutput{
+			Configured: cfg.Addresses.Swarm,
+			Advertised: classifyAddrs(nd.PeerHost.Addrs(), listen, announce),
+		})
+	},
+	Type: EffectiveAddrsOutput{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *EffectiveAddrsOutput) error {
+			fmt.Fprintln(w, "Configured:")
+			for _, a := range out.Configured {
+				fmt.Fprintf(w, "\t%s\n", a)
+			}
+			fmt.Fprintln(w, "Advertised:")
+			for _, a := range out.Advertised {
+				fmt.Fprintf(w, "\t%s (%s)\n", a.Addr, a.Source)
+			}
+			return nil
+		}),
+	},
+}
+
+// classifyAddrs tells where each advertised address comes from. Addresses
+// that are neither listened on, announced nor relayed can only have been
+// observed by peers or mapped on the NAT.
+func classifyAddrs(advertised, listen, announce []ma.Multiaddr) []EffectiveAddr {
+	contains := func(addrs []ma.Multiaddr, a ma.Multiaddr) bool {
+		for _, b := range addrs {
+			if a.Equal(b) {
+				return true
+			}
+		}
+		return false
+	}
+
+	out := make([]EffectiveAddr, 0, len(advertised))
+	for _, a := range advertised {
+		source := addrSourceObserved
+		switch {
+		case contains(announce, a):
+			source = addrSourceAnnounce
+		case isRelayAddr(a):
+			source = addrSourceRelay
+		case contains(listen, a):
+			source = addrSourceListen
+		}
+		out = append(out, EffectiveAddr{Addr: a.String(), Source: source})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Source != out[j].Source {
+			return out[i].Source < out[j].Source
+		}
+		return out[i].Addr < out[j].Addr
+	})
+	return out
+}
+
+func isRelayAddr(a ma.Multiaddr) bool {
+	_, err := a.ValueForProtocol(ma.P_CIRCUIT)
+	return err == nil
+}
+
+func parseMultiaddrs(addrs []string) ([]ma.Multiaddr, error) {
+	out := make([]ma.Multiaddr, 0, len(addrs))
+	for _, s := range addrs {
+		a, err := ma.NewMultiaddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multiaddr %q: %s", s, err)
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestClassifyAddrs(t *testing.T) {
+	addrs := func(ss ...string) []ma.Multiaddr {
+		out := make([]ma.Multiaddr, len(ss))
+		for i, s := range ss {
+			out[i] = ma.StringCast(s)
+		}
+		return out
+	}
+
+	listen := addrs(
+		"/ip4/127.0.0.1/tcp/4001",
+		"/ip4/192.168.1.10/tcp/4001",
+	)
+	announce := addrs("/dns4/node.example.com/tcp/4001")
+	advertised := addrs(
+		"/ip4/192.168.1.10/tcp/4001",
+		"/ip4/203.0.113.7/tcp/4001",  // observed by peers
+		"/ip4/203.0.113.7/tcp/53124", // mapped on the NAT
+		"/dns4/node.example.com/tcp/4001",
+		"/ip4/198.51.100.1/tcp/4001/p2p/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ/p2p-circuit",
+	)
+
+	expected := []EffectiveAddr{
+		{"/dns4/node.example.com/tcp/4001", addrSourceAnnounce},
+		{"/ip4/192.168.1.10/tcp/4001", addrSourceListen},
+		{"/ip4/203.0.113.7/tcp/4001", addrSourceObserved},
+		{"/ip4/203.0.113.7/tcp/53124", addrSourceObserved},
+		{"/ip4/198.51.100.1/tcp/4001/p2p/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ/p2p-circuit", addrSourceRelay},
+	}
+
+	out := classifyAddrs(advertised, listen, announce)
+	if len(out) != len(expected) {
+		t.Fatalf("expected %d addresses, got %v", len(expected), out)
+	}
+	for i := range expected {
+		if out[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], out[i])
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	"github.com/ipfs/go-ipfs/core/node/libp2p"
+
+	humanize "github.com/dustin/go-humanize"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+)
+
+const (
+	swarmLimitUpOptionName   = "up"
+	swarmLimitDownOptionName = "down"
+)
+
+// SwarmLimit is the bandwidth limit of a peer, in bytes per second, zero for
+// no limit.
+type SwarmLimit struct {
+	Peer string
+	Up   int64 `json:",omitempty"`
+	Down int64 `json:",omitempty"`
+}
+
+// SwarmLimits are the peers with a bandwidth limit.
+type SwarmLimits struct {
+	Limits []SwarmLimit
+}
+
+var swarmLimitCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Limit the bandwidth used with some peers.",
+		ShortDescription: `
+'ipfs swarm limit' caps the bandwidth used with a peer, over all the
+connections with it, including the ones already open. The limits last until
+the daemon stops.
+
+Only the connections multiplexed by a stream muxer, like TCP and websocket
+ones, are limited: QUIC connections multiplex their streams themselves, and
+the bandwidth used over them isn't.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"set": swarmLimitSetCmd,
+		"rm":  swarmLimitRmCmd,
+		"ls":  swarmLimitLsCmd,
+	},
+}
+
+var swarmLimitSetCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Limit the bandwidth used with a peer.",
+		ShortDescription: `
+'ipfs swarm limit set' caps the rate of the data sent to (--up) and
+received from (--down) a peer, replacing its previous limit:
+
+  > ipfs swarm limit set QmSoLer265NRgSp2LA3dPaeykiS1J6DifTC88f5uVQKNAd --up 100KB/s --down 1MB/s
+
+Rates are given in bytes per second, like '512KB/s' or '2MiB'. A direction
+without rate isn't limited.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("peer", true, false, "ID of the peer to limit."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(swarmLimitUpOptionName, "Maximum rate of the data sent to the peer, e.g. 100KB/s."),
+		cmds.StringOption(swarmLimitDownOptionName, "Maximum rate of the data received from the peer, e.g. 1MB/s."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if n.RateLimiter == nil {
+			return ErrNotOnline
+		}
+
+		p, err := peer.Decode(req.Arguments[0])
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid peer ID %q: %s", req.Arguments[0], err)
+		}
+		var limit libp2p.PeerLimit
+		for name, rate := range map[string]*int64{
+			swarmLimitUpOptionName:   &limit.Up,
+			swarmLimitDownOptionName: &limit.Down,
+		} {
+			s, _ := req.Options[name].(string)
+			if s == "" {
+				continue
+			}
+			if *rate, err = parseRate(s); err != nil {
+				return cmds.Errorf(cmds.ErrClient, "invalid --%s: %s", name, err)
+			}
+		}
+		if limit.Up == 0 && limit.Down == 0 {
+			return cmds.Errorf(cmds.ErrClient, "--%s or --%s is required", swarmLimitUpOptionName, swarmLimitDownOptionName)
+		}
+
+		n.RateLimiter.SetLimit(p, limit)
+		return cmds.EmitOnce(res, &SwarmLimit{Peer: p.Pretty(), Up: limit.Up, Down: limit.Down})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *SwarmLimit) error {
+			return writeSwarmLimit(w, out)
+		}),
+	},
+	Type: SwarmLimit{},
+}
+
+var swarmLimitRmCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Remove the bandwidth limit of a peer.",
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("peer", true, false, "ID of the peer to stop limiting."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if n.RateLimiter == nil {
+			return ErrNotOnline
+		}
+
+		p, err := peer.Decode(req.Arguments[0])
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid peer ID %q: %s", req.Arguments[0], err)
+		}
+		if _, limited := n.RateLimiter.Limits()[p]; !limited {
+			return fmt.Errorf("%s is not limited", p)
+		}
+		n.RateLimiter.SetLimit(p, libp2p.PeerLimit{})
+		return nil
+	},
+}
+
+var swarmLimitLsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the bandwidth limits of the peers.",
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if n.RateLimiter == nil {
+			return ErrNotOnline
+		}
+
+		out := &SwarmLimits{Limits: []SwarmLimit{}}
+		for p, limit := range n.RateLimiter.Limits() {
+			out.Limits = append(out.Limits, SwarmLimit{Peer: p.Pretty(), Up: limit.Up, Down: limit.Down})
+		}
+		sort.Slice(out.Limits, func(i, j int) bool {
+			return out.Limits[i].Peer < out.Limits[j].Peer
+		})
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *SwarmLimits) error {
+			for i := range out.Limits {
+				if err := writeSwarmLimit(w, &out.Limits[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+	Type: SwarmLimits{},
+}
+
+func writeSwarmLimit(w io.Writer, l *SwarmLimit) error {
+	rate := func(r int64) string {
+		if r == 0 {
+			return "unlimited"
+		}
+		return humanize.Bytes(uint64(r)) + "/s"
+	}
+	_, err := fmt.Fprintf(w, "%s up: %s, down: %s\n", l.Peer, rate(l.Up), rate(l.Down))
+	return err
+}
+
+// parseRate parses a positive rate in bytes per second, like "100KB/s".
+func parseRate(s string) (int64, error) {
+	b, err := humanize.ParseBytes(strings.TrimSuffix(s, "/s"))
+	if err != nil {
+		return 0, err
+	}
+	if b == 0 || b > 1<<62 {
+		return 0, fmt.Errorf("%q is out of range", s)
+	}
+	return int64(b), nil
+}
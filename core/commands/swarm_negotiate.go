@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	host "github.com/libp2p/go-libp2p-core/host"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	protocol "github.com/libp2p/go-libp2p-core/protocol"
+	msmux "github.com/multiformats/go-multistream"
+)
+
+// SwarmNegotiateOutput is the outcome of 'ipfs swarm negotiate'.
+type SwarmNegotiateOutput struct {
+	Peer     string
+	Protocol string
+	// Advertised is set when the peer listed the protocol when identified.
+	Advertised bool
+	Success    bool
+	Error      string `json:",omitempty"`
+	Duration   time.Duration
+}
+
+var swarmNegotiateCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Try to open a stream for a protocol with a peer.",
+		ShortDescription: `
+'ipfs swarm negotiate' opens a stream to the given peer, connecting to it if
+needed, and negotiates the given protocol on it. It reports whether the peer
+accepted the protocol, the error otherwise, and whether the peer advertised
+the protocol when it was identified.
+
+This helps diagnose a protocol failing with a peer that is otherwise
+reachable:
+
+  > ipfs swarm negotiate QmSoLnSGccFuZQJzRadHn95W2CrSFmZuTdDWP8HXaHca9z /ipfs/bitswap/1.2.0
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("peer", true, false, "ID of the peer to negotiate with."),
+		cmds.StringArg("protocol", true, false, "Protocol to negotiate."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsOnline {
+			return ErrNotOnline
+		}
+
+		p, err := peer.Decode(req.Arguments[0])
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid peer ID: %s", err)
+		}
+
+		return cmds.EmitOnce(res, negotiateProtocol(req.Context, nd.PeerHost, p, protocol.ID(req.Arguments[1])))
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *SwarmNegotiateOutput) error {
+			if out.Success {
+				fmt.Fprintf(w, "%s: negotiated %s in %s\n", out.Peer, out.Protocol, out.Duration)
+			} else {
+				fmt.Fprintf(w, "%s: failed to negotiate %s: %s\n", out.Peer, out.Protocol, out.Error)
+			}
+			if out.Advertised {
+				fmt.Fprintln(w, "the peer advertises the protocol")
+			} else {
+				fmt.Fprintln(w, "the peer does not advertise the protocol")
+			}
+			return nil
+		}),
+	},
+	Type: SwarmNegotiateOutput{},
+}
+
+// negotiateProtocol opens a stream to p with h and negotiates proto on it.
+// Unlike h.NewStream, which skips negotiation until the stream is used when
+// the peer advertised the protocol, the protocol is always negotiated right
+// away so its outcome can be reported.
+func negotiateProtocol(ctx context.Context, h host.Host, p peer.ID, proto protocol.ID) *SwarmNegotiateOutput {
+	out := &SwarmNegotiateOutput{
+		Peer:     p.Pretty(),
+		Protocol: string(proto),
+	}
+	if supported, err := h.Peerstore().SupportsProtocols(p, string(proto)); err == nil {
+		out.Advertised = len(supported) > 0
+	}
+
+	start := time.Now()
+	defer func() { out.Duration = time.Since(start) }()
+
+	s, err := h.Network().NewStream(ctx, p)
+	if err != nil {
+		out.Error = fmt.Sprintf("opening a stream: %s", err)
+		return out
+	}
+
+	if err := msmux.SelectProtoOrFail(string(proto), s); err != nil {
+		s.Reset()
+		out.Error = err.Error()
+		return out
+	}
+	s.SetProtocol(proto)
+	s.Close()
+
+	out.Success = true
+	return out
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/network"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+func TestNegotiateProtocol(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn := mocknet.New(ctx)
+	self, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+	other.SetStreamHandler("/test/1.0.0", func(s network.Stream) {
+		defer s.Close()
+		io.Copy(ioutil.Discard, s)
+	})
+
+	out := negotiateProtocol(ctx, self, other.ID(), "/test/1.0.0")
+	if !out.Success || out.Error != "" {
+		t.Fatalf("expected /test/1.0.0 to be negotiated, got %+v", out)
+	}
+	if out.Protocol != "/test/1.0.0" || out.Peer != other.ID().Pretty() {
+		t.Fatalf("unexpected output %+v", out)
+	}
+
+	out = negotiateProtocol(ctx, self, other.ID(), "/missing/1.0.0")
+	if out.Success || out.Error == "" {
+		t.Fatalf("expected /missing/1.0.0 to fail, got %+v", out)
+	}
+	if out.Advertised {
+		t.Fatal("/missing/1.0.0 is not advertised")
+	}
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+)
+
+const swarmPartitionDurationOptionName = "duration"
+
+// SwarmPartitionOutput is the output of 'ipfs swarm simulate-partition'.
+type SwarmPartitionOutput struct {
+	Peer  string
+	Until time.Time
+	// Disconnected is set when connections with the peer were closed.
+	Disconnected bool
+}
+
+var swarmSimulatePartitionCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Cut this node off from a peer for some time, for testing.",
+		ShortDescription: `
+'ipfs swarm simulate-partition' simulates a network partition with a peer:
+the connections with the peer are closed, and new ones are refused, both ways,
+until the partition ends:
+
+  > ipfs swarm simulate-partition QmSoLer265NRgSp2LA3dPaeykiS1J6DifTC88f5uVQKNAd --duration 5m
+  partitioned from QmSoLer265NRgSp2LA3dPaeykiS1J6DifTC88f5uVQKNAd until 2020-03-02T10:09:51Z
+
+Once it ends, the peer can be connected to again, for example to test how
+content is provided and fetched again. Partitioning from the peer again
+replaces the end of the partition.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("peer", true, false, "ID of the peer to cut off."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(swarmPartitionDurationOptionName, "How long the partition lasts, e.g. 30s.").WithDefault("1m"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if n.PeerHost == nil || n.ConnGater == nil {
+			return ErrNotOnline
+		}
+
+		p, err := peer.Decode(req.Arguments[0])
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid peer ID %q: %s", req.Arguments[0], err)
+		}
+		if p == n.Identity {
+			return cmds.Errorf(cmds.ErrClient, "cannot partition from self")
+		}
+		s, _ := req.Options[swarmPartitionDurationOptionName].(string)
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return cmds.Errorf(cmds.ErrClient, "invalid --%s %q", swarmPartitionDurationOptionName, s)
+		}
+
+		out := &SwarmPartitionOutput{
+			Peer:  p.Pretty(),
+			Until: n.ConnGater.Partition(p, d).UTC(),
+		}
+		net := n.PeerHost.Network()
+		if len(net.ConnsToPeer(p)) > 0 {
+			if err := net.ClosePeer(p); err != nil {
+				return err
+			}
+			out.Disconnected = true
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *SwarmPartitionOutput) error {
+			_, err := fmt.Fprintf(w, "partitioned from %s until %s\n", out.Peer, out.Until.Format(time.RFC3339))
+			return err
+		}),
+	},
+	Type: SwarmPartitionOutput{},
+}
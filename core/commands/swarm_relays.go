@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	host "github.com/libp2p/go-libp2p-core/host"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// RelayReservation is a relay the node can be reached through.
+type RelayReservation struct {
+	Relay string
+	Addrs []string
+	InUse bool
+	Conns int
+}
+
+// SwarmRelaysOutput is the output of 'ipfs swarm relays'.
+type SwarmRelaysOutput struct {
+	Relays []RelayReservation
+}
+
+var swarmRelaysCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the relays the node holds reservations with.",
+		ShortDescription: `
+'ipfs swarm relays' lists the relays the node can currently be reached
+through, as picked by the relay client (see Swarm.EnableAutoRelay). For each
+relay, it shows the relay addresses the node advertises, and whether peers
+are connected to the node through it. Relays of the circuit v1 protocol keep
+relaying for as long as the node stays connected to them: reservations have
+no expiry.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsOnline {
+			return ErrNotOnline
+		}
+
+		return cmds.EmitOnce(res, &SwarmRelaysOutput{Relays: relayReservations(nd.PeerHost)})
+	},
+	Type: SwarmRelaysOutput{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *SwarmRelaysOutput) error {
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			for _, r := range out.Relays {
+				state := "idle"
+				if r.InUse {
+					state = fmt.Sprintf("in use (%d conns)", r.Conns)
+				}
+				fmt.Fprintf(tw, "%s\t%s\n", r.Relay, state)
+			}
+			return tw.Flush()
+		}),
+	},
+}
+
+// relayReservations lists the relays h advertises circuit addresses for,
+// with the number of connections relayed through each.
+func relayReservations(h host.Host) []RelayReservation {
+	byRelay := make(map[peer.ID]*RelayReservation)
+	for _, a := range h.Addrs() {
+		relay, ok := circuitRelay(a)
+		if !ok {
+			continue
+		}
+		r, ok := byRelay[relay]
+		if !ok {
+			r = &RelayReservation{Relay: relay.Pretty()}
+			byRelay[relay] = r
+		}
+		r.Addrs = append(r.Addrs, a.String())
+	}
+
+	for _, c := range h.Network().Conns() {
+		relay, ok := circuitRelay(c.RemoteMultiaddr())
+		if !ok {
+			continue
+		}
+		if r, ok := byRelay[relay]; ok {
+			r.Conns++
+			r.InUse = true
+		}
+	}
+
+	out := make([]RelayReservation, 0, len(byRelay))
+	for _, r := range byRelay {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Relay < out[j].Relay
+	})
+	return out
+}
+
+// circuitRelay returns the relay of a circuit address, the last peer before
+// /p2p-circuit.
+func circuitRelay(a ma.Multiaddr) (peer.ID, bool) {
+	var (
+		relay   peer.ID
+		circuit bool
+	)
+	ma.ForEach(a, func(c ma.Component) bool {
+		switch c.Protocol().Code {
+		case ma.P_P2P:
+			relay, _ = peer.IDFromBytes(c.RawValue())
+		case ma.P_CIRCUIT:
+			circuit = true
+			return false
+		}
+		return true
+	})
+	return relay, circuit && relay != ""
+}
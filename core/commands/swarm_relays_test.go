@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/test"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// reservationHost stubs the relay client state of a host.
+type reservationHost struct {
+	host.Host
+	addrs []ma.Multiaddr
+	conns []network.Conn
+}
+
+func (h *reservationHost) Addrs() []ma.Multiaddr {
+	return h.addrs
+}
+
+func (h *reservationHost) Network() network.Network {
+	return &connsNetwork{h.Host.Network(), h.conns}
+}
+
+type connsNetwork struct {
+	network.Network
+	conns []network.Conn
+}
+
+func (n *connsNetwork) Conns() []network.Conn {
+	return n.conns
+}
+
+type relayedConn struct {
+	network.Conn
+	remote ma.Multiaddr
+}
+
+func (c *relayedConn) RemoteMultiaddr() ma.Multiaddr {
+	return c.remote
+}
+
+func TestRelayReservations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn := mocknet.New(ctx)
+	self, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reserved, err := test.RandPeerID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1, err := test.RandPeerID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	circuit := func(relay peer.ID) ma.Multiaddr {
+		return ma.StringCast("/ip4/198.51.100.1/tcp/4001/p2p/" + relay.Pretty() + "/p2p-circuit")
+	}
+	h := &reservationHost{
+		Host: self,
+		addrs: []ma.Multiaddr{
+			ma.StringCast("/ip4/192.168.1.10/tcp/4001"),
+			circuit(reserved),
+			circuit(v1),
+		},
+		conns: []network.Conn{
+			&relayedConn{remote: ma.StringCast("/ip4/203.0.113.7/tcp/4001")},
+			&relayedConn{remote: circuit(reserved)},
+		},
+	}
+
+	out := relayReservations(h)
+	if len(out) != 2 {
+		t.Fatalf("expected 2 relays, got %v", out)
+	}
+	byRelay := make(map[string]RelayReservation)
+	for _, r := range out {
+		byRelay[r.Relay] = r
+	}
+
+	r, ok := byRelay[reserved.Pretty()]
+	if !ok {
+		t.Fatalf("reserved relay %s not listed", reserved)
+	}
+	if !r.InUse || r.Conns != 1 {
+		t.Errorf("expected the reserved relay to be in use by 1 conn, got %v/%d", r.InUse, r.Conns)
+	}
+	if len(r.Addrs) != 1 || r.Addrs[0] != circuit(reserved).String() {
+		t.Errorf("unexpected addrs %v", r.Addrs)
+	}
+
+	r, ok = byRelay[v1.Pretty()]
+	if !ok {
+		t.Fatalf("relay %s not listed", v1)
+	}
+	if r.InUse || r.Conns != 0 {
+		t.Errorf("expected an idle relay, got %+v", r)
+	}
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	network "github.com/libp2p/go-libp2p-core/network"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// ConnTransportParams describes one QUIC connection to a peer.
+type ConnTransportParams struct {
+	Addr      string
+	Direction string `json:",omitempty"`
+}
+
+// SwarmTransportParamsOutput is the output of 'ipfs swarm transport-params'.
+type SwarmTransportParamsOutput struct {
+	Peer  string
+	Conns []ConnTransportParams
+}
+
+var swarmTransportParamsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the QUIC connections to a peer.",
+		ShortDescription: `
+'ipfs swarm transport-params' lists the QUIC connections to the given peer,
+with their direction.
+
+No QUIC statistics are available: the QUIC transport doesn't expose the
+state of its congestion controller, like the congestion window or the
+round-trip time estimates, to the swarm.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("peer", true, false, "ID of the connected peer."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsOnline {
+			return ErrNotOnline
+		}
+
+		p, err := peer.Decode(req.Arguments[0])
+		if err != nil {
+			return cmds.Errorf(cmds.ErrClient, "invalid peer ID: %s", err)
+		}
+
+		conns := quicConns(nd.PeerHost.Network().ConnsToPeer(p))
+		if len(conns) == 0 {
+			return fmt.Errorf("not connected to %s over QUIC", p.Pretty())
+		}
+		return cmds.EmitOnce(res, &SwarmTransportParamsOutput{
+			Peer:  p.Pretty(),
+			Conns: conns,
+		})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *SwarmTransportParamsOutput) error {
+			for _, c := range out.Conns {
+				fmt.Fprintf(w, "%s %s\n", c.Addr, c.Direction)
+			}
+			return nil
+		}),
+	},
+	Type: SwarmTransportParamsOutput{},
+}
+
+// quicConns describes the QUIC connections among conns.
+func quicConns(conns []network.Conn) []ConnTransportParams {
+	var out []ConnTransportParams
+	for _, c := range conns {
+		addr := c.RemoteMultiaddr()
+		if _, err := addr.ValueForProtocol(ma.P_QUIC); err != nil {
+			continue
+		}
+
+		out = append(out, ConnTransportParams{
+			Addr:      addr.String(),
+			Direction: directionString(c.Stat().Direction),
+		})
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"testing"
+
+	network "github.com/libp2p/go-libp2p-core/network"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// stubConn is a connection to addr, only its address and stat can be
+// queried.
+type stubConn struct {
+	network.Conn
+	addr ma.Multiaddr
+	stat network.Stat
+}
+
+func (c *stubConn) RemoteMultiaddr() ma.Multiaddr {
+	return c.addr
+}
+
+func (c *stubConn) Stat() network.Stat {
+	return c.stat
+}
+
+func TestQuicConns(t *testing.T) {
+	conns := []network.Conn{
+		&stubConn{addr: ma.StringCast("/ip4/1.2.3.4/udp/4001/quic"), stat: network.Stat{Direction: network.DirOutbound}},
+		&stubConn{addr: ma.StringCast("/ip4/1.2.3.4/tcp/4001")},
+		&stubConn{addr: ma.StringCast("/ip4/1.2.3.4/udp/4002/quic"), stat: network.Stat{Direction: network.DirInbound}},
+	}
+
+	out := quicConns(conns)
+	if len(out) != 2 {
+		t.Fatalf("expected the 2 QUIC connections, got %+v", out)
+	}
+	if out[0].Addr != "/ip4/1.2.3.4/udp/4001/quic" || out[0].Direction != directionString(network.DirOutbound) {
+		t.Errorf("unexpected first connection %+v", out[0])
+	}
+	if out[1].Addr != "/ip4/1.2.3.4/udp/4002/quic" || out[1].Direction != directionString(network.DirInbound) {
+		t.Errorf("unexpected second connection %+v", out[1])
+	}
+}
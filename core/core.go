@@ -41,14 +41,19 @@ import (
 	p2pbhost "github.com/libp2p/go-libp2p/p2p/host/basic"
 	ma "github.com/multiformats/go-multiaddr"
 
+	"github.com/ipfs/go-ipfs/announcelog"
 	"github.com/ipfs/go-ipfs/core/bootstrap"
+	"github.com/ipfs/go-ipfs/core/drain"
+	"github.com/ipfs/go-ipfs/core/haveprobe"
 	"github.com/ipfs/go-ipfs/core/node"
 	"github.com/ipfs/go-ipfs/core/node/libp2p"
+	"github.com/ipfs/go-ipfs/core/reqstats"
 	"github.com/ipfs/go-ipfs/fuse/mount"
 	"github.com/ipfs/go-ipfs/namesys"
 	ipnsrp "github.com/ipfs/go-ipfs/namesys/republisher"
 	"github.com/ipfs/go-ipfs/p2p"
 	"github.com/ipfs/go-ipfs/repo"
+	"github.com/ipfs/go-ipfs/reprovidestats"
 )
 
 var log = logging.Logger("core")
@@ -78,19 +83,27 @@ type IpfsNode struct {
 	Resolver        *resolver.Resolver        // the path resolution system
 	Reporter        *metrics.BandwidthCounter `optional:"true"`
 	Discovery       discovery.Service         `optional:"true"`
+	Drainer         *drain.Drainer            `optional:"true"` // tracks in-flight API and gateway requests
+	RequestStats    *reqstats.Tracker         `optional:"true"` // counts the gateway requests by CID
+	HaveProber      *haveprobe.Prober         `optional:"true"` // asks peers whether they have blocks
 	FilesRoot       *mfs.Root
 	RecordValidator record.Validator
 
 	// Online
-	PeerHost      p2phost.Host            `optional:"true"` // the network host (server+client)
-	Filters       *ma.Filters             `optional:"true"`
-	Bootstrapper  io.Closer               `optional:"true"` // the periodic bootstrapper
-	Routing       routing.Routing         `optional:"true"` // the routing system. recommend ipfs-dht
-	Exchange      exchange.Interface      // the block exchange + strategy (bitswap)
-	Namesys       namesys.NameSystem      // the name system, resolves paths to hashes
-	Provider      provider.System         // the value provider system
-	IpnsRepub     *ipnsrp.Republisher     `optional:"true"`
-	GraphExchange graphsync.GraphExchange `optional:"true"`
+	PeerHost          p2phost.Host            `optional:"true"` // the network host (server+client)
+	Filters           *ma.Filters             `optional:"true"`
+	ConnGater         *libp2p.ConnectionGater `optional:"true"`
+	RateLimiter       *libp2p.PeerRateLimiter `optional:"true"` // per-peer bandwidth limits
+	Bootstrapper      io.Closer               `optional:"true"` // the periodic bootstrapper
+	BootstrapProgress *bootstrap.Progress     `optional:"true"` // progress of the bootstrapper
+	Routing           routing.Routing         `optional:"true"` // the routing system. recommend ipfs-dht
+	Exchange          exchange.Interface      // the block exchange + strategy (bitswap)
+	Namesys           namesys.NameSystem      // the name system, resolves paths to hashes
+	Provider          provider.System         // the value provider system
+	AnnounceLog       *announcelog.Log        `optional:"true"` // recent provider announcements
+	ReprovideStats    *reprovidestats.Tracker `optional:"true"` // progress of reprovide cycles
+	IpnsRepub         *ipnsrp.Republisher     `optional:"true"`
+	GraphExchange     graphsync.GraphExchange `optional:"true"`
 
 	PubSub   *pubsub.PubSub             `optional:"true"`
 	PSRouter *psrouter.PubsubValueStore `optional:"true"`
@@ -152,11 +165,25 @@ func (n *IpfsNode) Bootstrap(cfg bootstrap.BootstrapConfig) error {
 		}
 	}
 
+	if cfg.Progress == nil {
+		cfg.Progress = bootstrap.NewProgress(n.routingTableSize)
+	}
+	n.BootstrapProgress = cfg.Progress
+
 	var err error
 	n.Bootstrapper, err = bootstrap.Bootstrap(n.Identity, n.PeerHost, n.Routing, cfg)
 	return err
 }
 
+// routingTableSize returns the number of peers in the routing tables of the
+// DHT, or -1 without DHT.
+func (n *IpfsNode) routingTableSize() int {
+	if n.DHT == nil {
+		return -1
+	}
+	return n.DHT.WAN.RoutingTable().Size() + n.DHT.LAN.RoutingTable().Size()
+}
+
 func (n *IpfsNode) loadBootstrapPeers() ([]peer.AddrInfo, error) {
 	cfg, err := n.Repo.Config()
 	if err != nil {
@@ -38,6 +38,7 @@ import (
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	record "github.com/libp2p/go-libp2p-record"
 
+	"github.com/ipfs/go-ipfs/announcelog"
 	"github.com/ipfs/go-ipfs/core"
 	"github.com/ipfs/go-ipfs/core/node"
 	"github.com/ipfs/go-ipfs/namesys"
@@ -68,7 +69,8 @@ type CoreAPI struct {
 	namesys namesys.NameSystem
 	routing routing.Routing
 
-	provider provider.System
+	provider    provider.System
+	announceLog *announcelog.Log
 
 	pubSub *pubsub.PubSub
 
@@ -178,7 +180,8 @@ func (api *CoreAPI) WithOptions(opts ...options.ApiOption) (coreiface.CoreAPI, e
 		exchange:        n.Exchange,
 		routing:         n.Routing,
 
-		provider: n.Provider,
+		provider:    n.Provider,
+		announceLog: n.AnnounceLog,
 
 		pubSub: n.PubSub,
 
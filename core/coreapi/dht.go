@@ -85,10 +85,15 @@ func (api *DhtAPI) Provide(ctx context.Context, path path.Path, opts ...caopts.D
 		return fmt.Errorf("block %s not found locally, cannot provide", c)
 	}
 
+	var r routing.ContentRouting = api.routing
+	if api.announceLog != nil {
+		r = api.announceLog.Router(r)
+	}
+
 	if settings.Recursive {
-		err = provideKeysRec(ctx, api.routing, api.blockstore, []cid.Cid{c})
+		err = provideKeysRec(ctx, r, api.blockstore, []cid.Cid{c})
 	} else {
-		err = provideKeys(ctx, api.routing, []cid.Cid{c})
+		err = provideKeys(ctx, r, []cid.Cid{c})
 	}
 	if err != nil {
 		return err
@@ -97,7 +102,7 @@ func (api *DhtAPI) Provide(ctx context.Context, path path.Path, opts ...caopts.D
 	return nil
 }
 
-func provideKeys(ctx context.Context, r routing.Routing, cids []cid.Cid) error {
+func provideKeys(ctx context.Context, r routing.ContentRouting, cids []cid.Cid) error {
 	for _, c := range cids {
 		err := r.Provide(ctx, c, true)
 		if err != nil {
@@ -107,7 +112,7 @@ func provideKeys(ctx context.Context, r routing.Routing, cids []cid.Cid) error {
 	return nil
 }
 
-func provideKeysRec(ctx context.Context, r routing.Routing, bs blockstore.Blockstore, cids []cid.Cid) error {
+func provideKeysRec(ctx context.Context, r routing.ContentRouting, bs blockstore.Blockstore, cids []cid.Cid) error {
 	provided := cidutil.NewStreamingSet()
 
 	errCh := make(chan error)
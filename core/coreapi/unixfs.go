@@ -119,10 +119,17 @@ func (api *UnixfsAPI) Add(ctx context.Context, files files.Node, opts ...options
 		}
 	}
 
-	fileAdder, err := coreunix.NewAdder(ctx, pinning, addblockstore, syncDserv)
+	var adderDserv ipld.DAGService = syncDserv
+	timings := coreunix.PhaseTimingsFrom(ctx)
+	if timings != nil {
+		adderDserv = timings.DAGService(syncDserv)
+	}
+
+	fileAdder, err := coreunix.NewAdder(ctx, pinning, addblockstore, adderDserv)
 	if err != nil {
 		return nil, err
 	}
+	fileAdder.Timings = timings
 
 	fileAdder.Chunker = settings.Chunker
 	if settings.Events != nil {
@@ -134,6 +141,9 @@ func (api *UnixfsAPI) Add(ctx context.Context, files files.Node, opts ...options
 	fileAdder.RawLeaves = settings.RawLeaves
 	fileAdder.NoCopy = settings.NoCopy
 	fileAdder.CidBuilder = prefix
+	if coreunix.Resumable(ctx) && !settings.OnlyHash {
+		fileAdder.Checkpoints = api.repo.Datastore()
+	}
 
 	switch settings.Layout {
 	case options.BalancedLayout:
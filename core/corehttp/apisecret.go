@@ -0,0 +1,67 @@
+package corehttp
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/ipfs/go-ipfs/core"
+	"github.com/ipfs/go-ipfs/repo/common"
+)
+
+// APISecretHeader carries the secret shared by the clients and the daemon,
+// when the API requires one.
+const APISecretHeader = "X-Ipfs-Api-Secret"
+
+// APISecretConfigKey is the key of the config holding the secret the API
+// requires, if any. It is kept at the top level of the config, where keys
+// unknown to go-ipfs-config are preserved.
+const APISecretConfigKey = "APISecret"
+
+// APISecretOption returns a ServeOption rejecting with 403 Forbidden the
+// requests that don't carry the secret set in the config in their
+// APISecretHeader. All requests are let through when no secret is set.
+func APISecretOption() ServeOption {
+	return func(n *core.IpfsNode, _ net.Listener, parent *http.ServeMux) (*http.ServeMux, error) {
+		// only a missing key means no secret: failing to read the config
+		// must not leave the API open
+		val, err := n.Repo.GetConfigKey(APISecretConfigKey)
+		var notFound *common.KeyNotFoundError
+		if errors.As(err, &notFound) {
+			return parent, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading config %s: %s", APISecretConfigKey, err)
+		}
+		if val == nil {
+			return parent, nil
+		}
+		secret, ok := val.(string)
+		if !ok {
+			return nil, fmt.Errorf("config %s must be a string, got %T", APISecretConfigKey, val)
+		}
+		if secret == "" {
+			return parent, nil
+		}
+
+		mux := http.NewServeMux()
+		parent.Handle("/", &apiSecretHandler{next: mux, secret: []byte(secret)})
+		return mux, nil
+	}
+}
+
+type apiSecretHandler struct {
+	next   http.Handler
+	secret []byte
+}
+
+func (h *apiSecretHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	given := []byte(r.Header.Get(APISecretHeader))
+	if subtle.ConstantTimeCompare(given, h.secret) != 1 {
+		http.Error(w, "403 - Forbidden: missing or wrong API secret", http.StatusForbidden)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,92 @@
+package corehttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	core "github.com/ipfs/go-ipfs/core"
+	repo "github.com/ipfs/go-ipfs/repo"
+	common "github.com/ipfs/go-ipfs/repo/common"
+)
+
+// secretRepo holds an API secret in its config, or fails to read it with err.
+type secretRepo struct {
+	repo.Mock
+	secret interface{}
+	err    error
+}
+
+func (r *secretRepo) GetConfigKey(key string) (interface{}, error) {
+	if key != APISecretConfigKey {
+		return r.Mock.GetConfigKey(key)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.secret == nil {
+		return nil, &common.KeyNotFoundError{}
+	}
+	return r.secret, nil
+}
+
+func TestAPISecretOption(t *testing.T) {
+	serveRepo := func(r repo.Repo) (*http.ServeMux, error) {
+		root := http.NewServeMux()
+		n := &core.IpfsNode{Repo: r}
+		mux, err := APISecretOption()(n, nil, root)
+		if err != nil {
+			return nil, err
+		}
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+		return root, nil
+	}
+	serve := func(secret interface{}) (*http.ServeMux, error) {
+		return serveRepo(&secretRepo{secret: secret})
+	}
+	send := func(h http.Handler, secret string) int {
+		r := httptest.NewRequest(http.MethodPost, APIPath+"/id", nil)
+		if secret != "" {
+			r.Header.Set(APISecretHeader, secret)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	h, err := serve("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := send(h, "s3cret"); code != http.StatusOK {
+		t.Fatalf("expected the request with the secret to succeed, got %d", code)
+	}
+	for _, secret := range []string{"", "wrong", "s3cret-and-more"} {
+		if code := send(h, secret); code != http.StatusForbidden {
+			t.Errorf("expected 403 with secret %q, got %d", secret, code)
+		}
+	}
+
+	// without a secret set, everything goes through
+	for _, secret := range []interface{}{nil, ""} {
+		h, err := serve(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := send(h, ""); code != http.StatusOK {
+			t.Fatalf("expected the API to be open without a secret set (%#v), got %d", secret, code)
+		}
+	}
+
+	if _, err := serve(42.0); err == nil {
+		t.Fatal("expected a secret that isn't a string to be rejected")
+	}
+
+	// a config that can't be read must not leave the API open
+	if _, err := serveRepo(&secretRepo{err: errors.New("invalid config")}); err == nil {
+		t.Fatal("expected failing to read the secret to be an error")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	version "github.com/ipfs/go-ipfs"
 	oldcmds "github.com/ipfs/go-ipfs/commands"
@@ -140,11 +141,31 @@ func commandsOption(cctx oldcmds.Context, command *cmds.Command, allowGet bool)
 		patchCORSVars(cfg, l.Addr())
 
 		cmdHandler := cmdsHttp.NewHandler(&cctx, command, cfg)
-		mux.Handle(APIPath+"/", cmdHandler)
+		mux.Handle(APIPath+"/", &commandMetricsHandler{next: cmdHandler, root: command})
 		return mux, nil
 	}
 }
 
+// commandMetricsHandler records the latency of the API requests by command,
+// see CommandDurationMetric.
+type commandMetricsHandler struct {
+	next http.Handler
+	root *cmds.Command
+}
+
+func (h *commandMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pth := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, APIPath), "/"), "/")
+	// only known commands, not to label metrics with arbitrary paths
+	if _, err := h.root.Get(pth); err != nil {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	start := time.Now()
+	h.next.ServeHTTP(w, r)
+	observeCommandDuration(pth, time.Since(start))
+}
+
 // CommandsOption constructs a ServerOption for hooking the commands into the
 // HTTP server. It will NOT allow GET requests.
 func CommandsOption(cctx oldcmds.Context) ServeOption {
@@ -0,0 +1,66 @@
+package corehttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	prometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// commandSamples returns the number of latencies recorded for command.
+func commandSamples(t *testing.T, command string) uint64 {
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(CommandDurationMetric); err != nil {
+		t.Fatal(err)
+	}
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "command" && l.GetValue() == command {
+					return m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestCommandMetrics(t *testing.T) {
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"pin": {
+				Subcommands: map[string]*cmds.Command{
+					"ls": {},
+				},
+			},
+		},
+	}
+	h := &commandMetricsHandler{
+		next: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		root: root,
+	}
+	serve := func(path string) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, APIPath+path, nil))
+	}
+
+	pinLs, pin := commandSamples(t, "pin/ls"), commandSamples(t, "pin")
+	serve("/pin/ls?stream=true")
+	serve("/pin/ls/")
+	serve("/pin")
+	serve("/nope")
+	if n := commandSamples(t, "pin/ls") - pinLs; n != 2 {
+		t.Errorf("expected 2 pin/ls latencies, got %d", n)
+	}
+	if n := commandSamples(t, "pin") - pin; n != 1 {
+		t.Errorf("expected 1 pin latency, got %d", n)
+	}
+	if n := commandSamples(t, "nope"); n != 0 {
+		t.Errorf("expected unknown commands not to be recorded, got %d", n)
+	}
+}
@@ -53,6 +53,10 @@ func makeHandler(n *core.IpfsNode, l net.Listener, options ...ServeOption) (http
 		}
 		topMux.ServeHTTP(w, r)
 	})
+	if n.Drainer != nil {
+		// refuse new requests once the node starts shutting down
+		return n.Drainer.Handler(handler), nil
+	}
 	return handler, nil
 }
 
@@ -52,46 +52,14 @@ func GatewayOption(writable bool, paths ...string) ServeOption {
 			return nil, err
 		}
 
-		headers := make(map[string][]string, len(cfg.Gateway.HTTPHeaders))
-		for h, v := range cfg.Gateway.HTTPHeaders {
-			headers[http.CanonicalHeaderKey(h)] = v
-		}
-
-		// Hard-coded headers.
-		const ACAHeadersName = "Access-Control-Allow-Headers"
-		const ACEHeadersName = "Access-Control-Expose-Headers"
-		const ACAOriginName = "Access-Control-Allow-Origin"
-		const ACAMethodsName = "Access-Control-Allow-Methods"
-
-		if _, ok := headers[ACAOriginName]; !ok {
-			// Default to *all*
-			headers[ACAOriginName] = []string{"*"}
-		}
-		if _, ok := headers[ACAMethodsName]; !ok {
-			// Default to GET
-			headers[ACAMethodsName] = []string{http.MethodGet}
-		}
-
-		headers[ACAHeadersName] = cleanHeaderSet(
-			append([]string{
-				"Content-Type",
-				"User-Agent",
-				"Range",
-				"X-Requested-With",
-			}, headers[ACAHeadersName]...))
-
-		headers[ACEHeadersName] = cleanHeaderSet(
-			append([]string{
-				"Content-Range",
-				"X-Chunked-Output",
-				"X-Stream-Output",
-			}, headers[ACEHeadersName]...))
+		headers := GatewayHeaders(cfg)
 
 		gateway := newGatewayHandler(GatewayConfig{
 			Headers:      headers,
 			Writable:     writable,
 			PathPrefixes: cfg.Gateway.PathPrefixes,
 		}, api)
+		gateway.stats = n.RequestStats
 
 		for _, p := range paths {
 			mux.Handle(p+"/", gateway)
@@ -0,0 +1,145 @@
+package corehttp
+
+import (
+	"net/http"
+
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+
+	config "github.com/ipfs/go-ipfs-config"
+)
+
+// GatewayHandler describes a handler served by the gateway of the daemon.
+type GatewayHandler struct {
+	Path string
+	// Option is the ServeOption registering the handler.
+	Option      string
+	Description string
+}
+
+// KnownGateways returns the gateways recognized by their hostname, the
+// default ones updated with Gateway.PublicGateways of cfg.
+func KnownGateways(cfg *config.Config) map[string]config.GatewaySpec {
+	knownGateways := make(
+		map[string]config.GatewaySpec,
+		len(defaultKnownGateways)+len(cfg.Gateway.PublicGateways),
+	)
+	for hostname, gw := range defaultKnownGateways {
+		knownGateways[hostname] = gw
+	}
+	for hostname, gw := range cfg.Gateway.PublicGateways {
+		if gw == nil {
+			// Allows the user to remove gateways but _also_
+			// allows us to continuously update the list.
+			delete(knownGateways, hostname)
+		} else {
+			knownGateways[hostname] = *gw
+		}
+	}
+	return knownGateways
+}
+
+// GatewayHeaders returns the headers the gateway sets on its responses,
+// Gateway.HTTPHeaders of cfg completed with the CORS defaults.
+func GatewayHeaders(cfg *config.Config) map[string][]string {
+	headers := make(map[string][]string, len(cfg.Gateway.HTTPHeaders))
+	for h, v := range cfg.Gateway.HTTPHeaders {
+		headers[http.CanonicalHeaderKey(h)] = v
+	}
+
+	// Hard-coded headers.
+	const ACAHeadersName = "Access-Control-Allow-Headers"
+	const ACEHeadersName = "Access-Control-Expose-Headers"
+	const ACAOriginName = "Access-Control-Allow-Origin"
+	const ACAMethodsName = "Access-Control-Allow-Methods"
+
+	if _, ok := headers[ACAOriginName]; !ok {
+		// Default to *all*
+		headers[ACAOriginName] = []string{"*"}
+	}
+	if _, ok := headers[ACAMethodsName]; !ok {
+		// Default to GET
+		headers[ACAMethodsName] = []string{http.MethodGet}
+	}
+
+	headers[ACAHeadersName] = cleanHeaderSet(
+		append([]string{
+			"Content-Type",
+			"User-Agent",
+			"Range",
+			"X-Requested-With",
+		}, headers[ACAHeadersName]...))
+
+	headers[ACEHeadersName] = cleanHeaderSet(
+		append([]string{
+			"Content-Range",
+			"X-Chunked-Output",
+			"X-Stream-Output",
+		}, headers[ACEHeadersName]...))
+	return headers
+}
+
+// gatewayServeOption is a ServeOption the daemon serves its gateway with,
+// and the handlers it registers.
+type gatewayServeOption struct {
+	option   ServeOption
+	handlers []GatewayHandler
+}
+
+// gatewayServeOptions lists the options the daemon serves its gateway with,
+// both to serve it and to report its handlers, with cfg, the gateway being
+// writable or not, and the read-only API commands run with cctx.
+func gatewayServeOptions(cfg *config.Config, writable bool, cctx oldcmds.Context) []gatewayServeOption {
+	gateway := "read-only IPFS and IPNS paths"
+	if writable {
+		gateway = "writable IPFS and IPNS paths"
+	}
+	opts := []gatewayServeOption{
+		{MetricsCollectionOption("gateway"), nil},
+		{HostnameOption(), []GatewayHandler{
+			{"/", "HostnameOption", "subdomain and DNSLink requests, by their Host header"},
+		}},
+		{GatewayOption(writable, "/ipfs", "/ipns"), []GatewayHandler{
+			{"/ipfs/", "GatewayOption", gateway},
+			{"/ipns/", "GatewayOption", gateway},
+		}},
+		{VersionOption(), []GatewayHandler{
+			{"/version", "VersionOption", "version of the node"},
+		}},
+		{CheckVersionOption(), nil},
+		{CommandsROOption(cctx), []GatewayHandler{
+			{"/api/v0/", "CommandsROOption", "read-only API commands"},
+		}},
+	}
+	if cfg.Experimental.P2pHttpProxy {
+		opts = append(opts, gatewayServeOption{P2PProxyOption(), []GatewayHandler{
+			{"/p2p/", "P2PProxyOption", "HTTP proxy to libp2p streams"},
+		}})
+	}
+	if len(cfg.Gateway.RootRedirect) > 0 {
+		opts = append(opts, gatewayServeOption{RedirectOption("", cfg.Gateway.RootRedirect), []GatewayHandler{
+			{"/", "RedirectOption", "redirect to " + cfg.Gateway.RootRedirect},
+		}})
+	}
+	return opts
+}
+
+// GatewayServeOptions returns the options the daemon serves its gateway
+// with, given cfg, the gateway being writable or not, and the context to run
+// the read-only API commands with.
+func GatewayServeOptions(cfg *config.Config, writable bool, cctx oldcmds.Context) []ServeOption {
+	var opts []ServeOption
+	for _, o := range gatewayServeOptions(cfg, writable, cctx) {
+		opts = append(opts, o.option)
+	}
+	return opts
+}
+
+// GatewayHandlers returns the handlers the daemon serves on its gateway
+// addresses with cfg, the gateway being writable or not.
+func GatewayHandlers(cfg *config.Config, writable bool) []GatewayHandler {
+	var handlers []GatewayHandler
+	for _, o := range gatewayServeOptions(cfg, writable, oldcmds.Context{}) {
+		handlers = append(handlers, o.handlers...)
+	}
+	return handlers
+}
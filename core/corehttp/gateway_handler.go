@@ -15,6 +15,8 @@ import (
 	"strings"
 	"time"
 
+	reqstats "github.com/ipfs/go-ipfs/core/reqstats"
+
 	humanize "github.com/dustin/go-humanize"
 	"github.com/gabriel-vasile/mimetype"
 	"github.com/ipfs/go-cid"
@@ -38,6 +40,7 @@ const (
 type gatewayHandler struct {
 	config GatewayConfig
 	api    coreiface.CoreAPI
+	stats  *reqstats.Tracker // counts the requests by CID, if set
 }
 
 // StatusResponseWriter enables us to override HTTP Status Code passed to
@@ -211,6 +214,9 @@ func (i *gatewayHandler) getOrHeadHandler(w http.ResponseWriter, r *http.Request
 		webError(w, "ipfs resolve -r "+escapedURLPath, err, http.StatusNotFound)
 		return
 	}
+	if i.stats != nil {
+		i.stats.Record(resolvedPath.Cid())
+	}
 
 	dr, err := i.api.Unixfs().Get(r.Context(), resolvedPath)
 	if err != nil {
@@ -369,6 +375,10 @@ func (i *gatewayHandler) getOrHeadHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// serveFile serves file with http.ServeContent, which also answers Range
+// requests. The UnixFS reader seeks through the DAG using the sizes recorded
+// in its nodes, so a range only fetches the blocks covering it: players can
+// seek in large videos without the whole file being read.
 func (i *gatewayHandler) serveFile(w http.ResponseWriter, req *http.Request, name string, modtime time.Time, file files.File) {
 	size, err := file.Size()
 	if err != nil {
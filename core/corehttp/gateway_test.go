@@ -1,9 +1,12 @@
 package corehttp
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,6 +16,7 @@ import (
 	version "github.com/ipfs/go-ipfs"
 	core "github.com/ipfs/go-ipfs/core"
 	"github.com/ipfs/go-ipfs/core/coreapi"
+	reqstats "github.com/ipfs/go-ipfs/core/reqstats"
 	namesys "github.com/ipfs/go-ipfs/namesys"
 	repo "github.com/ipfs/go-ipfs/repo"
 
@@ -22,6 +26,7 @@ import (
 	files "github.com/ipfs/go-ipfs-files"
 	path "github.com/ipfs/go-path"
 	iface "github.com/ipfs/interface-go-ipfs-core"
+	options "github.com/ipfs/interface-go-ipfs-core/options"
 	nsopts "github.com/ipfs/interface-go-ipfs-core/options/namesys"
 	ipath "github.com/ipfs/interface-go-ipfs-core/path"
 	ci "github.com/libp2p/go-libp2p-core/crypto"
@@ -680,3 +685,83 @@ func TestVersion(t *testing.T) {
 		t.Fatalf("response doesn't contain protocol version:\n%s", s)
 	}
 }
+
+func TestGatewayRange(t *testing.T) {
+	ts, api, ctx := newTestServerAndNode(t, nil)
+	defer ts.Close()
+
+	data := make([]byte, 64*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	dir := files.NewMapDirectory(map[string]files.Node{
+		"video.mp4": files.NewBytesFile(data),
+	})
+	k, err := api.Unixfs().Add(ctx, dir, options.Unixfs.Chunker("size-1024"), options.Unixfs.RawLeaves(true), options.Unixfs.Pin(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drop the leaves outside of the range, it can only be served if the
+	// rest of the file isn't read
+	const start, end = 10000, 12999
+	nd, err := api.ResolveNode(ctx, ipath.Join(k, "video.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range nd.Links() {
+		if lstart, lend := i*1024, (i+1)*1024-1; lend < start || lstart > end {
+			if err := api.Block().Rm(ctx, ipath.IpfsPath(l.Cid)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+k.String()+"/video.mp4", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != http.StatusPartialContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusPartialContent, res.StatusCode, body)
+	}
+	if cr, expected := res.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)); cr != expected {
+		t.Fatalf("expected Content-Range %q, got %q", expected, cr)
+	}
+	if !bytes.Equal(body, data[start:end+1]) {
+		t.Fatalf("expected %d bytes of the range, got %d different bytes", end+1-start, len(body))
+	}
+}
+
+func TestGatewayRequestStats(t *testing.T) {
+	ts, api, ctx := newTestServerAndNode(t, nil)
+	ts.Close()
+
+	k, err := api.Unixfs().Add(ctx, files.NewBytesFile([]byte("popular")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newGatewayHandler(GatewayConfig{Headers: map[string][]string{}}, api)
+	h.stats = reqstats.New()
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, k.String(), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+
+	top := h.stats.Top(-1)
+	if len(top) != 1 || top[0].Cid != k.Cid() || top[0].Requests != 2 {
+		t.Fatalf("expected 2 requests for %s, got %v", k.Cid(), top)
+	}
+}
@@ -0,0 +1,277 @@
+package corehttp
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-ipfs/core"
+)
+
+// IdempotencyKeyHeader carries a key chosen by the client to identify a
+// request. Requests repeated with the same key within the idempotency window
+// are not run again.
+const IdempotencyKeyHeader = "X-Ipfs-Idempotency-Key"
+
+// DefaultIdempotencyWindow is how long the API remembers idempotency keys.
+const DefaultIdempotencyWindow = 10 * time.Minute
+
+// maxReplayBody is the largest response body kept to be replayed to a
+// repeated request. Repeats of requests with larger responses are refused.
+const maxReplayBody = 64 << 10
+
+// maxIdempotentResponses is the number of keys remembered, the least
+// recently used being forgotten first. With maxReplayBody, it bounds the
+// memory kept for replays to 16MiB.
+const maxIdempotentResponses = 256
+
+// IdempotencyOption returns a ServeOption that runs requests carrying an
+// IdempotencyKeyHeader at most once per key within window. A repeated
+// request gets the response of the first one, or 409 Conflict if the first
+// one is still running or its response was too large to keep. A key reused
+// for a different request, by method, path, arguments or body, gets 422
+// Unprocessable Entity.
+func IdempotencyOption(window time.Duration) ServeOption {
+	return func(_ *core.IpfsNode, _ net.Listener, parent *http.ServeMux) (*http.ServeMux, error) {
+		mux := http.NewServeMux()
+		parent.Handle("/", newIdempotencyHandler(mux, window))
+		return mux, nil
+	}
+}
+
+// idempotentResponse is what was answered to the first request with a key.
+type idempotentResponse struct {
+	key       string
+	done      bool
+	expires   time.Time
+	request   []byte // the fingerprint of the request
+	code      int
+	header    http.Header
+	trailer   http.Header
+	body      []byte
+	truncated bool
+}
+
+type idempotencyHandler struct {
+	next   http.Handler
+	window time.Duration
+
+	mu        sync.Mutex
+	responses map[string]*list.Element // of *idempotentResponse
+	lru       *list.List               // the most recently used first
+}
+
+func newIdempotencyHandler(next http.Handler, window time.Duration) *idempotencyHandler {
+	return &idempotencyHandler{
+		next:      next,
+		window:    window,
+		responses: make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+func (h *idempotencyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	h.mu.Lock()
+	now := time.Now()
+	for e := h.lru.Front(); e != nil; {
+		next := e.Next()
+		if resp := e.Value.(*idempotentResponse); resp.done && now.After(resp.expires) {
+			h.forget(e)
+		}
+		e = next
+	}
+	var resp *idempotentResponse
+	e, seen := h.responses[key]
+	if seen {
+		h.lru.MoveToFront(e)
+		resp = e.Value.(*idempotentResponse)
+	} else {
+		if h.lru.Len() >= maxIdempotentResponses {
+			h.forget(h.lru.Back())
+		}
+		resp = &idempotentResponse{key: key}
+		h.responses[key] = h.lru.PushFront(resp)
+	}
+	h.mu.Unlock()
+
+	fp := newRequestFingerprint(r)
+	if seen {
+		// the whole request is read to tell whether it's the same one
+		io.Copy(fp, r.Body)
+		h.replay(w, resp, fp.Sum())
+		return
+	}
+
+	r.Body = ioutil.NopCloser(io.TeeReader(r.Body, fp))
+	rec := &recordingWriter{ResponseWriter: w}
+	h.next.ServeHTTP(rec, r)
+	// the fingerprint covers the body the handler didn't read
+	io.Copy(ioutil.Discard, r.Body)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	resp.done = true
+	resp.expires = time.Now().Add(h.window)
+	resp.request = fp.Sum()
+	resp.code = rec.code
+	resp.header = rec.header
+	resp.trailer = rec.trailer()
+	resp.body = rec.body.Bytes()
+	resp.truncated = rec.truncated
+	if resp.code == 0 {
+		resp.code = http.StatusOK
+		resp.header = w.Header().Clone()
+	}
+}
+
+// forget drops the response of e. h.mu must be held.
+func (h *idempotencyHandler) forget(e *list.Element) {
+	h.lru.Remove(e)
+	delete(h.responses, e.Value.(*idempotentResponse).key)
+}
+
+// replay answers a repeated request, whose fingerprint is request, with the
+// recorded response.
+func (h *idempotencyHandler) replay(w http.ResponseWriter, resp *idempotentResponse, request []byte) {
+	h.mu.Lock()
+	done, truncated, same := resp.done, resp.truncated, bytes.Equal(resp.request, request)
+	h.mu.Unlock()
+
+	switch {
+	case !done:
+		http.Error(w, "a request with this idempotency key is still running", http.StatusConflict)
+		return
+	case !same:
+		http.Error(w, "this idempotency key was used for a different request", http.StatusUnprocessableEntity)
+		return
+	case truncated:
+		http.Error(w, "a request with this idempotency key was already applied", http.StatusConflict)
+		return
+	}
+
+	for k, v := range resp.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.code)
+	w.Write(resp.body)
+	for k, v := range resp.trailer {
+		w.Header()[k] = v
+	}
+}
+
+// requestFingerprint hashes what identifies a request: its method, path,
+// arguments and body. The boundary of a multipart body, random for every
+// request sent, is left out.
+type requestFingerprint struct {
+	h        hash.Hash
+	boundary []byte
+	pending  []byte // the end of the body written, maybe a partial boundary
+}
+
+func newRequestFingerprint(r *http.Request) *requestFingerprint {
+	fp := &requestFingerprint{h: sha256.New()}
+	fmt.Fprintf(fp.h, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && params["boundary"] != "" {
+		fp.boundary = []byte(params["boundary"])
+	}
+	return fp
+}
+
+func (fp *requestFingerprint) Write(b []byte) (int, error) {
+	if len(fp.boundary) == 0 {
+		return fp.h.Write(b)
+	}
+	fp.pending = append(fp.pending, b...)
+	for {
+		i := bytes.Index(fp.pending, fp.boundary)
+		if i < 0 {
+			break
+		}
+		fp.h.Write(fp.pending[:i])
+		fp.h.Write([]byte("boundary"))
+		fp.pending = fp.pending[i+len(fp.boundary):]
+	}
+	// keep what may be the start of a boundary
+	if keep := len(fp.boundary) - 1; len(fp.pending) > keep {
+		fp.h.Write(fp.pending[:len(fp.pending)-keep])
+		fp.pending = append([]byte(nil), fp.pending[len(fp.pending)-keep:]...)
+	}
+	return len(b), nil
+}
+
+// Sum returns the fingerprint of the request written so far.
+func (fp *requestFingerprint) Sum() []byte {
+	fp.h.Write(fp.pending)
+	fp.pending = nil
+	return fp.h.Sum(nil)
+}
+
+// recordingWriter keeps a copy of the response written through it.
+type recordingWriter struct {
+	http.ResponseWriter
+
+	code      int
+	header    http.Header
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+		w.header = w.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.truncated {
+		if w.body.Len()+len(b) > maxReplayBody {
+			w.truncated = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// trailer returns the values of the declared trailers, which are only set
+// once the body has been written.
+func (w *recordingWriter) trailer() http.Header {
+	trailer := make(http.Header)
+	for _, decl := range w.Header()["Trailer"] {
+		for _, k := range strings.Split(decl, ",") {
+			k = http.CanonicalHeaderKey(strings.TrimSpace(k))
+			if v, ok := w.Header()[k]; ok {
+				trailer[k] = v
+			}
+		}
+	}
+	return trailer
+}
@@ -0,0 +1,197 @@
+package corehttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyOption(t *testing.T) {
+	applied := 0
+	root := http.NewServeMux()
+	mux, err := IdempotencyOption(time.Minute)(nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		applied++
+		w.Header().Set("Trailer", "X-Stream-Error")
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(strings.Repeat("x", applied)))
+		w.Header().Set("X-Stream-Error", "oops")
+	})
+
+	send := func(path, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		if key != "" {
+			r.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		root.ServeHTTP(w, r)
+		return w
+	}
+
+	first := send(APIPath+"/pin/add", "key1")
+	if applied != 1 || first.Body.String() != "x" {
+		t.Fatalf("expected the first request to be applied, got %d applications and %q", applied, first.Body.String())
+	}
+
+	// a duplicate is not applied again, and gets the first response
+	dup := send(APIPath+"/pin/add", "key1")
+	if applied != 1 {
+		t.Fatalf("expected a duplicated request not to be applied again, got %d applications", applied)
+	}
+	if dup.Code != http.StatusOK || dup.Body.String() != "x" || dup.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("expected the first response to be replayed, got %d %q", dup.Code, dup.Body.String())
+	}
+	if dup.Header().Get("X-Stream-Error") != "oops" {
+		t.Errorf("expected the trailer to be replayed, got %v", dup.Header())
+	}
+
+	// other keys and requests without a key are applied
+	send(APIPath+"/pin/add", "key2")
+	send(APIPath+"/pin/add", "")
+	send(APIPath+"/pin/add", "")
+	if applied != 4 {
+		t.Fatalf("expected 4 applications, got %d", applied)
+	}
+
+	// a key reused for another request is refused
+	for _, path := range []string{APIPath + "/pin/rm", APIPath + "/pin/add?arg=QmOther"} {
+		if code := send(path, "key1").Code; code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected a reused key to be refused, got %d", path, code)
+		}
+	}
+	if applied != 4 {
+		t.Fatalf("expected a reused key not to be applied, got %d applications", applied)
+	}
+}
+
+func TestIdempotencyBody(t *testing.T) {
+	applied := 0
+	h := newIdempotencyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		applied++
+		// only part of the body is read
+		io.CopyN(ioutil.Discard, r.Body, 10)
+	}), time.Minute)
+
+	// the multipart boundary changes every time a request is sent
+	send := func(content string) int {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", "file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+		mw.Close()
+
+		r := httptest.NewRequest(http.MethodPost, APIPath+"/add", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.Header.Set(IdempotencyKeyHeader, "key")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	content := strings.Repeat("content ", 1000)
+	send(content)
+	if code := send(content); code != http.StatusOK || applied != 1 {
+		t.Fatalf("expected the same body to be replayed, got %d and %d applications", code, applied)
+	}
+	if code := send(content + "more"); code != http.StatusUnprocessableEntity || applied != 1 {
+		t.Fatalf("expected another body to be refused, got %d and %d applications", code, applied)
+	}
+}
+
+func TestIdempotencyLimit(t *testing.T) {
+	applied := 0
+	h := newIdempotencyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		applied++
+	}), time.Minute)
+
+	send := func(key string) {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set(IdempotencyKeyHeader, key)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	for i := 0; i <= maxIdempotentResponses; i++ {
+		send(fmt.Sprint(i))
+	}
+	if len(h.responses) != maxIdempotentResponses {
+		t.Fatalf("expected %d keys remembered, got %d", maxIdempotentResponses, len(h.responses))
+	}
+
+	// the least recently used key is forgotten
+	send("1")
+	send("0")
+	if applied != maxIdempotentResponses+2 {
+		t.Fatalf("expected only the first key to be forgotten, got %d applications", applied)
+	}
+}
+
+func TestIdempotencyConflicts(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	h := newIdempotencyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-finish
+		}
+		w.Write(make([]byte, maxReplayBody+1))
+	}), time.Minute)
+
+	send := func(path string) int {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.Header.Set(IdempotencyKeyHeader, path)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// a duplicate of a request still running is refused
+	done := make(chan struct{})
+	go func() {
+		send("/slow")
+		close(done)
+	}()
+	<-started
+	if code := send("/slow"); code != http.StatusConflict {
+		t.Errorf("expected a duplicate of a running request to be refused, got %d", code)
+	}
+	close(finish)
+	<-done
+
+	// so is a duplicate of a request whose response was too large to keep
+	if code := send("/large"); code != http.StatusOK {
+		t.Fatalf("expected the first request to succeed, got %d", code)
+	}
+	if code := send("/large"); code != http.StatusConflict {
+		t.Errorf("expected a duplicate of an unreplayable request to be refused, got %d", code)
+	}
+}
+
+func TestIdempotencyWindow(t *testing.T) {
+	applied := 0
+	h := newIdempotencyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		applied++
+	}), time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set(IdempotencyKeyHeader, "key")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if applied != 2 {
+		t.Fatalf("expected the key to be forgotten after the window, got %d applications", applied)
+	}
+}
@@ -3,6 +3,8 @@ package corehttp
 import (
 	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	core "github.com/ipfs/go-ipfs/core"
 
@@ -105,8 +107,22 @@ var (
 		Name:      "unixfs_get_latency_seconds",
 		Help:      "The time till the first block is received when 'getting' a file from the gateway.",
 	}, []string{"namespace"})
+
+	// CommandDurationMetric holds the execution latency of commands, by
+	// command path, like "pin/add".
+	CommandDurationMetric = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "ipfs",
+		Subsystem: "cmds",
+		Name:      "duration_seconds",
+		Help:      "The execution latency of commands in seconds.",
+	}, []string{"command"})
 )
 
+// observeCommandDuration records that the command at path took d to run.
+func observeCommandDuration(path []string, d time.Duration) {
+	CommandDurationMetric.WithLabelValues(strings.Join(path, "/")).Observe(d.Seconds())
+}
+
 type IpfsNodeCollector struct {
 	Node *core.IpfsNode
 }
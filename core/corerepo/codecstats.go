@@ -0,0 +1,65 @@
+package corerepo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	cid "github.com/ipfs/go-cid"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+)
+
+// CodecStat holds the number and total size of the blocks of a codec.
+type CodecStat struct {
+	Codec     string
+	NumBlocks uint64
+	Size      uint64
+}
+
+// CodecStats walks every block in bs and totals block counts and sizes per
+// codec. The result is sorted by size, largest first.
+func CodecStats(ctx context.Context, bs bstore.Blockstore) ([]CodecStat, error) {
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[uint64]*CodecStat)
+	for c := range keys {
+		size, err := bs.GetSize(c)
+		if err != nil {
+			return nil, err
+		}
+
+		codec := c.Type()
+		st, ok := stats[codec]
+		if !ok {
+			st = &CodecStat{Codec: codecName(codec)}
+			stats[codec] = st
+		}
+		st.NumBlocks++
+		st.Size += uint64(size)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]CodecStat, 0, len(stats))
+	for _, st := range stats {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Size != out[j].Size {
+			return out[i].Size > out[j].Size
+		}
+		return out[i].Codec < out[j].Codec
+	})
+	return out, nil
+}
+
+func codecName(codec uint64) string {
+	if name, ok := cid.CodecToStr[codec]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", codec)
+}
@@ -0,0 +1,53 @@
+package corerepo
+
+import (
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	u "github.com/ipfs/go-ipfs-util"
+)
+
+func TestCodecStats(t *testing.T) {
+	bstore := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+
+	put := func(codec uint64, data string) {
+		c := cid.NewCidV1(codec, u.Hash([]byte(data)))
+		b, err := blocks.NewBlockWithCid([]byte(data), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bstore.Put(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put(cid.Raw, "raw one")        // 7 bytes
+	put(cid.Raw, "raw number two") // 14 bytes
+	put(cid.DagProtobuf, "some protobuf node")
+	put(cid.DagCBOR, "cbor")
+	put(0x300001, "unknown")
+
+	stats, err := CodecStats(context.Background(), bstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []CodecStat{
+		{Codec: "raw", NumBlocks: 2, Size: 21},
+		{Codec: "protobuf", NumBlocks: 1, Size: 18},
+		{Codec: "0x300001", NumBlocks: 1, Size: 7},
+		{Codec: "cbor", NumBlocks: 1, Size: 4},
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("expected %d codecs, got %v", len(expected), stats)
+	}
+	for i, st := range stats {
+		if st != expected[i] {
+			t.Errorf("entry %d: expected %+v, got %+v", i, expected[i], st)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package corerepo
+
+import (
+	"context"
+
+	cid "github.com/ipfs/go-cid"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	pin "github.com/ipfs/go-ipfs-pinner"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// DedupStat compares the size pinned content would take without block-level
+// deduplication with the size it actually takes.
+type DedupStat struct {
+	LogicalSize uint64 // sum of the block sizes of all pinned DAGs, counting duplicates
+	UniqueSize  uint64 // sum of the sizes of distinct pinned blocks
+	Savings     uint64 // LogicalSize - UniqueSize
+	Ratio       float64
+}
+
+// DedupSavings walks every direct and recursive pin and computes how much
+// space block deduplication saves. Blocks must be available locally.
+func DedupSavings(ctx context.Context, pinning pin.Pinner, ng ipld.NodeGetter, bs bstore.Blockstore) (DedupStat, error) {
+	w := &dedupWalker{
+		ng:      ng,
+		bs:      bs,
+		logical: make(map[cid.Cid]uint64),
+		sizes:   make(map[cid.Cid]uint64),
+	}
+
+	var stat DedupStat
+
+	direct, err := pinning.DirectKeys(ctx)
+	if err != nil {
+		return stat, err
+	}
+	for _, c := range direct {
+		size, err := w.blockSize(c)
+		if err != nil {
+			return stat, err
+		}
+		stat.LogicalSize += size
+	}
+
+	recursive, err := pinning.RecursiveKeys(ctx)
+	if err != nil {
+		return stat, err
+	}
+	for _, c := range recursive {
+		size, err := w.logicalSize(ctx, c)
+		if err != nil {
+			return stat, err
+		}
+		stat.LogicalSize += size
+	}
+
+	for _, size := range w.sizes {
+		stat.UniqueSize += size
+	}
+	stat.Savings = stat.LogicalSize - stat.UniqueSize
+	if stat.UniqueSize > 0 {
+		stat.Ratio = float64(stat.LogicalSize) / float64(stat.UniqueSize)
+	}
+	return stat, nil
+}
+
+type dedupWalker struct {
+	ng ipld.NodeGetter
+	bs bstore.Blockstore
+
+	// logical memoizes the logical size of every DAG walked so far so that
+	// shared sub-DAGs are only traversed once.
+	logical map[cid.Cid]uint64
+	// sizes holds the size of every distinct block seen.
+	sizes map[cid.Cid]uint64
+}
+
+func (w *dedupWalker) blockSize(c cid.Cid) (uint64, error) {
+	if size, ok := w.sizes[c]; ok {
+		return size, nil
+	}
+	size, err := w.bs.GetSize(c)
+	if err != nil {
+		return 0, err
+	}
+	w.sizes[c] = uint64(size)
+	return uint64(size), nil
+}
+
+func (w *dedupWalker) logicalSize(ctx context.Context, c cid.Cid) (uint64, error) {
+	if size, ok := w.logical[c]; ok {
+		return size, nil
+	}
+
+	size, err := w.blockSize(c)
+	if err != nil {
+		return 0, err
+	}
+
+	nd, err := w.ng.Get(ctx, c)
+	if err != nil {
+		return 0, err
+	}
+	for _, l := range nd.Links() {
+		childSize, err := w.logicalSize(ctx, l.Cid)
+		if err != nil {
+			return 0, err
+		}
+		size += childSize
+	}
+
+	w.logical[c] = size
+	return size, nil
+}
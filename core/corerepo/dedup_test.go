@@ -0,0 +1,70 @@
+package corerepo
+
+import (
+	"context"
+	"testing"
+
+	bs "github.com/ipfs/go-blockservice"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	pin "github.com/ipfs/go-ipfs-pinner"
+	mdag "github.com/ipfs/go-merkledag"
+)
+
+func TestDedupSavings(t *testing.T) {
+	ctx := context.Background()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(dstore)
+	dserv := mdag.NewDAGService(bs.New(bstore, offline.Exchange(bstore)))
+	pinning := pin.NewPinner(dstore, dserv, dserv)
+
+	// Two roots sharing the same leaf, the shared leaf being linked twice
+	// from the second root.
+	shared := mdag.NodeWithData(make([]byte, 1000))
+	rootA := mdag.NodeWithData([]byte("a"))
+	rootB := mdag.NodeWithData([]byte("b"))
+	if err := rootA.AddNodeLink("shared", shared); err != nil {
+		t.Fatal(err)
+	}
+	if err := rootB.AddNodeLink("shared1", shared); err != nil {
+		t.Fatal(err)
+	}
+	if err := rootB.AddNodeLink("shared2", shared); err != nil {
+		t.Fatal(err)
+	}
+	for _, nd := range []*mdag.ProtoNode{shared, rootA, rootB} {
+		if err := dserv.Add(ctx, nd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, nd := range []*mdag.ProtoNode{rootA, rootB} {
+		if err := pinning.Pin(ctx, nd, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sharedSize := uint64(len(shared.RawData()))
+	aSize := uint64(len(rootA.RawData()))
+	bSize := uint64(len(rootB.RawData()))
+
+	stat, err := DedupSavings(ctx, pinning, dserv, bstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := aSize + bSize + 3*sharedSize; stat.LogicalSize != expected {
+		t.Fatalf("expected logical size %d, got %d", expected, stat.LogicalSize)
+	}
+	if expected := aSize + bSize + sharedSize; stat.UniqueSize != expected {
+		t.Fatalf("expected unique size %d, got %d", expected, stat.UniqueSize)
+	}
+	if stat.Savings != 2*sharedSize {
+		t.Fatalf("expected savings of %d, got %d", 2*sharedSize, stat.Savings)
+	}
+	if stat.Ratio <= 1 {
+		t.Fatalf("expected a ratio above 1, got %f", stat.Ratio)
+	}
+}
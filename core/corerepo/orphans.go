@@ -0,0 +1,71 @@
+package corerepo
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ipfs/go-ipfs/gc"
+
+	cid "github.com/ipfs/go-cid"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	pin "github.com/ipfs/go-ipfs-pinner"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// Orphans returns the roots of the DAGs stored in bs that are neither pinned
+// nor reachable from bestEffortRoots (the MFS root), which are the DAGs the
+// next garbage collection would remove. A root is an unreachable block that
+// no other unreachable block links to. The roots are sorted by CID.
+func Orphans(ctx context.Context, pinning pin.Pinner, ng ipld.NodeGetter, bs bstore.Blockstore, bestEffortRoots []cid.Cid) ([]cid.Cid, error) {
+	// ColoredSet reports the links it fails to fetch on the side, its
+	// returned error is enough for us.
+	output := make(chan gc.Result)
+	go func() {
+		for range output {
+		}
+	}()
+	reachable, err := gc.ColoredSet(ctx, pinning, ng, bestEffortRoots, output)
+	close(output)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unreachable := cid.NewSet()
+	linked := cid.NewSet()
+	for c := range keys {
+		if reachable.Has(c) {
+			continue
+		}
+		unreachable.Add(c)
+
+		links, err := ipld.GetLinks(ctx, ng, c)
+		if err != nil {
+			// undecodable blocks can't link to anything we know of
+			log.Debugf("orphans: cannot read the links of %s: %s", c, err)
+			continue
+		}
+		for _, l := range links {
+			linked.Add(l.Cid)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var roots []cid.Cid
+	unreachable.ForEach(func(c cid.Cid) error {
+		if !linked.Has(c) {
+			roots = append(roots, c)
+		}
+		return nil
+	})
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].String() < roots[j].String()
+	})
+	return roots, nil
+}
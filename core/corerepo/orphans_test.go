@@ -0,0 +1,54 @@
+package corerepo
+
+import (
+	"context"
+	"testing"
+
+	bs "github.com/ipfs/go-blockservice"
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	pin "github.com/ipfs/go-ipfs-pinner"
+	mdag "github.com/ipfs/go-merkledag"
+)
+
+func TestOrphans(t *testing.T) {
+	ctx := context.Background()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(dstore)
+	dserv := mdag.NewDAGService(bs.New(bstore, offline.Exchange(bstore)))
+	pinning := pin.NewPinner(dstore, dserv, dserv)
+
+	// a DAG of two blocks for each of: pinned, in MFS, and orphaned
+	dags := make(map[string]*mdag.ProtoNode)
+	for _, name := range []string{"pinned", "mfs", "orphan"} {
+		leaf := mdag.NodeWithData([]byte(name + " leaf"))
+		root := mdag.NodeWithData([]byte(name))
+		if err := root.AddNodeLink("leaf", leaf); err != nil {
+			t.Fatal(err)
+		}
+		for _, nd := range []*mdag.ProtoNode{leaf, root} {
+			if err := dserv.Add(ctx, nd); err != nil {
+				t.Fatal(err)
+			}
+		}
+		dags[name] = root
+	}
+	if err := pinning.Pin(ctx, dags["pinned"], true); err != nil {
+		t.Fatal(err)
+	}
+	if err := pinning.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	orphans, err := Orphans(ctx, pinning, dserv, bstore, []cid.Cid{dags["mfs"].Cid()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 1 || orphans[0] != dags["orphan"].Cid() {
+		t.Fatalf("expected only the orphan root %s, got %v", dags["orphan"].Cid(), orphans)
+	}
+}
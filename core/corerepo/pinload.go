@@ -0,0 +1,44 @@
+package corerepo
+
+import (
+	"context"
+	"time"
+
+	bserv "github.com/ipfs/go-blockservice"
+	ds "github.com/ipfs/go-datastore"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	pin "github.com/ipfs/go-ipfs-pinner"
+	dag "github.com/ipfs/go-merkledag"
+)
+
+// PinLoadStat is the time it took to load a pinset and its size.
+type PinLoadStat struct {
+	Duration  time.Duration
+	Recursive int
+	Direct    int
+}
+
+// PinLoad loads the pinset stored in dstore, with its blocks in bs, the way
+// the node does when it starts (see pin.LoadPinner), and times it.
+func PinLoad(ctx context.Context, dstore ds.Datastore, bs bstore.Blockstore) (PinLoadStat, error) {
+	dserv := dag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))
+
+	start := time.Now()
+	pinning, err := pin.LoadPinner(dstore, dserv, dserv)
+	if err != nil {
+		return PinLoadStat{}, err
+	}
+	stat := PinLoadStat{Duration: time.Since(start)}
+
+	recursive, err := pinning.RecursiveKeys(ctx)
+	if err != nil {
+		return PinLoadStat{}, err
+	}
+	direct, err := pinning.DirectKeys(ctx)
+	if err != nil {
+		return PinLoadStat{}, err
+	}
+	stat.Recursive, stat.Direct = len(recursive), len(direct)
+	return stat, nil
+}
@@ -0,0 +1,53 @@
+package corerepo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	bs "github.com/ipfs/go-blockservice"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	pin "github.com/ipfs/go-ipfs-pinner"
+	mdag "github.com/ipfs/go-merkledag"
+)
+
+func TestPinLoad(t *testing.T) {
+	ctx := context.Background()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(dstore)
+	dserv := mdag.NewDAGService(bs.New(bstore, offline.Exchange(bstore)))
+
+	if _, err := PinLoad(ctx, dstore, bstore); err == nil {
+		t.Fatal("expected loading a missing pinset to fail")
+	}
+
+	pinning := pin.NewPinner(dstore, dserv, dserv)
+	for i := 0; i < 300; i++ {
+		nd := mdag.NodeWithData([]byte(fmt.Sprintf("pin %d", i)))
+		if err := dserv.Add(ctx, nd); err != nil {
+			t.Fatal(err)
+		}
+		// enough recursive pins for the set to be split in buckets
+		if err := pinning.Pin(ctx, nd, i%10 != 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pinning.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stat, err := PinLoad(ctx, dstore, bstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Recursive != 270 || stat.Direct != 30 {
+		t.Fatalf("expected 270 recursive and 30 direct pins, got %d and %d", stat.Recursive, stat.Direct)
+	}
+	if stat.Duration <= 0 {
+		t.Fatalf("expected the load to be timed, got %s", stat.Duration)
+	}
+}
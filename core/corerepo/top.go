@@ -0,0 +1,139 @@
+package corerepo
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+
+	cid "github.com/ipfs/go-cid"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	pin "github.com/ipfs/go-ipfs-pinner"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// SizedCid is a CID along with a size in bytes.
+type SizedCid struct {
+	Cid  cid.Cid
+	Size uint64
+}
+
+// LargestBlocks returns the n largest blocks in bs, largest first.
+func LargestBlocks(ctx context.Context, bs bstore.Blockstore, n int) ([]SizedCid, error) {
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	top := newTopN(n)
+	for c := range keys {
+		size, err := bs.GetSize(c)
+		if err != nil {
+			return nil, err
+		}
+		top.add(SizedCid{Cid: c, Size: uint64(size)})
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return top.sorted(), nil
+}
+
+// LargestPins returns the n largest recursively pinned DAGs, largest first.
+// The size of a DAG is the sum of the sizes of its distinct blocks, which
+// must be available locally.
+func LargestPins(ctx context.Context, pinning pin.Pinner, ng ipld.NodeGetter, bs bstore.Blockstore, n int) ([]SizedCid, error) {
+	roots, err := pinning.RecursiveKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	top := newTopN(n)
+	for _, root := range roots {
+		size, err := dagSize(ctx, ng, bs, root)
+		if err != nil {
+			return nil, err
+		}
+		top.add(SizedCid{Cid: root, Size: size})
+	}
+	return top.sorted(), nil
+}
+
+func dagSize(ctx context.Context, ng ipld.NodeGetter, bs bstore.Blockstore, root cid.Cid) (uint64, error) {
+	var total uint64
+	seen := cid.NewSet()
+	var walk func(c cid.Cid) error
+	walk = func(c cid.Cid) error {
+		if !seen.Visit(c) {
+			return nil
+		}
+		size, err := bs.GetSize(c)
+		if err != nil {
+			return err
+		}
+		total += uint64(size)
+
+		nd, err := ng.Get(ctx, c)
+		if err != nil {
+			return err
+		}
+		for _, l := range nd.Links() {
+			if err := walk(l.Cid); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return total, walk(root)
+}
+
+// topN keeps the n largest entries added to it, using a min-heap so the
+// smallest retained entry can be evicted cheaply.
+type topN struct {
+	n       int
+	entries sizedCidHeap
+}
+
+func newTopN(n int) *topN {
+	return &topN{n: n}
+}
+
+func (t *topN) add(e SizedCid) {
+	if t.n <= 0 {
+		return
+	}
+	if len(t.entries) < t.n {
+		heap.Push(&t.entries, e)
+		return
+	}
+	if e.Size > t.entries[0].Size {
+		t.entries[0] = e
+		heap.Fix(&t.entries, 0)
+	}
+}
+
+// sorted returns the retained entries, largest first. Entries of equal size
+// are ordered by CID so the output is stable.
+func (t *topN) sorted() []SizedCid {
+	out := make([]SizedCid, len(t.entries))
+	copy(out, t.entries)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Size != out[j].Size {
+			return out[i].Size > out[j].Size
+		}
+		return out[i].Cid.KeyString() < out[j].Cid.KeyString()
+	})
+	return out
+}
+
+type sizedCidHeap []SizedCid
+
+func (h sizedCidHeap) Len() int            { return len(h) }
+func (h sizedCidHeap) Less(i, j int) bool  { return h[i].Size < h[j].Size }
+func (h sizedCidHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *sizedCidHeap) Push(x interface{}) { *h = append(*h, x.(SizedCid)) }
+func (h *sizedCidHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,87 @@
+package corerepo
+
+import (
+	"context"
+	"testing"
+
+	bs "github.com/ipfs/go-blockservice"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	pin "github.com/ipfs/go-ipfs-pinner"
+	mdag "github.com/ipfs/go-merkledag"
+)
+
+func TestLargest(t *testing.T) {
+	ctx := context.Background()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(dstore)
+	dserv := mdag.NewDAGService(bs.New(bstore, offline.Exchange(bstore)))
+	pinning := pin.NewPinner(dstore, dserv, dserv)
+
+	// small: a single 10 byte node
+	// medium: a root linking to a 300 byte leaf
+	// large: a root linking to a 200 byte leaf twice and a 250 byte leaf
+	small := mdag.NodeWithData(make([]byte, 10))
+
+	mediumLeaf := mdag.NodeWithData(make([]byte, 300))
+	medium := mdag.NodeWithData([]byte("medium"))
+	if err := medium.AddNodeLink("leaf", mediumLeaf); err != nil {
+		t.Fatal(err)
+	}
+
+	largeLeaf1 := mdag.NodeWithData(make([]byte, 200))
+	largeLeaf2 := mdag.NodeWithData(make([]byte, 250))
+	large := mdag.NodeWithData([]byte("large"))
+	for name, leaf := range map[string]*mdag.ProtoNode{"a": largeLeaf1, "b": largeLeaf1, "c": largeLeaf2} {
+		if err := large.AddNodeLink(name, leaf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, nd := range []*mdag.ProtoNode{small, mediumLeaf, medium, largeLeaf1, largeLeaf2, large} {
+		if err := dserv.Add(ctx, nd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, nd := range []*mdag.ProtoNode{small, medium, large} {
+		if err := pinning.Pin(ctx, nd, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pins, err := LargestPins(ctx, pinning, dserv, bstore, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 2 {
+		t.Fatalf("expected 2 pins, got %d", len(pins))
+	}
+	if !pins[0].Cid.Equals(large.Cid()) || !pins[1].Cid.Equals(medium.Cid()) {
+		t.Fatalf("wrong order: %v", pins)
+	}
+	size := func(nds ...*mdag.ProtoNode) (s uint64) {
+		for _, nd := range nds {
+			s += uint64(len(nd.RawData()))
+		}
+		return s
+	}
+	if expected := size(large, largeLeaf1, largeLeaf2); pins[0].Size != expected {
+		t.Fatalf("expected the shared leaf to be counted once (%d), got %d", expected, pins[0].Size)
+	}
+
+	blocks, err := LargestBlocks(ctx, bstore, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(blocks))
+	}
+	for i, nd := range []*mdag.ProtoNode{mediumLeaf, largeLeaf2, largeLeaf1} {
+		if !blocks[i].Cid.Equals(nd.Cid()) {
+			t.Fatalf("block %d: expected %s, got %s", i, nd.Cid(), blocks[i].Cid)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
 	bstore "github.com/ipfs/go-ipfs-blockstore"
 	chunker "github.com/ipfs/go-ipfs-chunker"
 	"github.com/ipfs/go-ipfs-files"
@@ -79,6 +80,15 @@ type Adder struct {
 	tempRoot   cid.Cid
 	CidBuilder cid.Builder
 	liveNodes  uint64
+
+	// Checkpoints, if set, makes adds of files from disk resumable, see
+	// WithResume.
+	Checkpoints        datastore.Datastore
+	checkpointsExpired bool
+
+	// Timings, if set, records the time spent chunking and hashing. Pass
+	// the DAG service through Timings.DAGService to record writes as well.
+	Timings *PhaseTimings
 }
 
 func (adder *Adder) mfsRoot() (*mfs.Root, error) {
@@ -101,18 +111,21 @@ func (adder *Adder) SetMfsRoot(r *mfs.Root) {
 }
 
 // Constructs a node from reader's data, and adds it. Doesn't pin.
-func (adder *Adder) add(reader io.Reader) (ipld.Node, error) {
+func (adder *Adder) add(reader io.Reader, builder cid.Builder) (ipld.Node, error) {
 	chnk, err := chunker.FromString(reader, adder.Chunker)
 	if err != nil {
 		return nil, err
 	}
+	if adder.Timings != nil {
+		chnk = &timedSplitter{Splitter: chnk, t: adder.Timings}
+	}
 
 	params := ihelper.DagBuilderParams{
 		Dagserv:    adder.bufferedDS,
 		RawLeaves:  adder.RawLeaves,
 		Maxlinks:   ihelper.DefaultLinksPerBlock,
 		NoCopy:     adder.NoCopy,
-		CidBuilder: adder.CidBuilder,
+		CidBuilder: builder,
 	}
 
 	db, err := params.New(chnk)
@@ -396,10 +409,36 @@ func (adder *Adder) addFile(path string, file files.File) error {
 		}
 	}
 
-	dagnode, err := adder.add(reader)
+	builder := adder.CidBuilder
+	if adder.Timings != nil && builder != nil {
+		// inside the checkpoint builder, to only time actual hashing
+		builder = &timedBuilder{Builder: builder, t: adder.Timings}
+	}
+	var cp *checkpoint
+	if adder.Checkpoints != nil && adder.CidBuilder != nil {
+		var err error
+		cp, err = adder.openCheckpoint(file)
+		if err != nil {
+			return err
+		}
+		if cp != nil {
+			builder = &checkpointBuilder{Builder: builder, cp: cp}
+		}
+	}
+
+	dagnode, err := adder.add(reader, builder)
 	if err != nil {
+		if cp != nil {
+			// keep the chunks done so far for the next attempt
+			adder.bufferedDS.Commit()
+		}
 		return err
 	}
+	if cp != nil {
+		if err := cp.clear(); err != nil {
+			return err
+		}
+	}
 
 	// patch it into the root
 	return adder.addNode(dagnode, path)
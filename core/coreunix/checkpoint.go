@@ -0,0 +1,220 @@
+package coreunix
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/ipfs/go-ipfs-files"
+)
+
+var (
+	// checkpointPrefix is where add checkpoints are kept, one key per chunk
+	// below a key per file.
+	checkpointPrefix = datastore.NewKey("/local/addcheckpoint")
+	// checkpointFiles holds, for each checkpoint, the version of the file
+	// it was made for and when it was last used.
+	checkpointFiles = checkpointPrefix.ChildString("files")
+)
+
+// checkpointExpiry is how long the checkpoint of an add that isn't resumed
+// is kept.
+const checkpointExpiry = 7 * 24 * time.Hour
+
+type resumeKey struct{}
+
+// WithResume returns a context under which adds are resumable: the CIDs of
+// the chunks of files added from disk are checkpointed, and adding the same
+// unchanged file again only hashes the chunks that weren't stored yet.
+// Checkpoints not used for a week are deleted.
+func WithResume(ctx context.Context) context.Context {
+	return context.WithValue(ctx, resumeKey{}, true)
+}
+
+// Resumable reports whether adds made with ctx should be resumable.
+func Resumable(ctx context.Context) bool {
+	resume, _ := ctx.Value(resumeKey{}).(bool)
+	return resume
+}
+
+// blockHaser is the part of the blockstore used to check that checkpointed
+// chunks were actually stored.
+type blockHaser interface {
+	Has(cid.Cid) (bool, error)
+}
+
+// checkpoint records the CIDs of the chunks of a file by offset.
+type checkpoint struct {
+	ds     datastore.Datastore
+	bs     blockHaser
+	key    datastore.Key
+	file   datastore.Key
+	offset uint64
+}
+
+// openCheckpoint returns the checkpoint of file, or nil if it can't have
+// one. There is one checkpoint per path on disk, made for a version of the
+// file identified by its size and modification time, as well as the chunker
+// and CID builder: changing any of them deletes it and starts over.
+//
+// The first checkpoint opened by an adder deletes the expired ones.
+func (adder *Adder) openCheckpoint(file files.File) (*checkpoint, error) {
+	fi, ok := file.(files.FileInfo)
+	if !ok || fi.AbsPath() == "" {
+		return nil, nil
+	}
+	bs, ok := adder.gcLocker.(blockHaser)
+	if !ok {
+		return nil, nil
+	}
+	// like the filestore, this relies on the file being local to the node
+	st, err := os.Stat(fi.AbsPath())
+	if err != nil {
+		log.Debugf("not checkpointing %s: %s", fi.AbsPath(), err)
+		return nil, nil
+	}
+
+	// the CID of nothing tells the CID version and hash function of leaves
+	probe, err := adder.CidBuilder.WithCodec(cid.Raw).Sum(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if !adder.checkpointsExpired {
+		adder.checkpointsExpired = true
+		if err := expireCheckpoints(adder.Checkpoints, time.Now().Add(-checkpointExpiry)); err != nil {
+			return nil, err
+		}
+	}
+
+	name := sha256.Sum256([]byte(fi.AbsPath()))
+	cp := &checkpoint{
+		ds:   adder.Checkpoints,
+		bs:   bs,
+		key:  checkpointPrefix.ChildString(hex.EncodeToString(name[:])),
+		file: checkpointFiles.ChildString(hex.EncodeToString(name[:])),
+	}
+
+	version := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%d\x00%s\x00%s", st.Size(), st.ModTime().UnixNano(), adder.Chunker, probe)))
+	v, err := cp.ds.Get(cp.file)
+	switch err {
+	case nil:
+		// the chunks of another version of the file are of no use
+		if _, n := binary.Varint(v); n <= 0 || !bytes.Equal(v[n:], version[:]) {
+			if err := cp.clear(); err != nil {
+				return nil, err
+			}
+		}
+	case datastore.ErrNotFound:
+	default:
+		return nil, err
+	}
+
+	v = make([]byte, binary.MaxVarintLen64)
+	v = append(v[:binary.PutVarint(v, time.Now().UnixNano())], version[:]...)
+	if err := cp.ds.Put(cp.file, v); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// expireCheckpoints deletes the checkpoints last used before the given time.
+func expireCheckpoints(ds datastore.Datastore, before time.Time) error {
+	res, err := ds.Query(query.Query{Prefix: checkpointFiles.String()})
+	if err != nil {
+		return err
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if used, n := binary.Varint(e.Value); n > 0 && !time.Unix(0, used).Before(before) {
+			continue
+		}
+		file := datastore.NewKey(e.Key)
+		cp := &checkpoint{
+			ds:   ds,
+			key:  checkpointPrefix.ChildString(file.BaseNamespace()),
+			file: file,
+		}
+		if err := cp.clear(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sum returns the CID of the next chunk, data. It comes from the checkpoint
+// when a chunk of the same size was stored at this offset, and from hash
+// otherwise.
+func (cp *checkpoint) sum(data []byte, hash func([]byte) (cid.Cid, error)) (cid.Cid, error) {
+	key := cp.key.ChildString(strconv.FormatUint(cp.offset, 10))
+	cp.offset += uint64(len(data))
+
+	if v, err := cp.ds.Get(key); err == nil {
+		size, n := binary.Uvarint(v)
+		if n > 0 && size == uint64(len(data)) {
+			c, err := cid.Cast(v[n:])
+			if err == nil {
+				if has, _ := cp.bs.Has(c); has {
+					return c, nil
+				}
+			}
+		}
+	}
+
+	c, err := hash(data)
+	if err != nil {
+		return cid.Undef, err
+	}
+	v := make([]byte, binary.MaxVarintLen64)
+	v = append(v[:binary.PutUvarint(v, uint64(len(data)))], c.Bytes()...)
+	return c, cp.ds.Put(key, v)
+}
+
+// clear removes the checkpoint, once the file has been added or when it can't
+// be used anymore.
+func (cp *checkpoint) clear() error {
+	res, err := cp.ds.Query(query.Query{Prefix: cp.key.String(), KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cp.ds.Delete(datastore.NewKey(e.Key)); err != nil {
+			return err
+		}
+	}
+	return cp.ds.Delete(cp.file)
+}
+
+// checkpointBuilder gets the CIDs of raw leaves, which are the chunks of the
+// file, from a checkpoint. Other nodes are always hashed.
+type checkpointBuilder struct {
+	cid.Builder
+	cp *checkpoint
+}
+
+func (b *checkpointBuilder) Sum(data []byte) (cid.Cid, error) {
+	if b.GetCodec() != cid.Raw {
+		return b.Builder.Sum(data)
+	}
+	return b.cp.sum(data, b.Builder.Sum)
+}
+
+func (b *checkpointBuilder) WithCodec(c uint64) cid.Builder {
+	return &checkpointBuilder{Builder: b.Builder.WithCodec(c), cp: b.cp}
+}
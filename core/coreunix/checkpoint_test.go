@@ -0,0 +1,288 @@
+package coreunix
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-ipfs/core"
+	"github.com/ipfs/go-ipfs/repo"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	syncds "github.com/ipfs/go-datastore/sync"
+	config "github.com/ipfs/go-ipfs-config"
+	files "github.com/ipfs/go-ipfs-files"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// countingBuilder counts the raw leaves with data it hashes.
+type countingBuilder struct {
+	cid.Builder
+	leaves *int
+}
+
+func (b countingBuilder) Sum(data []byte) (cid.Cid, error) {
+	if b.GetCodec() == cid.Raw && len(data) > 0 {
+		*b.leaves++
+	}
+	return b.Builder.Sum(data)
+}
+
+func (b countingBuilder) WithCodec(c uint64) cid.Builder {
+	return countingBuilder{b.Builder.WithCodec(c), b.leaves}
+}
+
+var errInterrupted = errors.New("interrupted")
+
+// failingReader fails once n bytes were read.
+type failingReader struct {
+	r io.Reader
+	n int
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.n <= 0 {
+		return 0, errInterrupted
+	}
+	if len(p) > f.n {
+		p = p[:f.n]
+	}
+	n, err := f.r.Read(p)
+	f.n -= n
+	return n, err
+}
+
+func TestAddResume(t *testing.T) {
+	r := &repo.Mock{
+		C: config.Config{
+			Identity: config.Identity{
+				PeerID: testPeerID, // required by offline node
+			},
+		},
+		D: syncds.MutexWrap(datastore.NewMapDatastore()),
+	}
+	node, err := core.NewNode(context.Background(), &core.BuildCfg{Repo: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	dir, err := ioutil.TempDir("", "add-resume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := make([]byte, 10*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	path := filepath.Join(dir, "big")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := cid.Prefix{Version: 1, Codec: cid.DagProtobuf, MhType: mh.SHA2_256, MhLength: -1}
+	add := func(reader io.Reader, checkpoints datastore.Datastore) (cid.Cid, int, error) {
+		adder, err := NewAdder(context.Background(), node.Pinning, node.Blockstore, node.DAG)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var leaves int
+		adder.Chunker = "size-1024"
+		adder.RawLeaves = true
+		adder.CidBuilder = countingBuilder{prefix, &leaves}
+		adder.Checkpoints = checkpoints
+
+		file, err := files.NewReaderPathFile(path, ioutil.NopCloser(reader), st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nd, err := adder.AddAllAndPin(file)
+		if err != nil {
+			return cid.Undef, leaves, err
+		}
+		return nd.Cid(), leaves, nil
+	}
+
+	// interrupted in the middle of the 6th chunk
+	_, leaves, err := add(&failingReader{bytes.NewReader(data), 5*1024 + 512}, r.D)
+	if err != errInterrupted {
+		t.Fatalf("expected the add to be interrupted, got %v", err)
+	}
+	if leaves != 5 {
+		t.Fatalf("expected 5 chunks to be hashed before the interruption, got %d", leaves)
+	}
+
+	resumed, leaves, err := add(bytes.NewReader(data), r.D)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaves != 5 {
+		t.Errorf("expected only the 5 remaining chunks to be hashed, got %d", leaves)
+	}
+
+	res, err := r.D.Query(query.Query{Prefix: checkpointPrefix.String(), KeysOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, err := res.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 0 {
+		t.Errorf("expected the checkpoint to be cleared, %d chunks left", len(left))
+	}
+
+	full, leaves, err := add(bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaves != 10 {
+		t.Errorf("expected all 10 chunks to be hashed without a checkpoint, got %d", leaves)
+	}
+	if !resumed.Equals(full) {
+		t.Errorf("resumed add gave %s, expected %s", resumed, full)
+	}
+}
+
+func TestAddResumeChangedFile(t *testing.T) {
+	ds := syncds.MutexWrap(datastore.NewMapDatastore())
+	r := &repo.Mock{
+		C: config.Config{
+			Identity: config.Identity{
+				PeerID: testPeerID, // required by offline node
+			},
+		},
+		D: ds,
+	}
+	node, err := core.NewNode(context.Background(), &core.BuildCfg{Repo: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	dir, err := ioutil.TempDir("", "add-resume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+
+	open := func(content []byte) *checkpoint {
+		// an unchanged file isn't written again, to keep its modification time
+		if current, _ := ioutil.ReadFile(path); !bytes.Equal(current, content) {
+			if err := ioutil.WriteFile(path, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		st, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		adder, err := NewAdder(context.Background(), node.Pinning, node.Blockstore, node.DAG)
+		if err != nil {
+			t.Fatal(err)
+		}
+		adder.CidBuilder = cid.Prefix{Version: 1, Codec: cid.Raw, MhType: mh.SHA2_256, MhLength: -1}
+		adder.Checkpoints = ds
+		file, err := files.NewReaderPathFile(path, ioutil.NopCloser(bytes.NewReader(content)), st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cp, err := adder.openCheckpoint(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cp == nil {
+			t.Fatal("expected a checkpoint for a file on disk")
+		}
+		return cp
+	}
+
+	chunks := func() int {
+		res, err := ds.Query(query.Query{Prefix: checkpointPrefix.String(), KeysOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := res.Rest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		for _, e := range entries {
+			if !checkpointFiles.IsAncestorOf(datastore.NewKey(e.Key)) {
+				n++
+			}
+		}
+		return n
+	}
+
+	first := open([]byte("some content"))
+	if _, err := first.sum([]byte("some content"), cid.Prefix{Version: 1, Codec: cid.Raw, MhType: mh.SHA2_256, MhLength: -1}.Sum); err != nil {
+		t.Fatal(err)
+	}
+	if n := chunks(); n != 1 {
+		t.Fatalf("expected 1 checkpointed chunk, got %d", n)
+	}
+
+	// the same file reopened keeps its chunks
+	open([]byte("some content"))
+	if n := chunks(); n != 1 {
+		t.Fatalf("expected the chunk to be kept, got %d", n)
+	}
+
+	second := open([]byte("other content"))
+	if !first.key.Equal(second.key) {
+		t.Error("expected one checkpoint per path")
+	}
+	if n := chunks(); n != 0 {
+		t.Errorf("expected the chunks of the previous version to be deleted, %d left", n)
+	}
+}
+
+func TestExpireCheckpoints(t *testing.T) {
+	ds := syncds.MutexWrap(datastore.NewMapDatastore())
+	now := time.Now()
+
+	put := func(key datastore.Key, v []byte) {
+		if err := ds.Put(key, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	used := func(when time.Time) []byte {
+		v := make([]byte, binary.MaxVarintLen64)
+		return v[:binary.PutVarint(v, when.UnixNano())]
+	}
+	put(checkpointFiles.ChildString("old"), used(now.Add(-2*checkpointExpiry)))
+	put(checkpointPrefix.ChildString("old").ChildString("0"), []byte("chunk"))
+	put(checkpointFiles.ChildString("recent"), used(now))
+	put(checkpointPrefix.ChildString("recent").ChildString("0"), []byte("chunk"))
+
+	if err := expireCheckpoints(ds, now.Add(-checkpointExpiry)); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range map[datastore.Key]bool{
+		checkpointFiles.ChildString("old"):                      false,
+		checkpointPrefix.ChildString("old").ChildString("0"):    false,
+		checkpointFiles.ChildString("recent"):                   true,
+		checkpointPrefix.ChildString("recent").ChildString("0"): true,
+	} {
+		if has, err := ds.Has(key); err != nil || has != expected {
+			t.Errorf("expected %s to be kept: %t, got %t (%v)", key, expected, has, err)
+		}
+	}
+}
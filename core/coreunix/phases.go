@@ -0,0 +1,108 @@
+package coreunix
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	chunker "github.com/ipfs/go-ipfs-chunker"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+type phaseTimingsKey struct{}
+
+// WithPhaseTimings returns a context under which adds record the time they
+// spend in each phase of the pipeline in t.
+func WithPhaseTimings(ctx context.Context, t *PhaseTimings) context.Context {
+	return context.WithValue(ctx, phaseTimingsKey{}, t)
+}
+
+// PhaseTimingsFrom returns the PhaseTimings adds made with ctx record to, or
+// nil.
+func PhaseTimingsFrom(ctx context.Context) *PhaseTimings {
+	t, _ := ctx.Value(phaseTimingsKey{}).(*PhaseTimings)
+	return t
+}
+
+// PhaseTimings accumulates the time adds spend chunking their input, hashing
+// blocks and writing them to the datastore. Blocks are written in parallel,
+// so the writing time is the total of the writes, which may be more than the
+// time the add took. It is safe for concurrent use.
+type PhaseTimings struct {
+	chunking, hashing, writing int64
+}
+
+// Phases is a snapshot of PhaseTimings.
+type Phases struct {
+	Chunking time.Duration
+	Hashing  time.Duration
+	Writing  time.Duration
+}
+
+// Phases returns the time spent in each phase so far.
+func (t *PhaseTimings) Phases() Phases {
+	return Phases{
+		Chunking: time.Duration(atomic.LoadInt64(&t.chunking)),
+		Hashing:  time.Duration(atomic.LoadInt64(&t.hashing)),
+		Writing:  time.Duration(atomic.LoadInt64(&t.writing)),
+	}
+}
+
+func (t *PhaseTimings) since(phase *int64, start time.Time) {
+	atomic.AddInt64(phase, int64(time.Since(start)))
+}
+
+// DAGService returns ds, recording the time spent writing to it.
+func (t *PhaseTimings) DAGService(ds ipld.DAGService) ipld.DAGService {
+	return &timedDAGService{DAGService: ds, t: t}
+}
+
+type timedDAGService struct {
+	ipld.DAGService
+	t *PhaseTimings
+}
+
+func (ds *timedDAGService) Add(ctx context.Context, nd ipld.Node) error {
+	defer ds.t.since(&ds.t.writing, time.Now())
+	return ds.DAGService.Add(ctx, nd)
+}
+
+func (ds *timedDAGService) AddMany(ctx context.Context, nds []ipld.Node) error {
+	defer ds.t.since(&ds.t.writing, time.Now())
+	return ds.DAGService.AddMany(ctx, nds)
+}
+
+// Sync flushes the underlying DAG service, if it needs to.
+func (ds *timedDAGService) Sync() error {
+	s, ok := ds.DAGService.(syncer)
+	if !ok {
+		return nil
+	}
+	defer ds.t.since(&ds.t.writing, time.Now())
+	return s.Sync()
+}
+
+type timedSplitter struct {
+	chunker.Splitter
+	t *PhaseTimings
+}
+
+func (s *timedSplitter) NextBytes() ([]byte, error) {
+	defer s.t.since(&s.t.chunking, time.Now())
+	return s.Splitter.NextBytes()
+}
+
+type timedBuilder struct {
+	cid.Builder
+	t *PhaseTimings
+}
+
+func (b *timedBuilder) Sum(data []byte) (cid.Cid, error) {
+	defer b.t.since(&b.t.hashing, time.Now())
+	return b.Builder.Sum(data)
+}
+
+func (b *timedBuilder) WithCodec(codec uint64) cid.Builder {
+	return &timedBuilder{Builder: b.Builder.WithCodec(codec), t: b.t}
+}
@@ -0,0 +1,54 @@
+package coreunix
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/ipfs/go-ipfs/core"
+	"github.com/ipfs/go-ipfs/repo"
+
+	"github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	config "github.com/ipfs/go-ipfs-config"
+	files "github.com/ipfs/go-ipfs-files"
+	dag "github.com/ipfs/go-merkledag"
+)
+
+func TestAddPhaseTimings(t *testing.T) {
+	r := &repo.Mock{
+		C: config.Config{
+			Identity: config.Identity{
+				PeerID: testPeerID, // required by offline node
+			},
+		},
+		D: syncds.MutexWrap(datastore.NewMapDatastore()),
+	}
+	node, err := core.NewNode(context.Background(), &core.BuildCfg{Repo: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	timings := new(PhaseTimings)
+	adder, err := NewAdder(context.Background(), node.Pinning, node.Blockstore, timings.DAGService(node.DAG))
+	if err != nil {
+		t.Fatal(err)
+	}
+	adder.Timings = timings
+	adder.CidBuilder = dag.V0CidPrefix()
+	adder.Chunker = "size-1024"
+
+	// a known input of 64 chunks
+	data := make([]byte, 64*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	if _, err := adder.AddAllAndPin(files.NewReaderFile(bytes.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+
+	p := timings.Phases()
+	if p.Chunking <= 0 || p.Hashing <= 0 || p.Writing <= 0 {
+		t.Fatalf("expected every phase to be timed, got %+v", p)
+	}
+}
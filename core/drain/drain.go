@@ -0,0 +1,91 @@
+// Package drain lets the node stop taking new requests while the ones in
+// flight complete, so it can be shut down without cutting them off.
+package drain
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Drainer counts in-flight requests and, once draining, refuses new ones.
+type Drainer struct {
+	mu       sync.Mutex
+	draining bool
+	inflight int
+	idle     chan struct{} // closed once draining with nothing in flight
+}
+
+// New returns a Drainer accepting requests.
+func New() *Drainer {
+	return &Drainer{idle: make(chan struct{})}
+}
+
+// Acquire registers a new request. It returns false if the Drainer is
+// draining, in which case the request must be refused. Every successful
+// Acquire must be paired with a Release.
+func (d *Drainer) Acquire() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.inflight++
+	return true
+}
+
+// Release marks a request registered with Acquire as done.
+func (d *Drainer) Release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inflight--
+	d.checkIdle()
+}
+
+// Draining reports whether new requests are being refused.
+func (d *Drainer) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// Drain stops accepting new requests and waits for the in-flight ones to
+// complete, or for ctx to be done. There is no going back from draining.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	d.draining = true
+	d.checkIdle()
+	d.mu.Unlock()
+
+	select {
+	case <-d.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Drainer) checkIdle() {
+	if !d.draining || d.inflight > 0 {
+		return
+	}
+	select {
+	case <-d.idle:
+	default:
+		close(d.idle)
+	}
+}
+
+// Handler wraps h so its requests are tracked by d. Requests arriving while
+// draining are answered with 503 Service Unavailable.
+func (d *Drainer) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !d.Acquire() {
+			w.Header().Set("Connection", "close")
+			http.Error(w, "node is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer d.Release()
+		h.ServeHTTP(w, r)
+	})
+}
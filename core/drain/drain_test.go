@@ -0,0 +1,96 @@
+package drain
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	d := New()
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	ts := httptest.NewServer(d.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-finish
+		}
+		w.Write([]byte("done"))
+	})))
+	defer ts.Close()
+
+	// start an in-flight request
+	slow := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(ts.URL + "/slow")
+		if err != nil {
+			slow <- err
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err == nil && (resp.StatusCode != http.StatusOK || string(body) != "done") {
+			t.Errorf("in-flight request: got %d %q", resp.StatusCode, body)
+		}
+		slow <- err
+	}()
+	<-started
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- d.Drain(context.Background())
+	}()
+	for !d.Draining() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// new requests are refused while draining
+	resp, err := http.Get(ts.URL + "/fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected a new request to be refused, got %d", resp.StatusCode)
+	}
+
+	select {
+	case <-drained:
+		t.Fatal("drain finished while a request was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// the in-flight request completes, and then the drain
+	close(finish)
+	if err := <-slow; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("drain never finished")
+	}
+}
+
+func TestDrainGracePeriod(t *testing.T) {
+	d := New()
+	if !d.Acquire() {
+		t.Fatal("expected to accept requests before draining")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.Drain(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the grace period to run out, got %v", err)
+	}
+	if d.Acquire() {
+		t.Fatal("expected requests to be refused after draining")
+	}
+}
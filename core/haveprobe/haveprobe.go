@@ -0,0 +1,158 @@
+// Package haveprobe asks peers over bitswap whether they have a block,
+// without fetching it, for instance to estimate how replicated it is.
+package haveprobe
+
+import (
+	"context"
+	"sync"
+
+	bsmsg "github.com/ipfs/go-bitswap/message"
+	pb "github.com/ipfs/go-bitswap/message/pb"
+	bsnet "github.com/ipfs/go-bitswap/network"
+	cid "github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+)
+
+var log = logging.Logger("core/haveprobe")
+
+// answer is what a peer answered about a block.
+type answer struct {
+	peer peer.ID
+	has  bool
+}
+
+// Prober sends want-have requests through a bitswap network, and sees the
+// answers to them before bitswap does.
+type Prober struct {
+	net bsnet.BitSwapNetwork
+
+	mu      sync.Mutex
+	waiting map[cid.Cid]map[chan answer]struct{}
+}
+
+// Wrap returns a Prober sending its requests through net, and the network
+// bitswap must use for the Prober to see the answers.
+func Wrap(net bsnet.BitSwapNetwork) (*Prober, bsnet.BitSwapNetwork) {
+	p := &Prober{
+		net:     net,
+		waiting: make(map[cid.Cid]map[chan answer]struct{}),
+	}
+	return p, &network{BitSwapNetwork: net, prober: p}
+}
+
+// Has asks peers whether they have the block c, and returns those that do,
+// in the order they answered. It returns once every peer answered or ctx
+// expires: peers that don't answer, such as those speaking a bitswap
+// version without want-have, are left out.
+func (p *Prober) Has(ctx context.Context, c cid.Cid, peers []peer.ID) []peer.ID {
+	if len(peers) == 0 {
+		return nil
+	}
+
+	answers := make(chan answer, len(peers))
+	p.mu.Lock()
+	if p.waiting[c] == nil {
+		p.waiting[c] = make(map[chan answer]struct{})
+	}
+	p.waiting[c][answers] = struct{}{}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.waiting[c], answers)
+		if len(p.waiting[c]) == 0 {
+			delete(p.waiting, c)
+		}
+		p.mu.Unlock()
+	}()
+
+	asked := make(map[peer.ID]bool, len(peers))
+	for _, pid := range peers {
+		msg := bsmsg.New(false)
+		msg.AddEntry(c, 1, pb.Message_Wantlist_Have, true)
+		if err := p.net.SendMessage(ctx, pid, msg); err != nil {
+			log.Debugf("asking %s for %s: %s", pid, c, err)
+			continue
+		}
+		asked[pid] = true
+	}
+	// the peers not answering must not keep wanting the block
+	defer func() {
+		for pid := range asked {
+			msg := bsmsg.New(false)
+			msg.Cancel(c)
+			if err := p.net.SendMessage(context.Background(), pid, msg); err != nil {
+				log.Debugf("canceling the request to %s for %s: %s", pid, c, err)
+			}
+		}
+	}()
+
+	var holders []peer.ID
+	for pending := len(asked); pending > 0; {
+		select {
+		case a := <-answers:
+			if !asked[a.peer] {
+				continue
+			}
+			pending--
+			if a.has {
+				holders = append(holders, a.peer)
+			}
+			// an answer is only counted once
+			asked[a.peer] = false
+		case <-ctx.Done():
+			return holders
+		}
+	}
+	return holders
+}
+
+// receive passes on the answers of from in msg to the requests waiting for
+// them.
+func (p *Prober) receive(from peer.ID, msg bsmsg.BitSwapMessage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.waiting) == 0 {
+		return
+	}
+
+	notify := func(c cid.Cid, has bool) {
+		for answers := range p.waiting[c] {
+			select {
+			case answers <- answer{peer: from, has: has}:
+			default:
+			}
+		}
+	}
+	for _, c := range msg.Haves() {
+		notify(c, true)
+	}
+	for _, c := range msg.DontHaves() {
+		notify(c, false)
+	}
+	// peers without want-have send the block itself
+	for _, b := range msg.Blocks() {
+		notify(b.Cid(), true)
+	}
+}
+
+// network is a bitswap network whose messages are seen by a Prober.
+type network struct {
+	bsnet.BitSwapNetwork
+	prober *Prober
+}
+
+func (n *network) SetDelegate(r bsnet.Receiver) {
+	n.BitSwapNetwork.SetDelegate(&receiver{Receiver: r, prober: n.prober})
+}
+
+// receiver shows the messages received to a Prober before bitswap.
+type receiver struct {
+	bsnet.Receiver
+	prober *Prober
+}
+
+func (r *receiver) ReceiveMessage(ctx context.Context, from peer.ID, msg bsmsg.BitSwapMessage) {
+	r.prober.receive(from, msg)
+	r.Receiver.ReceiveMessage(ctx, from, msg)
+}
@@ -0,0 +1,90 @@
+package haveprobe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bsmsg "github.com/ipfs/go-bitswap/message"
+	pb "github.com/ipfs/go-bitswap/message/pb"
+	bsnet "github.com/ipfs/go-bitswap/network"
+	blocks "github.com/ipfs/go-block-format"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/test"
+)
+
+// stubNetwork answers the want-haves sent through it, the peers in haves
+// having the block and the others not answering.
+type stubNetwork struct {
+	bsnet.BitSwapNetwork
+	receiver bsnet.Receiver
+	haves    map[peer.ID]bool
+	canceled map[peer.ID]bool
+}
+
+func (n *stubNetwork) SetDelegate(r bsnet.Receiver) {
+	n.receiver = r
+}
+
+func (n *stubNetwork) SendMessage(ctx context.Context, p peer.ID, msg bsmsg.BitSwapMessage) error {
+	for _, e := range msg.Wantlist() {
+		if e.Cancel {
+			n.canceled[p] = true
+			continue
+		}
+		if e.WantType != pb.Message_Wantlist_Have || !e.SendDontHave {
+			continue
+		}
+		if has, ok := n.haves[p]; ok {
+			reply := bsmsg.New(false)
+			if has {
+				reply.AddHave(e.Cid)
+			} else {
+				reply.AddDontHave(e.Cid)
+			}
+			go n.receiver.ReceiveMessage(ctx, p, reply)
+		}
+	}
+	return nil
+}
+
+// nopReceiver is bitswap, ignoring the messages.
+type nopReceiver struct {
+	bsnet.Receiver
+}
+
+func (r *nopReceiver) ReceiveMessage(context.Context, peer.ID, bsmsg.BitSwapMessage) {}
+
+func TestHas(t *testing.T) {
+	holder, other, silent := test.RandPeerIDFatal(t), test.RandPeerIDFatal(t), test.RandPeerIDFatal(t)
+	stub := &stubNetwork{
+		haves:    map[peer.ID]bool{holder: true, other: false},
+		canceled: make(map[peer.ID]bool),
+	}
+	prober, net := Wrap(stub)
+	net.SetDelegate(&nopReceiver{})
+
+	c := blocks.NewBlock([]byte("probed")).Cid()
+
+	// every peer answered
+	holders := prober.Has(context.Background(), c, []peer.ID{holder, other})
+	if len(holders) != 1 || holders[0] != holder {
+		t.Fatalf("expected only %s to have the block, got %v", holder, holders)
+	}
+
+	// a peer not answering holds the request until ctx expires
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	holders = prober.Has(ctx, c, []peer.ID{holder, silent})
+	if len(holders) != 1 || holders[0] != holder {
+		t.Fatalf("expected only %s to have the block, got %v", holder, holders)
+	}
+	for _, p := range []peer.ID{holder, other, silent} {
+		if !stub.canceled[p] {
+			t.Errorf("expected the request to %s to be canceled", p)
+		}
+	}
+	if len(prober.waiting) != 0 {
+		t.Fatalf("expected no request left waiting, got %v", prober.waiting)
+	}
+}
@@ -37,6 +37,10 @@ type BuildCfg struct {
 	// If NilRepo is set, a Repo backed by a nil datastore will be constructed
 	NilRepo bool
 
+	// Announce, if set, replaces the addresses to announce from the config,
+	// Addresses.Announce, without changing the config.
+	Announce []string
+
 	Routing libp2p.RoutingOption
 	Host    libp2p.HostOption
 	Repo    repo.Repo
@@ -22,7 +22,9 @@ import (
 	"github.com/libp2p/go-libp2p-core/routing"
 	"go.uber.org/fx"
 
+	"github.com/ipfs/go-ipfs/core/haveprobe"
 	"github.com/ipfs/go-ipfs/core/node/helpers"
+	"github.com/ipfs/go-ipfs/pinmeta"
 	"github.com/ipfs/go-ipfs/repo"
 )
 
@@ -62,7 +64,7 @@ func Pinning(bstore blockstore.Blockstore, ds format.DAGService, repo repo.Repo)
 		pinning = pin.NewPinner(rootDS, syncDs, syncInternalDag)
 	}
 
-	return pinning, nil
+	return pinmeta.Wrap(pinning, rootDS), nil
 }
 
 var (
@@ -89,17 +91,18 @@ func Dag(bs blockservice.BlockService) format.DAGService {
 	return merkledag.NewDAGService(bs)
 }
 
-// OnlineExchange creates new LibP2P backed block exchange (BitSwap)
+// OnlineExchange creates new LibP2P backed block exchange (BitSwap), and the
+// prober asking peers whether they have blocks over it
 func OnlineExchange(provide bool) interface{} {
-	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, host host.Host, rt routing.Routing, bs blockstore.GCBlockstore) exchange.Interface {
-		bitswapNetwork := network.NewFromIpfsHost(host, rt)
+	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, host host.Host, rt routing.Routing, bs blockstore.GCBlockstore) (exchange.Interface, *haveprobe.Prober) {
+		prober, bitswapNetwork := haveprobe.Wrap(network.NewFromIpfsHost(host, rt))
 		exch := bitswap.New(helpers.LifecycleCtx(mctx, lc), bitswapNetwork, bs, bitswap.ProvideEnabled(provide))
 		lc.Append(fx.Hook{
 			OnStop: func(ctx context.Context) error {
 				return exch.Close()
 			},
 		})
-		return exch
+		return exch, prober
 
 	}
 }
@@ -12,7 +12,9 @@ import (
 	peer "github.com/libp2p/go-libp2p-core/peer"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 
+	"github.com/ipfs/go-ipfs/core/drain"
 	"github.com/ipfs/go-ipfs/core/node/libp2p"
+	"github.com/ipfs/go-ipfs/core/reqstats"
 	"github.com/ipfs/go-ipfs/p2p"
 
 	offline "github.com/ipfs/go-ipfs-exchange-offline"
@@ -109,13 +111,20 @@ func LibP2P(bcfg *BuildCfg, cfg *config.Config) fx.Option {
 		autonat = fx.Provide(libp2p.AutoNATService(cfg.AutoNAT.Throttle))
 	}
 
+	announce := cfg.Addresses.Announce
+	if len(bcfg.Announce) > 0 {
+		announce = bcfg.Announce
+	}
+
 	// Gather all the options
 
 	opts := fx.Options(
 		BaseLibP2P,
 
 		fx.Provide(libp2p.AddrFilters(cfg.Swarm.AddrFilters)),
-		fx.Provide(libp2p.AddrsFactory(cfg.Addresses.Announce, cfg.Addresses.NoAnnounce)),
+		fx.Provide(libp2p.ConnGater),
+		fx.Provide(libp2p.AddrsFactory(announce, cfg.Addresses.NoAnnounce)),
+		fx.Provide(libp2p.NewPeerRateLimiter),
 		fx.Provide(libp2p.SmuxTransport(bcfg.getOpt("mplex"))),
 		fx.Provide(libp2p.Relay(cfg.Swarm.DisableRelay, cfg.Swarm.EnableRelayHop)),
 		fx.Invoke(libp2p.StartListening(cfg.Addresses.Swarm)),
@@ -156,7 +165,7 @@ func Storage(bcfg *BuildCfg, cfg *config.Config) fx.Option {
 	return fx.Options(
 		fx.Provide(RepoConfig),
 		fx.Provide(Datastore),
-		fx.Provide(BaseBlockstoreCtor(cacheOpts, bcfg.NilRepo, cfg.Datastore.HashOnRead)),
+		fx.Provide(BaseBlockstoreCtor(cacheOpts, bcfg.NilRepo, cfg.Datastore.HashOnRead, bcfg.getOpt("blockatime"))),
 		finalBstore,
 	)
 }
@@ -279,6 +288,8 @@ var Core = fx.Options(
 	fx.Provide(resolver.NewBasicResolver),
 	fx.Provide(Pinning),
 	fx.Provide(Files),
+	fx.Provide(drain.New),
+	fx.Provide(reqstats.New),
 )
 
 func Networked(bcfg *BuildCfg, cfg *config.Config) fx.Option {
@@ -10,18 +10,19 @@ import (
 	mamask "github.com/whyrusleeping/multiaddr-filter"
 )
 
-func AddrFilters(filters []string) func() (*ma.Filters, Libp2pOpts, error) {
-	return func() (filter *ma.Filters, opts Libp2pOpts, err error) {
-		filter = ma.NewFilters()
-		opts.Opts = append(opts.Opts, libp2p.Filters(filter)) //nolint
+// AddrFilters creates the swarm address filters, enforced by the connection
+// gater (see ConnGater).
+func AddrFilters(filters []string) func() (*ma.Filters, error) {
+	return func() (*ma.Filters, error) {
+		filter := ma.NewFilters()
 		for _, s := range filters {
 			f, err := mamask.NewMask(s)
 			if err != nil {
-				return filter, opts, fmt.Errorf("incorrectly formatted address filter in config: %s", s)
+				return filter, fmt.Errorf("incorrectly formatted address filter in config: %s", s)
 			}
-			opts.Opts = append(opts.Opts, libp2p.FilterAddresses(f)) //nolint
+			filter.AddFilter(*f, ma.ActionDeny)
 		}
-		return filter, opts, nil
+		return filter, nil
 	}
 }
 
@@ -0,0 +1,204 @@
+package libp2p
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-ipfs/repo"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/connmgr"
+	"github.com/libp2p/go-libp2p-core/control"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	mamask "github.com/whyrusleeping/multiaddr-filter"
+)
+
+// Denylist lists the peers and IP ranges the node refuses to connect with.
+type Denylist struct {
+	Peers  []peer.ID
+	Ranges []net.IPNet
+}
+
+// ParseDenylist parses a denylist file. Each line holds a peer ID, an IP
+// address, a CIDR range (e.g. 10.0.0.0/8) or a multiaddr filter (e.g.
+// /ip4/10.0.0.0/ipcidr/8). Blank lines and lines starting with '#' are
+// ignored.
+func ParseDenylist(data []byte) (*Denylist, error) {
+	dl := new(Denylist)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(entry, "/") {
+			mask, err := mamask.NewMask(entry)
+			if err != nil {
+				return nil, fmt.Errorf("denylist line %d: invalid address filter %q", line, entry)
+			}
+			dl.Ranges = append(dl.Ranges, *mask)
+			continue
+		}
+
+		if _, ipnet, err := net.ParseCIDR(entry); err == nil {
+			dl.Ranges = append(dl.Ranges, *ipnet)
+			continue
+		}
+
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			dl.Ranges = append(dl.Ranges, net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		p, err := peer.Decode(entry)
+		if err != nil {
+			return nil, fmt.Errorf("denylist line %d: %q is not a peer ID, IP address or IP range", line, entry)
+		}
+		dl.Peers = append(dl.Peers, p)
+	}
+	return dl, scanner.Err()
+}
+
+// ConnectionGater enforces the swarm address filters on outbound dials, and
+// the denylist and the simulated partitions on all connections.
+type ConnectionGater struct {
+	filters *ma.Filters
+
+	mu          sync.RWMutex
+	deniedPeers map[peer.ID]struct{}
+	deniedAddrs *ma.Filters
+	// partitions are the peers cut off, until the time they map to
+	partitions map[peer.ID]time.Time
+}
+
+var _ connmgr.ConnectionGater = (*ConnectionGater)(nil)
+
+// NewConnectionGater returns a gater enforcing filters, with an empty
+// denylist.
+func NewConnectionGater(filters *ma.Filters) *ConnectionGater {
+	g := &ConnectionGater{filters: filters, partitions: make(map[peer.ID]time.Time)}
+	g.SetDenylist(new(Denylist))
+	return g
+}
+
+// SetDenylist replaces the denylist enforced by g. Existing connections are
+// left untouched.
+func (g *ConnectionGater) SetDenylist(dl *Denylist) {
+	peers := make(map[peer.ID]struct{}, len(dl.Peers))
+	for _, p := range dl.Peers {
+		peers[p] = struct{}{}
+	}
+	addrs := ma.NewFilters()
+	for _, r := range dl.Ranges {
+		addrs.AddFilter(r, ma.ActionDeny)
+	}
+
+	g.mu.Lock()
+	g.deniedPeers = peers
+	g.deniedAddrs = addrs
+	g.mu.Unlock()
+}
+
+// PeerDenied reports whether p is on the denylist.
+func (g *ConnectionGater) PeerDenied(p peer.ID) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_, denied := g.deniedPeers[p]
+	return denied
+}
+
+// AddrDenied reports whether addr falls in a denylisted IP range.
+func (g *ConnectionGater) AddrDenied(addr ma.Multiaddr) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.deniedAddrs.AddrBlocked(addr)
+}
+
+// Partition simulates a network partition with p: the connections with p
+// are refused for d, then allowed again. Existing connections are left
+// untouched.
+func (g *ConnectionGater) Partition(p peer.ID, d time.Duration) time.Time {
+	until := time.Now().Add(d)
+	g.mu.Lock()
+	g.partitions[p] = until
+	g.mu.Unlock()
+	return until
+}
+
+// Partitioned reports whether a simulated partition with p is ongoing.
+func (g *ConnectionGater) Partitioned(p peer.ID) bool {
+	g.mu.RLock()
+	until, found := g.partitions[p]
+	g.mu.RUnlock()
+	if !found {
+		return false
+	}
+	if time.Now().Before(until) {
+		return true
+	}
+
+	g.mu.Lock()
+	if until, found := g.partitions[p]; found && !time.Now().Before(until) {
+		delete(g.partitions, p)
+	}
+	g.mu.Unlock()
+	return false
+}
+
+// peerBlocked reports whether the connections with p are refused.
+func (g *ConnectionGater) peerBlocked(p peer.ID) bool {
+	return g.PeerDenied(p) || g.Partitioned(p)
+}
+
+func (g *ConnectionGater) InterceptPeerDial(p peer.ID) (allow bool) {
+	return !g.peerBlocked(p)
+}
+
+func (g *ConnectionGater) InterceptAddrDial(p peer.ID, addr ma.Multiaddr) (allow bool) {
+	return !g.filters.AddrBlocked(addr) && !g.peerBlocked(p) && !g.AddrDenied(addr)
+}
+
+func (g *ConnectionGater) InterceptAccept(addrs network.ConnMultiaddrs) (allow bool) {
+	return !g.AddrDenied(addrs.RemoteMultiaddr())
+}
+
+func (g *ConnectionGater) InterceptSecured(_ network.Direction, p peer.ID, addrs network.ConnMultiaddrs) (allow bool) {
+	return !g.peerBlocked(p) && !g.AddrDenied(addrs.RemoteMultiaddr())
+}
+
+func (g *ConnectionGater) InterceptUpgraded(network.Conn) (allow bool, reason control.DisconnectReason) {
+	return true, 0
+}
+
+// ConnGater creates the connection gater, loading the denylist from the
+// repo.
+func ConnGater(filters *ma.Filters, r repo.Repo) (*ConnectionGater, Libp2pOpts, error) {
+	var opts Libp2pOpts
+	g := NewConnectionGater(filters)
+
+	data, err := r.Denylist()
+	if err != nil {
+		return nil, opts, err
+	}
+	dl, err := ParseDenylist(data)
+	if err != nil {
+		return nil, opts, err
+	}
+	g.SetDenylist(dl)
+
+	opts.Opts = append(opts.Opts, libp2p.ConnectionGater(g))
+	return g, opts, nil
+}
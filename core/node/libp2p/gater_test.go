@@ -0,0 +1,139 @@
+package libp2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestParseDenylist(t *testing.T) {
+	dl, err := ParseDenylist([]byte(`
+# abusive peers
+QmSoLPppuBtQSGwKDZT2M73ULpjvfd3aZ6ha4oFGL1KrGM
+
+10.0.0.0/8
+192.168.1.7
+/ip4/172.16.0.0/ipcidr/12
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dl.Peers) != 1 || dl.Peers[0].Pretty() != "QmSoLPppuBtQSGwKDZT2M73ULpjvfd3aZ6ha4oFGL1KrGM" {
+		t.Fatalf("unexpected peers %v", dl.Peers)
+	}
+	if len(dl.Ranges) != 3 {
+		t.Fatalf("expected 3 ranges, got %d", len(dl.Ranges))
+	}
+
+	g := NewConnectionGater(ma.NewFilters())
+	g.SetDenylist(dl)
+	for addr, denied := range map[string]bool{
+		"/ip4/10.1.2.3/tcp/4001":       true,
+		"/ip4/192.168.1.7/tcp/4001":    true,
+		"/ip4/192.168.1.8/tcp/4001":    false,
+		"/ip4/172.20.0.1/udp/4001":     true,
+		"/ip4/8.8.8.8/tcp/4001":        false,
+		"/dns4/example.com/tcp/4001":   false,
+		"/ip6/::1/tcp/4001":            false,
+		"/ip4/172.15.255.255/tcp/4001": false,
+	} {
+		if got := g.AddrDenied(ma.StringCast(addr)); got != denied {
+			t.Errorf("%s: expected denied=%t, got %t", addr, denied, got)
+		}
+	}
+
+	if _, err := ParseDenylist([]byte("not-a-peer")); err == nil {
+		t.Fatal("expected an error for an invalid entry")
+	}
+}
+
+func newGatedHost(t *testing.T, g *ConnectionGater) host.Host {
+	opts := []libp2p.Option{libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0")}
+	if g != nil {
+		opts = append(opts, libp2p.ConnectionGater(g))
+	}
+	h, err := libp2p.New(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestDenylistedPeerRejected(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	g := NewConnectionGater(ma.NewFilters())
+	gated := newGatedHost(t, g)
+	defer gated.Close()
+	other := newGatedHost(t, nil)
+	defer other.Close()
+
+	g.SetDenylist(&Denylist{Peers: []peer.ID{other.ID()}})
+
+	// dial
+	if err := gated.Connect(ctx, peer.AddrInfo{ID: other.ID(), Addrs: other.Addrs()}); err == nil {
+		t.Fatal("expected the dial to a denylisted peer to fail")
+	}
+
+	// accept
+	if err := other.Connect(ctx, peer.AddrInfo{ID: gated.ID(), Addrs: gated.Addrs()}); err == nil {
+		// The dialer may consider the connection established before the
+		// gated side refuses it, make sure it did not survive.
+		time.Sleep(100 * time.Millisecond)
+		if len(gated.Network().ConnsToPeer(other.ID())) != 0 {
+			t.Fatal("expected the connection from a denylisted peer to be refused")
+		}
+	}
+
+	// peers that are not denylisted are unaffected
+	allowed := newGatedHost(t, nil)
+	defer allowed.Close()
+	if err := allowed.Connect(ctx, peer.AddrInfo{ID: gated.ID(), Addrs: gated.Addrs()}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPartitionedPeerUnreachable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	g := NewConnectionGater(ma.NewFilters())
+	gated := newGatedHost(t, g)
+	defer gated.Close()
+	other := newGatedHost(t, nil)
+	defer other.Close()
+	otherInfo := peer.AddrInfo{ID: other.ID(), Addrs: other.Addrs()}
+
+	g.Partition(other.ID(), 500*time.Millisecond)
+	if !g.Partitioned(other.ID()) {
+		t.Fatal("expected the peer to be partitioned")
+	}
+
+	// dial
+	if err := gated.Connect(ctx, otherInfo); err == nil {
+		t.Fatal("expected the dial to a partitioned peer to fail")
+	}
+
+	// accept
+	if err := other.Connect(ctx, peer.AddrInfo{ID: gated.ID(), Addrs: gated.Addrs()}); err == nil {
+		time.Sleep(100 * time.Millisecond)
+		if len(gated.Network().ConnsToPeer(other.ID())) != 0 {
+			t.Fatal("expected the connection from a partitioned peer to be refused")
+		}
+	}
+
+	// the peer is reachable again once the partition ends
+	time.Sleep(500 * time.Millisecond)
+	if g.Partitioned(other.ID()) {
+		t.Fatal("expected the partition to have ended")
+	}
+	if err := gated.Connect(ctx, otherInfo); err != nil {
+		t.Fatalf("expected the peer to be reachable after the partition, got %s", err)
+	}
+}
@@ -0,0 +1,178 @@
+package libp2p
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	smux "github.com/libp2p/go-libp2p-core/mux"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// PeerLimit is the bandwidth allowed with a peer, in bytes per second, over
+// all the connections with it. Zero is unlimited.
+type PeerLimit struct {
+	Up   int64
+	Down int64
+}
+
+// PeerRateLimiter limits the bandwidth used with some peers. The limits are
+// enforced on the connections muxed by the muxers it wraps, see
+// SmuxTransport: QUIC connections, muxed by QUIC itself, aren't limited.
+type PeerRateLimiter struct {
+	mu    sync.Mutex
+	peers map[peer.ID]*peerBuckets
+}
+
+type peerBuckets struct {
+	limit    PeerLimit
+	up, down *tokenBucket
+}
+
+// NewPeerRateLimiter returns a PeerRateLimiter without limits.
+func NewPeerRateLimiter() *PeerRateLimiter {
+	return &PeerRateLimiter{peers: make(map[peer.ID]*peerBuckets)}
+}
+
+// SetLimit limits the bandwidth with p, including on the connections
+// already open. A limit without rates removes the limit of p.
+func (rl *PeerRateLimiter) SetLimit(p peer.ID, limit PeerLimit) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if limit.Up <= 0 && limit.Down <= 0 {
+		delete(rl.peers, p)
+		return
+	}
+	rl.peers[p] = &peerBuckets{
+		limit: limit,
+		up:    newTokenBucket(limit.Up),
+		down:  newTokenBucket(limit.Down),
+	}
+}
+
+// Limits returns the limited peers with their limit.
+func (rl *PeerRateLimiter) Limits() map[peer.ID]PeerLimit {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	limits := make(map[peer.ID]PeerLimit, len(rl.peers))
+	for p, b := range rl.peers {
+		limits[p] = b.limit
+	}
+	return limits
+}
+
+// buckets returns the buckets of p, nil if p isn't limited.
+func (rl *PeerRateLimiter) buckets(p peer.ID) *peerBuckets {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.peers[p]
+}
+
+// WrapMuxer returns m enforcing the limits of rl on the connections it
+// muxes, m itself if rl is nil.
+func (rl *PeerRateLimiter) WrapMuxer(m smux.Multiplexer) smux.Multiplexer {
+	if rl == nil {
+		return m
+	}
+	return &limitedMuxer{Multiplexer: m, rl: rl}
+}
+
+type limitedMuxer struct {
+	smux.Multiplexer
+	rl *PeerRateLimiter
+}
+
+func (m *limitedMuxer) NewConn(c net.Conn, isServer bool) (smux.MuxedConn, error) {
+	// the muxers are handed secured connections, which know their peer
+	if sc, ok := c.(interface{ RemotePeer() peer.ID }); ok {
+		c = &limitedConn{Conn: c, rl: m.rl, peer: sc.RemotePeer()}
+	}
+	return m.Multiplexer.NewConn(c, isServer)
+}
+
+// limitedConn is a connection with a peer, limited to the bandwidth set for
+// the peer when reading or writing.
+type limitedConn struct {
+	net.Conn
+	rl   *PeerRateLimiter
+	peer peer.ID
+}
+
+func (c *limitedConn) Read(b []byte) (int, error) {
+	pb := c.rl.buckets(c.peer)
+	if pb == nil || pb.down == nil {
+		return c.Conn.Read(b)
+	}
+	if len(b) > pb.down.burst {
+		b = b[:pb.down.burst]
+	}
+	n, err := c.Conn.Read(b)
+	pb.down.take(n)
+	return n, err
+}
+
+func (c *limitedConn) Write(b []byte) (int, error) {
+	pb := c.rl.buckets(c.peer)
+	if pb == nil || pb.up == nil {
+		return c.Conn.Write(b)
+	}
+	var written int
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > pb.up.burst {
+			chunk = chunk[:pb.up.burst]
+		}
+		pb.up.take(len(chunk))
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// tokenBucket lets rate bytes through per second, with bursts of a tenth of
+// a second.
+type tokenBucket struct {
+	rate  float64
+	burst int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a bucket for rate bytes per second, nil if rate is
+// unlimited.
+func newTokenBucket(rate int64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	burst := int(rate / 10)
+	if burst < 1024 {
+		burst = 1024
+	}
+	return &tokenBucket{rate: float64(rate), burst: burst, tokens: float64(burst), last: time.Now()}
+}
+
+// take takes n tokens, waiting for the bucket to have paid them back when
+// it goes in debt.
+func (b *tokenBucket) take(n int) {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	time.Sleep(wait)
+}
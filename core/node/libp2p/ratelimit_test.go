@@ -0,0 +1,108 @@
+package libp2p
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+)
+
+const testRateProto = protocol.ID("/test/ratelimit")
+
+func newRateLimitedHost(t *testing.T, rl *PeerRateLimiter) host.Host {
+	h, err := libp2p.New(context.Background(),
+		libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+		makeSmuxTransportOption(false, rl),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestPeerRateLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rl := NewPeerRateLimiter()
+	limited := newRateLimitedHost(t, rl)
+	defer limited.Close()
+
+	// time to send size bytes to each peer
+	const size = 256 << 10
+	send := func(to host.Host) time.Duration {
+		done := make(chan int64, 1)
+		to.SetStreamHandler(testRateProto, func(s network.Stream) {
+			n, _ := io.Copy(ioutil.Discard, s)
+			s.Close()
+			done <- n
+		})
+		if err := limited.Connect(ctx, peer.AddrInfo{ID: to.ID(), Addrs: to.Addrs()}); err != nil {
+			t.Fatal(err)
+		}
+		s, err := limited.NewStream(ctx, to.ID(), testRateProto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		if _, err := s.Write(make([]byte, size)); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if n := <-done; n != size {
+			t.Fatalf("expected %d bytes to be received, got %d", size, n)
+		}
+		return time.Since(start)
+	}
+
+	slow := newRateLimitedHost(t, nil)
+	defer slow.Close()
+	fast := newRateLimitedHost(t, nil)
+	defer fast.Close()
+
+	const rate = 512 << 10
+	rl.SetLimit(slow.ID(), PeerLimit{Up: rate})
+	if limits := rl.Limits(); len(limits) != 1 || limits[slow.ID()].Up != rate {
+		t.Fatalf("unexpected limits %v", limits)
+	}
+
+	// the first burst goes through at once, the rest at the rate
+	if d, min := send(slow), time.Duration(size-rate/10)*time.Second/rate; d < min {
+		t.Fatalf("expected sending %d bytes at %d B/s to take at least %s, took %s", size, rate, min, d)
+	}
+	if d := send(fast); d > 200*time.Millisecond {
+		t.Fatalf("expected the peers without limit to be unaffected, took %s", d)
+	}
+
+	rl.SetLimit(slow.ID(), PeerLimit{})
+	if limits := rl.Limits(); len(limits) != 0 {
+		t.Fatalf("expected the limit to be removed, got %v", limits)
+	}
+	if d := send(slow); d > 200*time.Millisecond {
+		t.Fatalf("expected the limit to be lifted, took %s", d)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	if newTokenBucket(0) != nil {
+		t.Fatal("expected no bucket without rate")
+	}
+
+	b := newTokenBucket(100 << 10)
+	start := time.Now()
+	// a burst, then 20KB at 100KB/s
+	b.take(b.burst)
+	b.take(20 << 10)
+	if d := time.Since(start); d < 150*time.Millisecond || d > time.Second {
+		t.Fatalf("expected to wait about 200ms, waited %s", d)
+	}
+}
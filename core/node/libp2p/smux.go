@@ -10,7 +10,7 @@ import (
 	yamux "github.com/libp2p/go-libp2p-yamux"
 )
 
-func makeSmuxTransportOption(mplexExp bool) libp2p.Option {
+func makeSmuxTransportOption(mplexExp bool, rl *PeerRateLimiter) libp2p.Option {
 	const yamuxID = "/yamux/1.0.0"
 	const mplexID = "/mplex/6.7.0"
 
@@ -40,15 +40,17 @@ func makeSmuxTransportOption(mplexExp bool) libp2p.Option {
 			continue
 		}
 		delete(muxers, id)
-		opts = append(opts, libp2p.Muxer(id, tpt))
+		opts = append(opts, libp2p.Muxer(id, rl.WrapMuxer(tpt)))
 	}
 
 	return libp2p.ChainOptions(opts...)
 }
 
-func SmuxTransport(mplex bool) func() (opts Libp2pOpts, err error) {
-	return func() (opts Libp2pOpts, err error) {
-		opts.Opts = append(opts.Opts, makeSmuxTransportOption(mplex))
+// SmuxTransport sets up the stream muxers, enforcing the per-peer bandwidth
+// limits of rl.
+func SmuxTransport(mplex bool) func(rl *PeerRateLimiter) (opts Libp2pOpts, err error) {
+	return func(rl *PeerRateLimiter) (opts Libp2pOpts, err error) {
+		opts.Opts = append(opts.Opts, makeSmuxTransportOption(mplex, rl))
 		return
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-ipfs-pinner"
 	"github.com/ipfs/go-ipfs-provider"
 	q "github.com/ipfs/go-ipfs-provider/queue"
@@ -13,28 +14,39 @@ import (
 	"github.com/libp2p/go-libp2p-core/routing"
 	"go.uber.org/fx"
 
+	"github.com/ipfs/go-ipfs/announcelog"
 	"github.com/ipfs/go-ipfs/core/node/helpers"
 	"github.com/ipfs/go-ipfs/repo"
+	"github.com/ipfs/go-ipfs/reprovidestats"
 )
 
 const kReprovideFrequency = time.Hour * 12
 
+const providerQueueName = "provider-v1"
+
 // SIMPLE
 
 // ProviderQueue creates new datastore backed provider queue
 func ProviderQueue(mctx helpers.MetricsCtx, lc fx.Lifecycle, repo repo.Repo) (*q.Queue, error) {
-	return q.NewQueue(helpers.LifecycleCtx(mctx, lc), "provider-v1", repo.Datastore())
+	return q.NewQueue(helpers.LifecycleCtx(mctx, lc), providerQueueName, repo.Datastore())
+}
+
+// ReprovideStats creates the tracker of reprovide cycles, reporting the depth
+// of the provider queue
+func ReprovideStats(repo repo.Repo) *reprovidestats.Tracker {
+	prefix := datastore.NewKey("/" + providerQueueName + "/queue")
+	return reprovidestats.New(reprovidestats.DatastoreQueueLen(repo.Datastore(), prefix))
 }
 
 // SimpleProvider creates new record provider
-func SimpleProvider(mctx helpers.MetricsCtx, lc fx.Lifecycle, queue *q.Queue, rt routing.Routing) provider.Provider {
-	return simple.NewProvider(helpers.LifecycleCtx(mctx, lc), queue, rt)
+func SimpleProvider(mctx helpers.MetricsCtx, lc fx.Lifecycle, queue *q.Queue, rt routing.Routing, alog *announcelog.Log) provider.Provider {
+	return simple.NewProvider(helpers.LifecycleCtx(mctx, lc), queue, alog.Router(rt))
 }
 
 // SimpleReprovider creates new reprovider
 func SimpleReprovider(reproviderInterval time.Duration) interface{} {
-	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, rt routing.Routing, keyProvider simple.KeyChanFunc) (provider.Reprovider, error) {
-		return simple.NewReprovider(helpers.LifecycleCtx(mctx, lc), reproviderInterval, rt, keyProvider), nil
+	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, rt routing.Routing, keyProvider simple.KeyChanFunc, alog *announcelog.Log, stats *reprovidestats.Tracker) (provider.Reprovider, error) {
+		return simple.NewReprovider(helpers.LifecycleCtx(mctx, lc), reproviderInterval, alog.Router(rt), stats.KeyProvider(keyProvider)), nil
 	}
 }
 
@@ -112,6 +124,8 @@ func SimpleProviders(reprovideStrategy string, reprovideInterval string) fx.Opti
 	}
 
 	return fx.Options(
+		fx.Provide(announcelog.New),
+		fx.Provide(ReprovideStats),
 		fx.Provide(ProviderQueue),
 		fx.Provide(SimpleProvider),
 		keyProvider,
@@ -9,6 +9,7 @@ import (
 	"github.com/ipfs/go-filestore"
 	"github.com/ipfs/go-ipfs/core/node/helpers"
 	"github.com/ipfs/go-ipfs/repo"
+	"github.com/ipfs/go-ipfs/thirdparty/atimebs"
 	"github.com/ipfs/go-ipfs/thirdparty/cidv0v1"
 	"github.com/ipfs/go-ipfs/thirdparty/verifbs"
 )
@@ -26,8 +27,9 @@ func Datastore(repo repo.Repo) datastore.Datastore {
 // BaseBlocks is the lower level blockstore without GC or Filestore layers
 type BaseBlocks blockstore.Blockstore
 
-// BaseBlockstoreCtor creates cached blockstore backed by the provided datastore
-func BaseBlockstoreCtor(cacheOpts blockstore.CacheOpts, nilRepo bool, hashOnRead bool) func(mctx helpers.MetricsCtx, repo repo.Repo, lc fx.Lifecycle) (bs BaseBlocks, err error) {
+// BaseBlockstoreCtor creates cached blockstore backed by the provided datastore,
+// recording the access times of the blocks if trackAccess is set
+func BaseBlockstoreCtor(cacheOpts blockstore.CacheOpts, nilRepo bool, hashOnRead bool, trackAccess bool) func(mctx helpers.MetricsCtx, repo repo.Repo, lc fx.Lifecycle) (bs BaseBlocks, err error) {
 	return func(mctx helpers.MetricsCtx, repo repo.Repo, lc fx.Lifecycle) (bs BaseBlocks, err error) {
 		// hash security
 		bs = blockstore.NewBlockstore(repo.Datastore())
@@ -40,6 +42,10 @@ func BaseBlockstoreCtor(cacheOpts blockstore.CacheOpts, nilRepo bool, hashOnRead
 			}
 		}
 
+		if trackAccess {
+			bs = atimebs.New(bs, repo.Datastore())
+		}
+
 		bs = blockstore.NewIdStore(bs)
 		bs = cidv0v1.NewBlockstore(bs)
 
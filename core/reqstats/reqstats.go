@@ -0,0 +1,80 @@
+// Package reqstats counts the requests for content by CID, to find out the
+// most requested content, for instance to keep it cached.
+package reqstats
+
+import (
+	"sort"
+	"sync"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// DefaultMaxTracked is the number of CIDs a Tracker counts requests for.
+const DefaultMaxTracked = 10000
+
+// Entry is the number of requests for a CID.
+type Entry struct {
+	Cid      cid.Cid
+	Requests uint64
+}
+
+// Tracker counts the requests by CID. Once it tracks too many CIDs, all the
+// counts are halved and the CIDs left without requests are dropped, so the
+// counts favor the recent requests.
+type Tracker struct {
+	mu         sync.Mutex
+	counts     map[cid.Cid]uint64
+	maxTracked int
+}
+
+// New returns a Tracker counting requests for up to DefaultMaxTracked CIDs.
+func New() *Tracker {
+	return NewWithMax(DefaultMaxTracked)
+}
+
+// NewWithMax returns a Tracker counting requests for up to max CIDs.
+func NewWithMax(max int) *Tracker {
+	return &Tracker{
+		counts:     make(map[cid.Cid]uint64),
+		maxTracked: max,
+	}
+}
+
+// Record counts a request for c.
+func (t *Tracker) Record(c cid.Cid) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, found := t.counts[c]; !found && len(t.counts) >= t.maxTracked {
+		for k, n := range t.counts {
+			if n /= 2; n == 0 {
+				delete(t.counts, k)
+			} else {
+				t.counts[k] = n
+			}
+		}
+	}
+	t.counts[c]++
+}
+
+// Top returns the n most requested CIDs, the most requested first. CIDs with
+// as many requests are sorted by CID.
+func (t *Tracker) Top(n int) []Entry {
+	t.mu.Lock()
+	entries := make([]Entry, 0, len(t.counts))
+	for c, requests := range t.counts {
+		entries = append(entries, Entry{Cid: c, Requests: requests})
+	}
+	t.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Requests != entries[j].Requests {
+			return entries[i].Requests > entries[j].Requests
+		}
+		return entries[i].Cid.KeyString() < entries[j].Cid.KeyString()
+	})
+	if n >= 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
@@ -0,0 +1,51 @@
+package reqstats
+
+import (
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	u "github.com/ipfs/go-ipfs-util"
+)
+
+func testCid(s string) cid.Cid {
+	return cid.NewCidV0(u.Hash([]byte(s)))
+}
+
+func TestTop(t *testing.T) {
+	tr := New()
+	a, b, c := testCid("a"), testCid("b"), testCid("c")
+	for i := 0; i < 3; i++ {
+		tr.Record(b)
+	}
+	tr.Record(a)
+	tr.Record(c)
+	tr.Record(a)
+
+	top := tr.Top(2)
+	if len(top) != 2 {
+		t.Fatalf("expected 2 entries, got %v", top)
+	}
+	if top[0].Cid != b || top[0].Requests != 3 || top[1].Cid != a || top[1].Requests != 2 {
+		t.Fatalf("expected b then a, got %v", top)
+	}
+	if all := tr.Top(10); len(all) != 3 || all[2].Cid != c {
+		t.Fatalf("expected the 3 CIDs, got %v", all)
+	}
+}
+
+func TestMaxTracked(t *testing.T) {
+	tr := NewWithMax(3)
+	a, b, c, d := testCid("a"), testCid("b"), testCid("c"), testCid("d")
+	for i := 0; i < 4; i++ {
+		tr.Record(a)
+	}
+	tr.Record(b)
+	tr.Record(c)
+
+	// tracking d halves the counts, dropping b and c
+	tr.Record(d)
+	top := tr.Top(-1)
+	if len(top) != 2 || top[0].Cid != a || top[0].Requests != 2 || top[1].Cid != d || top[1].Requests != 1 {
+		t.Fatalf("expected a with 2 requests and d with 1, got %v", top)
+	}
+}
@@ -0,0 +1,120 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// apiProbeTimeout bounds how long we try to connect to each of several API
+// endpoints before failing over to the next one.
+const apiProbeTimeout = 5 * time.Second
+
+// defaultAPIResolveTimeout bounds how long resolving an API endpoint and
+// trying the addresses it resolved to takes altogether, unless set with
+// --api-resolve-timeout.
+const defaultAPIResolveTimeout = 10 * time.Second
+
+// parseAPIAddrs parses the value of --api, a comma-separated list of API
+// endpoints.
+func parseAPIAddrs(s string) ([]ma.Multiaddr, error) {
+	var addrs []ma.Multiaddr
+	for _, a := range strings.Split(s, ",") {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		addr, err := ma.NewMultiaddr(a)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no API endpoint in %q", s)
+	}
+	return addrs, nil
+}
+
+// apiResolveTimeoutOption returns the duration given with
+// --api-resolve-timeout, defaultAPIResolveTimeout when the option isn't set.
+func apiResolveTimeoutOption(req *cmds.Request) (time.Duration, error) {
+	s, ok := req.Options[corecmds.ApiResolveTimeoutOption].(string)
+	if !ok {
+		return defaultAPIResolveTimeout, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s: %s", corecmds.ApiResolveTimeoutOption, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("--%s must be positive", corecmds.ApiResolveTimeoutOption)
+	}
+	return d, nil
+}
+
+// selectAPIAddr resolves the API endpoints in order and returns the first
+// one accepting connections, with its resolved address. A single endpoint
+// resolving to a single address is returned without being probed, the
+// request itself reports if it's down. Resolving each endpoint and trying its
+// addresses takes at most resolveTimeout.
+func selectAPIAddr(ctx context.Context, addrs []ma.Multiaddr, resolveTimeout time.Duration) (addr, resolved ma.Multiaddr, err error) {
+	if len(addrs) == 1 {
+		resolved, err := resolveAPIAddr(ctx, addrs[0], false, resolveTimeout)
+		return addrs[0], resolved, err
+	}
+
+	errs := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		resolved, err := resolveAPIAddr(ctx, addr, true, resolveTimeout)
+		if err == nil {
+			return addr, resolved, nil
+		}
+		log.Debugf("API endpoint %s unavailable: %s", addr, err)
+		errs = append(errs, fmt.Sprintf("%s: %s", addr, err))
+	}
+	return nil, nil, fmt.Errorf("no API endpoint available (%s)", strings.Join(errs, "; "))
+}
+
+// resolveAPIAddr resolves addr and returns the first of the addresses it
+// resolved to accepting connections, in order, or the last error if none
+// does. A single address is returned without being probed unless probe is
+// set. It gives up after timeout.
+func resolveAPIAddr(ctx context.Context, addr ma.Multiaddr, probe bool, timeout time.Duration) (ma.Multiaddr, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resolved, err := resolveAddr(ctx, addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(resolved) == 1 && !probe {
+		return resolved[0], nil
+	}
+	for _, r := range resolved {
+		if err = probeAPIAddr(ctx, r); err == nil {
+			return r, nil
+		}
+		log.Debugf("API address %s unavailable: %s", r, err)
+	}
+	return nil, err
+}
+
+func probeAPIAddr(ctx context.Context, addr ma.Multiaddr) error {
+	ctx, cancel := context.WithTimeout(ctx, apiProbeTimeout)
+	defer cancel()
+
+	var d manet.Dialer
+	conn, err := d.DialContext(ctx, addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
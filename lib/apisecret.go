@@ -0,0 +1,33 @@
+package lib
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/ipfs/go-ipfs/core/corehttp"
+)
+
+// secretTransport sets the API secret on every request.
+type secretTransport struct {
+	secret string
+	next   http.RoundTripper // http.DefaultTransport if nil
+}
+
+func (t *secretTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	// RoundTrippers must not modify the request they are given.
+	req = req.Clone(req.Context())
+	req.Header.Set(corehttp.APISecretHeader, t.secret)
+	return next.RoundTrip(req)
+}
+
+// apiSecret returns the secret to send to an API requiring one, see
+// corehttp.APISecretOption, given with $IPFS_API_SECRET, or the variable of
+// the same name under EnvPrefix, "" for none.
+func apiSecret() string {
+	return os.Getenv(envVar("API_SECRET"))
+}
@@ -0,0 +1,39 @@
+// +build !windows
+
+package lib
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkAPISocket checks that the API unix socket at path can be connected
+// to, to report why not rather than the dial error. A socket that doesn't
+// exist is left to the dial, the daemon may not be running.
+func checkAPISocket(path string) error {
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("api socket at %s is not accessible: %s", path, underlyingError(err))
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("api socket at %s is not a socket", path)
+	}
+	// connecting requires write permission on the socket
+	if err := unix.Access(path, unix.W_OK); err != nil {
+		return fmt.Errorf("api socket at %s is not accessible: %s", path, err)
+	}
+	return nil
+}
+
+// underlyingError returns the error of the system call behind err.
+func underlyingError(err error) error {
+	if pe, ok := err.(*os.PathError); ok {
+		return pe.Err
+	}
+	return err
+}
@@ -1,13 +1,19 @@
 package lib
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"net"
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
 	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +31,7 @@ import (
 	"github.com/ipfs/go-ipfs-config"
 	u "github.com/ipfs/go-ipfs-util"
 	logging "github.com/ipfs/go-log"
+	homedir "github.com/mitchellh/go-homedir"
 	ma "github.com/multiformats/go-multiaddr"
 	madns "github.com/multiformats/go-multiaddr-dns"
 	manet "github.com/multiformats/go-multiaddr-net"
@@ -37,11 +44,36 @@ var log = logging.Logger("ipfs/lib")
 var dnsResolver = madns.DefaultResolver
 
 const (
+	// Deprecated: IPFS_PROF is read with the EnvPrefix of the embedder.
 	EnvEnableProfiling = "IPFS_PROF"
-	cpuProfile         = "ipfs.cpuprof"
-	heapProfile        = "ipfs.memprof"
+	// Deprecated: IPFS_API is read with the EnvPrefix of the embedder.
+	EnvAPIAddr = "IPFS_API"
+	// Deprecated: IPFS_PLUGIN_TIMEOUT is read with the EnvPrefix of the
+	// embedder.
+	EnvPluginTimeout = "IPFS_PLUGIN_TIMEOUT"
+	// extensions of the profile files
+	cpuProfile       = "cpuprof"
+	heapProfile      = "memprof"
+	mutexProfile     = "mutexprof"
+	blockProfile     = "blockprof"
+	goroutineProfile = "goroutineprof"
+	executionTrace   = "trace"
 )
 
+// profileTime returns the time profiling starts at, named in the profile
+// files. Declared as a var for testing purposes.
+var profileTime = time.Now
+
+// EnvPrefix is the prefix of the environment variables read by this package,
+// e.g. IPFS_PATH, IPFS_LOGGING and IPFS_PROF. Embedders shipping ipfs under
+// another name can set it to read MYAPP_PATH and so on instead.
+var EnvPrefix = "IPFS"
+
+// envVar returns the name of the prefixed environment variable for name.
+func envVar(name string) string {
+	return EnvPrefix + "_" + name
+}
+
 var (
 	ErrNormalExit = errors.New("Normal exit")
 )
@@ -50,39 +82,79 @@ var (
 	daemonCommand = []string{"ipfs", "daemon", "--init"}
 )
 
-func loadPlugins(repoPath string) (*loader.PluginLoader, error) {
+// loadPlugins loads, initializes and injects the plugins of the repo at
+// repoPath, but the ones named in disabled.
+func loadPlugins(repoPath string, disabled []string) (*loader.PluginLoader, error) {
 	plugins, err := loader.NewPluginLoader(repoPath)
 	if err != nil {
 		return nil, fmt.Errorf("error loading plugins: %s", err)
 	}
 
-	if err := plugins.Initialize(); err != nil {
-		return nil, fmt.Errorf("error initializing plugins: %s", err)
+	unknown, err := plugins.Disable(disabled...)
+	if err != nil {
+		return nil, err
+	}
+	// a typo mustn't keep the daemon from starting
+	for _, name := range unknown {
+		fmt.Fprintf(os.Stderr, "Warning: --%s=%s matches no plugin, ignoring it\n", corecmds.DisablePluginOption, name)
 	}
 
-	if err := plugins.Inject(); err != nil {
-		return nil, fmt.Errorf("error initializing plugins: %s", err)
+	timeout, err := pluginTimeout()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, pl := range plugins.Plugins() {
+		names = append(names, pl.Name)
+	}
+	err = initPlugins(names, timeout, func() error {
+		if err := plugins.Initialize(); err != nil {
+			return fmt.Errorf("error initializing plugins: %s", pluginError(plugins, err))
+		}
+
+		if err := plugins.Inject(); err != nil {
+			return fmt.Errorf("error initializing plugins: %s", pluginError(plugins, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return plugins, nil
 }
 
+// command runs the command line args, sending ErrNormalExit on errCh when
+// it succeeds. The errors of cli.Run are sent as a *CommandError, with the
+// exit code of their class of failure.
 func command(ctx context.Context, args []string, envCh chan<- *oldcmds.Context, errCh chan<- error) {
 	var err error
 
 	// we'll call this local helper to output errors.
 	// this is so we control how to print errors in one place.
+	errFormat, formatErr := errorFormat(args)
 	printErr := func(err error) {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		writeError(os.Stderr, errFormat, err)
+	}
+	if formatErr != nil {
+		printErr(formatErr)
+		envCh <- nil
+		errCh <- formatErr
+		return
 	}
 
-	stopFunc, err := profileIfEnabled()
+	stopFunc, err := profileIfEnabled(args)
 	if err != nil {
 		printErr(err)
 		envCh <- nil
 		errCh <- err
 		return
 	}
-	defer stopFunc() // to be executed as late as possible
+	// to be executed as late as possible
+	defer func() {
+		if err := stopFunc(); err != nil {
+			log.Error(err)
+		}
+	}()
 
 	// Handle `ipfs version` or `ipfs help`
 	if len(args) > 1 {
@@ -110,8 +182,19 @@ func command(ctx context.Context, args []string, envCh chan<- *oldcmds.Context,
 	// so we need to make sure it's stable
 	args[0] = "ipfs"
 
+	// stopHeapDumps is set once the command is known to be the daemon, see
+	// buildEnv
+	stopHeapDumps := func() {}
+	defer func() { stopHeapDumps() }()
+
+	// cli.Run only builds the environment once the command line is parsed
+	var parsed bool
 	buildEnv := func(ctx context.Context, req *cmds.Request) (cmds.Environment, error) {
+		parsed = true
 		checkDebug(req)
+		if req.Command == daemonCmd {
+			stopHeapDumps = heapDumpOnSignal()
+		}
 		repoPath, err := getRepoPath(req)
 		if err != nil {
 			envCh <- nil
@@ -119,7 +202,30 @@ func command(ctx context.Context, args []string, envCh chan<- *oldcmds.Context,
 		}
 		log.Debugf("config path is %s", repoPath)
 
-		plugins, err := loadPlugins(repoPath)
+		overrides, _ := req.Options[corecmds.WithConfigOption].([]string)
+
+		disabledPlugins, _ := req.Options[corecmds.DisablePluginOption].([]string)
+		loadRepoPlugins := func() (*loader.PluginLoader, error) {
+			return loadPlugins(repoPath, disabledPlugins)
+		}
+		// the commands not using the repo only load the plugins if they
+		// construct a node or decode blocks, the others may open the repo,
+		// its datastore provided by a plugin, without constructing one.
+		var plugins *loader.PluginLoader
+		if details := commandDetails(req.Path); details.loadsPlugins() {
+			if plugins, err = loadRepoPlugins(); err != nil {
+				envCh <- nil
+				return nil, err
+			}
+		}
+
+		flushTimeout, err := flushTimeoutOption(req)
+		if err != nil {
+			envCh <- nil
+			return nil, err
+		}
+
+		maxMemory, err := maxMemoryOption(req)
 		if err != nil {
 			envCh <- nil
 			return nil, err
@@ -128,42 +234,98 @@ func command(ctx context.Context, args []string, envCh chan<- *oldcmds.Context,
 		// this sets up the function that will initialize the node
 		// this is so that we can construct the node lazily.
 		env := &oldcmds.Context{
-			ConfigRoot: repoPath,
-			LoadConfig: loadConfig,
-			ReqLog:     &oldcmds.ReqLog{},
-			Plugins:    plugins,
-			ConstructNode: func() (n *core.IpfsNode, err error) {
-				if req == nil {
-					return nil, errors.New("constructing node without a request")
-				}
+			ConfigRoot:   repoPath,
+			LoadConfig:   configLoader(overrides),
+			ReqLog:       &oldcmds.ReqLog{},
+			Plugins:      plugins,
+			LoadPlugins:  loadRepoPlugins,
+			FlushTimeout: flushTimeout,
+			MaxMemory:    maxMemory,
+		}
+		env.ConstructNode = func() (n *core.IpfsNode, err error) {
+			if req == nil {
+				return nil, errors.New("constructing node without a request")
+			}
+			if _, err := env.GetPlugins(); err != nil {
+				return nil, err
+			}
 
-				r, err := fsrepo.Open(repoPath)
-				if err != nil { // repo is owned by the node
-					return nil, err
-				}
+			r, err := fsrepo.Open(repoPath)
+			if err != nil { // repo is owned by the node
+				return nil, err
+			}
 
-				// ok everything is good. set it on the invocation (for ownership)
-				// and return it.
-				n, err = core.NewNode(ctx, &core.BuildCfg{
-					Repo: r,
-				})
+			// apply --with-config in memory only, the overridden repo
+			// refuses to write its config back.
+			if len(overrides) > 0 {
+				or, err := repo.WithConfigOverrides(r, overrides)
 				if err != nil {
+					r.Close()
 					return nil, err
 				}
+				r = or
+			}
+
+			// ok everything is good. set it on the invocation (for ownership)
+			// and return it.
+			n, err = core.NewNode(ctx, &core.BuildCfg{
+				Repo: r,
+			})
+			if err != nil {
+				return nil, err
+			}
 
-				return n, nil
-			},
+			return n, nil
 		}
 
 		envCh <- env
 		return env, nil
 	}
 
-	err = cli.Run(ctx, Root, args, os.Stdin, os.Stdout, os.Stderr, buildEnv, makeExecutor)
+	stdout, err := outputFile(args)
+	if err != nil {
+		printErr(err)
+		envCh <- nil
+		errCh <- err
+		return
+	}
+	if stdout != os.Stdout {
+		defer stdout.Close()
+	}
+
+	ctx, cancel, err := withDeadline(ctx, args, time.Now())
 	if err != nil {
+		printErr(err)
+		envCh <- nil
 		errCh <- err
 		return
 	}
+	defer cancel()
+
+	// a config that can't be read shouldn't keep the built-in commands, like
+	// 'ipfs config edit', from running
+	root, err := withExternalCommands(Root, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: external commands not loaded: %s\n", err)
+		root = Root
+	}
+
+	// cli.Run prints its errors itself
+	stderr, waitStderr := os.Stderr, func() {}
+	if errFormat == errorFormatJSON {
+		if stderr, waitStderr, err = jsonErrors(os.Stderr); err != nil {
+			printErr(err)
+			envCh <- nil
+			errCh <- err
+			return
+		}
+	}
+	err = cli.Run(ctx, root, args, os.Stdin, stdout, stderr, buildEnv, makeExecutor)
+	waitStderr()
+	if err != nil {
+		errCh <- classifyError(err, parsed)
+		return
+	}
 
 	// everything went better than expected :)
 	errCh <- ErrNormalExit
@@ -172,7 +334,7 @@ func command(ctx context.Context, args []string, envCh chan<- *oldcmds.Context,
 func checkDebug(req *cmds.Request) {
 	// check if user wants to debug. option OR env var.
 	debug, _ := req.Options["debug"].(bool)
-	if debug || os.Getenv("IPFS_LOGGING") == "debug" {
+	if debug || os.Getenv(envVar("LOGGING")) == "debug" {
 		u.Debug = true
 		logging.SetDebugLogging()
 	}
@@ -181,22 +343,64 @@ func checkDebug(req *cmds.Request) {
 	}
 }
 
-func apiAddrOption(req *cmds.Request) (ma.Multiaddr, error) {
-	apiAddrStr, apiSpecified := req.Options[corecmds.ApiOption].(string)
-	if !apiSpecified {
+// apiAddrOption returns the API endpoints given with --api, or else with
+// $IPFS_API, none if neither is set.
+func apiAddrOption(req *cmds.Request) ([]ma.Multiaddr, error) {
+	if apiAddrStr, apiSpecified := req.Options[corecmds.ApiOption].(string); apiSpecified {
+		return parseAPIAddrs(apiAddrStr)
+	}
+	apiAddrStr := os.Getenv(envVar("API"))
+	if apiAddrStr == "" {
 		return nil, nil
 	}
-	return ma.NewMultiaddr(apiAddrStr)
+	addrs, err := parseAPIAddrs(apiAddrStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $%s: %s", envVar("API"), err)
+	}
+	return addrs, nil
+}
+
+// flushTimeoutOption returns how long to wait for the repo to be flushed
+// before the node used by the command is closed, zero for the default.
+func flushTimeoutOption(req *cmds.Request) (time.Duration, error) {
+	s, ok := req.Options[corecmds.FlushTimeoutOption].(string)
+	if !ok {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s: %s", corecmds.FlushTimeoutOption, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("--%s must be positive", corecmds.FlushTimeoutOption)
+	}
+	return d, nil
 }
 
 func makeExecutor(req *cmds.Request, env interface{}) (cmds.Executor, error) {
-	exe := cmds.NewExecutor(req.Root)
+	// 'ipfs pin add --from-file' reads its paths before the request is
+	// checked, for them to be sent in its body
+	if err := corecmds.ReadPinAddLists(req); err != nil {
+		return nil, err
+	}
+
+	var exe cmds.Executor = cmds.NewExecutor(req.Root)
 	cctx := env.(*oldcmds.Context)
+	if cctx.MaxMemory > 0 {
+		exe = &memLimitExecutor{Executor: exe, limit: cctx.MaxMemory}
+	}
 	details := commandDetails(req.Path)
 
 	// Check if the command is disabled.
 	if details.cannotRunOnClient && details.cannotRunOnDaemon {
-		return nil, fmt.Errorf("command disabled: %v", req.Path)
+		return nil, usageError(fmt.Errorf("command disabled: %v", req.Path))
+	}
+
+	// Config overrides only apply to the repo opened by this process, the
+	// daemon reads its config on its own.
+	overrides, _ := req.Options[corecmds.WithConfigOption].([]string)
+	if len(overrides) > 0 && req.Command == daemonCmd {
+		return nil, usageError(fmt.Errorf("--%s cannot be used to start the daemon", corecmds.WithConfigOption))
 	}
 
 	// Can we just run this locally?
@@ -204,81 +408,147 @@ func makeExecutor(req *cmds.Request, env interface{}) (cmds.Executor, error) {
 		return exe, nil
 	}
 
-	// Get the API option from the commandline.
-	apiAddr, err := apiAddrOption(req)
+	// Never use the daemon when asked to run on the repo directly (unless
+	// we're trying to _run_ the daemon). --offline still uses a running
+	// daemon, which then runs the command offline.
+	noDaemon, _ := req.Options[corecmds.NoDaemonOption].(bool)
+	if noDaemon && req.Command != daemonCmd {
+		if details.cannotRunOnClient {
+			return nil, usageError(fmt.Errorf("command must be run on the daemon, which --%s doesn't use: %v", corecmds.NoDaemonOption, req.Path))
+		}
+		if _, apiSpecified := req.Options[corecmds.ApiOption]; apiSpecified {
+			return nil, usageError(fmt.Errorf("--%s cannot be used with --%s", corecmds.ApiOption, corecmds.NoDaemonOption))
+		}
+		return exe, nil
+	}
+
+	// Get the API option from the commandline, or the environment.
+	apiAddrs, err := apiAddrOption(req)
 	if err != nil {
-		return nil, err
+		return nil, usageError(err)
 	}
 
 	// Require that the command be run on the daemon when the API flag is
-	// passed (unless we're trying to _run_ the daemon).
-	daemonRequested := apiAddr != nil && req.Command != daemonCmd
+	// passed (unless we're trying to _run_ the daemon). $IPFS_API is only
+	// used like the API file of the repo, when there is a daemon to use.
+	_, apiFlag := req.Options[corecmds.ApiOption].(string)
+	daemonRequested := apiFlag && req.Command != daemonCmd
 
 	// Run this on the client if required.
 	if details.cannotRunOnDaemon || req.Command.External {
 		if daemonRequested {
 			// User requested that the command be run on the daemon but we can't.
 			// NOTE: We drop this check for the `ipfs daemon` command.
-			return nil, errors.New("api flag specified but command cannot be run on the daemon")
+			return nil, usageError(errors.New("api flag specified but command cannot be run on the daemon"))
 		}
 		return exe, nil
 	}
 
-	// Finally, look in the repo for an API file.
-	if apiAddr == nil {
-		var err error
-		apiAddr, err = fsrepo.APIAddr(cctx.ConfigRoot)
+	// Finally, look in the repo for an API file, waiting for a starting
+	// daemon to write it when asked to retry.
+	if len(apiAddrs) == 0 {
+		apiAddr, err := waitAPIAddr(cctx.ConfigRoot, apiRetries(req, details))
 		switch err {
-		case nil, repo.ErrApiNotRunning:
+		case nil:
+			apiAddrs = []ma.Multiaddr{apiAddr}
+		case repo.ErrApiNotRunning:
 		default:
 			return nil, err
 		}
 	}
 
 	// Still no api specified? Run it on the client or fail.
-	if apiAddr == nil {
+	if len(apiAddrs) == 0 {
 		if details.cannotRunOnClient {
-			return nil, fmt.Errorf("command must be run on the daemon: %v", req.Path)
+			return nil, daemonUnreachable(fmt.Errorf("command must be run on the daemon: %v", req.Path))
 		}
 		return exe, nil
 	}
 
-	// Resolve the API addr.
-	apiAddr, err = resolveAddr(req.Context, apiAddr)
+	if len(overrides) > 0 {
+		return nil, usageError(fmt.Errorf("--%s cannot be used while the daemon is running", corecmds.WithConfigOption))
+	}
+	// the memory of the daemon isn't the command's to limit
+	if cctx.MaxMemory > 0 {
+		return nil, usageError(fmt.Errorf("--%s cannot be used while the daemon is running", corecmds.MaxMemoryOption))
+	}
+
+	// Resolve the API addr, failing over to the next one given with --api
+	// when an endpoint is down.
+	resolveTimeout, err := apiResolveTimeoutOption(req)
 	if err != nil {
-		return nil, err
+		return nil, usageError(err)
 	}
-	network, host, err := manet.DialArgs(apiAddr)
+	apiAddr, resolved, err := selectAPIAddr(req.Context, apiAddrs, resolveTimeout)
+	if err != nil {
+		return nil, daemonUnreachable(err)
+	}
+	network, host, err := manet.DialArgs(resolved)
 	if err != nil {
 		return nil, err
 	}
 
+	// The certificate is checked against the address given, not the one it
+	// resolved to.
+	var tlsConfig *tls.Config
+	if apiUsesTLS(apiAddr) {
+		if tlsConfig, err = apiTLSConfig(req, apiAddr); err != nil {
+			return nil, err
+		}
+	}
+
 	// Construct the executor.
 	opts := []cmdhttp.ClientOpt{
 		cmdhttp.ClientWithAPIPrefix(corehttp.APIPath),
 	}
 
-	// Fallback on a local executor if we (a) have a repo and (b) aren't
-	// forcing a daemon.
-	if !daemonRequested && fsrepo.IsInitialized(cctx.ConfigRoot) {
+	// Fallback on a local executor if we (a) have a repo, (b) aren't
+	// forcing a daemon and (c) weren't told not to.
+	noFallback, _ := req.Options[corecmds.NoFallbackOption].(bool)
+	if !daemonRequested && !noFallback && fsrepo.IsInitialized(cctx.ConfigRoot) {
 		opts = append(opts, cmdhttp.ClientWithFallback(exe))
 	}
 
+	apiTimeout, err := apiTimeoutOption(req)
+	if err != nil {
+		return nil, usageError(err)
+	}
+
+	// streaming commands may only answer once they have something to output
+	var headerTimeout time.Duration
+	if !details.streaming {
+		headerTimeout = apiTimeout
+	}
+	transport := apiTransport(network, host, apiTimeout, headerTimeout, apiRetries(req, details), tlsConfig)
 	switch network {
 	case "tcp", "tcp4", "tcp6":
 	case "unix":
-		path := host
+		if err := checkAPISocket(host); err != nil {
+			return nil, daemonUnreachable(err)
+		}
 		host = "unix"
-		opts = append(opts, cmdhttp.ClientWithHTTPClient(&http.Client{
-			Transport: &http.Transport{
-				DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
-					return net.Dial("unix", path)
-				},
-			},
-		}))
 	default:
 		return nil, fmt.Errorf("unsupported API address: %s", apiAddr)
 	}
+	if retries := retryTransientOption(req, details); retries > 0 {
+		transport = &retryTransport{retries: retries, next: transport}
+	}
+
+	// A request sent again with its idempotency key, by running the command
+	// again, isn't applied twice by the daemon.
+	if key, _ := req.Options[corecmds.IdempotencyKeyOption].(string); key != "" {
+		transport = &idempotencyTransport{key: key, next: transport}
+	}
+	if token := apiAuthToken(req); token != "" {
+		transport = &authTransport{token: token, next: transport}
+	}
+	if secret := apiSecret(); secret != "" {
+		transport = &secretTransport{secret: secret, next: transport}
+	}
+
+	if transport != nil {
+		opts = append(opts, cmdhttp.ClientWithHTTPClient(&http.Client{Transport: transport}))
+	}
 
 	return cmdhttp.NewClient(host, opts...), nil
 }
@@ -305,6 +575,10 @@ func getRepoPath(req *cmds.Request) (string, error) {
 		return repoOpt, nil
 	}
 
+	if envPath := os.Getenv(envVar("PATH")); envPath != "" {
+		return homedir.Expand(envPath)
+	}
+
 	repoPath, err := fsrepo.BestKnownPath()
 	if err != nil {
 		return "", err
@@ -316,11 +590,64 @@ func loadConfig(path string) (*config.Config, error) {
 	return fsrepo.ConfigAt(path)
 }
 
+// configLoader returns a loadConfig that applies the --with-config overrides
+// on top of the config read from disk.
+func configLoader(overrides []string) func(string) (*config.Config, error) {
+	if len(overrides) == 0 {
+		return loadConfig
+	}
+	return func(path string) (*config.Config, error) {
+		cfg, err := loadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		return repo.ApplyConfigOverrides(cfg, overrides)
+	}
+}
+
 // startProfiling begins CPU profiling and returns a `stop` function to be
-// executed as late as possible. The stop function captures the memprofile.
-func startProfiling() (func(), error) {
+// executed as late as possible. Profiles are written to IPFS_PROF_DIR, the
+// current directory by default, in files named after the process and the
+// time profiling started, like ipfs.1234.20200102T150405.cpuprof. The
+// memprofile is captured every IPFS_PROF_HEAP_INTERVAL, 30s by default. The
+// stop function captures a dump of all the goroutines, with the stack traces
+// format selected by IPFS_PROF_GOROUTINE_DEBUG: 2 (the default) or 1, see
+// pprof.Profile.WriteTo.
+//
+// Mutex and block profiling are off unless IPFS_PROF_MUTEX_FRACTION and
+// IPFS_PROF_BLOCK_RATE are set, see runtime.SetMutexProfileFraction and
+// runtime.SetBlockProfileRate. The stop function then captures these
+// profiles too, and turns them off.
+//
+// The profiles are compressed with gzip when IPFS_PROF_GZIP is set, in files
+// with an additional .gz extension.
+//
+// The stop function returns the error writing the last heap profile, if
+// any, for the failure to be noticed after the command.
+func startProfiling() (func() error, error) {
+	mutexFraction, err := profileRateFromEnv("PROF_MUTEX_FRACTION")
+	if err != nil {
+		return nil, err
+	}
+	blockRate, err := profileRateFromEnv("PROF_BLOCK_RATE")
+	if err != nil {
+		return nil, err
+	}
+	goroutineDebug := 2
+	if v := os.Getenv(envVar("PROF_GOROUTINE_DEBUG")); v != "" {
+		goroutineDebug, err = strconv.Atoi(v)
+		if err != nil || (goroutineDebug != 1 && goroutineDebug != 2) {
+			return nil, fmt.Errorf("invalid %s %q: expected 1 or 2", envVar("PROF_GOROUTINE_DEBUG"), v)
+		}
+	}
+
+	profilePath, err := profilePaths()
+	if err != nil {
+		return nil, err
+	}
+
 	// start CPU profiling as early as possible
-	ofi, err := os.Create(cpuProfile)
+	ofi, err := createProfile(profilePath(cpuProfile))
 	if err != nil {
 		return nil, err
 	}
@@ -329,46 +656,239 @@ func startProfiling() (func(), error) {
 		ofi.Close()
 		return nil, err
 	}
+	runtime.SetMutexProfileFraction(mutexFraction)
+	runtime.SetBlockProfileRate(blockRate)
+
+	heapTicker := time.NewTicker(heapProfileInterval())
+	done, exited := make(chan struct{}), make(chan struct{})
+	// only read once the goroutine exited
+	var heapErr error
 	go func() {
-		for range time.NewTicker(time.Second * 30).C {
-			err := writeHeapProfileToFile()
-			if err != nil {
-				log.Error(err)
+		defer close(exited)
+		for {
+			select {
+			case <-heapTicker.C:
+				// a later profile written fine replaces a failed one
+				heapErr = writeHeapProfileToFile(profilePath(heapProfile))
+			case <-done:
+				return
 			}
 		}
 	}()
 
-	stopProfiling := func() {
+	stopProfiling := func() error {
+		// before stopping anything, to see what everything was up to
+		if err := writeProfileToFile("goroutine", profilePath(goroutineProfile), goroutineDebug); err != nil {
+			log.Error(err)
+		}
+
+		heapTicker.Stop()
+		close(done)
+		<-exited
+
 		pprof.StopCPUProfile()
-		ofi.Close() // captured by the closure
+		// captured by the closure, closes the gzip stream first if any
+		if err := ofi.Close(); err != nil {
+			log.Errorf("writing the CPU profile: %s", err)
+		}
+
+		if mutexFraction > 0 {
+			if err := writeProfileToFile("mutex", profilePath(mutexProfile), 0); err != nil {
+				log.Error(err)
+			}
+		}
+		if blockRate > 0 {
+			if err := writeProfileToFile("block", profilePath(blockProfile), 0); err != nil {
+				log.Error(err)
+			}
+		}
+		runtime.SetMutexProfileFraction(0)
+		runtime.SetBlockProfileRate(0)
+
+		if heapErr != nil {
+			return fmt.Errorf("writing the heap profile: %s", heapErr)
+		}
+		return nil
 	}
 	return stopProfiling, nil
 }
 
-func writeHeapProfileToFile() error {
-	mprof, err := os.Create(heapProfile)
+// startTracing begins the execution tracing, see runtime/trace, and returns
+// a `stop` function to be executed as late as possible. The trace is written
+// next to the profiles, like ipfs.1234.20200102T150405.trace, and can be
+// viewed with 'go tool trace'.
+func startTracing() (func(), error) {
+	profilePath, err := profilePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(profilePath(executionTrace))
 	if err != nil {
+		return nil, err
+	}
+	if err := trace.Start(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		trace.Stop()
+		f.Close()
+	}, nil
+}
+
+// profilePaths creates the profiling directory, IPFS_PROF_DIR, and returns a
+// function giving the path of the profile file with the given extension.
+func profilePaths() (func(ext string) string, error) {
+	dir := os.Getenv(envVar("PROF_DIR"))
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating the profiling directory: %s", err)
+	}
+	prefix := fmt.Sprintf("ipfs.%d.%s", os.Getpid(), profileTime().Format("20060102T150405"))
+	return func(ext string) string {
+		return filepath.Join(dir, prefix+"."+ext)
+	}, nil
+}
+
+// defaultHeapProfileInterval is how often the heap profile is written unless
+// IPFS_PROF_HEAP_INTERVAL says otherwise.
+const defaultHeapProfileInterval = 30 * time.Second
+
+// heapProfileInterval returns how often to write the heap profile, from
+// IPFS_PROF_HEAP_INTERVAL.
+func heapProfileInterval() time.Duration {
+	v := os.Getenv(envVar("PROF_HEAP_INTERVAL"))
+	if v == "" {
+		return defaultHeapProfileInterval
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Errorf("invalid %s %q, writing the heap profile every %s", envVar("PROF_HEAP_INTERVAL"), v, defaultHeapProfileInterval)
+		return defaultHeapProfileInterval
+	}
+	return d
+}
+
+// profileRateFromEnv reads a profiling rate from the environment variable
+// name, 0 when unset.
+func profileRateFromEnv(name string) (int, error) {
+	v := os.Getenv(envVar(name))
+	if v == "" {
+		return 0, nil
+	}
+	rate, err := strconv.Atoi(v)
+	if err != nil || rate < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a positive integer", envVar(name), v)
+	}
+	return rate, nil
+}
+
+func writeHeapProfileToFile(file string) error {
+	mprof, err := createProfile(file)
+	if err != nil {
+		return err
+	}
+	if err := pprof.WriteHeapProfile(mprof); err != nil {
+		mprof.Close()
+		return err
+	}
+	return mprof.Close() // _after_ writing the heap profile
+}
+
+// writeProfileToFile writes the named runtime profile to file, in the format
+// selected by debug.
+func writeProfileToFile(name, file string, debug int) error {
+	f, err := createProfile(file)
+	if err != nil {
+		return err
+	}
+	if err := pprof.Lookup(name).WriteTo(f, debug); err != nil {
+		f.Close()
 		return err
 	}
-	defer mprof.Close() // _after_ writing the heap profile
-	return pprof.WriteHeapProfile(mprof)
+	return f.Close()
+}
+
+// profileName returns the name of the profile file, with a .gz extension when
+// IPFS_PROF_GZIP is set.
+func profileName(file string) string {
+	if os.Getenv(envVar("PROF_GZIP")) != "" {
+		return file + ".gz"
+	}
+	return file
 }
 
-func profileIfEnabled() (func(), error) {
+// createProfile creates the profile file, see profileName, compressing what
+// is written to it with gzip when IPFS_PROF_GZIP is set.
+func createProfile(file string) (io.WriteCloser, error) {
+	name := profileName(file)
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == file {
+		return f, nil
+	}
+	return &gzipFile{Writer: gzip.NewWriter(f), f: f}, nil
+}
+
+// gzipFile compresses what is written to f.
+type gzipFile struct {
+	*gzip.Writer
+	f *os.File
+}
+
+// Close ends the gzip stream, then closes the file.
+func (g *gzipFile) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		g.f.Close()
+		return err
+	}
+	return g.f.Close()
+}
+
+// profileIfEnabled starts the profiling when IPFS_PROF is set or
+// --profiling is passed in args, and the execution tracing when IPFS_TRACE
+// is set. Both can be enabled together, the returned function then stops the
+// tracing first.
+func profileIfEnabled(args []string) (func() error, error) {
+	profiling, err := rootBoolOptionArg(args, corecmds.ProfilingOption)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := func() error { return nil }
 	// FIXME this is a temporary hack so profiling of asynchronous operations
 	// works as intended.
-	if os.Getenv(EnvEnableProfiling) != "" {
-		stopProfilingFunc, err := startProfiling() // TODO maybe change this to its own option... profiling makes it slower.
+	if profiling || os.Getenv(envVar("PROF")) != "" {
+		stopProfilingFunc, err := startProfiling()
+		if err != nil {
+			return nil, err
+		}
+		stop = stopProfilingFunc
+	}
+	if os.Getenv(envVar("TRACE")) != "" {
+		stopTracing, err := startTracing()
 		if err != nil {
+			stop()
 			return nil, err
 		}
-		return stopProfilingFunc, nil
+		stopProfiling := stop
+		stop = func() error {
+			stopTracing()
+			return stopProfiling()
+		}
 	}
-	return func() {}, nil
+	return stop, nil
 }
 
-func resolveAddr(ctx context.Context, addr ma.Multiaddr) (ma.Multiaddr, error) {
-	ctx, cancelFunc := context.WithTimeout(ctx, 10*time.Second)
+// resolveAddr returns the addresses addr resolves to, in order, giving up
+// after timeout.
+func resolveAddr(ctx context.Context, addr ma.Multiaddr, timeout time.Duration) ([]ma.Multiaddr, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, timeout)
 	defer cancelFunc()
 
 	addrs, err := dnsResolver.Resolve(ctx, addr)
@@ -380,5 +900,5 @@ func resolveAddr(ctx context.Context, addr ma.Multiaddr) (ma.Multiaddr, error) {
 		return nil, errors.New("non-resolvable API endpoint")
 	}
 
-	return addrs[0], nil
+	return addrs, nil
 }
@@ -0,0 +1,603 @@
+package lib
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	u "github.com/ipfs/go-ipfs-util"
+)
+
+// withEnvPrefix sets EnvPrefix and returns a function restoring it.
+func withEnvPrefix(prefix string) func() {
+	old := EnvPrefix
+	EnvPrefix = prefix
+	return func() { EnvPrefix = old }
+}
+
+// setenv sets an environment variable and returns a function restoring it.
+func setenv(t *testing.T, key, value string) func() {
+	old, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// withProfileTime makes profile files named after a fixed time, see
+// profileFile, and returns a function restoring profileTime.
+func withProfileTime() func() {
+	old := profileTime
+	profileTime = func() time.Time { return time.Date(2020, 1, 2, 15, 4, 5, 0, time.Local) }
+	return func() { profileTime = old }
+}
+
+// profileFile returns the path of the profile with extension ext written to
+// dir, under withProfileTime.
+func profileFile(dir, ext string) string {
+	return filepath.Join(dir, fmt.Sprintf("ipfs.%d.20200102T150405.%s", os.Getpid(), ext))
+}
+
+func TestEnvPrefixRepoPath(t *testing.T) {
+	defer withEnvPrefix("MYAPP")()
+	defer setenv(t, "IPFS_PATH", "/ipfs/repo")()
+	defer setenv(t, "MYAPP_PATH", "/myapp/repo")()
+
+	req := &cmds.Request{Options: cmds.OptMap{}}
+	repoPath, err := getRepoPath(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repoPath != "/myapp/repo" {
+		t.Fatalf("expected MYAPP_PATH to be used, got %q", repoPath)
+	}
+
+	// the repo path set up by the embedder is found again
+	if err := setupRepoPath("/setup/repo"); err != nil {
+		t.Fatal(err)
+	}
+	if repoPath, err = getRepoPath(req); err != nil || repoPath != "/setup/repo" {
+		t.Fatalf("expected the repo path set up to be used, got %q (%v)", repoPath, err)
+	}
+
+	// the config option still takes precedence
+	req.Options["config"] = "/option/repo"
+	if repoPath, err = getRepoPath(req); err != nil || repoPath != "/option/repo" {
+		t.Fatalf("expected the config option to be used, got %q (%v)", repoPath, err)
+	}
+}
+
+func TestEnvPrefixDebug(t *testing.T) {
+	defer withEnvPrefix("MYAPP")()
+	defer setenv(t, "MYAPP_LOGGING", "debug")()
+
+	old := u.Debug
+	u.Debug = false
+	defer func() { u.Debug = old }()
+
+	checkDebug(&cmds.Request{Options: cmds.OptMap{}})
+	if !u.Debug {
+		t.Fatal("expected MYAPP_LOGGING=debug to enable debug mode")
+	}
+}
+
+func TestEnvPrefixAPI(t *testing.T) {
+	defer withEnvPrefix("MYAPP")()
+	defer setenv(t, "IPFS_API", "/ip4/127.0.0.1/tcp/5001")()
+	defer setenv(t, "MYAPP_API", "/ip4/127.0.0.1/tcp/5002")()
+
+	addrs, err := apiAddrOption(&cmds.Request{Options: cmds.OptMap{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0].String() != "/ip4/127.0.0.1/tcp/5002" {
+		t.Fatalf("expected MYAPP_API to be used, got %v", addrs)
+	}
+}
+
+func TestEnvPrefixAPIAuth(t *testing.T) {
+	defer withEnvPrefix("MYAPP")()
+	defer setenv(t, "IPFS_API_AUTH", "ipfs-token")()
+	defer setenv(t, "MYAPP_API_AUTH", "myapp-token")()
+
+	if token := apiAuthToken(&cmds.Request{Options: cmds.OptMap{}}); token != "myapp-token" {
+		t.Fatalf("expected MYAPP_API_AUTH to be used, got %q", token)
+	}
+}
+
+func TestEnvPrefixAPISecret(t *testing.T) {
+	defer withEnvPrefix("MYAPP")()
+	defer setenv(t, "IPFS_API_SECRET", "ipfs-secret")()
+	defer setenv(t, "MYAPP_API_SECRET", "myapp-secret")()
+
+	if secret := apiSecret(); secret != "myapp-secret" {
+		t.Fatalf("expected MYAPP_API_SECRET to be used, got %q", secret)
+	}
+}
+
+func TestEnvPrefixPluginTimeout(t *testing.T) {
+	defer withEnvPrefix("MYAPP")()
+	defer setenv(t, "IPFS_PLUGIN_TIMEOUT", "1m")()
+	defer setenv(t, "MYAPP_PLUGIN_TIMEOUT", "2m")()
+
+	if timeout, err := pluginTimeout(); err != nil || timeout != 2*time.Minute {
+		t.Fatalf("expected MYAPP_PLUGIN_TIMEOUT to be used, got %s (%v)", timeout, err)
+	}
+}
+
+func TestEnvPrefixProfiling(t *testing.T) {
+	defer withProfileTime()()
+	defer withEnvPrefix("MYAPP")()
+
+	dir, err := ioutil.TempDir("", "ipfs-lib-prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	defer setenv(t, "IPFS_PROF", "true")()
+	stop, err := profileIfEnabled(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if _, err := os.Stat(profileFile(dir, cpuProfile)); !os.IsNotExist(err) {
+		t.Fatal("IPFS_PROF should be ignored with a custom prefix")
+	}
+
+	defer setenv(t, "MYAPP_PROF", "true")()
+	stop, err = profileIfEnabled(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if _, err := os.Stat(profileFile(dir, cpuProfile)); err != nil {
+		t.Fatalf("expected MYAPP_PROF to enable profiling: %s", err)
+	}
+}
+
+func TestMutexBlockProfiling(t *testing.T) {
+	defer withProfileTime()()
+	dir, err := ioutil.TempDir("", "ipfs-lib-prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// off by default
+	stop, err := startProfiling()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := runtime.SetMutexProfileFraction(-1); f != 0 {
+		t.Fatalf("expected mutex profiling to be off, got a fraction of %d", f)
+	}
+	stop()
+	for _, f := range []string{mutexProfile, blockProfile} {
+		if _, err := os.Stat(profileFile(dir, f)); !os.IsNotExist(err) {
+			t.Fatalf("expected no %s without the rates set", f)
+		}
+	}
+
+	defer setenv(t, "IPFS_PROF_MUTEX_FRACTION", "5")()
+	defer setenv(t, "IPFS_PROF_BLOCK_RATE", "1")()
+	stop, err = startProfiling()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := runtime.SetMutexProfileFraction(-1); f != 5 {
+		t.Fatalf("expected a mutex profile fraction of 5, got %d", f)
+	}
+	stop()
+	if f := runtime.SetMutexProfileFraction(-1); f != 0 {
+		t.Fatalf("expected stop to turn mutex profiling off, got a fraction of %d", f)
+	}
+	for _, f := range []string{mutexProfile, blockProfile} {
+		if _, err := os.Stat(profileFile(dir, f)); err != nil {
+			t.Fatalf("expected %s to be written: %s", f, err)
+		}
+	}
+
+	defer setenv(t, "IPFS_PROF_BLOCK_RATE", "often")()
+	if _, err := startProfiling(); err == nil {
+		t.Fatal("expected an invalid block rate to be rejected")
+	}
+}
+
+func TestGoroutineProfile(t *testing.T) {
+	defer withProfileTime()()
+	dir, err := ioutil.TempDir("", "ipfs-lib-prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	for debug, expected := range map[string]string{
+		"":  "goroutine 1 [", // full stack traces by default
+		"2": "goroutine 1 [",
+		"1": "goroutine profile: total",
+	} {
+		defer setenv(t, "IPFS_PROF_GOROUTINE_DEBUG", debug)()
+		stop, err := startProfiling()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stop()
+		dump, err := ioutil.ReadFile(profileFile(dir, goroutineProfile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(dump), expected) {
+			t.Errorf("debug %q: expected the dump to contain %q", debug, expected)
+		}
+	}
+
+	defer setenv(t, "IPFS_PROF_GOROUTINE_DEBUG", "3")()
+	if _, err := startProfiling(); err == nil {
+		t.Fatal("expected an invalid goroutine debug level to be rejected")
+	}
+
+	// failing to write the dump doesn't prevent stopping the profiling
+	defer setenv(t, "IPFS_PROF_GOROUTINE_DEBUG", "")()
+	if err := os.Remove(profileFile(dir, goroutineProfile)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(profileFile(dir, goroutineProfile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	stop, err := startProfiling()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if err := pprof.StartCPUProfile(ioutil.Discard); err != nil {
+		t.Fatalf("expected the CPU profile to be stopped: %s", err)
+	}
+	pprof.StopCPUProfile()
+}
+
+func TestHeapProfileInterval(t *testing.T) {
+	defer withProfileTime()()
+	dir, err := ioutil.TempDir("", "ipfs-lib-prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	for v, expected := range map[string]time.Duration{
+		"":       defaultHeapProfileInterval,
+		"5s":     5 * time.Second,
+		"often":  defaultHeapProfileInterval,
+		"-1s":    defaultHeapProfileInterval,
+		"250ms":  250 * time.Millisecond,
+		"0s":     defaultHeapProfileInterval,
+		"1m30s":  90 * time.Second,
+		"100000": defaultHeapProfileInterval,
+	} {
+		defer setenv(t, "IPFS_PROF_HEAP_INTERVAL", v)()
+		if d := heapProfileInterval(); d != expected {
+			t.Errorf("%q: expected %s, got %s", v, expected, d)
+		}
+	}
+
+	defer setenv(t, "IPFS_PROF_HEAP_INTERVAL", "10ms")()
+	stop, err := startProfiling()
+	if err != nil {
+		t.Fatal(err)
+	}
+	heap := profileFile(dir, heapProfile)
+	for start := time.Now(); ; time.Sleep(5 * time.Millisecond) {
+		if _, err := os.Stat(heap); err == nil {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("expected the heap profile to be written")
+		}
+	}
+	stop()
+
+	// the heap profile isn't written anymore once stopped
+	if err := os.Remove(heap); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(heap); !os.IsNotExist(err) {
+		t.Fatal("expected the heap profile not to be written after stop")
+	}
+}
+
+func TestProfileDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipfs-lib-prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the directory is created if missing
+	profDir := filepath.Join(dir, "profiles", "ipfs")
+	defer setenv(t, "IPFS_PROF_DIR", profDir)()
+	stop, err := startProfiling()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+
+	files, err := filepath.Glob(filepath.Join(profDir, fmt.Sprintf("ipfs.%d.*.%s", os.Getpid(), cpuProfile)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected a CPU profile named after the process in %s, got %v", profDir, files)
+	}
+
+	// profiling fails when the directory can't be used
+	notDir := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(notDir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer setenv(t, "IPFS_PROF_DIR", notDir)()
+	defer setenv(t, "IPFS_PROF", "true")()
+	if _, err := profileIfEnabled(nil); err == nil {
+		t.Fatal("expected profiling to fail when the directory is a file")
+	}
+}
+
+func TestTracing(t *testing.T) {
+	defer withProfileTime()()
+
+	dir, err := ioutil.TempDir("", "ipfs-lib-prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setenv(t, "IPFS_PROF_DIR", dir)()
+
+	// tracing alone
+	defer setenv(t, "IPFS_TRACE", "true")()
+	stop, err := profileIfEnabled(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if _, err := os.Stat(profileFile(dir, cpuProfile)); !os.IsNotExist(err) {
+		t.Fatal("expected no CPU profile without IPFS_PROF")
+	}
+	if fi, err := os.Stat(profileFile(dir, executionTrace)); err != nil || fi.Size() == 0 {
+		t.Fatalf("expected an execution trace: %v", err)
+	}
+	os.Remove(profileFile(dir, executionTrace))
+
+	// along with profiling
+	defer setenv(t, "IPFS_PROF", "true")()
+	stop, err = profileIfEnabled(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	for _, ext := range []string{cpuProfile, executionTrace} {
+		if fi, err := os.Stat(profileFile(dir, ext)); err != nil || fi.Size() == 0 {
+			t.Fatalf("expected the %s file to be written: %v", ext, err)
+		}
+	}
+
+	// profiling is stopped when the tracing fails to start
+	if err := os.Remove(profileFile(dir, executionTrace)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(profileFile(dir, executionTrace), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := profileIfEnabled(nil); err == nil {
+		t.Fatal("expected the tracing to fail")
+	}
+	if err := pprof.StartCPUProfile(ioutil.Discard); err != nil {
+		t.Fatalf("expected the CPU profiling to be stopped: %s", err)
+	}
+	pprof.StopCPUProfile()
+}
+
+func TestHeapDumpOnSignal(t *testing.T) {
+	if len(heapDumpSignals) == 0 {
+		t.Skip("no heap dump signal on this platform")
+	}
+	defer withProfileTime()()
+
+	dir, err := ioutil.TempDir("", "ipfs-lib-prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setenv(t, "IPFS_PROF_DIR", dir)()
+
+	stop := heapDumpOnSignal()
+	defer stop()
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(heapDumpSignals[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	heap := profileFile(dir, heapProfile)
+	for i := 0; ; i++ {
+		if fi, err := os.Stat(heap); err == nil && fi.Size() > 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("expected the signal to write a heap profile")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if _, err := os.Stat(profileFile(dir, cpuProfile)); !os.IsNotExist(err) {
+		t.Fatal("expected the signal not to start the CPU profiling")
+	}
+}
+
+func TestProfilingOption(t *testing.T) {
+	defer withProfileTime()()
+
+	dir, err := ioutil.TempDir("", "ipfs-lib-prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setenv(t, "IPFS_PROF_DIR", dir)()
+
+	stop, err := profileIfEnabled([]string{"ipfs", "--profiling", "daemon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if _, err := os.Stat(profileFile(dir, cpuProfile)); err != nil {
+		t.Fatalf("expected --profiling to enable profiling: %s", err)
+	}
+
+	if _, err := profileIfEnabled([]string{"ipfs", "--profiling=maybe", "daemon"}); err == nil {
+		t.Fatal("expected an invalid --profiling value to be rejected")
+	}
+}
+
+func TestHeapProfileError(t *testing.T) {
+	defer withProfileTime()()
+
+	dir, err := ioutil.TempDir("", "ipfs-lib-prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setenv(t, "IPFS_PROF_DIR", dir)()
+	defer setenv(t, "IPFS_PROF_HEAP_INTERVAL", "10ms")()
+
+	stop, err := startProfiling()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := stop(); err != nil {
+		t.Fatalf("expected no error writing the heap profile, got %s", err)
+	}
+
+	// the heap profile can't be written where a directory is
+	heap := profileFile(dir, heapProfile)
+	if err := os.Remove(heap); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(heap, 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer setenv(t, "IPFS_PROF", "true")()
+	stop, err = profileIfEnabled(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := stop(); err == nil {
+		t.Fatal("expected the failure to write the heap profile to be returned")
+	}
+
+	// a heap profile written fine after failing ones clears the error
+	stop, err = profileIfEnabled(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := os.Remove(heap); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := stop(); err != nil {
+		t.Fatalf("expected the heap profile written after the failures to clear the error, got %s", err)
+	}
+}
+
+func TestProfileGzip(t *testing.T) {
+	defer withProfileTime()()
+
+	dir, err := ioutil.TempDir("", "ipfs-lib-prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setenv(t, "IPFS_PROF_DIR", dir)()
+	defer setenv(t, "IPFS_PROF_GZIP", "true")()
+	defer setenv(t, "IPFS_PROF_HEAP_INTERVAL", "10ms")()
+
+	stop, err := startProfiling()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ext := range []string{cpuProfile, heapProfile, goroutineProfile} {
+		if _, err := os.Stat(profileFile(dir, ext)); !os.IsNotExist(err) {
+			t.Fatalf("expected no uncompressed %s file", ext)
+		}
+		f, err := os.Open(profileFile(dir, ext) + ".gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("%s: %s", ext, err)
+		}
+		if _, err := ioutil.ReadAll(gz); err != nil {
+			t.Fatalf("expected a complete gzip stream for the %s file: %s", ext, err)
+		}
+		f.Close()
+	}
+}
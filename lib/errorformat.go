@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// errorFormat returns the format of the errors printed on stderr, given with
+// --error-format: text, the default, or json.
+func errorFormat(args []string) (string, error) {
+	val, found, err := rootOptionArg(args, corecmds.ErrorFormatOption)
+	if err != nil || !found {
+		return errorFormatText, err
+	}
+	switch val {
+	case errorFormatText, errorFormatJSON:
+		return val, nil
+	default:
+		return errorFormatText, fmt.Errorf("invalid error format %q, expected %s or %s", val, errorFormatText, errorFormatJSON)
+	}
+}
+
+// writeError writes err to w as "Error: <message>", or in json as the API
+// errors, like {"Message":"<message>","Code":0,"Type":"error"}.
+func writeError(w io.Writer, format string, err error) {
+	if format != errorFormatJSON {
+		fmt.Fprintf(w, "Error: %s\n", err.Error())
+		return
+	}
+	e := cmds.Error{Message: err.Error(), Code: cmds.ErrNormal}
+	switch err := err.(type) {
+	case cmds.Error:
+		e = err
+	case *cmds.Error:
+		e = *err
+	}
+	b, _ := json.Marshal(e)
+	fmt.Fprintf(w, "%s\n", b)
+}
+
+// jsonErrors returns a file for cli.Run to print on, copied to stderr with
+// the "Error: " lines it prints turned into json, and a func to call once
+// cli.Run returned, waiting for everything to be copied.
+func jsonErrors(stderr *os.File) (*os.File, func(), error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		copyJSONErrors(stderr, r)
+	}()
+
+	return w, func() {
+		w.Close()
+		<-done
+		r.Close()
+	}, nil
+}
+
+// copyJSONErrors copies r to w, writing the "Error: " lines in json.
+func copyJSONErrors(w io.Writer, r io.Reader) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if msg := strings.TrimPrefix(line, "Error: "); msg != line {
+			writeError(w, errorFormatJSON, errors.New(strings.TrimSuffix(msg, "\n")))
+		} else if line != "" {
+			io.WriteString(w, line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package lib
+
+import (
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// Exit codes of the classes of failures, so scripts can tell a daemon down,
+// worth retrying, from a bad command line.
+const (
+	ExitInternal          = 1
+	ExitUsage             = 2
+	ExitCommandNotFound   = 3
+	ExitDaemonUnreachable = 4
+)
+
+// CommandError is the error a command failed with, and the exit code of its
+// class of failure.
+type CommandError struct {
+	Err      error
+	ExitCode int
+}
+
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// usageError marks err as a misuse of the command line.
+func usageError(err error) error {
+	return &CommandError{Err: err, ExitCode: ExitUsage}
+}
+
+// daemonUnreachable marks err as the daemon not being reachable.
+func daemonUnreachable(err error) error {
+	return &CommandError{Err: err, ExitCode: ExitDaemonUnreachable}
+}
+
+// classifyError returns err from cli.Run with the exit code of its class.
+// parsed tells whether the command line was parsed, cli.Run only building
+// the environment of the command after.
+func classifyError(err error, parsed bool) *CommandError {
+	if cerr, ok := err.(*CommandError); ok {
+		return cerr
+	}
+
+	code := ExitInternal
+	switch {
+	case !parsed && strings.HasPrefix(err.Error(), "Unknown Command"):
+		code = ExitCommandNotFound
+	case !parsed:
+		code = ExitUsage
+	case isClientError(err):
+		code = ExitUsage
+	// the error of the HTTP client when it can't dial the API
+	case strings.HasPrefix(err.Error(), "cannot connect to the api"):
+		code = ExitDaemonUnreachable
+	}
+	return &CommandError{Err: err, ExitCode: code}
+}
+
+func isClientError(err error) bool {
+	switch err := err.(type) {
+	case cmds.Error:
+		return err.Code == cmds.ErrClient
+	case *cmds.Error:
+		return err.Code == cmds.ErrClient
+	}
+	return false
+}
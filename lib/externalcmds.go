@@ -0,0 +1,109 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	config "github.com/ipfs/go-ipfs-config"
+	serialize "github.com/ipfs/go-ipfs-config/serialize"
+)
+
+// externalCommandsConfigKey is the config section mapping command names to
+// the binaries they run, e.g. {"mytool": "/usr/local/bin/ipfs-mytool"}.
+const externalCommandsConfigKey = "ExternalCommands"
+
+// withExternalCommands returns root with the commands registered in the
+// ExternalCommands section of the repo config added to its subcommands.
+//
+// The commands have to be known before the command line is parsed, so the
+// repo path is found in args and the config read from disk here. A repo
+// that isn't initialized has no external commands. Built-in commands can't
+// be replaced, names clashing with them are skipped with a warning.
+func withExternalCommands(root *cmds.Command, args []string) (*cmds.Command, error) {
+	repoPath, found, err := repoPathArg(args)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if repoPath, err = fsrepo.BestKnownPath(); err != nil {
+			return nil, err
+		}
+	}
+
+	externals, err := readExternalCommands(repoPath)
+	if err != nil || len(externals) == 0 {
+		return root, err
+	}
+
+	names := make([]string, 0, len(externals))
+	for name := range externals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ext := *root
+	ext.Subcommands = make(map[string]*cmds.Command, len(root.Subcommands)+len(externals))
+	for name, sub := range root.Subcommands {
+		ext.Subcommands[name] = sub
+	}
+	for _, name := range names {
+		if _, found := ext.Subcommands[name]; found {
+			fmt.Fprintf(os.Stderr, "Warning: %s.%s cannot replace the built-in command, ignoring it\n", externalCommandsConfigKey, name)
+			continue
+		}
+		ext.Subcommands[name] = corecmds.ExternalCommand(name, externals[name])
+	}
+	return &ext, nil
+}
+
+// readExternalCommands reads the ExternalCommands section of the config in
+// repoPath.
+func readExternalCommands(repoPath string) (map[string]string, error) {
+	filename, err := config.Filename(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg struct {
+		ExternalCommands map[string]string
+	}
+	switch err := serialize.ReadConfigFile(filename, &cfg); err {
+	case nil:
+	case serialize.ErrNotInitialized:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("reading %s: %s", externalCommandsConfigKey, err)
+	}
+
+	for name, binary := range cfg.ExternalCommands {
+		if name == "" || binary == "" {
+			return nil, fmt.Errorf("%s: empty command name or binary for %q", externalCommandsConfigKey, name)
+		}
+	}
+	return cfg.ExternalCommands, nil
+}
+
+// repoPathArg looks for the repo path given with --config or -c in args.
+func repoPathArg(args []string) (string, bool, error) {
+	val, found, err := rootOptionArg(args, corecmds.ConfigOption)
+	if err != nil || found {
+		return val, found, err
+	}
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--":
+			return "", false, nil
+		case "-c":
+			if i+1 == len(args) {
+				return "", false, fmt.Errorf("missing argument for option %q", corecmds.ConfigOption)
+			}
+			return args[i+1], true, nil
+		}
+	}
+	return "", false, nil
+}
@@ -0,0 +1,55 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// heapDumpOnSignal writes a heap profile each time the process receives one
+// of heapDumpSignals, SIGUSR1 where available, so the memory of a running
+// daemon can be looked at without restarting it with IPFS_PROF. The profiles
+// are written to IPFS_PROF_DIR, named after the time of the dump. It returns
+// a function to stop handling the signals.
+func heapDumpOnSignal() func() {
+	if len(heapDumpSignals) == 0 {
+		return func() {}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, heapDumpSignals...)
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-sigs:
+				file, err := dumpHeap()
+				if err != nil {
+					log.Errorf("writing the heap profile: %s", err)
+					continue
+				}
+				fmt.Fprintf(os.Stderr, "Heap profile written to %s\n", file)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+		<-exited
+	}
+}
+
+// dumpHeap writes a heap profile next to the other profiles and returns its
+// path.
+func dumpHeap() (string, error) {
+	profilePath, err := profilePaths()
+	if err != nil {
+		return "", err
+	}
+	file := profilePath(heapProfile)
+	return profileName(file), writeHeapProfileToFile(file)
+}
@@ -0,0 +1,10 @@
+// +build !windows
+
+package lib
+
+import (
+	"os"
+	"syscall"
+)
+
+var heapDumpSignals = []os.Signal{syscall.SIGUSR1}
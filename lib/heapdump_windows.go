@@ -0,0 +1,6 @@
+package lib
+
+import "os"
+
+// there is no signal to spare on windows
+var heapDumpSignals []os.Signal
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	commands "github.com/ipfs/go-ipfs/core/commands"
+	gateway "github.com/ipfs/go-ipfs/core/commands/gateway"
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
 )
@@ -17,7 +18,7 @@ var Root = &cmds.Command{
 }
 
 // commandsClientCmd is the "ipfs commands" command for local cli
-var commandsClientCmd = commands.CommandsCmd(Root)
+var commandsClientCmd = commands.ClientCommandsCmd(Root, runsWithoutRepo)
 
 // Commands in localCommands should always be run locally (even if daemon is running).
 // They can override subcommands in commands.Root by defining a subcommand with the same name.
@@ -25,6 +26,7 @@ var localCommands = map[string]*cmds.Command{
 	"daemon":   daemonCmd,
 	"init":     initCmd,
 	"commands": commandsClientCmd,
+	"gateway":  gateway.GatewayCmd,
 }
 
 func init() {
@@ -57,6 +59,21 @@ type cmdDetails struct {
 	// preemptsAutoUpdate describes commands that must be executed without the
 	// auto-update pre-command hook
 	preemptsAutoUpdate bool
+
+	// idempotent describes commands that only read state, so running them
+	// again after a transient failure is harmless. Only these commands are
+	// retried with --retry-transient.
+	idempotent bool
+
+	// streaming describes commands whose output goes on for as long as they
+	// run, like 'ipfs log tail'. --api-timeout only bounds connecting to the
+	// daemon for them.
+	streaming bool
+
+	// decodesIPLD describes commands that don't use the repo but decode
+	// blocks, with the codecs the plugins provide, like git. The plugins
+	// are loaded for them as for the commands using the repo.
+	decodesIPLD bool
 }
 
 func (d *cmdDetails) String() string {
@@ -78,19 +95,74 @@ func (d *cmdDetails) usesConfigAsInput() bool { return !d.doesNotUseConfigAsInpu
 func (d *cmdDetails) canRunOnClient() bool    { return !d.cannotRunOnClient }
 func (d *cmdDetails) canRunOnDaemon() bool    { return !d.cannotRunOnDaemon }
 func (d *cmdDetails) usesRepo() bool          { return !d.doesNotUseRepo }
+func (d *cmdDetails) loadsPlugins() bool      { return d.usesRepo() || d.decodesIPLD }
+
+// runsWithoutRepo reports whether the command at path runs on the client
+// without a repo.
+func runsWithoutRepo(path []string) bool {
+	d := commandDetails(path)
+	return d.canRunOnClient() && !d.usesRepo()
+}
 
 // "What is this madness!?" you ask. Our commands have the unfortunate problem of
 // not being able to run on all the same contexts. This map describes these
 // properties so that other code can make decisions about whether to invoke a
 // command or return an error to the user.
 var cmdDetailsMap = map[string]cmdDetails{
-	"init":        {doesNotUseConfigAsInput: true, cannotRunOnDaemon: true, doesNotUseRepo: true},
-	"daemon":      {doesNotUseConfigAsInput: true, cannotRunOnDaemon: true},
-	"commands":    {doesNotUseRepo: true},
-	"version":     {doesNotUseConfigAsInput: true, doesNotUseRepo: true}, // must be permitted to run before init
-	"log":         {cannotRunOnClient: true},
-	"diag/cmds":   {cannotRunOnClient: true},
-	"repo/fsck":   {cannotRunOnDaemon: true},
-	"config/edit": {cannotRunOnDaemon: true, doesNotUseRepo: true},
-	"cid":         {doesNotUseRepo: true},
+	"init":               {doesNotUseConfigAsInput: true, cannotRunOnDaemon: true, doesNotUseRepo: true},
+	"daemon":             {doesNotUseConfigAsInput: true, cannotRunOnDaemon: true},
+	"commands":           {doesNotUseRepo: true},
+	"version":            {doesNotUseConfigAsInput: true, doesNotUseRepo: true}, // must be permitted to run before init
+	"log":                {cannotRunOnClient: true},
+	"diag/cmds":          {cannotRunOnClient: true},
+	"cache/prewarm":      {cannotRunOnClient: true},
+	"diag/replay":        {cannotRunOnDaemon: true, doesNotUseRepo: true},
+	"repo/fsck":          {cannotRunOnDaemon: true},
+	"repo/lock":          {cannotRunOnDaemon: true, doesNotUseRepo: true},
+	"config/edit":        {cannotRunOnDaemon: true, doesNotUseRepo: true},
+	"config/check-addrs": {cannotRunOnDaemon: true},
+	"gateway":            {cannotRunOnDaemon: true},
+	"gateway/config":     {cannotRunOnDaemon: true, doesNotUseRepo: true},
+	"cid":                {doesNotUseRepo: true},
+	"name/convert":       {doesNotUseRepo: true},
+	"dag/car/verify":     {doesNotUseRepo: true, decodesIPLD: true},
+	"dag/car/index":      {doesNotUseRepo: true},
+
+	"cat":              {idempotent: true},
+	"get":              {idempotent: true},
+	"ls":               {idempotent: true},
+	"refs":             {idempotent: true},
+	"resolve":          {idempotent: true},
+	"dns":              {idempotent: true},
+	"id":               {idempotent: true},
+	"name/resolve":     {idempotent: true},
+	"block/get":        {idempotent: true},
+	"block/stat":       {idempotent: true},
+	"object/get":       {idempotent: true},
+	"object/data":      {idempotent: true},
+	"object/links":     {idempotent: true},
+	"object/stat":      {idempotent: true},
+	"dag/get":          {idempotent: true},
+	"dag/resolve":      {idempotent: true},
+	"dag/walk":         {idempotent: true},
+	"dht/findpeer":     {idempotent: true},
+	"dht/findprovs":    {idempotent: true},
+	"dht/get":          {idempotent: true},
+	"dht/query":        {idempotent: true},
+	"files/ls":         {idempotent: true},
+	"files/read":       {idempotent: true},
+	"files/stat":       {idempotent: true},
+	"pin/ls":           {idempotent: true},
+	"repo/stat":        {idempotent: true},
+	"swarm/addrs":      {idempotent: true},
+	"swarm/peers":      {idempotent: true},
+	"bitswap/stat":     {idempotent: true},
+	"bitswap/wantlist": {idempotent: true},
+	"stats/bw":         {idempotent: true, streaming: true},
+
+	"log/tail":         {streaming: true},
+	"pubsub/sub":       {streaming: true},
+	"events/subscribe": {streaming: true},
+	"ping":             {streaming: true},
+	"dht/announce-log": {streaming: true},
 }
@@ -143,8 +143,8 @@ func setupRepoPath(repoPath string) error {
 		}
 	}
 
-	// Set IPFS_PATH environment varbile
-	if err = os.Setenv("IPFS_PATH", repoPath); err != nil {
+	// Set IPFS_PATH environment varbile, read by getRepoPath
+	if err = os.Setenv(envVar("PATH"), repoPath); err != nil {
 		return err
 	}
 
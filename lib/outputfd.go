@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+)
+
+// outputFile returns the file command output should be written to: the
+// descriptor passed with --output-fd, or stdout.
+//
+// The option has to be found before the command line is parsed, since the
+// output is set up first. It is still declared on the root command so the
+// parser accepts it.
+func outputFile(args []string) (*os.File, error) {
+	fd, found, err := outputFdArg(args)
+	if err != nil || !found {
+		return os.Stdout, err
+	}
+	return openOutputFd(fd)
+}
+
+// outputFdArg looks for --output-fd=<n> or --output-fd <n> in args.
+func outputFdArg(args []string) (int, bool, error) {
+	val, found, err := rootOptionArg(args, corecmds.OutputFdOption)
+	if err != nil || !found {
+		return 0, false, err
+	}
+
+	fd, err := strconv.Atoi(val)
+	if err != nil || fd < 0 {
+		return 0, false, fmt.Errorf("invalid file descriptor %q for option %q", val, corecmds.OutputFdOption)
+	}
+	return fd, true, nil
+}
+
+// rootOptionArg returns the value of the root option name in args, given as
+// --name=<value> or --name <value>, for the options needed before the
+// command line is parsed.
+func rootOptionArg(args []string, name string) (string, bool, error) {
+	flag := "--" + name
+	for i := 1; i < len(args); i++ {
+		switch {
+		case args[i] == "--":
+			return "", false, nil
+		case args[i] == flag:
+			if i+1 == len(args) {
+				return "", false, fmt.Errorf("missing argument for option %q", name)
+			}
+			return args[i+1], true, nil
+		case strings.HasPrefix(args[i], flag+"="):
+			return strings.TrimPrefix(args[i], flag+"="), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// rootBoolOptionArg returns whether the root boolean option name is set in
+// args, given as --name or --name=<bool>, for the options needed before the
+// command line is parsed.
+func rootBoolOptionArg(args []string, name string) (bool, error) {
+	flag := "--" + name
+	for i := 1; i < len(args); i++ {
+		switch {
+		case args[i] == "--":
+			return false, nil
+		case args[i] == flag:
+			return true, nil
+		case strings.HasPrefix(args[i], flag+"="):
+			val := strings.TrimPrefix(args[i], flag+"=")
+			set, err := strconv.ParseBool(val)
+			if err != nil {
+				return false, fmt.Errorf("invalid value %q for option %q", val, name)
+			}
+			return set, nil
+		}
+	}
+	return false, nil
+}
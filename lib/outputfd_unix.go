@@ -0,0 +1,18 @@
+// +build !windows
+
+package lib
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// openOutputFd returns the inherited file descriptor fd as a file.
+func openOutputFd(fd int) (*os.File, error) {
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return nil, fmt.Errorf("cannot write to file descriptor %d: %s", fd, err)
+	}
+	return os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd)), nil
+}
@@ -0,0 +1,12 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+
+	corecmds "github.com/ipfs/go-ipfs/core/commands"
+)
+
+func openOutputFd(fd int) (*os.File, error) {
+	return nil, fmt.Errorf("--%s is not supported on windows, file descriptors are not inherited", corecmds.OutputFdOption)
+}
@@ -59,17 +59,7 @@ func (r *DNSResolver) resolveOnceAsync(ctx context.Context, name string, options
 	}
 	log.Debugf("DNSResolver resolving %s", domain)
 
-	if strings.HasSuffix(domain, ".") {
-		fqdn = domain
-	} else {
-		fqdn = domain + "."
-	}
-
-	if strings.HasSuffix(fqdn, "."+ethTLD+".") {
-		// This is an ENS name.  As we're resolving via an arbitrary DNS server
-		// that may not know about .eth we need to add our link domain suffix.
-		fqdn += linkTLD + "."
-	}
+	fqdn = dnsFqdn(domain)
 
 	rootChan := make(chan lookupRes, 1)
 	go workDomain(r, fqdn, rootChan)
@@ -119,6 +109,22 @@ func (r *DNSResolver) resolveOnceAsync(ctx context.Context, name string, options
 	return out
 }
 
+// dnsFqdn returns the fully qualified name TXT records of domain are looked
+// up under.
+func dnsFqdn(domain string) string {
+	fqdn := domain
+	if !strings.HasSuffix(fqdn, ".") {
+		fqdn += "."
+	}
+
+	if strings.HasSuffix(fqdn, "."+ethTLD+".") {
+		// This is an ENS name.  As we're resolving via an arbitrary DNS server
+		// that may not know about .eth we need to add our link domain suffix.
+		fqdn += linkTLD + "."
+	}
+	return fqdn
+}
+
 func workDomain(r *DNSResolver, name string, res chan lookupRes) {
 	defer close(res)
 
@@ -1,6 +1,7 @@
 package namesys
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -170,3 +171,60 @@ func TestDNSResolution(t *testing.T) {
 	testResolution(t, r, "www.wealdtech.eth", 2, "/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjD", nil)
 	testResolution(t, r, "www.wealdtech.eth.link", 2, "/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjD", nil)
 }
+
+func TestDNSLinkTrace(t *testing.T) {
+	mock := newMockDNS()
+	r := &DNSResolver{lookupTXT: mock.lookupTXT}
+	ctx := context.Background()
+
+	lookups, p, err := r.Trace(ctx, "dns2.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjD" {
+		t.Fatalf("unexpected final path %s", p)
+	}
+	expected := []struct {
+		name string
+		link string
+	}{
+		{"_dnslink.dns2.example.com.", ""},
+		{"dns2.example.com.", "/ipns/dns1.example.com"},
+		{"_dnslink.dns1.example.com.", ""},
+		{"dns1.example.com.", "/ipns/ipfs.example.com"},
+		{"_dnslink.ipfs.example.com.", ""},
+		{"ipfs.example.com.", "/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjD"},
+	}
+	if len(lookups) != len(expected) {
+		t.Fatalf("expected %d lookups, got %v", len(expected), lookups)
+	}
+	for i, e := range expected {
+		l := lookups[i]
+		if l.Name != e.name || l.Link.String() != e.link {
+			t.Errorf("lookup %d: expected %s -> %q, got %s -> %q", i, e.name, e.link, l.Name, l.Link)
+		}
+		if e.link == "" && l.Error == "" {
+			t.Errorf("lookup %d: expected an error", i)
+		}
+	}
+	if got := lookups[5].Records; len(got) != 1 || got[0] != "dnslink=/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjD" {
+		t.Errorf("expected the raw record, got %v", got)
+	}
+
+	lookups, p, err = r.Trace(ctx, "dipfs.example.com")
+	if err != nil || len(lookups) != 1 || p.String() != "/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjD" {
+		t.Errorf("expected the _dnslink record to be used directly, got %v %s %v", lookups, p, err)
+	}
+
+	_, p, err = r.Trace(ctx, "withrecsegment.example.com")
+	if err != nil || p.String() != "/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjD/sub/segment/subsub" {
+		t.Errorf("expected segments to be kept, got %s %v", p, err)
+	}
+
+	if _, _, err := r.Trace(ctx, "loop1.example.com"); err != ErrResolveRecursion {
+		t.Errorf("expected a loop to fail with ErrResolveRecursion, got %v", err)
+	}
+	if _, _, err := r.Trace(ctx, "bad.example.com"); err != ErrResolveFailed {
+		t.Errorf("expected ErrResolveFailed, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package namesys
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	path "github.com/ipfs/go-path"
+	opts "github.com/ipfs/interface-go-ipfs-core/options/namesys"
+	isd "github.com/jbenet/go-is-domain"
+)
+
+// DNSLinkLookup is a TXT lookup made while tracing a DNSLink.
+type DNSLinkLookup struct {
+	// Name is the name the TXT records were looked up under.
+	Name    string
+	Records []string
+	// Link is the path the records point to, if any.
+	Link  path.Path `json:",omitempty"`
+	Error string    `json:",omitempty"`
+}
+
+// Trace resolves the DNSLink of name like Resolve, but also returns every
+// TXT lookup made on the way, including the raw records. DNSLinks pointing
+// to /ipns/<domain> are followed until a path that isn't one is reached.
+func (r *DNSResolver) Trace(ctx context.Context, name string) ([]DNSLinkLookup, path.Path, error) {
+	var (
+		lookups []DNSLinkLookup
+		seen    = make(map[string]bool)
+	)
+	for depth := 0; depth < opts.DefaultDepthLimit; depth++ {
+		if err := ctx.Err(); err != nil {
+			return lookups, "", err
+		}
+
+		segments := strings.SplitN(strings.TrimPrefix(name, "/ipns/"), "/", 2)
+		domain := segments[0]
+		if !isd.IsDomain(domain) {
+			return lookups, "", errors.New("not a valid domain name")
+		}
+		if seen[domain] {
+			return lookups, "", ErrResolveRecursion
+		}
+		seen[domain] = true
+
+		var link path.Path
+		fqdn := dnsFqdn(domain)
+		for _, qname := range []string{"_dnslink." + fqdn, fqdn} {
+			lookup := r.lookupLink(qname)
+			lookups = append(lookups, lookup)
+			if lookup.Link != "" {
+				link = lookup.Link
+				break
+			}
+		}
+		if link == "" {
+			return lookups, "", ErrResolveFailed
+		}
+		if len(segments) > 1 {
+			var err error
+			link, err = path.FromSegments("", strings.TrimRight(link.String(), "/"), segments[1])
+			if err != nil {
+				return lookups, "", err
+			}
+		}
+
+		next := link.Segments()
+		if len(next) < 2 || next[0] != "ipns" || !isd.IsDomain(next[1]) {
+			return lookups, link, nil
+		}
+		name = strings.Join(next[1:], "/")
+	}
+	return lookups, "", ErrResolveRecursion
+}
+
+// lookupLink looks up the TXT records of name, and the path the first valid
+// one points to.
+func (r *DNSResolver) lookupLink(name string) DNSLinkLookup {
+	lookup := DNSLinkLookup{Name: name}
+	txt, err := r.lookupTXT(name)
+	if err != nil {
+		lookup.Error = err.Error()
+		return lookup
+	}
+	lookup.Records = txt
+
+	for _, t := range txt {
+		if p, err := parseEntry(t); err == nil {
+			lookup.Link = p
+			return lookup
+		}
+	}
+	lookup.Error = ErrResolveFailed.Error()
+	return lookup
+}
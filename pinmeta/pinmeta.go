@@ -0,0 +1,123 @@
+// Package pinmeta records metadata about pins that the pinner itself does
+// not keep track of, such as when a pin was added.
+package pinmeta
+
+import (
+	"context"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	pin "github.com/ipfs/go-ipfs-pinner"
+	ipld "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log"
+)
+
+var log = logging.Logger("pinmeta")
+
+// addedPrefix is the datastore namespace holding the time each pin was
+// added, keyed by CID.
+var addedPrefix = ds.NewKey("/local/pins/added")
+
+func addedKey(c cid.Cid) ds.Key {
+	return addedPrefix.ChildString(c.String())
+}
+
+// Pinner wraps a pin.Pinner and records the time at which direct and
+// recursive pins are added. The record is dropped when the pin is removed.
+type Pinner struct {
+	pin.Pinner
+
+	ds  ds.Datastore
+	now func() time.Time
+}
+
+var _ pin.Pinner = (*Pinner)(nil)
+
+// Wrap returns a Pinner recording pin times for p in d.
+func Wrap(p pin.Pinner, d ds.Datastore) *Pinner {
+	return &Pinner{Pinner: p, ds: d, now: time.Now}
+}
+
+// Pin pins node and records the time at which it was pinned.
+func (p *Pinner) Pin(ctx context.Context, node ipld.Node, recursive bool) error {
+	if err := p.Pinner.Pin(ctx, node, recursive); err != nil {
+		return err
+	}
+	p.recordAdded(node.Cid())
+	return nil
+}
+
+// PinWithMode pins c and records the time at which it was pinned.
+func (p *Pinner) PinWithMode(c cid.Cid, mode pin.Mode) {
+	p.Pinner.PinWithMode(c, mode)
+	if mode == pin.Direct || mode == pin.Recursive {
+		p.recordAdded(c)
+	}
+}
+
+// Update updates a recursive pin, recording the time at which the new pin
+// was added.
+func (p *Pinner) Update(ctx context.Context, from, to cid.Cid, unpin bool) error {
+	if err := p.Pinner.Update(ctx, from, to, unpin); err != nil {
+		return err
+	}
+	p.recordAdded(to)
+	if unpin {
+		p.removeAdded(from)
+	}
+	return nil
+}
+
+// Unpin unpins c and forgets the time it was pinned at.
+func (p *Pinner) Unpin(ctx context.Context, c cid.Cid, recursive bool) error {
+	if err := p.Pinner.Unpin(ctx, c, recursive); err != nil {
+		return err
+	}
+	p.removeAdded(c)
+	return nil
+}
+
+// RemovePinWithMode unpins c and forgets the time it was pinned at.
+func (p *Pinner) RemovePinWithMode(c cid.Cid, mode pin.Mode) {
+	p.Pinner.RemovePinWithMode(c, mode)
+	p.removeAdded(c)
+}
+
+// Failing to record metadata must never fail the pin operation itself, so
+// errors are only logged.
+
+func (p *Pinner) recordAdded(c cid.Cid) {
+	t, err := p.now().UTC().MarshalText()
+	if err != nil {
+		log.Errorf("failed to encode pin time for %s: %s", c, err)
+		return
+	}
+	if err := p.ds.Put(addedKey(c), t); err != nil {
+		log.Errorf("failed to record pin time for %s: %s", c, err)
+	}
+}
+
+func (p *Pinner) removeAdded(c cid.Cid) {
+	if err := p.ds.Delete(addedKey(c)); err != nil && err != ds.ErrNotFound {
+		log.Errorf("failed to remove pin time for %s: %s", c, err)
+	}
+}
+
+// AddedAt returns the time at which c was pinned. ok is false when no time
+// was recorded, e.g. for pins added before pin times were tracked.
+func AddedAt(d ds.Datastore, c cid.Cid) (t time.Time, ok bool, err error) {
+	buf, err := d.Get(addedKey(c))
+	switch err {
+	case nil:
+	case ds.ErrNotFound:
+		return time.Time{}, false, nil
+	default:
+		return time.Time{}, false, err
+	}
+
+	if err := t.UnmarshalText(buf); err != nil {
+		return time.Time{}, false, err
+	}
+	return t, true, nil
+}
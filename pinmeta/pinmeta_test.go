@@ -0,0 +1,65 @@
+package pinmeta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bs "github.com/ipfs/go-blockservice"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	pin "github.com/ipfs/go-ipfs-pinner"
+	mdag "github.com/ipfs/go-merkledag"
+)
+
+func TestAddedAt(t *testing.T) {
+	ctx := context.Background()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(dstore)
+	dserv := mdag.NewDAGService(bs.New(bstore, offline.Exchange(bstore)))
+
+	p := Wrap(pin.NewPinner(dstore, dserv, dserv), dstore)
+
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ages := []time.Duration{0, 30 * time.Minute, 2 * time.Hour}
+
+	var nodes []*mdag.ProtoNode
+	for i, age := range ages {
+		nd := mdag.NodeWithData([]byte{byte(i)})
+		if err := dserv.Add(ctx, nd); err != nil {
+			t.Fatal(err)
+		}
+		p.now = func() time.Time { return base.Add(-age) }
+		if err := p.Pin(ctx, nd, i%2 == 0); err != nil {
+			t.Fatal(err)
+		}
+		nodes = append(nodes, nd)
+	}
+
+	cutoff := base.Add(-time.Hour)
+	for i, nd := range nodes {
+		added, ok, err := AddedAt(dstore, nd.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("no pin time recorded for pin %d", i)
+		}
+		if !added.Equal(base.Add(-ages[i])) {
+			t.Fatalf("pin %d: expected %s, got %s", i, base.Add(-ages[i]), added)
+		}
+		if recent := added.After(cutoff); recent != (ages[i] < time.Hour) {
+			t.Fatalf("pin %d: wrong recency %t", i, recent)
+		}
+	}
+
+	if err := p.Unpin(ctx, nodes[0].Cid(), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := AddedAt(dstore, nodes[0].Cid()); err != nil || ok {
+		t.Fatalf("expected pin time to be removed with the pin (ok=%t, err=%v)", ok, err)
+	}
+}
@@ -17,8 +17,8 @@ func init() {
 	loadPluginsFunc = linuxLoadFunc
 }
 
-func linuxLoadFunc(pluginDir string) ([]iplugin.Plugin, error) {
-	var plugins []iplugin.Plugin
+func linuxLoadFunc(pluginDir string) (map[string][]iplugin.Plugin, error) {
+	plugins := make(map[string][]iplugin.Plugin)
 
 	err := filepath.Walk(pluginDir, func(fi string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -40,7 +40,7 @@ func linuxLoadFunc(pluginDir string) ([]iplugin.Plugin, error) {
 		}
 
 		if newPlugins, err := loadPlugin(fi); err == nil {
-			plugins = append(plugins, newPlugins...)
+			plugins[fi] = newPlugins
 		} else {
 			return fmt.Errorf("loading plugin %s: %s", fi, err)
 		}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	config "github.com/ipfs/go-ipfs-config"
@@ -30,7 +31,9 @@ func Preload(plugins ...plugin.Plugin) {
 
 var log = logging.Logger("plugin/loader")
 
-var loadPluginsFunc = func(string) ([]plugin.Plugin, error) {
+// loadPluginsFunc loads the plugins found in a directory, keyed by the file
+// they were loaded from.
+var loadPluginsFunc = func(string) (map[string][]plugin.Plugin, error) {
 	return nil, nil
 }
 
@@ -88,14 +91,26 @@ func (ls loaderState) String() string {
 type PluginLoader struct {
 	state   loaderState
 	plugins map[string]plugin.Plugin
+	paths   map[string]string
 	started []plugin.Plugin
 	config  config.Plugins
 	repo    string
+
+	// stages and errs are how far every plugin got, and why it failed if
+	// it did.
+	stages map[string]PluginStage
+	errs   map[string]error
 }
 
 // NewPluginLoader creates new plugin loader
 func NewPluginLoader(repo string) (*PluginLoader, error) {
-	loader := &PluginLoader{plugins: make(map[string]plugin.Plugin, len(preloadPlugins)), repo: repo}
+	loader := &PluginLoader{
+		plugins: make(map[string]plugin.Plugin, len(preloadPlugins)),
+		paths:   make(map[string]string),
+		repo:    repo,
+		stages:  make(map[string]PluginStage),
+		errs:    make(map[string]error),
+	}
 	if repo != "" {
 		cfg, err := cserialize.Load(filepath.Join(repo, config.DefaultConfigFile))
 		switch err {
@@ -152,6 +167,7 @@ func (loader *PluginLoader) Load(pl plugin.Plugin) error {
 		return nil
 	}
 	loader.plugins[name] = pl
+	loader.stages[name] = PluginLoaded
 	return nil
 }
 
@@ -165,15 +181,26 @@ func (loader *PluginLoader) LoadDirectory(pluginDir string) error {
 		return err
 	}
 
-	for _, pl := range newPls {
-		if err := loader.Load(pl); err != nil {
-			return err
+	paths := make([]string, 0, len(newPls))
+	for path := range newPls {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		for _, pl := range newPls[path] {
+			if err := loader.Load(pl); err != nil {
+				return err
+			}
+			if _, loaded := loader.plugins[pl.Name()]; loaded {
+				loader.paths[pl.Name()] = path
+			}
 		}
 	}
 	return nil
 }
 
-func loadDynamicPlugins(pluginDir string) ([]plugin.Plugin, error) {
+func loadDynamicPlugins(pluginDir string) (map[string][]plugin.Plugin, error) {
 	_, err := os.Stat(pluginDir)
 	if os.IsNotExist(err) {
 		return nil, nil
@@ -185,20 +212,128 @@ func loadDynamicPlugins(pluginDir string) ([]plugin.Plugin, error) {
 	return loadPluginsFunc(pluginDir)
 }
 
+// PluginInfo describes a loaded plugin.
+type PluginInfo struct {
+	Name    string
+	Version string
+	// Path is the file the plugin was loaded from, empty for plugins built
+	// into the binary.
+	Path string
+	// Config is the plugin's config from the repo config, if any.
+	Config interface{}
+}
+
+// Plugins returns information about the loaded plugins, sorted by name.
+func (loader *PluginLoader) Plugins() []PluginInfo {
+	infos := make([]PluginInfo, 0, len(loader.plugins))
+	for name, pl := range loader.plugins {
+		infos = append(infos, PluginInfo{
+			Name:    name,
+			Version: pl.Version(),
+			Path:    loader.paths[name],
+			Config:  loader.config.Plugins[name].Config,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// PluginStage is how far a plugin got in being loaded.
+type PluginStage string
+
+const (
+	PluginLoaded      PluginStage = "loaded"
+	PluginInitialized PluginStage = "initialized"
+	PluginInjected    PluginStage = "injected"
+	PluginFailed      PluginStage = "failed"
+)
+
+// PluginStatus describes a loaded plugin and how far it got in being
+// initialized and injected.
+type PluginStatus struct {
+	PluginInfo
+	Stage PluginStage
+	// Error is why the plugin failed to initialize or inject.
+	Error string `json:",omitempty"`
+}
+
+// Status returns the status of the loaded plugins, sorted by name. Those
+// that failed keep their error, the plugins after them, in the order of
+// their names, staying at the stage they were at.
+func (loader *PluginLoader) Status() []PluginStatus {
+	infos := loader.Plugins()
+	statuses := make([]PluginStatus, 0, len(infos))
+	for _, info := range infos {
+		st := PluginStatus{PluginInfo: info, Stage: loader.stages[info.Name]}
+		if err := loader.errs[info.Name]; err != nil {
+			st.Error = err.Error()
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses
+}
+
+// names returns the names of the loaded plugins, sorted, so they are
+// initialized, injected and started in the same order on every run.
+func (loader *PluginLoader) names() []string {
+	names := make([]string, 0, len(loader.plugins))
+	for name := range loader.plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fail records the plugin name failed with err, failing the loader.
+func (loader *PluginLoader) fail(name string, err error) {
+	loader.state = loaderFailed
+	loader.stages[name] = PluginFailed
+	loader.errs[name] = err
+}
+
+// Disable unloads the plugins named in names, compared case-insensitively,
+// so they are neither initialized nor injected. It returns the names that
+// match no loaded plugin.
+func (loader *PluginLoader) Disable(names ...string) ([]string, error) {
+	if err := loader.assertState(loaderLoading); err != nil {
+		return nil, err
+	}
+
+	var unknown []string
+	for _, name := range names {
+		found := false
+		for loaded := range loader.plugins {
+			if strings.EqualFold(loaded, name) {
+				log.Infof("not loading plugin %s disabled on the command line", loaded)
+				delete(loader.plugins, loaded)
+				delete(loader.paths, loaded)
+				delete(loader.stages, loaded)
+				found = true
+			}
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown, nil
+}
+
 // Initialize initializes all loaded plugins
 func (loader *PluginLoader) Initialize() error {
 	if err := loader.transition(loaderLoading, loaderInitializing); err != nil {
 		return err
 	}
-	for name, p := range loader.plugins {
+	for _, name := range loader.names() {
+		p := loader.plugins[name]
 		err := p.Init(&plugin.Environment{
 			Repo:   loader.repo,
 			Config: loader.config.Plugins[name].Config,
 		})
 		if err != nil {
-			loader.state = loaderFailed
+			loader.fail(name, err)
 			return err
 		}
+		loader.stages[name] = PluginInitialized
 	}
 
 	return loader.transition(loaderInitializing, loaderInitialized)
@@ -210,28 +345,30 @@ func (loader *PluginLoader) Inject() error {
 		return err
 	}
 
-	for _, pl := range loader.plugins {
+	for _, name := range loader.names() {
+		pl := loader.plugins[name]
 		if pl, ok := pl.(plugin.PluginIPLD); ok {
 			err := injectIPLDPlugin(pl)
 			if err != nil {
-				loader.state = loaderFailed
+				loader.fail(name, err)
 				return err
 			}
 		}
 		if pl, ok := pl.(plugin.PluginTracer); ok {
 			err := injectTracerPlugin(pl)
 			if err != nil {
-				loader.state = loaderFailed
+				loader.fail(name, err)
 				return err
 			}
 		}
 		if pl, ok := pl.(plugin.PluginDatastore); ok {
 			err := injectDatastorePlugin(pl)
 			if err != nil {
-				loader.state = loaderFailed
+				loader.fail(name, err)
 				return err
 			}
 		}
+		loader.stages[name] = PluginInjected
 	}
 
 	return loader.transition(loaderInjecting, loaderInjected)
@@ -246,7 +383,8 @@ func (loader *PluginLoader) Start(node *core.IpfsNode) error {
 	if err != nil {
 		return err
 	}
-	for _, pl := range loader.plugins {
+	for _, name := range loader.names() {
+		pl := loader.plugins[name]
 		if pl, ok := pl.(plugin.PluginDaemon); ok {
 			err := pl.Start(iface)
 			if err != nil {
@@ -0,0 +1,202 @@
+package loader
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	config "github.com/ipfs/go-ipfs-config"
+	cserialize "github.com/ipfs/go-ipfs-config/serialize"
+
+	plugin "github.com/ipfs/go-ipfs/plugin"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+type stubPlugin struct {
+	name string
+}
+
+func (p *stubPlugin) Name() string                       { return p.name }
+func (p *stubPlugin) Version() string                    { return "0.1.0" }
+func (p *stubPlugin) Init(env *plugin.Environment) error { return nil }
+
+type brokenTracerPlugin struct {
+	stubPlugin
+}
+
+func (p *brokenTracerPlugin) InitTracer() (opentracing.Tracer, error) {
+	return nil, errors.New("no collector")
+}
+
+type brokenInitPlugin struct {
+	stubPlugin
+}
+
+func (p *brokenInitPlugin) Init(env *plugin.Environment) error {
+	return errors.New("bad config")
+}
+
+func TestPluginsExportConfig(t *testing.T) {
+	repo, err := ioutil.TempDir("", "plugin-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repo)
+
+	stubConfig := map[string]interface{}{"endpoint": "http://127.0.0.1:1234", "retries": float64(3)}
+	cfg := &config.Config{
+		Plugins: config.Plugins{
+			Plugins: map[string]config.Plugin{
+				"stub":     {Config: stubConfig},
+				"disabled": {Disabled: true},
+			},
+		},
+	}
+	if err := cserialize.WriteConfigFile(filepath.Join(repo, config.DefaultConfigFile), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	loader, err := NewPluginLoader(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"stub", "disabled"} {
+		if err := loader.Load(&stubPlugin{name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stub *PluginInfo
+	for _, info := range loader.Plugins() {
+		info := info
+		switch info.Name {
+		case "stub":
+			stub = &info
+		case "disabled":
+			t.Fatal("disabled plugins must not be exported")
+		}
+	}
+	if stub == nil {
+		t.Fatal("stub plugin was not exported")
+	}
+	if stub.Version != "0.1.0" || stub.Path != "" {
+		t.Fatalf("unexpected plugin info %+v", stub)
+	}
+	if !reflect.DeepEqual(stub.Config, stubConfig) {
+		t.Fatalf("expected config %v, got %v", stubConfig, stub.Config)
+	}
+}
+
+func TestDisable(t *testing.T) {
+	loader, err := NewPluginLoader("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"stub", "crashing-datastore"} {
+		if err := loader.Load(&stubPlugin{name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	unknown, err := loader.Disable("Crashing-Datastore", "typo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unknown, []string{"typo"}) {
+		t.Fatalf("expected the unknown plugins [typo], got %v", unknown)
+	}
+	for _, info := range loader.Plugins() {
+		if info.Name == "crashing-datastore" {
+			t.Fatal("disabled plugin is still loaded")
+		}
+	}
+	if _, ok := loader.plugins["stub"]; !ok {
+		t.Fatal("plugin not disabled was unloaded")
+	}
+
+	if err := loader.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.Disable("stub"); err == nil {
+		t.Fatal("expected disabling plugins once initialized to fail")
+	}
+}
+
+// newBareLoader returns a loader without the preloaded plugins, which can
+// only be injected once per process.
+func newBareLoader(t *testing.T) *PluginLoader {
+	preloaded := preloadPlugins
+	preloadPlugins = nil
+	defer func() { preloadPlugins = preloaded }()
+
+	loader, err := NewPluginLoader("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loader
+}
+
+func TestStatus(t *testing.T) {
+	loader := newBareLoader(t)
+	if err := loader.Load(&stubPlugin{name: "stub"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Load(&brokenTracerPlugin{stubPlugin{name: "tracer"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Load(&stubPlugin{name: "unreached"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stages := func() map[string]PluginStage {
+		m := make(map[string]PluginStage)
+		for _, st := range loader.Status() {
+			m[st.Name] = st.Stage
+		}
+		return m
+	}
+	if got := stages(); got["stub"] != PluginLoaded || got["tracer"] != PluginLoaded {
+		t.Fatalf("expected the plugins loaded, got %v", got)
+	}
+
+	if err := loader.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if got := stages(); got["stub"] != PluginInitialized || got["tracer"] != PluginInitialized {
+		t.Fatalf("expected the plugins initialized, got %v", got)
+	}
+
+	if err := loader.Inject(); err == nil {
+		t.Fatal("expected injecting the broken tracer to fail")
+	}
+	for _, st := range loader.Status() {
+		if st.Name != "tracer" {
+			continue
+		}
+		if st.Stage != PluginFailed || st.Error != "no collector" {
+			t.Fatalf("expected the tracer to have failed with its error, got %+v", st)
+		}
+	}
+	// the plugins are injected in the order of their names
+	if got := stages(); got["stub"] != PluginInjected || got["unreached"] != PluginInitialized {
+		t.Fatalf("expected the plugins before the tracer injected, and those after it not, got %v", got)
+	}
+}
+
+func TestStatusInitFailure(t *testing.T) {
+	loader := newBareLoader(t)
+	if err := loader.Load(&brokenInitPlugin{stubPlugin{name: "broken"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Initialize(); err == nil {
+		t.Fatal("expected initializing the broken plugin to fail")
+	}
+	st := loader.Status()
+	if len(st) != 1 || st[0].Stage != PluginFailed || st[0].Error != "bad config" {
+		t.Fatalf("expected the plugin to have failed with its error, got %+v", st)
+	}
+}
@@ -5,6 +5,16 @@ import (
 	"strings"
 )
 
+// KeyNotFoundError is returned by MapGetKV for a key missing from the map,
+// Key being the part of it that was found.
+type KeyNotFoundError struct {
+	Key string
+}
+
+func (e *KeyNotFoundError) Error() string {
+	return fmt.Sprintf("%s key has no attributes", e.Key)
+}
+
 func MapGetKV(v map[string]interface{}, key string) (interface{}, error) {
 	var ok bool
 	var mcursor map[string]interface{}
@@ -21,7 +31,7 @@ func MapGetKV(v map[string]interface{}, key string) (interface{}, error) {
 
 		cursor, ok = mcursor[part]
 		if !ok {
-			return nil, fmt.Errorf("%s key has no attributes", sofar)
+			return nil, &KeyNotFoundError{Key: sofar}
 		}
 	}
 	return cursor, nil
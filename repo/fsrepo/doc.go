@@ -13,6 +13,7 @@
 //   │   ├── ipfs-daemon.cpuprof
 //   │   └── ipfs-daemon.memprof
 //   ├── datastore/
+//   ├── denylist                 <------ peers and IP ranges to refuse connections with
 //   ├── repo.lock                <------ protects datastore/ and config
 //   └── version
 package fsrepo
@@ -65,6 +65,7 @@ func (err NoRepoError) Error() string {
 
 const apiFile = "api"
 const swarmKeyFile = "swarm.key"
+const denylistFile = "denylist"
 
 const specFn = "datastore_spec"
 
@@ -700,8 +701,18 @@ func (r *FSRepo) GetStorageUsage() (uint64, error) {
 }
 
 func (r *FSRepo) SwarmKey() ([]byte, error) {
+	return r.readOptionalFile(swarmKeyFile)
+}
+
+func (r *FSRepo) Denylist() ([]byte, error) {
+	return r.readOptionalFile(denylistFile)
+}
+
+// readOptionalFile reads the named file from the repo, returning nil if it
+// does not exist.
+func (r *FSRepo) readOptionalFile(name string) ([]byte, error) {
 	repoPath := filepath.Clean(r.path)
-	spath := filepath.Join(repoPath, swarmKeyFile)
+	spath := filepath.Join(repoPath, name)
 
 	f, err := os.Open(spath)
 	if err != nil {
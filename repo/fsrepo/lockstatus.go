@@ -0,0 +1,81 @@
+package fsrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// LockStatus describes the repo lock file and the process holding it.
+type LockStatus struct {
+	Path   string
+	Exists bool
+	// PID is the process holding the lock, or the owner recorded in the
+	// lock file. Zero when unknown.
+	PID   int
+	Alive bool
+	// Stale is set when the lock file names a process that is gone. Such a
+	// lock keeps the repo from being opened until it is removed.
+	Stale bool
+}
+
+// ErrLockNotStale is returned by BreakStaleLock when the lock is missing,
+// held or doesn't name its owner.
+var ErrLockNotStale = errors.New("repo lock is not stale")
+
+// ReadLockStatus returns the status of the lock of the repo at repoPath.
+//
+// The status must not be read by the process holding the lock: on unix,
+// closing any descriptor of the lock file releases the locks this process
+// holds on it.
+func ReadLockStatus(repoPath string) (*LockStatus, error) {
+	st := &LockStatus{Path: filepath.Join(filepath.Clean(repoPath), LockFile)}
+
+	data, err := ioutil.ReadFile(st.Path)
+	switch {
+	case os.IsNotExist(err):
+		return st, nil
+	case err != nil:
+		return nil, err
+	}
+	st.Exists = true
+
+	if len(data) > 0 {
+		// the portable lock records its owner in the file, which also keeps
+		// the lock from being taken again while the file is around
+		var meta struct{ OwnerPID int }
+		if err := json.Unmarshal(data, &meta); err != nil || meta.OwnerPID <= 0 {
+			return nil, fmt.Errorf("unrecognized content in %s", st.Path)
+		}
+		st.PID = meta.OwnerPID
+		st.Alive = processAlive(st.PID)
+		st.Stale = !st.Alive
+		return st, nil
+	}
+
+	st.PID, err = lockHolder(st.Path)
+	if err != nil {
+		return nil, err
+	}
+	st.Alive = st.PID != 0
+	return st, nil
+}
+
+// BreakStaleLock removes the lock of the repo at repoPath if it is stale,
+// returning ErrLockNotStale otherwise.
+func BreakStaleLock(repoPath string) (*LockStatus, error) {
+	st, err := ReadLockStatus(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if !st.Stale {
+		return st, ErrLockNotStale
+	}
+	if err := os.Remove(st.Path); err != nil {
+		return st, err
+	}
+	return st, nil
+}
@@ -0,0 +1,20 @@
+// +build windows plan9
+
+package fsrepo
+
+import "os"
+
+// lockHolder is not supported here, the locks record their owner in the
+// lock file instead.
+func lockHolder(path string) (int, error) {
+	return 0, nil
+}
+
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,71 @@
+package fsrepo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// deadPID is above the PID limit of the systems we run on, no process can
+// have it.
+const deadPID = 1 << 30
+
+func writeLockOwner(t *testing.T, repoPath string, pid int) {
+	data := fmt.Sprintf(`{"OwnerPID":%d}`, pid)
+	if err := ioutil.WriteFile(filepath.Join(repoPath, LockFile), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockStatus(t *testing.T) {
+	t.Parallel()
+	path := testRepoPath("lock", t)
+	defer os.RemoveAll(path)
+
+	st, err := ReadLockStatus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Exists || st.Stale {
+		t.Fatalf("expected no lock, got %+v", st)
+	}
+	if _, err := BreakStaleLock(path); err != ErrLockNotStale {
+		t.Fatalf("expected %s without a lock, got %v", ErrLockNotStale, err)
+	}
+
+	// a live owner is left alone
+	writeLockOwner(t, path, os.Getpid())
+	st, err = ReadLockStatus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Exists || st.PID != os.Getpid() || !st.Alive || st.Stale {
+		t.Fatalf("expected a lock held by this process, got %+v", st)
+	}
+	if _, err := BreakStaleLock(path); err != ErrLockNotStale {
+		t.Fatalf("expected %s for a live owner, got %v", ErrLockNotStale, err)
+	}
+
+	writeLockOwner(t, path, deadPID)
+	st, err = ReadLockStatus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Exists || st.PID != deadPID || st.Alive || !st.Stale {
+		t.Fatalf("expected a stale lock, got %+v", st)
+	}
+	if st, err = BreakStaleLock(path); err != nil || !st.Stale {
+		t.Fatalf("expected the stale lock to be removed, got %+v, %v", st, err)
+	}
+	if _, err := os.Stat(filepath.Join(path, LockFile)); !os.IsNotExist(err) {
+		t.Fatalf("expected the lock file to be gone, got %v", err)
+	}
+
+	// the repo can be locked again
+	locked, err := LockedByOtherProcess(path)
+	if err != nil || locked {
+		t.Fatalf("expected the repo to be unlocked, got %t, %v", locked, err)
+	}
+}
@@ -55,4 +55,8 @@ func (m *Mock) SwarmKey() ([]byte, error) {
 	return nil, nil
 }
 
+func (m *Mock) Denylist() ([]byte, error) {
+	return nil, nil
+}
+
 func (m *Mock) FileManager() *filestore.FileManager { return m.F }
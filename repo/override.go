@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ipfs/go-ipfs/repo/common"
+
+	config "github.com/ipfs/go-ipfs-config"
+)
+
+// ErrConfigOverridden is returned when trying to write the config of a repo
+// wrapped with WithConfigOverrides.
+var ErrConfigOverridden = errors.New("config has temporary overrides applied and cannot be written")
+
+// ApplyConfigOverrides returns a copy of cfg with the given overrides
+// applied. Each override has the form <key>=<value>, where key is a dotted
+// config key such as "Gateway.NoFetch". Values are parsed as JSON unless the
+// current value at key is a string. cfg itself is left untouched.
+func ApplyConfigOverrides(cfg *config.Config, overrides []string) (*config.Config, error) {
+	mapconf, err := config.ToMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range overrides {
+		kv := strings.SplitN(o, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid config override %q, expected <key>=<value>", o)
+		}
+		key, valueStr := kv[0], kv[1]
+
+		var value interface{} = valueStr
+		if old, err := common.MapGetKV(mapconf, key); err == nil {
+			if _, isString := old.(string); !isString {
+				if err := json.Unmarshal([]byte(valueStr), &value); err != nil {
+					return nil, fmt.Errorf("config override %q: %s", o, err)
+				}
+			}
+		} else if err := json.Unmarshal([]byte(valueStr), &value); err != nil {
+			value = valueStr
+		}
+
+		if err := common.MapSetKV(mapconf, key, value); err != nil {
+			return nil, fmt.Errorf("config override %q: %s", o, err)
+		}
+	}
+
+	newCfg, err := config.FromMap(mapconf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown keys are silently dropped when decoding, make sure every
+	// override made it through.
+	newMap, err := config.ToMap(newCfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		key := strings.SplitN(o, "=", 2)[0]
+		if _, err := common.MapGetKV(newMap, key); err != nil {
+			return nil, fmt.Errorf("config override %q: unknown config key %q", o, key)
+		}
+	}
+	return newCfg, nil
+}
+
+// WithConfigOverrides wraps r so that its config has the given overrides
+// applied (see ApplyConfigOverrides). The overrides only live in memory:
+// the wrapped repo refuses to write its config so they can never be
+// persisted by accident.
+func WithConfigOverrides(r Repo, overrides []string) (Repo, error) {
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err = ApplyConfigOverrides(cfg, overrides)
+	if err != nil {
+		return nil, err
+	}
+	return &overriddenRepo{Repo: r, cfg: cfg}, nil
+}
+
+type overriddenRepo struct {
+	Repo
+	cfg *config.Config
+}
+
+func (r *overriddenRepo) Config() (*config.Config, error) {
+	return r.cfg, nil
+}
+
+func (r *overriddenRepo) GetConfigKey(key string) (interface{}, error) {
+	mapconf, err := config.ToMap(r.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return common.MapGetKV(mapconf, key)
+}
+
+func (r *overriddenRepo) SetConfig(*config.Config) error {
+	return ErrConfigOverridden
+}
+
+func (r *overriddenRepo) SetConfigKey(string, interface{}) error {
+	return ErrConfigOverridden
+}
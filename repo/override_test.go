@@ -0,0 +1,99 @@
+package repo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ipfs/go-ipfs/plugin/loader"
+	"github.com/ipfs/go-ipfs/repo"
+	"github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	config "github.com/ipfs/go-ipfs-config"
+)
+
+func TestWithConfigOverrides(t *testing.T) {
+	loader, err := loader.NewPluginLoader("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Inject(); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := ioutil.TempDir("", "override")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	cfg := &config.Config{Datastore: config.DefaultDatastoreConfig()}
+	cfg.Addresses.API = []string{"/ip4/127.0.0.1/tcp/5001"}
+	if err := fsrepo.Init(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := fsrepo.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	or, err := repo.WithConfigOverrides(r, []string{
+		"Gateway.NoFetch=true",
+		"Addresses.API=/ip4/127.0.0.1/tcp/5002",
+		"Swarm.ConnMgr.HighWater=42",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := or.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Gateway.NoFetch {
+		t.Error("expected Gateway.NoFetch to be overridden")
+	}
+	if len(got.Addresses.API) != 1 || got.Addresses.API[0] != "/ip4/127.0.0.1/tcp/5002" {
+		t.Errorf("expected Addresses.API to be overridden, got %v", got.Addresses.API)
+	}
+	if got.Swarm.ConnMgr.HighWater != 42 {
+		t.Errorf("expected Swarm.ConnMgr.HighWater to be overridden, got %d", got.Swarm.ConnMgr.HighWater)
+	}
+	if v, err := or.GetConfigKey("Gateway.NoFetch"); err != nil || v != true {
+		t.Errorf("expected GetConfigKey to see the override, got %v (%v)", v, err)
+	}
+
+	if err := or.SetConfig(got); err != repo.ErrConfigOverridden {
+		t.Errorf("expected SetConfig to be refused, got %v", err)
+	}
+	if err := or.SetConfigKey("Gateway.NoFetch", true); err != repo.ErrConfigOverridden {
+		t.Errorf("expected SetConfigKey to be refused, got %v", err)
+	}
+
+	onDisk, err := fsrepo.ConfigAt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onDisk.Gateway.NoFetch || onDisk.Addresses.API[0] != "/ip4/127.0.0.1/tcp/5001" || onDisk.Swarm.ConnMgr.HighWater != 0 {
+		t.Error("overrides leaked into the config on disk")
+	}
+}
+
+func TestApplyConfigOverridesInvalid(t *testing.T) {
+	cfg := &config.Config{}
+	for _, o := range []string{
+		"Gateway.NoFetch",
+		"=true",
+		"Gateway.NoFetch=notabool",
+		"Gateway.NoSuchKey=true",
+	} {
+		if _, err := repo.ApplyConfigOverrides(cfg, []string{o}); err == nil {
+			t.Errorf("expected %q to be rejected", o)
+		}
+	}
+}
@@ -53,6 +53,10 @@ type Repo interface {
 	// SwarmKey returns the configured shared symmetric key for the private networks feature.
 	SwarmKey() ([]byte, error)
 
+	// Denylist returns the contents of the connection denylist, or nil if
+	// there is none.
+	Denylist() ([]byte, error)
+
 	io.Closer
 }
 
@@ -0,0 +1,178 @@
+// Package reprovidestats tracks the progress of reprovide cycles so the node
+// can report how fast it reprovides and how long a full cycle takes.
+package reprovidestats
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	query "github.com/ipfs/go-datastore/query"
+	simple "github.com/ipfs/go-ipfs-provider/simple"
+)
+
+// QueueLenFunc returns the number of CIDs waiting in the provide queue.
+type QueueLenFunc func() (int, error)
+
+// DatastoreQueueLen counts the entries the provide queue keeps under prefix
+// in ds.
+func DatastoreQueueLen(ds datastore.Datastore, prefix datastore.Key) QueueLenFunc {
+	return func() (int, error) {
+		res, err := ds.Query(query.Query{Prefix: prefix.String(), KeysOnly: true})
+		if err != nil {
+			return 0, err
+		}
+		defer res.Close()
+
+		n := 0
+		for r := range res.Next() {
+			if r.Error != nil {
+				return 0, r.Error
+			}
+			n++
+		}
+		return n, nil
+	}
+}
+
+// Stats is a snapshot of the reprovider's progress.
+type Stats struct {
+	// Queued is the number of CIDs waiting in the provide queue.
+	Queued int
+	// Running is set while a reprovide cycle is in progress.
+	Running bool
+	// Provided is the number of CIDs handed to the reprovider in the
+	// running cycle, or in the last one when none is running.
+	Provided int
+	// Remaining is the number of CIDs left in the running cycle, estimated
+	// from the size of the last complete cycle.
+	Remaining int
+	// Rate is the number of CIDs reprovided per second.
+	Rate float64
+	// LastCycle is the number of CIDs reprovided by the last complete
+	// cycle, and LastCycleDuration how long it took.
+	LastCycle         int
+	LastCycleDuration time.Duration
+	// CycleEstimate is the estimated duration of a full cycle at the
+	// current rate, zero until it can be estimated.
+	CycleEstimate time.Duration
+}
+
+// Tracker counts the keys going through the reprovider.
+type Tracker struct {
+	queueLen QueueLenFunc
+	now      func() time.Time
+
+	mu           sync.Mutex
+	running      bool
+	start        time.Time
+	provided     int
+	lastTotal    int
+	lastDuration time.Duration
+}
+
+// New returns a Tracker reporting the depth of the provide queue with
+// queueLen, which may be nil.
+func New(queueLen QueueLenFunc) *Tracker {
+	return &Tracker{
+		queueLen: queueLen,
+		now:      time.Now,
+	}
+}
+
+// KeyProvider wraps the key provider of a reprovider so every key it reads
+// is counted. A cycle starts when the reprovider asks for the keys and is
+// complete once they have all been read.
+func (t *Tracker) KeyProvider(kp simple.KeyChanFunc) simple.KeyChanFunc {
+	return func(ctx context.Context) (<-chan cid.Cid, error) {
+		keys, err := kp(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		t.startCycle()
+		out := make(chan cid.Cid)
+		go func() {
+			defer close(out)
+			for c := range keys {
+				select {
+				case out <- c:
+					t.count()
+				case <-ctx.Done():
+					t.endCycle(false)
+					return
+				}
+			}
+			t.endCycle(true)
+		}()
+		return out, nil
+	}
+}
+
+func (t *Tracker) startCycle() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running = true
+	t.start = t.now()
+	t.provided = 0
+}
+
+func (t *Tracker) count() {
+	t.mu.Lock()
+	t.provided++
+	t.mu.Unlock()
+}
+
+func (t *Tracker) endCycle(complete bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running = false
+	if complete {
+		t.lastTotal = t.provided
+		t.lastDuration = t.now().Sub(t.start)
+	}
+}
+
+// Stats returns the current progress of the reprovider.
+func (t *Tracker) Stats() (Stats, error) {
+	var queued int
+	if t.queueLen != nil {
+		var err error
+		if queued, err = t.queueLen(); err != nil {
+			return Stats{}, err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := Stats{
+		Queued:            queued,
+		Running:           t.running,
+		Provided:          t.provided,
+		LastCycle:         t.lastTotal,
+		LastCycleDuration: t.lastDuration,
+	}
+
+	if elapsed := t.now().Sub(t.start); t.running && t.provided > 0 && elapsed > 0 {
+		s.Rate = float64(t.provided) / elapsed.Seconds()
+	} else if t.lastDuration > 0 {
+		s.Rate = float64(t.lastTotal) / t.lastDuration.Seconds()
+	}
+
+	if t.running && t.lastTotal > t.provided {
+		s.Remaining = t.lastTotal - t.provided
+	}
+
+	total := t.lastTotal
+	if t.running && t.provided > total {
+		// the cycle already outgrew the last one
+		total = t.provided
+	}
+	if s.Rate > 0 && total > 0 {
+		s.CycleEstimate = time.Duration(float64(total) / s.Rate * float64(time.Second))
+	}
+	return s, nil
+}
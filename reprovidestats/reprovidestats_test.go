@@ -0,0 +1,175 @@
+package reprovidestats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	u "github.com/ipfs/go-ipfs-util"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func testCids(n int) []cid.Cid {
+	cids := make([]cid.Cid, n)
+	for i := range cids {
+		cids[i] = cid.NewCidV0(u.Hash([]byte{byte(i)}))
+	}
+	return cids
+}
+
+// stubKeyProvider streams a known set of keys, like the blockstore or
+// pinned key providers of the reprovider.
+func stubKeyProvider(cids []cid.Cid) func(context.Context) (<-chan cid.Cid, error) {
+	return func(ctx context.Context) (<-chan cid.Cid, error) {
+		ch := make(chan cid.Cid, len(cids))
+		for _, c := range cids {
+			ch <- c
+		}
+		close(ch)
+		return ch, nil
+	}
+}
+
+func TestTrackerCycle(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	tr := New(func() (int, error) { return 7, nil })
+	tr.now = clock.now
+
+	s, err := tr.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Queued != 7 || s.Running || s.Rate != 0 || s.CycleEstimate != 0 {
+		t.Fatalf("unexpected stats before any cycle: %+v", s)
+	}
+
+	kp := tr.KeyProvider(stubKeyProvider(testCids(10)))
+
+	// first cycle: read the keys one second apart
+	keys, err := kp(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		clock.t = clock.t.Add(time.Second)
+		<-keys
+	}
+	// make sure the count of the last key went through
+	waitProvided(t, tr, 4)
+
+	s, _ = tr.Stats()
+	if !s.Running || s.Rate != 1 || s.Remaining != 0 || s.CycleEstimate != 4*time.Second {
+		t.Fatalf("unexpected stats during the first cycle: %+v", s)
+	}
+
+	for i := 4; i < 10; i++ {
+		clock.t = clock.t.Add(time.Second)
+		<-keys
+	}
+	if _, ok := <-keys; ok {
+		t.Fatal("expected the keys to be closed")
+	}
+	waitStopped(t, tr)
+
+	s, _ = tr.Stats()
+	if s.Running || s.Provided != 10 || s.LastCycle != 10 || s.LastCycleDuration != 10*time.Second {
+		t.Fatalf("unexpected stats after the first cycle: %+v", s)
+	}
+	if s.Rate != 1 || s.CycleEstimate != 10*time.Second {
+		t.Fatalf("expected the rate of the last cycle, got %+v", s)
+	}
+
+	// second cycle, twice as fast
+	keys, err = kp(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		clock.t = clock.t.Add(500 * time.Millisecond)
+		<-keys
+	}
+	waitProvided(t, tr, 4)
+
+	s, _ = tr.Stats()
+	if !s.Running || s.Rate != 2 || s.Remaining != 6 || s.CycleEstimate != 5*time.Second {
+		t.Fatalf("unexpected stats during the second cycle: %+v", s)
+	}
+}
+
+func TestTrackerCanceledCycle(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	tr := New(nil)
+	tr.now = clock.now
+
+	ctx, cancel := context.WithCancel(context.Background())
+	keys, err := tr.KeyProvider(stubKeyProvider(testCids(5)))(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.t = clock.t.Add(time.Second)
+	<-keys
+	cancel()
+	for range keys {
+	}
+	waitStopped(t, tr)
+
+	s, _ := tr.Stats()
+	if s.LastCycle != 0 || s.LastCycleDuration != 0 {
+		t.Fatalf("a canceled cycle should not count as complete: %+v", s)
+	}
+}
+
+func TestDatastoreQueueLen(t *testing.T) {
+	ds := datastore.NewMapDatastore()
+	for i, c := range testCids(3) {
+		k := datastore.NewKey("/provider-v1/queue").ChildString(string(rune('a' + i)))
+		if err := ds.Put(k, c.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ds.Put(datastore.NewKey("/other/key"), []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := DatastoreQueueLen(ds, datastore.NewKey("/provider-v1/queue"))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 queued CIDs, got %d", n)
+	}
+}
+
+func waitProvided(t *testing.T, tr *Tracker, n int) {
+	t.Helper()
+	waitFor(t, func() bool {
+		s, _ := tr.Stats()
+		return s.Provided == n
+	})
+}
+
+func waitStopped(t *testing.T, tr *Tracker) {
+	t.Helper()
+	waitFor(t, func() bool {
+		s, _ := tr.Stats()
+		return !s.Running
+	})
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out")
+}
@@ -229,7 +229,7 @@ test_expect_success "can read block with different hash" '
 #
 
 test_expect_success "'ipfs block stat' with nothing from stdin doesn't crash" '
-  test_expect_code 1 ipfs block stat < /dev/null 2> stat_out
+  test_expect_code 2 ipfs block stat < /dev/null 2> stat_out
 '
 
 test_expect_success "no panic in output" '
@@ -139,7 +139,7 @@ test_expect_success "resolve output looks good" '
 # test publishing nothing
 
 test_expect_success "'ipfs name publish' fails" '
-  printf '' | test_expect_code 1 ipfs name publish --allow-offline  >publish_out 2>&1
+  printf '' | test_expect_code 2 ipfs name publish --allow-offline  >publish_out 2>&1
 '
 
 test_expect_success "publish output has the correct error" '
@@ -147,7 +147,7 @@ test_expect_success "publish output has the correct error" '
 '
 
 test_expect_success "'ipfs name publish' fails" '
-  printf '' | test_expect_code 1 ipfs name publish -Q --allow-offline  >publish_out 2>&1
+  printf '' | test_expect_code 2 ipfs name publish -Q --allow-offline  >publish_out 2>&1
 '
 
 test_expect_success "publish output has the correct error" '
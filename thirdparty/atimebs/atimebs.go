@@ -0,0 +1,168 @@
+// Package atimebs records when the blocks of a blockstore were last
+// accessed, to find the blocks of a cache not used anymore.
+package atimebs
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	dshelp "github.com/ipfs/go-ipfs-ds-help"
+	logging "github.com/ipfs/go-log"
+)
+
+var log = logging.Logger("atimebs")
+
+// keyPrefix is where the access times are kept, by block key.
+var keyPrefix = datastore.NewKey("/local/blockatime")
+
+// Resolution is how often the access time of a block is updated: accessing
+// a block again within Resolution of the recorded time is not recorded, so
+// reading hot blocks doesn't write to the datastore every time.
+const Resolution = time.Hour
+
+// recentSize is the number of recorded access times remembered in memory.
+const recentSize = 4096
+
+// Blockstore is a blockstore recording the time its blocks are put or read.
+type Blockstore struct {
+	bstore.Blockstore
+
+	ds  datastore.Datastore
+	now func() time.Time
+	// recent are the last recorded access times, to skip the datastore for
+	// blocks accessed within Resolution
+	recent *lru.Cache
+}
+
+// New returns bs recording the access times of its blocks in ds.
+func New(bs bstore.Blockstore, ds datastore.Datastore) *Blockstore {
+	recent, _ := lru.New(recentSize)
+	return &Blockstore{
+		Blockstore: bs,
+		ds:         ds,
+		now:        time.Now,
+		recent:     recent,
+	}
+}
+
+func (bs *Blockstore) Get(c cid.Cid) (blocks.Block, error) {
+	b, err := bs.Blockstore.Get(c)
+	if err == nil {
+		bs.touch(c)
+	}
+	return b, err
+}
+
+func (bs *Blockstore) Put(b blocks.Block) error {
+	if err := bs.Blockstore.Put(b); err != nil {
+		return err
+	}
+	bs.touch(b.Cid())
+	return nil
+}
+
+func (bs *Blockstore) PutMany(blks []blocks.Block) error {
+	if err := bs.Blockstore.PutMany(blks); err != nil {
+		return err
+	}
+	for _, b := range blks {
+		bs.touch(b.Cid())
+	}
+	return nil
+}
+
+func (bs *Blockstore) DeleteBlock(c cid.Cid) error {
+	if err := bs.Blockstore.DeleteBlock(c); err != nil {
+		return err
+	}
+	bs.recent.Remove(c)
+	if err := bs.ds.Delete(accessKey(c)); err != nil && err != datastore.ErrNotFound {
+		log.Warnf("removing the access time of %s: %s", c, err)
+	}
+	return nil
+}
+
+// touch records c was accessed now. Failing to record it doesn't fail the
+// access.
+func (bs *Blockstore) touch(c cid.Cid) {
+	now := bs.now()
+	if last, ok := bs.recent.Get(c); ok && now.Sub(last.(time.Time)) < Resolution {
+		return
+	}
+	if err := Record(bs.ds, c, now); err != nil {
+		log.Warnf("recording the access time of %s: %s", c, err)
+		return
+	}
+	bs.recent.Add(c, now)
+}
+
+func accessKey(c cid.Cid) datastore.Key {
+	return keyPrefix.Child(dshelp.CidToDsKey(c))
+}
+
+// Record records c was accessed at t in ds.
+func Record(ds datastore.Datastore, c cid.Cid, t time.Time) error {
+	return ds.Put(accessKey(c), []byte(strconv.FormatInt(t.Unix(), 10)))
+}
+
+// AccessTime returns the last recorded access time of c in ds, or false if
+// none was recorded.
+func AccessTime(ds datastore.Datastore, c cid.Cid) (time.Time, bool, error) {
+	b, err := ds.Get(accessKey(c))
+	if err == datastore.ErrNotFound {
+		return time.Time{}, false, nil
+	} else if err != nil {
+		return time.Time{}, false, err
+	}
+	secs, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(secs, 0), true, nil
+}
+
+// ColdBlock is a block not accessed since some time.
+type ColdBlock struct {
+	Cid cid.Cid
+	// LastAccess is zero if no access was ever recorded, for the blocks
+	// stored before the access times were tracked.
+	LastAccess time.Time
+}
+
+// Cold returns the blocks of bs not accessed since before according to the
+// access times recorded in ds, the least recently accessed first.
+func Cold(ctx context.Context, bs bstore.Blockstore, ds datastore.Datastore, before time.Time) ([]ColdBlock, error) {
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cold []ColdBlock
+	for c := range keys {
+		t, _, err := AccessTime(ds, c)
+		if err != nil {
+			return nil, err
+		}
+		if t.Before(before) {
+			cold = append(cold, ColdBlock{Cid: c, LastAccess: t})
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	sort.Slice(cold, func(i, j int) bool {
+		if !cold[i].LastAccess.Equal(cold[j].LastAccess) {
+			return cold[i].LastAccess.Before(cold[j].LastAccess)
+		}
+		return cold[i].Cid.KeyString() < cold[j].Cid.KeyString()
+	})
+	return cold, nil
+}
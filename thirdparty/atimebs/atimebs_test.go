@@ -0,0 +1,100 @@
+package atimebs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	datastore "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+)
+
+func TestAccessTimes(t *testing.T) {
+	ds := syncds.MutexWrap(datastore.NewMapDatastore())
+	bs := New(bstore.NewBlockstore(ds), ds)
+	now := time.Unix(1600000000, 0)
+	bs.now = func() time.Time { return now }
+
+	b := blocks.NewBlock([]byte("accessed"))
+	if err := bs.Put(b); err != nil {
+		t.Fatal(err)
+	}
+	if at, ok, err := AccessTime(ds, b.Cid()); err != nil || !ok || !at.Equal(now) {
+		t.Fatalf("expected the put to be recorded at %s, got %s (%t, %v)", now, at, ok, err)
+	}
+
+	// reads within Resolution are not recorded again
+	put := now
+	now = now.Add(Resolution / 2)
+	if _, err := bs.Get(b.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	if at, _, _ := AccessTime(ds, b.Cid()); !at.Equal(put) {
+		t.Fatalf("expected the access time to stay %s, got %s", put, at)
+	}
+	now = now.Add(Resolution)
+	if _, err := bs.Get(b.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	if at, _, _ := AccessTime(ds, b.Cid()); !at.Equal(now) {
+		t.Fatalf("expected the access time to be updated to %s, got %s", now, at)
+	}
+
+	if err := bs.DeleteBlock(b.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := AccessTime(ds, b.Cid()); err != nil || ok {
+		t.Fatalf("expected the access time to be removed with the block (%v)", err)
+	}
+}
+
+func TestCold(t *testing.T) {
+	ctx := context.Background()
+	ds := syncds.MutexWrap(datastore.NewMapDatastore())
+	bs := bstore.NewBlockstore(ds)
+	now := time.Unix(1600000000, 0)
+	day := 24 * time.Hour
+
+	put := func(data string, age time.Duration) blocks.Block {
+		b := blocks.NewBlock([]byte(data))
+		if err := bs.Put(b); err != nil {
+			t.Fatal(err)
+		}
+		if age >= 0 {
+			if err := Record(ds, b.Cid(), now.Add(-age)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return b
+	}
+	put("hot", day)
+	month := put("month", 30*day)
+	week := put("week", 8*day)
+	untracked := put("untracked", -1)
+
+	cold, err := Cold(ctx, bs, ds, now.Add(-7*day))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cold) != 3 {
+		t.Fatalf("expected 3 cold blocks, got %v", cold)
+	}
+	for i, b := range []blocks.Block{untracked, month, week} {
+		if cold[i].Cid != b.Cid() {
+			t.Errorf("expected %s at %d, got %s", b.Cid(), i, cold[i].Cid)
+		}
+	}
+	if !cold[0].LastAccess.IsZero() || !cold[1].LastAccess.Equal(now.Add(-30*day)) {
+		t.Errorf("unexpected access times %v", cold)
+	}
+
+	cold, err = Cold(ctx, bs, ds, now.Add(-10*day))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cold) != 2 || cold[1].Cid != month.Cid() {
+		t.Fatalf("expected the untracked and month old blocks, got %v", cold)
+	}
+}